@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"gin/internal/config"
+	"gin/internal/database"
+
+	"github.com/spf13/cobra"
+)
+
+// MigrateCmd 定义migrate子命令
+var MigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "管理数据库迁移",
+	Long: `按版本执行或回滚 SQL 迁移文件。
+
+迁移文件位于 internal/database/migrations/<driver>/ 目录下，
+命名格式为 <版本>_<名称>.up.sql 与 <版本>_<名称>.down.sql。`,
+}
+
+var (
+	migrateSteps int
+	migrateDir   string
+)
+
+func init() {
+	migrateDownCmd.Flags().IntVarP(&migrateSteps, "steps", "n", 1, "回滚的迁移数量")
+	migrateCreateCmd.Flags().StringVar(&migrateDir, "dir", database.MigrationsDir, "迁移文件目录")
+
+	MigrateCmd.AddCommand(migrateUpCmd)
+	MigrateCmd.AddCommand(migrateDownCmd)
+	MigrateCmd.AddCommand(migrateStatusCmd)
+	MigrateCmd.AddCommand(migrateCreateCmd)
+}
+
+// migrateUpCmd 执行所有未执行的迁移
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "执行所有未执行的迁移",
+	Run: func(cmd *cobra.Command, args []string) {
+		migrator, closeDB := openMigrator()
+		defer closeDB()
+
+		executed, err := migrator.Up(context.Background())
+		for _, m := range executed {
+			fmt.Printf("已执行: %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("迁移失败: %v", err)
+		}
+		if len(executed) == 0 {
+			fmt.Println("没有需要执行的迁移")
+		}
+	},
+}
+
+// migrateDownCmd 回滚最近的迁移
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "回滚最近执行的迁移",
+	Run: func(cmd *cobra.Command, args []string) {
+		migrator, closeDB := openMigrator()
+		defer closeDB()
+
+		rolledBack, err := migrator.Down(context.Background(), migrateSteps)
+		for _, m := range rolledBack {
+			fmt.Printf("已回滚: %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("回滚失败: %v", err)
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+	},
+}
+
+// migrateStatusCmd 查看迁移状态
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "查看迁移状态",
+	Run: func(cmd *cobra.Command, args []string) {
+		migrator, closeDB := openMigrator()
+		defer closeDB()
+
+		statuses, err := migrator.Status(context.Background())
+		if err != nil {
+			log.Fatalf("查询迁移状态失败: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", "-"
+			if s.Applied {
+				state = "applied"
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state = "modified"
+			}
+			if s.Missing {
+				state = "missing"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		w.Flush()
+	},
+}
+
+// migrateCreateCmd 生成新的迁移文件
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "为所有支持的驱动生成新的迁移文件",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		created, err := database.CreateMigration(migrateDir, args[0], database.SupportedDrivers())
+		for _, path := range created {
+			fmt.Printf("已创建: %s\n", path)
+		}
+		if err != nil {
+			log.Fatalf("生成迁移文件失败: %v", err)
+		}
+	},
+}
+
+// openMigrator 根据配置连接数据库并创建迁移执行器
+func openMigrator() (*database.Migrator, func()) {
+	cfg := config.LoadConfig()
+
+	db, err := database.InitDB(cfg.Database.Driver, cfg.Database.DSN)
+	if err != nil {
+		log.Fatalf("数据库初始化失败: %v", err)
+	}
+
+	return database.NewMigrator(db, cfg.Database.Driver), func() {
+		if err := db.Close(); err != nil {
+			log.Printf("关闭数据库连接失败: %v", err)
+		}
+	}
+}
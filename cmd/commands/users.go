@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/database"
+	"gin/internal/models"
+	"gin/internal/repository"
+	"gin/internal/service"
+
+	"github.com/spf13/cobra"
+)
+
+// UsersCmd 定义users子命令
+var UsersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "批量导入、导出用户",
+	Long: `直接连接配置中的数据库，以管理员身份批量导入或导出用户。
+
+支持 CSV（第一行为表头）和 NDJSON（每行一个 JSON 对象）两种格式，
+校验规则与 POST /api/v1/users/import 相同。`,
+}
+
+var (
+	usersFile    string
+	usersOutput  string
+	usersFormat  string
+	usersMode    string
+	usersDryRun  bool
+	usersRole    string
+	usersDeleted bool
+)
+
+func init() {
+	usersImportCmd.Flags().StringVarP(&usersFile, "file", "f", "-", "导入文件，- 表示标准输入")
+	usersImportCmd.Flags().StringVar(&usersFormat, "format", "", "文件格式：csv、ndjson（默认按扩展名识别）")
+	usersImportCmd.Flags().StringVar(&usersMode, "mode", models.ImportModeAtomic, "导入模式：atomic（任一行失败时不导入）、best_effort（跳过失败的行）")
+	usersImportCmd.Flags().BoolVar(&usersDryRun, "dry-run", false, "只校验不写入")
+
+	usersExportCmd.Flags().StringVarP(&usersOutput, "output", "o", "-", "导出文件，- 表示标准输出")
+	usersExportCmd.Flags().StringVar(&usersFormat, "format", "", "文件格式：csv、ndjson（默认按扩展名识别，无法识别时为 csv）")
+	usersExportCmd.Flags().StringVar(&usersRole, "role", "", "只导出指定角色的用户")
+	usersExportCmd.Flags().BoolVar(&usersDeleted, "deleted", false, "只导出已删除的用户")
+
+	UsersCmd.AddCommand(usersImportCmd)
+	UsersCmd.AddCommand(usersExportCmd)
+}
+
+// usersImportCmd 批量导入用户
+// 有失败的行时返回错误，由 Execute 以非零状态码退出
+var usersImportCmd = &cobra.Command{
+	Use:           "import",
+	Short:         "从 CSV 或 NDJSON 文件批量导入用户",
+	SilenceUsage:  true,
+	SilenceErrors: true, // 错误由 Execute 输出
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := fileFormat(usersFormat, usersFile)
+		if format == "" {
+			return fmt.Errorf("无法识别文件格式，请通过 --format 指定 csv 或 ndjson")
+		}
+
+		var in io.Reader = os.Stdin
+		if usersFile != "-" {
+			file, err := os.Open(usersFile)
+			if err != nil {
+				return fmt.Errorf("打开导入文件失败: %w", err)
+			}
+			defer file.Close()
+			in = file
+		}
+
+		ctx, userService, closeDB, err := openUserService()
+		if err != nil {
+			return err
+		}
+		result, err := userService.ImportUsers(ctx, in, models.ImportUsersOptions{
+			Format: format,
+			Mode:   usersMode,
+			DryRun: usersDryRun,
+		})
+		closeDB()
+		if err != nil {
+			return fmt.Errorf("导入失败: %w", err)
+		}
+
+		if result.Failed > 0 {
+			w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "LINE\tEMAIL\tERROR")
+			for _, row := range result.Rows {
+				if row.Status == models.ImportRowFailed {
+					fmt.Fprintf(w, "%d\t%s\t%s\n", row.Line, row.Email, row.Error)
+				}
+			}
+			w.Flush()
+		}
+
+		switch {
+		case result.DryRun:
+			fmt.Printf("试运行：共 %d 行，校验通过 %d 行，失败 %d 行，未写入任何用户\n", result.Total, result.Total-result.Failed, result.Failed)
+		case !result.Committed && result.Failed > 0:
+			fmt.Printf("共 %d 行，失败 %d 行，未导入任何用户\n", result.Total, result.Failed)
+		default:
+			fmt.Printf("共 %d 行，已导入 %d 行，失败 %d 行\n", result.Total, result.Created, result.Failed)
+		}
+		if result.Failed > 0 {
+			return fmt.Errorf("%d 行导入失败", result.Failed)
+		}
+		return nil
+	},
+}
+
+// usersExportCmd 批量导出用户
+var usersExportCmd = &cobra.Command{
+	Use:           "export",
+	Short:         "以 CSV 或 NDJSON 格式导出用户",
+	SilenceUsage:  true,
+	SilenceErrors: true, // 错误由 Execute 输出
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := fileFormat(usersFormat, usersOutput)
+		if format == "" {
+			format = models.UserFileFormatCSV
+		}
+
+		var out io.Writer = os.Stdout
+		if usersOutput != "-" {
+			file, err := os.Create(usersOutput)
+			if err != nil {
+				return fmt.Errorf("创建导出文件失败: %w", err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		ctx, userService, closeDB, err := openUserService()
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		filter := models.UserFilter{Role: usersRole, Deleted: usersDeleted}
+		if err := userService.ExportUsers(ctx, out, format, filter); err != nil {
+			return fmt.Errorf("导出失败: %w", err)
+		}
+		return nil
+	},
+}
+
+// fileFormat 返回指定的文件格式，未指定时按文件扩展名识别
+func fileFormat(format, path string) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return models.UserFileFormatCSV
+	case ".ndjson", ".jsonl":
+		return models.UserFileFormatNDJSON
+	}
+	return ""
+}
+
+// openUserService 根据配置连接数据库并创建用户服务，返回以管理员身份执行的 context
+func openUserService() (context.Context, service.UserService, func(), error) {
+	cfg := config.LoadConfig()
+
+	db, err := database.InitDB(cfg.Database.Driver, cfg.Database.DSN,
+		database.WithQueryTimeout(time.Duration(cfg.Database.QueryTimeout)*time.Second),
+	)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("数据库初始化失败: %w", err)
+	}
+	closeDB := func() {
+		if err := db.Close(); err != nil {
+			log.Printf("关闭数据库连接失败: %v", err)
+		}
+	}
+
+	passwordPolicy, err := auth.LoadPasswordPolicy(&cfg.Password)
+	if err != nil {
+		closeDB()
+		return nil, nil, nil, fmt.Errorf("加载密码策略失败: %w", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	userService := service.NewUserService(userRepo,
+		service.WithTxManager(database.NewTxManager(db)),
+		service.WithPasswordPolicy(passwordPolicy),
+		service.WithRoleRepository(roleRepo),
+		// 命令行导入导出同样记录审计日志（操作者ID为 0）
+		service.WithAuditRecorder(service.NewAuditService(repository.NewAuditLogRepository(db))),
+	)
+
+	// 导出已删除的用户需要 users:restore 权限，按数据库中的授权关系鉴权
+	ctx := context.Background()
+	if err := service.NewRoleService(roleRepo, userRepo, nil).ReloadPermissions(ctx); err != nil {
+		closeDB()
+		return nil, nil, nil, fmt.Errorf("加载角色权限失败: %w", err)
+	}
+
+	// 命令行由有数据库访问权限的运维人员执行，视为超级管理员
+	return auth.WithPrincipal(ctx, auth.Principal{Role: auth.RoleAdmin}), userService, closeDB, nil
+}
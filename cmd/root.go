@@ -15,6 +15,8 @@ var rootCmd = &cobra.Command{
 	
 使用子命令来区分不同功能：
   - server: 运行Gin API服务
+  - migrate: 管理数据库迁移
+  - users: 批量导入、导出用户
   - ds: 运行数据结构示例
   - examples: 运行Go语法示例`,
 }
@@ -23,6 +25,8 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	// 添加子命令
 	rootCmd.AddCommand(commands.ServerCmd)
+	rootCmd.AddCommand(commands.MigrateCmd)
+	rootCmd.AddCommand(commands.UsersCmd)
 	//rootCmd.AddCommand(commands.DSCmd)
 	//rootCmd.AddCommand(commands.ExamplesCmd)
 
@@ -3,22 +3,29 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	_ "net/http/pprof" // 启用 pprof
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // 内置时区数据，校验用户资料中的时区不依赖系统的 zoneinfo
 
 	"gin/internal/api"
 	"gin/internal/api/handlers"
+	"gin/internal/api/middleware"
+	"gin/internal/auth"
 	"gin/internal/config"
 	"gin/internal/database"
 	"gin/internal/di"
+	"gin/internal/imaging"
 	"gin/internal/logger"
 	"gin/internal/metrics"
+	"gin/internal/notify"
 	"gin/internal/repository"
 	"gin/internal/service"
+	"gin/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -51,7 +58,17 @@ func Main() {
 	var db database.DB
 	if cfg.Database.DSN != "" && cfg.Database.DSN != "user:password@tcp(localhost:3306)/dbname" {
 		var err error
-		db, err = database.InitDB(cfg.Database.Driver, cfg.Database.DSN)
+		db, err = database.InitDB(cfg.Database.Driver, cfg.Database.DSN,
+			database.WithQueryTimeout(time.Duration(cfg.Database.QueryTimeout)*time.Second),
+			database.WithSlowQueryThreshold(time.Duration(cfg.Database.SlowQueryThreshold)*time.Millisecond),
+			database.WithPool(database.PoolConfig{
+				MaxOpenConns:    cfg.Database.MaxOpenConns,
+				MaxIdleConns:    cfg.Database.MaxIdleConns,
+				ConnMaxLifetime: time.Duration(cfg.Database.ConnMaxLifetime) * time.Second,
+				ConnMaxIdleTime: time.Duration(cfg.Database.ConnMaxIdleTime) * time.Second,
+			}),
+			database.WithReplicas(cfg.Database.Replicas, time.Duration(cfg.Database.ReplicaCheckInterval)*time.Second),
+		)
 		if err != nil {
 			log.Error("数据库初始化失败", zap.Error(err))
 		} else {
@@ -62,33 +79,138 @@ func Main() {
 				}
 			}(db)
 
-			// 初始化数据库表结构
-			if err := database.InitSchema(db); err != nil {
-				log.Error("数据库表初始化失败", zap.Error(err))
-			} else {
-				log.Info("数据库表初始化成功")
+			// 执行数据库迁移
+			if cfg.Database.AutoMigrate {
+				executed, err := database.NewMigrator(db, cfg.Database.Driver).Up(context.Background())
+				if err != nil {
+					log.Error("数据库迁移失败", zap.Error(err))
+				} else {
+					log.Info("数据库迁移完成", zap.Int("executed", len(executed)))
+				}
+			}
+
+			// 导出连接池指标
+			if err := metrics.RegisterDBStats(database.Pools(db)); err != nil {
+				log.Error("注册数据库连接池指标失败", zap.Error(err))
 			}
 
 			// 注册到DI容器
 			di.GetContainer().Register("db", db)
-			log.Info("数据库连接成功", zap.String("driver", cfg.Database.Driver), zap.String("dsn", cfg.Database.DSN))
+			log.Info("数据库连接成功", zap.String("driver", cfg.Database.Driver), zap.String("dsn", cfg.Database.DSN), zap.Int("replicas", len(cfg.Database.Replicas)))
 		}
 	}
 
 	// 4. 初始化三层架构（如果数据库连接成功）
 	var router *gin.Engine
+	var userService service.UserService
+	var revocationStore repository.TokenRevocationStore
+	var resetTokenRepo repository.PasswordResetTokenRepository
+	var loginAttemptStore repository.LoginAttemptStore
+	var mfaChallengeRepo repository.MFAChallengeRepository
+	var roleService service.RoleService
+	var uploadService service.UploadService
 	if db != nil {
+		// 加载JWT签名密钥（签发与验签共用）
+		jwtConfig, err := auth.LoadJWTConfig(&cfg.JWT)
+		if err != nil {
+			log.Fatal("加载JWT密钥失败", zap.Error(err))
+		}
+
+		// 加载密码策略与通知方式
+		passwordPolicy, err := auth.LoadPasswordPolicy(&cfg.Password)
+		if err != nil {
+			log.Fatal("加载密码策略失败", zap.Error(err))
+		}
+		notifier, err := notify.New(&cfg.Notifier)
+		if err != nil {
+			log.Fatal("初始化通知失败", zap.Error(err))
+		}
+		fileStorage, err := storage.New(&cfg.Storage)
+		if err != nil {
+			log.Fatal("初始化文件存储失败", zap.Error(err))
+		}
+		if cfg.Storage.SignedURL.Secret == "" {
+			log.Warn("未配置 storage.signed_url.secret，使用随机密钥，重启后已签发的文件签名链接失效")
+		}
+		urlSigner, err := auth.NewHMACSigner(cfg.Storage.SignedURL.Secret)
+		if err != nil {
+			log.Fatal("初始化签名链接失败", zap.Error(err))
+		}
+		imageProcessor, err := imaging.New(cfg.Images)
+		if err != nil {
+			log.Fatal("初始化图片处理失败", zap.Error(err))
+		}
+
 		// 创建 Repository 层
 		userRepo := repository.NewUserRepository(db)
+		refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+		revocationStore = repository.NewTokenRevocationStore(db)
+		roleRepo := repository.NewRoleRepository(db)
+		resetTokenRepo = repository.NewPasswordResetTokenRepository(db)
+		loginAttemptStore = repository.NewLoginAttemptStore(db)
+		mfaChallengeRepo = repository.NewMFAChallengeRepository(db)
+		fileRepo := repository.NewFileRepository(db)
 
 		// 创建 Service 层
-		userService := service.NewUserService(userRepo)
+		auditService := service.NewAuditService(repository.NewAuditLogRepository(db))
+		userService = service.NewUserService(userRepo,
+			service.WithTxManager(database.NewTxManager(db)),
+			service.WithRefreshTokenRepository(refreshTokenRepo),
+			service.WithTokenRevocationStore(revocationStore),
+			service.WithJWTConfig(jwtConfig),
+			service.WithPasswordPolicy(passwordPolicy),
+			service.WithPasswordResetTokenRepository(resetTokenRepo),
+			service.WithNotifier(notifier),
+			service.WithLoginAttemptStore(loginAttemptStore),
+			service.WithLoginConfig(cfg.Login),
+			service.WithMFARepository(repository.NewMFARepository(db)),
+			service.WithMFAChallengeRepository(mfaChallengeRepo),
+			service.WithMFAConfig(cfg.MFA),
+			service.WithRoleRepository(roleRepo),
+			service.WithUserProfileRepository(repository.NewUserProfileRepository(db)),
+			service.WithFileRepository(fileRepo),
+			service.WithFileStorage(fileStorage),
+			service.WithAuditRecorder(auditService),
+		)
+		roleService = service.NewRoleService(roleRepo, userRepo, revocationStore, service.WithRoleAuditRecorder(auditService))
+		apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), userRepo, cfg.APIKey, service.WithAPIKeyAuditRecorder(auditService))
+		fileService := service.NewFileService(fileRepo, fileStorage,
+			service.WithFileTxManager(database.NewTxManager(db)),
+			service.WithFileAuditRecorder(auditService),
+			service.WithFileURLSigner(urlSigner, cfg.Storage.SignedURL),
+			service.WithFileImageProcessor(imageProcessor),
+		)
+		uploadService = service.NewUploadService(storage.NewPartialStore(cfg.Storage.Tus.Dir), fileService, cfg.Storage.Tus)
+
+		// 加载角色权限到内存缓存
+		if err := roleService.ReloadPermissions(context.Background()); err != nil {
+			log.Error("加载角色权限失败", zap.Error(err))
+		}
 
 		// 创建 Handler 层
 		userHandler := handlers.NewUserHandler(userService)
+		roleHandler := handlers.NewRoleHandler(roleService)
+		apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+		auditHandler := handlers.NewAuditHandler(auditService)
+		fileHandler := handlers.NewFileHandler(fileService)
+		uploadHandler := handlers.NewUploadHandler(uploadService, time.Duration(cfg.Storage.Tus.ChunkTimeout)*time.Second)
 
 		// 设置路由（带三层架构）
-		router = api.SetupRouterWithDI(userHandler)
+		router = api.SetupRouterWithDI(api.RouterDeps{
+			UserHandler:   userHandler,
+			RoleHandler:   roleHandler,
+			APIKeyHandler: apiKeyHandler,
+			AuditHandler:  auditHandler,
+			FileHandler:   fileHandler,
+			UploadHandler: uploadHandler,
+			// 先尝试 Authorization: Bearer <jwt>，再尝试 X-API-Key
+			AuthMiddleware: middleware.AuthMiddleware(
+				middleware.NewJWTAuthenticator(jwtConfig, revocationStore),
+				middleware.NewAPIKeyAuthenticator(apiKeyService),
+			),
+			JWTConfig:     jwtConfig,
+			AuditRecorder: auditService,
+		})
 	} else {
 		// 使用原有路由（无数据库）
 		router = api.SetupRouter()
@@ -112,11 +234,14 @@ func Main() {
 
 	// 为每个服务器启动一个goroutine
 	for _, s := range servers {
+		// 请求 context 派生自 baseCtx，优雅关闭超时后取消仍在处理的请求及其中的SQL
+		baseCtx, cancelRequests := context.WithCancel(context.Background())
 		srv := &http.Server{
 			Addr:         s.addr,
 			Handler:      s.handler,
 			ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
 			WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
+			BaseContext:  func(net.Listener) context.Context { return baseCtx },
 		}
 
 		// 使用局部变量保存当前服务器地址，避免闭包问题
@@ -136,10 +261,152 @@ func Main() {
 			log.Info("正在关闭服务器", zap.String("addr", addr))
 			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
+			defer cancelRequests()
 			return srv.Shutdown(shutdownCtx)
 		})
 	}
 
+	// 定期清理过期的令牌撤销记录
+	if revocationStore != nil {
+		g.Go(func() error {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if purged, err := revocationStore.PurgeExpired(ctx); err != nil {
+						log.Error("清理令牌撤销记录失败", zap.Error(err))
+					} else if purged > 0 {
+						log.Info("已清理过期的令牌撤销记录", zap.Int64("purged", purged))
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+	}
+
+	// 定期清理过期的重置密码令牌
+	if resetTokenRepo != nil {
+		g.Go(func() error {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if purged, err := resetTokenRepo.PurgeExpired(ctx); err != nil {
+						log.Error("清理重置密码令牌失败", zap.Error(err))
+					} else if purged > 0 {
+						log.Info("已清理过期的重置密码令牌", zap.Int64("purged", purged))
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+	}
+
+	// 定期清理过期的两步验证令牌
+	if mfaChallengeRepo != nil {
+		g.Go(func() error {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if purged, err := mfaChallengeRepo.PurgeExpired(ctx); err != nil {
+						log.Error("清理两步验证令牌失败", zap.Error(err))
+					} else if purged > 0 {
+						log.Info("已清理过期的两步验证令牌", zap.Int64("purged", purged))
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+	}
+
+	// 定期清理已过期的登录失败记录
+	if loginAttemptStore != nil {
+		// 超过失败统计窗口且不在锁定期内的记录已不再生效
+		retention := time.Duration(max(cfg.Login.FailureWindow, cfg.Login.LockoutDuration)) * time.Minute
+		g.Go(func() error {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if purged, err := loginAttemptStore.PurgeStale(ctx, time.Now().Add(-retention)); err != nil {
+						log.Error("清理登录失败记录失败", zap.Error(err))
+					} else if purged > 0 {
+						log.Info("已清理过期的登录失败记录", zap.Int64("purged", purged))
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+	}
+
+	// 定期清理过期的断点续传上传
+	if uploadService != nil {
+		g.Go(func() error {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if purged, err := uploadService.PurgeExpired(ctx); err != nil {
+						log.Error("清理过期的断点续传上传失败", zap.Error(err))
+					} else if purged > 0 {
+						log.Info("已清理过期的断点续传上传", zap.Int64("purged", purged))
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+	}
+
+	// 定期彻底删除超过保留期的已删除用户
+	if userService != nil && cfg.Users.DeletedRetention > 0 {
+		retention := cfg.Users.DeletedRetention
+		g.Go(func() error {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if purged, err := userService.PurgeDeletedUsers(ctx, time.Now().AddDate(0, 0, -retention)); err != nil {
+						log.Error("清理已删除用户失败", zap.Int64("purged", purged), zap.Error(err))
+					} else if purged > 0 {
+						log.Info("已清理过期的已删除用户", zap.Int64("purged", purged))
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+	}
+
+	// 定期重新加载角色权限（多实例部署时同步其他实例的角色变更）
+	if roleService != nil {
+		g.Go(func() error {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := roleService.ReloadPermissions(ctx); err != nil {
+						log.Error("加载角色权限失败", zap.Error(err))
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		})
+	}
+
 	// 监听中断信号
 	g.Go(func() error {
 		c := make(chan os.Signal, 1)
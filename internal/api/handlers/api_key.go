@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"gin/internal/api/response"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/models"
+	"gin/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler API密钥处理器
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+// NewAPIKeyHandler 创建API密钥处理器
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey 创建API密钥
+// @Summary 创建API密钥
+// @Description 为指定用户创建机器客户端使用的API密钥（请求头 X-API-Key），密钥明文只在响应中返回一次
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param api_key body models.CreateAPIKeyRequest true "密钥信息"
+// @Success 201 {object} response.Response{data=models.CreateAPIKeyResponse} "创建成功"
+// @Failure 400 {object} response.Response "请求参数错误或权限范围无效"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 404 {object} response.Response "用户不存在"
+// @Router /api/v1/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		var req models.CreateAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		created, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), adminID, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Created(c, i18n.UserMessage(i18n.UserAPIKeyCreateSuccess), created)
+	}
+}
+
+// ListAPIKeys 获取API密钥列表
+// @Summary 获取API密钥列表
+// @Description 列出API密钥（不含密钥明文），可按所属用户过滤
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param user_id query int false "所属用户ID"
+// @Success 200 {object} response.Response{data=[]models.APIKey} "获取成功"
+// @Failure 400 {object} response.Response "无效的用户ID"
+// @Failure 403 {object} response.Response "权限不足"
+// @Router /api/v1/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var userID int64
+		if userIDStr := c.Query("user_id"); userIDStr != "" {
+			id, err := strconv.ParseInt(userIDStr, 10, 64)
+			if err != nil {
+				c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidID), userIDStr), err))
+				return
+			}
+			userID = id
+		}
+
+		keys, err := h.apiKeyService.ListAPIKeys(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserAPIKeyListSuccess), keys)
+	}
+}
+
+// RevokeAPIKey 吊销API密钥
+// @Summary 吊销API密钥
+// @Description 吊销指定的API密钥，吊销后立即失效
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "API密钥ID"
+// @Success 200 {object} response.Response "吊销成功"
+// @Failure 400 {object} response.Response "无效的密钥ID"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 404 {object} response.Response "密钥不存在或已吊销"
+// @Router /api/v1/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidID), idStr), err))
+			return
+		}
+
+		if err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), id); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserAPIKeyRevokeSuccess), nil)
+	}
+}
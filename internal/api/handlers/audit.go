@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"gin/internal/api/response"
+	"gin/internal/i18n"
+	"gin/internal/models"
+	"gin/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 审计日志处理器
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// NewAuditHandler 创建审计日志处理器
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// ListAuditLogs 获取审计日志列表
+// @Summary 获取审计日志列表
+// @Description 分页查询审计日志，支持按操作者、事件类型、结果、操作对象、请求ID、IP 和时间过滤；提供 cursor 时使用游标分页并忽略 offset
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param actor_id query int false "操作者ID（0 表示匿名）"
+// @Param action query string false "事件类型，如 user.update；以 . 结尾时按前缀匹配，如 user."
+// @Param outcome query string false "结果：success、failure、denied"
+// @Param target_type query string false "操作对象类型：user、role、api_key"
+// @Param target_id query string false "操作对象ID"
+// @Param request_id query string false "请求ID"
+// @Param ip query string false "客户端IP"
+// @Param since query string false "不早于（RFC 3339）"
+// @Param until query string false "早于（RFC 3339）"
+// @Param limit query int false "每页条数（默认20，最大100）"
+// @Param offset query int false "偏移量"
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param sort query string false "排序字段：id、created_at，前缀 - 表示倒序（默认 -id）"
+// @Success 200 {object} response.PageResponse{data=[]models.AuditLog} "获取成功"
+// @Failure 400 {object} response.Response "查询参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/audit [get]
+func (h *AuditHandler) ListAuditLogs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ListAuditLogsRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		page, err := h.auditService.ListAuditLogs(c.Request.Context(), &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.SuccessWithPage(c, i18n.UserMessage(i18n.UserAuditListSuccess), page.Items, response.Pagination{
+			Total:      page.Total,
+			Limit:      page.Limit,
+			Offset:     page.Offset,
+			NextCursor: page.NextCursor,
+			HasMore:    page.HasMore,
+		})
+	}
+}
+
+// VerifyAuditChain 校验审计日志哈希链
+// @Summary 校验审计日志哈希链
+// @Description 按写入顺序重新计算每条审计日志的哈希，检查日志是否被修改、删除或插入；valid 为 false 时 broken_id 为第一条校验失败的日志
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=models.AuditVerifyResult} "校验完成"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/audit/verify [get]
+func (h *AuditHandler) VerifyAuditChain() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := h.auditService.VerifyChain(c.Request.Context())
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		msg := i18n.UserMessage(i18n.UserAuditVerifySuccess)
+		if !result.Valid {
+			msg = i18n.UserMessage(i18n.UserAuditVerifyBroken)
+		}
+		response.Success(c, msg, result)
+	}
+}
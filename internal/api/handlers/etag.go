@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gin/internal/errors"
+	"gin/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionETag 根据乐观锁版本号生成强 ETag，例如 "3"
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion 解析 If-Match 请求头中的版本号
+// 没有 If-Match 或为 * 时返回 0（不校验版本）；If-Match 使用强比较，弱 ETag（W/ 前缀）、多个 ETag 或无法识别的格式返回 412
+func ifMatchVersion(c *gin.Context) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	value, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		value, ok = strings.CutSuffix(value, `"`)
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if !ok || err != nil || version <= 0 {
+		return 0, errors.NewPreconditionFailedError(i18n.UserMessage(i18n.UserErrorIfMatch), fmt.Errorf("invalid If-Match: %s", header))
+	}
+	return version, nil
+}
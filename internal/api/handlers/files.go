@@ -1,81 +1,294 @@
 package handlers
 
 import (
+	stderrors "errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
+	"time"
+
+	"gin/internal/api/response"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/models"
+	"gin/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// UploadPageHandler 处理上传页面路由
-func UploadPageHandler() gin.HandlerFunc {
+// multipartOverhead 请求体中 multipart 边界、表单字段等额外内容允许的字节数
+const multipartOverhead = 1 << 20
+
+// FileHandler 文件处理器
+type FileHandler struct {
+	fileService service.FileService
+}
+
+// NewFileHandler 创建文件处理器
+func NewFileHandler(fileService service.FileService) *FileHandler {
+	return &FileHandler{
+		fileService: fileService,
+	}
+}
+
+// UploadFile 上传单个文件
+// @Summary 上传文件
+// @Description 以 multipart/form-data 上传一个文件（字段 file）。文件类型按内容识别，不信任客户端提供的 Content-Type 和扩展名；返回文件ID
+// @Tags files
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param file formData file true "上传的文件"
+// @Success 201 {object} response.Response{data=models.File} "上传成功"
+// @Failure 400 {object} response.Response "没有上传文件或文件为空"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 413 {object} response.Response "文件过大"
+// @Failure 415 {object} response.Response "不支持的文件类型"
+// @Router /api/v1/files [post]
+func (h *FileHandler) UploadFile(policy models.UploadPolicy) gin.HandlerFunc {
+	policy.MaxFiles = 1
 	return func(c *gin.Context) {
-		c.HTML(http.StatusOK, "upload_file.html", nil)
+		files, ok := h.upload(c, "file", policy)
+		if !ok {
+			return
+		}
+		response.Created(c, i18n.UserMessage(i18n.UserFileUploadSuccess), files[0])
 	}
 }
 
-// UploadHandler 处理文件上传路由
-func UploadHandler() gin.HandlerFunc {
+// UploadFiles 批量上传文件
+// @Summary 批量上传文件
+// @Description 以 multipart/form-data 一次上传多个文件（字段 files）。任一文件不符合限制时不保存任何文件；返回的文件顺序与上传顺序一致
+// @Tags files
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param files formData file true "上传的文件（可重复）"
+// @Success 201 {object} response.Response{data=[]models.File} "上传成功"
+// @Failure 400 {object} response.Response "没有上传文件、文件为空或文件数超过限制"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 413 {object} response.Response "文件过大"
+// @Failure 415 {object} response.Response "不支持的文件类型"
+// @Router /api/v1/files/batch [post]
+func (h *FileHandler) UploadFiles(policy models.UploadPolicy) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		file, err := c.FormFile("f1")
-		if err != nil {
-			c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		files, ok := h.upload(c, "files", policy)
+		if !ok {
 			return
 		}
-		dst := fmt.Sprintf("C:/tmp/%s", file.Filename)
-		err = c.SaveUploadedFile(file, dst)
+		response.Created(c, i18n.UserMessage(i18n.UserFileUploadSuccess), files)
+	}
+}
+
+// DownloadFile 下载文件
+// @Summary 下载文件
+// @Description 文件所有者、管理员或拥有 files:read 权限的用户可以下载。支持 Range（包括多个范围）、If-None-Match / If-Modified-Since 和 If-Range。
+// @Description disposition=inline 时在浏览器中直接打开，默认作为附件下载。
+// @Description 图片（JPEG、PNG、GIF、WebP）可以通过 w、h、fit 缩放，或通过 thumbnail 获取配置的缩略图；JPEG 输出 JPEG，其他输出 PNG
+// @Tags files
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Param id path int true "文件ID"
+// @Param disposition query string false "attachment（默认）或 inline"
+// @Param w query int false "图片宽度"
+// @Param h query int false "图片高度"
+// @Param fit query string false "缩放方式：contain（默认）、cover 或 fill"
+// @Param thumbnail query string false "缩略图名称，不能与 w、h、fit 同时使用"
+// @Success 200 {file} file "文件内容"
+// @Success 206 {file} file "部分内容"
+// @Success 304 "未修改"
+// @Failure 400 {object} response.Response "图片变换参数无效或文件不支持变换"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "文件不存在"
+// @Failure 416 "请求的范围无效"
+// @Router /api/v1/files/{id} [get]
+func (h *FileHandler) DownloadFile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := fileID(c)
+		if !ok {
+			return
+		}
+		transform, ok := imageTransform(c)
+		if !ok {
+			return
+		}
+		file, content, err := h.fileService.OpenFile(c.Request.Context(), id, transform)
 		if err != nil {
-			c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+			c.Error(err)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{
-			"message": "upload success",
-		})
+		defer content.Close()
+		serveFile(c, file, content)
 	}
 }
 
-// UploadMultiPageHandler 处理多文件上传页面路由
-func UploadMultiPageHandler() gin.HandlerFunc {
+// CreateSignedURL 生成文件签名链接
+// @Summary 生成文件签名链接
+// @Description 生成有时效的下载链接，持有链接即可下载文件，不需要登录。只能为自己可以下载的文件生成；指定 w、h、fit、thumbnail 时链接只能下载按这些参数变换后的图片
+// @Tags files
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "文件ID"
+// @Param request body models.CreateSignedURLRequest false "有效期和图片变换参数"
+// @Success 201 {object} response.Response{data=models.SignedURL} "生成成功"
+// @Failure 400 {object} response.Response "有效期无效"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "文件不存在"
+// @Router /api/v1/files/{id}/signed-url [post]
+func (h *FileHandler) CreateSignedURL() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.HTML(http.StatusOK, "upload_multi.html", nil)
+		id, ok := fileID(c)
+		if !ok {
+			return
+		}
+		var req models.CreateSignedURLRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !stderrors.Is(err, io.EOF) {
+			c.Error(err)
+			return
+		}
+
+		signature, expiresAt, err := h.fileService.SignFile(c.Request.Context(), id, time.Duration(req.ExpiresIn)*time.Second, req.ImageTransform)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		query := req.ImageTransform.Values()
+		query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+		query.Set("signature", signature)
+		response.Created(c, i18n.UserMessage(i18n.UserFileSignedURLSuccess), &models.SignedURL{
+			URL:       fmt.Sprintf("/api/v1/files/%d/shared?%s", id, query.Encode()),
+			ExpiresAt: expiresAt,
+		})
 	}
 }
 
-// UploadMultiHandler 处理多文件上传路由
-func UploadMultiHandler() gin.HandlerFunc {
+// DownloadSignedFile 通过签名链接下载文件
+// @Summary 通过签名链接下载文件
+// @Description 校验签名和有效期后下载文件，不需要登录；与 /api/v1/files/{id} 一样支持 Range 和条件请求，图片变换参数参与签名，必须与生成链接时指定的一致
+// @Tags files
+// @Produce octet-stream
+// @Param id path int true "文件ID"
+// @Param expires query int true "过期时间（Unix 时间戳）"
+// @Param signature query string true "签名"
+// @Param disposition query string false "attachment（默认）或 inline"
+// @Param w query int false "图片宽度"
+// @Param h query int false "图片高度"
+// @Param fit query string false "缩放方式：contain（默认）、cover 或 fill"
+// @Param thumbnail query string false "缩略图名称"
+// @Success 200 {file} file "文件内容"
+// @Success 206 {file} file "部分内容"
+// @Failure 400 {object} response.Response "图片变换参数无效或文件不支持变换"
+// @Failure 403 {object} response.Response "签名无效（包括图片变换参数与签名时不一致）或链接已过期"
+// @Failure 404 {object} response.Response "文件不存在"
+// @Router /api/v1/files/{id}/shared [get]
+func (h *FileHandler) DownloadSignedFile() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1.解析multiform
-		form, err := c.MultipartForm()
+		id, ok := fileID(c)
+		if !ok {
+			return
+		}
+		expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.Error(errors.NewForbiddenError(i18n.UserMessage(i18n.UserErrorSignedURLInvalid), err))
 			return
 		}
-		// 2. 提取文件数组
-		files := form.File["files"]
-		if len(files) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "no files uploaded"})
+		transform, ok := imageTransform(c)
+		if !ok {
 			return
 		}
-		// 3. 保存文件目录
-		err = os.MkdirAll("C:/tmp/upload", 0755)
+		file, content, err := h.fileService.OpenSignedFile(c.Request.Context(), id, expires, c.Query("signature"), transform)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "make dir error"})
+			c.Error(err)
 			return
 		}
-		var name []string
-		for _, file := range files {
-			dst := filepath.Join("C:/tmp/upload", file.Filename)
-			if err := c.SaveUploadedFile(file, dst); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			name = append(name, file.Filename)
+		defer content.Close()
+		serveFile(c, file, content)
+	}
+}
+
+// fileID 解析路径中的文件ID；失败时已写入错误
+func fileID(c *gin.Context) (int64, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidID), idStr), err))
+		return 0, false
+	}
+	return id, true
+}
+
+// imageTransform 解析查询参数中的图片变换参数；失败时已写入错误
+func imageTransform(c *gin.Context) (models.ImageTransform, bool) {
+	var transform models.ImageTransform
+	if err := c.ShouldBindQuery(&transform); err != nil {
+		// w=abc 等无法转换为数字的参数也按参数错误处理
+		c.Error(errors.NewBadRequestError(i18n.UserMessage(i18n.UserErrorBadRequest), err))
+		return transform, false
+	}
+	return transform, true
+}
+
+// serveFile 输出文件内容
+// Range、If-None-Match、If-Modified-Since、If-Range 由 http.ServeContent 处理，ETag 使用内容的 SHA-256
+func serveFile(c *gin.Context, file *models.File, content io.ReadSeeker) {
+	disposition := "attachment"
+	if c.Query("disposition") == "inline" {
+		disposition = "inline"
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", file.ContentType)
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": file.Name}))
+	header.Set("ETag", `"`+file.Checksum+`"`)
+	header.Set("Cache-Control", "private, no-cache")
+	header.Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, "", file.CreatedAt, content)
+}
+
+// upload 解析表单中 field 字段的文件并保存；失败时已写入错误
+func (h *FileHandler) upload(c *gin.Context, field string, policy models.UploadPolicy) ([]*models.File, bool) {
+	maxFiles := int64(max(policy.MaxFiles, 1))
+	maxBody := policy.MaxSize*maxFiles + multipartOverhead
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if stderrors.As(err, &tooLarge) {
+			c.Error(errors.NewRequestEntityTooLargeError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorUploadTooLarge), maxBody>>20), err))
+			return nil, false
+		}
+		c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorUploadNoFile), field), err))
+		return nil, false
+	}
+	defer form.RemoveAll()
+
+	headers := form.File[field]
+	if len(headers) == 0 {
+		c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorUploadNoFile), field), http.ErrMissingFile))
+		return nil, false
+	}
+
+	uploads := make([]*service.FileUpload, 0, len(headers))
+	for _, header := range headers {
+		f, err := header.Open()
+		if err != nil {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorUploadNoFile), field), err))
+			return nil, false
 		}
-		c.JSON(http.StatusOK, gin.H{
-			"message": fmt.Sprintf("upload success, %d files", len(name)),
-			"name":    name,
-		})
+		defer f.Close()
+		uploads = append(uploads, &service.FileUpload{Name: header.Filename, Size: header.Size, Content: f})
+	}
+
+	files, err := h.fileService.UploadFiles(c.Request.Context(), uploads, policy)
+	if err != nil {
+		c.Error(err)
+		return nil, false
 	}
+	return files, true
 }
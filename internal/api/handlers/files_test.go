@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/errors"
+	"gin/internal/models"
+	"gin/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockFileService 是 FileService 的 mock 实现
+type MockFileService struct {
+	mock.Mock
+}
+
+func (m *MockFileService) UploadFiles(ctx context.Context, uploads []*service.FileUpload, policy models.UploadPolicy) ([]*models.File, error) {
+	args := m.Called(ctx, uploads, policy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileService) GetFile(ctx context.Context, id int64) (*models.File, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockFileService) OpenFile(ctx context.Context, id int64, transform models.ImageTransform) (*models.File, io.ReadSeekCloser, error) {
+	args := m.Called(ctx, id, transform)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.File), args.Get(1).(io.ReadSeekCloser), args.Error(2)
+}
+
+func (m *MockFileService) SignFile(ctx context.Context, id int64, ttl time.Duration, transform models.ImageTransform) (string, time.Time, error) {
+	args := m.Called(ctx, id, ttl, transform)
+	return args.String(0), args.Get(1).(time.Time), args.Error(2)
+}
+
+func (m *MockFileService) OpenSignedFile(ctx context.Context, id, expires int64, signature string, transform models.ImageTransform) (*models.File, io.ReadSeekCloser, error) {
+	args := m.Called(ctx, id, expires, signature, transform)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.File), args.Get(1).(io.ReadSeekCloser), args.Error(2)
+}
+
+// nopSeekCloser 为 strings.Reader 添加 Close
+type nopSeekCloser struct {
+	*strings.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// setupFileTestRouter 设置文件测试路由
+func setupFileTestRouter(handler *FileHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(errors.ErrorHandler())
+
+	files := router.Group("/api/v1/files")
+	{
+		files.GET("/:id", handler.DownloadFile())
+		files.POST("/:id/signed-url", handler.CreateSignedURL())
+		files.GET("/:id/shared", handler.DownloadSignedFile())
+	}
+	return router
+}
+
+// TestFileHandler_DownloadFile 测试下载文件
+func TestFileHandler_DownloadFile(t *testing.T) {
+	const content = "0123456789"
+	file := &models.File{
+		ID:          1,
+		Name:        "报告.txt",
+		ContentType: "text/plain",
+		Size:        int64(len(content)),
+		Checksum:    "abc123",
+		CreatedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	newRouter := func() *gin.Engine {
+		mockService := new(MockFileService)
+		mockService.On("OpenFile", mock.Anything, int64(1), models.ImageTransform{}).Return(file, nopSeekCloser{strings.NewReader(content)}, nil)
+		mockService.On("OpenFile", mock.Anything, int64(2), models.ImageTransform{}).Return(nil, nil, errors.NewNotFoundError("文件不存在", nil))
+		mockService.On("OpenFile", mock.Anything, int64(3), models.ImageTransform{Width: 200, Height: 200, Fit: "cover"}).
+			Return(&models.File{ID: 3, Name: "头像.png", ContentType: "image/png", Size: 4, Checksum: "variant"}, nopSeekCloser{strings.NewReader("\x89PNG")}, nil)
+		mockService.On("OpenFile", mock.Anything, int64(3), models.ImageTransform{Thumbnail: "small"}).
+			Return(nil, nil, errors.NewBadRequestError("缩略图不存在: small", nil))
+		return setupFileTestRouter(NewFileHandler(mockService))
+	}
+	get := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		newRouter().ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("完整下载", func(t *testing.T) {
+		w := get("/api/v1/files/1", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, content, w.Body.String())
+		assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+		assert.Equal(t, `"abc123"`, w.Header().Get("ETag"))
+		assert.Equal(t, "Tue, 02 Jan 2024 03:04:05 GMT", w.Header().Get("Last-Modified"))
+		assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+		disposition, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition"))
+		require.NoError(t, err)
+		assert.Equal(t, "attachment", disposition)
+		assert.Equal(t, "报告.txt", params["filename"])
+
+		w = get("/api/v1/files/1?disposition=inline", nil)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Content-Disposition"), "inline;"))
+	})
+
+	t.Run("单个范围", func(t *testing.T) {
+		w := get("/api/v1/files/1", map[string]string{"Range": "bytes=2-5"})
+		require.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "2345", w.Body.String())
+		assert.Equal(t, "bytes 2-5/10", w.Header().Get("Content-Range"))
+
+		w = get("/api/v1/files/1", map[string]string{"Range": "bytes=20-"})
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	})
+
+	t.Run("多个范围", func(t *testing.T) {
+		w := get("/api/v1/files/1", map[string]string{"Range": "bytes=0-1,8-"})
+		require.Equal(t, http.StatusPartialContent, w.Code)
+
+		mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/byteranges", mediaType)
+
+		reader := multipart.NewReader(w.Body, params["boundary"])
+		var parts []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			data, _ := io.ReadAll(part)
+			parts = append(parts, part.Header.Get("Content-Range")+"="+string(data))
+		}
+		assert.Equal(t, []string{"bytes 0-1/10=01", "bytes 8-9/10=89"}, parts)
+	})
+
+	t.Run("条件请求", func(t *testing.T) {
+		w := get("/api/v1/files/1", map[string]string{"If-None-Match": `"abc123"`})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+
+		w = get("/api/v1/files/1", map[string]string{"If-None-Match": `"other"`})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// If-Range 不匹配时忽略 Range，返回完整内容
+		w = get("/api/v1/files/1", map[string]string{"Range": "bytes=0-1", "If-Range": `"other"`})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, content, w.Body.String())
+	})
+
+	t.Run("文件不存在", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get("/api/v1/files/2", nil).Code)
+		assert.Equal(t, http.StatusBadRequest, get("/api/v1/files/abc", nil).Code)
+	})
+
+	t.Run("图片变换参数", func(t *testing.T) {
+		w := get("/api/v1/files/3?w=200&h=200&fit=cover", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, `"variant"`, w.Header().Get("ETag"))
+
+		assert.Equal(t, http.StatusBadRequest, get("/api/v1/files/3?thumbnail=small", nil).Code)
+		// 参数格式错误时不调用服务
+		assert.Equal(t, http.StatusBadRequest, get("/api/v1/files/3?w=200&fit=crop", nil).Code)
+		assert.Equal(t, http.StatusBadRequest, get("/api/v1/files/3?h=abc", nil).Code)
+	})
+}
+
+// TestFileHandler_SignedURL 测试签名链接
+func TestFileHandler_SignedURL(t *testing.T) {
+	expiresAt := time.Unix(1700000000, 0)
+	mockService := new(MockFileService)
+	mockService.On("SignFile", mock.Anything, int64(1), 10*time.Minute, models.ImageTransform{}).Return("c2ln", expiresAt, nil)
+	mockService.On("SignFile", mock.Anything, int64(1), time.Duration(0), models.ImageTransform{}).Return("c2ln", expiresAt, nil)
+	mockService.On("SignFile", mock.Anything, int64(1), time.Duration(0), models.ImageTransform{Width: 200, Fit: "cover"}).Return("c2ln", expiresAt, nil)
+	mockService.On("OpenSignedFile", mock.Anything, int64(1), int64(1700000000), "c2ln", models.ImageTransform{}).
+		Return(&models.File{ID: 1, Name: "a.txt", ContentType: "text/plain", Size: 5}, nopSeekCloser{strings.NewReader("hello")}, nil)
+	mockService.On("OpenSignedFile", mock.Anything, int64(1), int64(1700000000), "c2ln", models.ImageTransform{Thumbnail: "small"}).
+		Return(&models.File{ID: 1, Name: "a.png", ContentType: "image/png", Size: 4}, nopSeekCloser{strings.NewReader("\x89PNG")}, nil)
+	router := setupFileTestRouter(NewFileHandler(mockService))
+
+	t.Run("生成签名链接", func(t *testing.T) {
+		for _, body := range []string{`{"expires_in": 600}`, ""} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/files/1/signed-url", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			var resp struct {
+				Data models.SignedURL `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "/api/v1/files/1/shared?expires=1700000000&signature=c2ln", resp.Data.URL)
+			assert.True(t, expiresAt.Equal(resp.Data.ExpiresAt))
+		}
+	})
+
+	t.Run("生成指定图片变换参数的签名链接", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/1/signed-url", strings.NewReader(`{"w": 200, "fit": "cover"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var resp struct {
+			Data models.SignedURL `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "/api/v1/files/1/shared?expires=1700000000&fit=cover&signature=c2ln&w=200", resp.Data.URL)
+
+		// 参数格式错误时不调用服务
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/files/1/signed-url", strings.NewReader(`{"w": 200, "fit": "crop"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("通过签名链接下载", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/files/1/shared?expires=1700000000&signature=c2ln", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "hello", w.Body.String())
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/files/1/shared?signature=c2ln", nil))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/files/1/shared?expires=1700000000&signature=c2ln&thumbnail=small", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	})
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"gin/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler 公开JWT验签公钥（JWKS）
+// 其他服务可据此按 kid 验证访问令牌，无需持有签名密钥
+// 按 RFC 7517 直接返回 JWKS 文档，不使用统一响应结构；HS256 模式下返回空列表
+// @Summary JWKS 公钥集合
+// @Description 返回当前仍可用于验签的非对称公钥，退役密钥在宽限期结束后自动移除
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.JSONWebKeySet "公钥集合"
+// @Router /.well-known/jwks.json [get]
+func JWKSHandler(jwtConfig *auth.JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwks := auth.JSONWebKeySet{Keys: []auth.JSONWebKey{}}
+		if jwtConfig != nil && jwtConfig.Keys != nil {
+			jwks = jwtConfig.Keys.JWKS(time.Now())
+		}
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwks)
+	}
+}
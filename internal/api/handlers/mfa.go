@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"gin/internal/api/response"
+	"gin/internal/i18n"
+	"gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyMFA 登录两步验证
+// @Summary 登录两步验证
+// @Description 使用登录返回的 mfa_token 和身份验证器中的验证码（或恢复码）换取访问令牌；首次设置时响应中附带恢复码
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param mfa body models.MFAVerifyRequest true "两步验证请求"
+// @Success 200 {object} response.Response{data=models.LoginResponse} "登录成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "验证码错误或两步验证已失效"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/mfa/verify [post]
+func (h *UserHandler) VerifyMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFAVerifyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		resp, err := h.userService.VerifyMFA(c.Request.Context(), &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserLoginSuccess), resp)
+	}
+}
+
+// GetMFAStatus 查询两步验证状态
+// @Summary 查询两步验证状态
+// @Description 查询当前用户是否启用了两步验证以及剩余恢复码数量
+// @Tags auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=models.MFAStatusResponse} "获取成功"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/mfa [get]
+func (h *UserHandler) GetMFAStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		status, err := h.userService.GetMFAStatus(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserMFAStatusSuccess), status)
+	}
+}
+
+// SetupMFA 获取两步验证密钥
+// @Summary 获取两步验证密钥
+// @Description 生成新的 TOTP 密钥和二维码内容，使用身份验证器扫描后调用启用接口确认
+// @Tags auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=models.MFASetupResponse} "获取成功"
+// @Failure 400 {object} response.Response "两步验证已启用"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/mfa/setup [post]
+func (h *UserHandler) SetupMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		setup, err := h.userService.SetupMFA(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserMFASetupSuccess), setup)
+	}
+}
+
+// EnableMFA 启用两步验证
+// @Summary 启用两步验证
+// @Description 输入身份验证器中的验证码确认密钥并启用两步验证，返回一次性恢复码（只返回这一次）
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param mfa body models.MFACodeRequest true "验证码"
+// @Success 200 {object} response.Response{data=models.MFARecoveryCodesResponse} "启用成功"
+// @Failure 400 {object} response.Response "验证码错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/mfa/enable [post]
+func (h *UserHandler) EnableMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		var req models.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		codes, err := h.userService.EnableMFA(c.Request.Context(), userID, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserMFAEnableSuccess), codes)
+	}
+}
+
+// DisableMFA 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 验证当前密码和验证码（或恢复码）后关闭两步验证；配置要求管理员必须启用时管理员不能关闭
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param mfa body models.MFADisableRequest true "关闭两步验证请求"
+// @Success 200 {object} response.Response "关闭成功"
+// @Failure 400 {object} response.Response "密码或验证码错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "管理员必须启用两步验证"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/mfa/disable [post]
+func (h *UserHandler) DisableMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		var req models.MFADisableRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := h.userService.DisableMFA(c.Request.Context(), userID, &req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserMFADisableSuccess), nil)
+	}
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 输入验证码后重新生成恢复码，之前的恢复码全部失效
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param mfa body models.MFACodeRequest true "验证码"
+// @Success 200 {object} response.Response{data=models.MFARecoveryCodesResponse} "生成成功"
+// @Failure 400 {object} response.Response "验证码错误或未启用两步验证"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/mfa/recovery-codes [post]
+func (h *UserHandler) RegenerateRecoveryCodes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		var req models.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		codes, err := h.userService.RegenerateRecoveryCodes(c.Request.Context(), userID, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserMFARecoveryCodesSuccess), codes)
+	}
+}
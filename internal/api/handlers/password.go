@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"gin/internal/api/response"
+	"gin/internal/i18n"
+	"gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePassword 修改密码
+// @Summary 修改密码
+// @Description 验证当前密码后设置新密码，成功后该用户的所有会话失效，需要重新登录
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param password body models.ChangePasswordRequest true "修改密码请求"
+// @Success 200 {object} response.Response "修改成功"
+// @Failure 400 {object} response.Response "当前密码错误或新密码不符合密码策略"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/password/change [post]
+func (h *UserHandler) ChangePassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		var req models.ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := h.userService.ChangePassword(c.Request.Context(), userID, &req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserPasswordChangeSuccess), nil)
+	}
+}
+
+// ForgotPassword 忘记密码
+// @Summary 忘记密码
+// @Description 向邮箱发送重置密码链接；为避免探测已注册邮箱，无论邮箱是否存在都返回成功
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param password body models.ForgotPasswordRequest true "忘记密码请求"
+// @Success 200 {object} response.Response "请求已受理"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/password/forgot [post]
+func (h *UserHandler) ForgotPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := h.userService.ForgotPassword(c.Request.Context(), &req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserPasswordForgotSuccess), nil)
+	}
+}
+
+// ResetPassword 重置密码
+// @Summary 重置密码
+// @Description 使用重置密码链接中的令牌设置新密码，令牌只能使用一次，成功后该用户的所有会话失效
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param password body models.ResetPasswordRequest true "重置密码请求"
+// @Success 200 {object} response.Response "重置成功"
+// @Failure 400 {object} response.Response "令牌无效、已过期或新密码不符合密码策略"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/password/reset [post]
+func (h *UserHandler) ResetPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := h.userService.ResetPassword(c.Request.Context(), &req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserPasswordResetSuccess), nil)
+	}
+}
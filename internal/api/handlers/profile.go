@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"gin/internal/api/response"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CurrentPasswordHeader 修改邮箱时传递当前密码的请求头（补丁正文只能包含要修改的字段）
+const CurrentPasswordHeader = "X-Current-Password"
+
+// GetMe 获取当前用户
+// @Summary 获取当前用户
+// @Description 获取当前登录用户的信息和扩展资料（头像、简介、语言、时区、偏好设置），ETag 响应头为用户的版本号
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=models.Me} "获取成功"
+// @Header 200 {string} ETag "用户版本号，更新时作为 If-Match 传回"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "用户不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/users/me [get]
+func (h *UserHandler) GetMe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		me, err := h.userService.GetMe(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.Header("ETag", versionETag(me.Version))
+		response.Success(c, i18n.UserMessage(i18n.UserGetSuccess), me)
+	}
+}
+
+// PatchMe 部分更新当前用户
+// @Summary 部分更新当前用户
+// @Description 按 JSON Merge Patch（application/merge-patch+json）或 JSON Patch（application/json-patch+json）部分更新当前用户，可以修改 name、email、age 和 profile 中的 avatar_file_id、bio、locale、timezone、preferences；头像必须是本人上传的图片；修改邮箱时需要在 X-Current-Password 中提供当前密码；不接受API密钥
+// @Tags users
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param If-Match header string false "获取当前用户时返回的 ETag"
+// @Param X-Current-Password header string false "当前密码，修改邮箱时必填"
+// @Param patch body object true "补丁"
+// @Success 200 {object} response.Response{data=models.Me} "更新成功"
+// @Header 200 {string} ETag "更新后的用户版本号"
+// @Failure 400 {object} response.Response "补丁无效、应用后的用户信息无效或修改邮箱时当前密码错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "使用API密钥认证"
+// @Failure 404 {object} response.Response "用户不存在"
+// @Failure 409 {object} response.Response "test 操作未通过或用户已被其他请求修改"
+// @Failure 412 {object} response.Response "If-Match 与用户当前的 ETag 不一致"
+// @Failure 415 {object} response.Response "不支持的补丁格式"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/users/me [patch]
+func (h *UserHandler) PatchMe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		patch, err := c.GetRawData()
+		if err != nil {
+			c.Error(errors.NewBadRequestError(i18n.UserMessage(i18n.UserErrorBadRequest), err))
+			return
+		}
+		req := models.PatchMeRequest{ContentType: c.ContentType(), Patch: patch, CurrentPassword: c.GetHeader(CurrentPasswordHeader)}
+		if req.ExpectedVersion, err = ifMatchVersion(c); err != nil {
+			c.Error(err)
+			return
+		}
+
+		me, err := h.userService.PatchMe(c.Request.Context(), userID, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.Header("ETag", versionETag(me.Version))
+		response.Success(c, i18n.UserMessage(i18n.UserUpdateSuccess), me)
+	}
+}
+
+// DeleteMe 注销当前用户
+// @Summary 注销当前用户
+// @Description 验证当前密码后注销当前用户（软删除，保留期内管理员可以恢复），同时撤销所有会话；不接受API密钥
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body models.DeleteMeRequest true "注销请求"
+// @Success 200 {object} response.Response "注销成功"
+// @Failure 400 {object} response.Response "密码错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "使用API密钥认证"
+// @Failure 404 {object} response.Response "用户不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/users/me [delete]
+func (h *UserHandler) DeleteMe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		var req models.DeleteMeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := h.userService.DeleteMe(c.Request.Context(), userID, &req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserDeleteSuccess), nil)
+	}
+}
@@ -16,7 +16,7 @@ func HTTPRedirectHandler() gin.HandlerFunc {
 // PathRedirectHandler 处理路径重定向路由
 func PathRedirectHandler(router *gin.Engine) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Request.URL.Path = "/upload/page"
+		c.Request.URL.Path = "/index"
 		router.HandleContext(c)
 	}
 }
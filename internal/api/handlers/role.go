@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"gin/internal/api/response"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/models"
+	"gin/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleHandler 角色处理器
+type RoleHandler struct {
+	roleService service.RoleService
+}
+
+// NewRoleHandler 创建角色处理器
+func NewRoleHandler(roleService service.RoleService) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+	}
+}
+
+// ListRoles 获取所有角色
+// @Summary 获取角色列表
+// @Description 获取所有角色及其权限
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]models.Role} "获取成功"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/roles [get]
+func (h *RoleHandler) ListRoles() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles, err := h.roleService.ListRoles(c.Request.Context())
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserRoleListSuccess), roles)
+	}
+}
+
+// GetRole 获取角色
+// @Summary 获取单个角色
+// @Description 根据ID获取角色及其权限
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} response.Response{data=models.Role} "获取成功"
+// @Failure 400 {object} response.Response "无效的角色ID"
+// @Failure 404 {object} response.Response "角色不存在"
+// @Router /api/v1/roles/{id} [get]
+func (h *RoleHandler) GetRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := roleIDParam(c)
+		if !ok {
+			return
+		}
+
+		role, err := h.roleService.GetRole(c.Request.Context(), id)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserRoleGetSuccess), role)
+	}
+}
+
+// CreateRole 创建角色
+// @Summary 创建角色
+// @Description 创建角色并授予权限
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param role body models.CreateRoleRequest true "角色信息"
+// @Success 201 {object} response.Response{data=models.Role} "创建成功"
+// @Failure 400 {object} response.Response "请求参数错误、角色名已存在或权限不存在"
+// @Failure 403 {object} response.Response "权限不足"
+// @Router /api/v1/roles [post]
+func (h *RoleHandler) CreateRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		role, err := h.roleService.CreateRole(c.Request.Context(), &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Created(c, i18n.UserMessage(i18n.UserRoleCreateSuccess), role)
+	}
+}
+
+// UpdateRole 更新角色
+// @Summary 更新角色
+// @Description 更新角色名称、描述；提供 permissions 时整体替换角色的授权
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Param role body models.UpdateRoleRequest true "角色信息"
+// @Success 200 {object} response.Response{data=models.Role} "更新成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 404 {object} response.Response "角色不存在"
+// @Router /api/v1/roles/{id} [put]
+func (h *RoleHandler) UpdateRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := roleIDParam(c)
+		if !ok {
+			return
+		}
+
+		var req models.UpdateRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		role, err := h.roleService.UpdateRole(c.Request.Context(), id, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserRoleUpdateSuccess), role)
+	}
+}
+
+// DeleteRole 删除角色
+// @Summary 删除角色
+// @Description 删除自定义角色（内置角色和仍被用户使用的角色不能删除）
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Failure 400 {object} response.Response "内置角色或角色仍被使用"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 404 {object} response.Response "角色不存在"
+// @Router /api/v1/roles/{id} [delete]
+func (h *RoleHandler) DeleteRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := roleIDParam(c)
+		if !ok {
+			return
+		}
+
+		if err := h.roleService.DeleteRole(c.Request.Context(), id); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserRoleDeleteSuccess), nil)
+	}
+}
+
+// ListPermissions 获取所有权限
+// @Summary 获取权限列表
+// @Description 获取系统中定义的所有权限
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]models.Permission} "获取成功"
+// @Failure 403 {object} response.Response "权限不足"
+// @Router /api/v1/permissions [get]
+func (h *RoleHandler) ListPermissions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, err := h.roleService.ListPermissions(c.Request.Context())
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserPermissionListSuccess), permissions)
+	}
+}
+
+// AssignRole 为用户分配角色
+// @Summary 分配角色
+// @Description 为用户分配角色，用户已签发的访问令牌立即失效，刷新令牌后获得新角色
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param role body models.AssignRoleRequest true "角色"
+// @Success 200 {object} response.Response{data=models.User} "分配成功"
+// @Failure 400 {object} response.Response "角色不存在"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 404 {object} response.Response "用户不存在"
+// @Router /api/v1/users/{id}/role [put]
+func (h *RoleHandler) AssignRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		userID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidID), idStr), err))
+			return
+		}
+
+		var req models.AssignRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		user, err := h.roleService.AssignRole(c.Request.Context(), userID, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserRoleAssignSuccess), user)
+	}
+}
+
+// roleIDParam 解析路径中的角色ID，失败时记录错误
+func roleIDParam(c *gin.Context) (int64, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidRoleID), idStr), err))
+		return 0, false
+	}
+	return id, true
+}
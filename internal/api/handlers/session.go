@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"gin/internal/api/response"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions 获取当前用户的会话列表
+// @Summary 获取我的会话列表
+// @Description 列出当前用户所有有效的登录会话，current 标记当前请求所属的会话
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.Response{data=[]models.Session} "获取成功"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/sessions [get]
+func (h *UserHandler) ListSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		sessions, err := h.userService.ListSessions(c.Request.Context(), userID, c.GetString("session_id"))
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserSessionListSuccess), sessions)
+	}
+}
+
+// RevokeSession 撤销当前用户的某个会话
+// @Summary 撤销会话
+// @Description 撤销当前用户的指定会话，该会话的刷新令牌和访问令牌立即失效
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} response.Response "撤销成功"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 404 {object} response.Response "会话不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/sessions/{id} [delete]
+func (h *UserHandler) RevokeSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
+			return
+		}
+
+		if err := h.userService.RevokeSession(c.Request.Context(), userID, c.Param("id")); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserSessionRevokeSuccess), nil)
+	}
+}
+
+// ForceLogoutUser 强制用户下线
+// @Summary 强制用户下线
+// @Description 撤销指定用户的所有会话和访问令牌（仅管理员）
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response "操作成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 404 {object} response.Response "用户不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/users/{id}/logout [post]
+func (h *UserHandler) ForceLogoutUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidID), idStr), err))
+			return
+		}
+
+		if err := h.userService.ForceLogout(c.Request.Context(), id); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserForceLogoutSuccess), nil)
+	}
+}
+
+// UnlockUser 解锁账户
+// @Summary 解锁账户
+// @Description 清除指定用户因登录失败次数过多产生的锁定（仅管理员）
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response "操作成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 404 {object} response.Response "用户不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/users/{id}/unlock [post]
+func (h *UserHandler) UnlockUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidID), idStr), err))
+			return
+		}
+
+		if err := h.userService.UnlockUser(c.Request.Context(), id); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserUnlockSuccess), nil)
+	}
+}
+
+// currentUserID 获取 AuthMiddleware 写入的当前用户ID
+func currentUserID(c *gin.Context) (int64, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	userID, ok := value.(int64)
+	return userID, ok
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/models"
+	"gin/internal/service"
+	"gin/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// tusVersion 支持的 tus 协议版本
+	tusVersion = "1.0.0"
+	// tusExtensions 支持的 tus 协议扩展
+	tusExtensions = "creation,creation-with-upload,termination,expiration"
+	// tusContentType PATCH 请求体的类型
+	tusContentType = "application/offset+octet-stream"
+	// maxUploadMetadataLength Upload-Metadata 请求头的最大长度
+	maxUploadMetadataLength = 4 << 10
+)
+
+// UploadHandler 断点续传上传处理器（tus 1.0 协议）
+type UploadHandler struct {
+	uploadService service.UploadService
+	chunkTimeout  time.Duration
+}
+
+// NewUploadHandler 创建断点续传上传处理器
+// chunkTimeout 为接收一个分片的读写超时，覆盖服务器的 ReadTimeout / WriteTimeout；为 0 时不修改
+func NewUploadHandler(uploadService service.UploadService, chunkTimeout time.Duration) *UploadHandler {
+	return &UploadHandler{
+		uploadService: uploadService,
+		chunkTimeout:  chunkTimeout,
+	}
+}
+
+// TusResumable 检查 Tus-Resumable 请求头并在响应中返回协议版本（OPTIONS 请求不检查）
+func (h *UploadHandler) TusResumable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", tusVersion)
+		if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != tusVersion {
+			c.Header("Tus-Version", tusVersion)
+			c.Error(errors.NewPreconditionFailedError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorTusVersion), tusVersion),
+				fmt.Errorf("unsupported Tus-Resumable %q", c.GetHeader("Tus-Resumable"))))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Options 返回服务端支持的协议版本、扩展和文件大小上限
+// @Summary 查询断点续传能力
+// @Description tus 协议的 OPTIONS 请求，返回 Tus-Version、Tus-Extension 和 Tus-Max-Size
+// @Tags uploads
+// @Success 204 "无内容"
+// @Router /api/v1/uploads [options]
+func (h *UploadHandler) Options(policy models.UploadPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Version", tusVersion)
+		c.Header("Tus-Extension", tusExtensions)
+		c.Header("Tus-Max-Size", strconv.FormatInt(policy.MaxSize, 10))
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// CreateUpload 创建上传
+// @Summary 创建断点续传上传
+// @Description tus 协议的 creation 扩展：Upload-Length 为文件总长度，Upload-Metadata 中的 filename 作为文件名。
+// @Description 请求体类型为 application/offset+octet-stream 时同时写入第一个分片（creation-with-upload）。Location 响应头为上传地址
+// @Tags uploads
+// @Security ApiKeyAuth
+// @Param Tus-Resumable header string true "协议版本 1.0.0"
+// @Param Upload-Length header int true "文件总长度"
+// @Param Upload-Metadata header string false "以逗号分隔的 key base64(value)"
+// @Success 201 "创建成功"
+// @Failure 400 {object} response.Response "请求头无效"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 412 {object} response.Response "不支持的协议版本"
+// @Failure 413 {object} response.Response "文件过大"
+// @Router /api/v1/uploads [post]
+func (h *UploadHandler) CreateUpload(policy models.UploadPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Upload-Defer-Length") != "" {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorUploadHeader), "Upload-Defer-Length"),
+				fmt.Errorf("creation-defer-length is not supported")))
+			return
+		}
+		length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+		if err != nil || length < 0 {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorUploadHeader), "Upload-Length"), err))
+			return
+		}
+		metadata, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+		if err != nil {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorUploadHeader), "Upload-Metadata"), err))
+			return
+		}
+
+		upload, err := h.uploadService.CreateUpload(c.Request.Context(), length, metadata, policy)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+
+		// creation-with-upload：请求体中带有第一个分片
+		if isTusContentType(c.GetHeader("Content-Type")) && c.Request.ContentLength != 0 {
+			if upload, err = h.writeChunk(c, upload.ID, 0, policy); err != nil {
+				c.Error(err)
+				return
+			}
+			setUploadHeaders(c, upload)
+		}
+		c.Status(http.StatusCreated)
+	}
+}
+
+// HeadUpload 查询上传进度
+// @Summary 查询断点续传进度
+// @Description tus 协议的 HEAD 请求：Upload-Offset 为已接收的字节数，上传完成后 X-File-ID 为保存后的文件ID
+// @Tags uploads
+// @Security ApiKeyAuth
+// @Param Tus-Resumable header string true "协议版本 1.0.0"
+// @Param id path string true "上传ID"
+// @Success 200 "上传进度"
+// @Failure 404 "上传不存在"
+// @Failure 410 "上传已过期"
+// @Router /api/v1/uploads/{id} [head]
+func (h *UploadHandler) HeadUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		upload, err := h.uploadService.GetUpload(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+		if len(upload.Metadata) > 0 {
+			c.Header("Upload-Metadata", formatUploadMetadata(upload.Metadata))
+		}
+		setUploadHeaders(c, upload)
+		c.Status(http.StatusOK)
+	}
+}
+
+// PatchUpload 写入分片
+// @Summary 写入断点续传分片
+// @Description tus 协议的 PATCH 请求：从 Upload-Offset 处追加请求体，全部接收后按内容识别类型并保存文件，X-File-ID 为文件ID
+// @Tags uploads
+// @Accept application/offset+octet-stream
+// @Security ApiKeyAuth
+// @Param Tus-Resumable header string true "协议版本 1.0.0"
+// @Param Upload-Offset header int true "写入位置，必须等于已接收的字节数"
+// @Param id path string true "上传ID"
+// @Success 204 "写入成功"
+// @Failure 400 {object} response.Response "请求头无效"
+// @Failure 404 {object} response.Response "上传不存在"
+// @Failure 409 {object} response.Response "写入位置不一致"
+// @Failure 410 {object} response.Response "上传已过期"
+// @Failure 413 {object} response.Response "分片超过剩余长度"
+// @Failure 415 {object} response.Response "请求体类型错误或不支持的文件类型"
+// @Router /api/v1/uploads/{id} [patch]
+func (h *UploadHandler) PatchUpload(policy models.UploadPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isTusContentType(c.GetHeader("Content-Type")) {
+			c.Error(errors.NewUnsupportedMediaTypeError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorUploadHeader), "Content-Type"),
+				fmt.Errorf("content type %q", c.GetHeader("Content-Type"))))
+			return
+		}
+		offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorUploadHeader), "Upload-Offset"), err))
+			return
+		}
+
+		upload, err := h.writeChunk(c, c.Param("id"), offset, policy)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		setUploadHeaders(c, upload)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// DeleteUpload 取消上传
+// @Summary 取消断点续传上传
+// @Description tus 协议的 termination 扩展：删除上传和已接收的内容，已保存的文件不受影响
+// @Tags uploads
+// @Security ApiKeyAuth
+// @Param Tus-Resumable header string true "协议版本 1.0.0"
+// @Param id path string true "上传ID"
+// @Success 204 "已取消"
+// @Failure 404 {object} response.Response "上传不存在"
+// @Failure 410 {object} response.Response "上传已过期"
+// @Router /api/v1/uploads/{id} [delete]
+func (h *UploadHandler) DeleteUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.uploadService.TerminateUpload(c.Request.Context(), c.Param("id")); err != nil {
+			c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// writeChunk 延长读写超时后写入请求体
+// 较大的分片可能超过服务器的 ReadTimeout / WriteTimeout；超时中断时已接收的内容会保留，客户端可以继续上传
+func (h *UploadHandler) writeChunk(c *gin.Context, id string, offset int64, policy models.UploadPolicy) (*storage.PartialUpload, error) {
+	if h.chunkTimeout > 0 {
+		rc := http.NewResponseController(c.Writer)
+		deadline := time.Now().Add(h.chunkTimeout)
+		_ = rc.SetReadDeadline(deadline) // 不支持时（如测试中的 ResponseRecorder）保持原超时
+		_ = rc.SetWriteDeadline(deadline)
+	}
+	return h.uploadService.WriteChunk(c.Request.Context(), id, offset, c.Request.ContentLength, c.Request.Body, policy)
+}
+
+// setUploadHeaders 写入上传进度相关的响应头
+func setUploadHeaders(c *gin.Context, upload *storage.PartialUpload) {
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.Completed() {
+		c.Header("X-File-ID", strconv.FormatInt(upload.FileID, 10))
+	}
+}
+
+// isTusContentType 请求体类型是否为 application/offset+octet-stream
+func isTusContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == tusContentType
+}
+
+// parseUploadMetadata 解析 Upload-Metadata：以逗号分隔的键值对，键和 base64 编码的值以空格分隔，值可以省略
+func parseUploadMetadata(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+	if len(header) > maxUploadMetadataLength {
+		return nil, fmt.Errorf("Upload-Metadata 超过 %d 字节", maxUploadMetadataLength)
+	}
+
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" || strings.ContainsAny(key, " ,") {
+			return nil, fmt.Errorf("Upload-Metadata 键无效: %q", key)
+		}
+		if _, ok := metadata[key]; ok {
+			return nil, fmt.Errorf("Upload-Metadata 键重复: %s", key)
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("Upload-Metadata 值不是有效的 base64: %s", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// formatUploadMetadata 按 Upload-Metadata 格式编码元数据
+func formatUploadMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		if value == "" {
+			pairs = append(pairs, key)
+			continue
+		}
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/errors"
+	"gin/internal/models"
+	"gin/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockUploadService 是 UploadService 的 mock 实现
+type MockUploadService struct {
+	mock.Mock
+}
+
+func (m *MockUploadService) CreateUpload(ctx context.Context, length int64, metadata map[string]string, policy models.UploadPolicy) (*storage.PartialUpload, error) {
+	args := m.Called(ctx, length, metadata, policy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.PartialUpload), args.Error(1)
+}
+
+func (m *MockUploadService) GetUpload(ctx context.Context, id string) (*storage.PartialUpload, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.PartialUpload), args.Error(1)
+}
+
+func (m *MockUploadService) WriteChunk(ctx context.Context, id string, offset, size int64, r io.Reader, policy models.UploadPolicy) (*storage.PartialUpload, error) {
+	data, _ := io.ReadAll(r)
+	args := m.Called(ctx, id, offset, size, string(data), policy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.PartialUpload), args.Error(1)
+}
+
+func (m *MockUploadService) TerminateUpload(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockUploadService) PurgeExpired(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// setupUploadTestRouter 设置断点续传测试路由
+func setupUploadTestRouter(handler *UploadHandler, policy models.UploadPolicy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(errors.ErrorHandler())
+
+	router.OPTIONS("/api/v1/uploads", handler.TusResumable(), handler.Options(policy))
+	uploads := router.Group("/api/v1/uploads", handler.TusResumable())
+	{
+		uploads.POST("", handler.CreateUpload(policy))
+		uploads.HEAD("/:id", handler.HeadUpload())
+		uploads.PATCH("/:id", handler.PatchUpload(policy))
+		uploads.DELETE("/:id", handler.DeleteUpload())
+	}
+	return router
+}
+
+// TestUploadHandler 测试 tus 协议请求头的处理
+func TestUploadHandler(t *testing.T) {
+	policy := models.UploadPolicy{MaxSize: 1 << 20, MaxFiles: 1}
+	expiresAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	id := strings.Repeat("a", 32)
+
+	serve := func(router *gin.Engine, method, target string, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("OPTIONS返回协议能力", func(t *testing.T) {
+		router := setupUploadTestRouter(NewUploadHandler(new(MockUploadService), 0), policy)
+		w := serve(router, http.MethodOptions, "/api/v1/uploads", "", nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "1.0.0", w.Header().Get("Tus-Version"))
+		assert.Equal(t, "1048576", w.Header().Get("Tus-Max-Size"))
+		assert.Contains(t, w.Header().Get("Tus-Extension"), "termination")
+	})
+
+	t.Run("缺少Tus-Resumable返回412", func(t *testing.T) {
+		mockService := new(MockUploadService)
+		router := setupUploadTestRouter(NewUploadHandler(mockService, 0), policy)
+		w := serve(router, http.MethodPost, "/api/v1/uploads", "", map[string]string{"Upload-Length": "10"})
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		assert.Equal(t, "1.0.0", w.Header().Get("Tus-Version"))
+		mockService.AssertNotCalled(t, "CreateUpload", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("创建上传并写入第一个分片", func(t *testing.T) {
+		mockService := new(MockUploadService)
+		upload := &storage.PartialUpload{ID: id, Length: 10, ExpiresAt: expiresAt}
+		mockService.On("CreateUpload", mock.Anything, int64(10), map[string]string{"filename": "a.png", "private": ""}, policy).Return(upload, nil)
+		mockService.On("WriteChunk", mock.Anything, id, int64(0), int64(4), "abcd", policy).
+			Return(&storage.PartialUpload{ID: id, Length: 10, Offset: 4, ExpiresAt: expiresAt}, nil)
+
+		router := setupUploadTestRouter(NewUploadHandler(mockService, time.Minute), policy)
+		w := serve(router, http.MethodPost, "/api/v1/uploads", "abcd", map[string]string{
+			"Tus-Resumable":   "1.0.0",
+			"Upload-Length":   "10",
+			"Upload-Metadata": "filename YS5wbmc=,private",
+			"Content-Type":    "application/offset+octet-stream",
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, "/api/v1/uploads/"+id, w.Header().Get("Location"))
+		assert.Equal(t, "4", w.Header().Get("Upload-Offset"))
+		assert.Equal(t, "Tue, 02 Jan 2024 03:04:05 GMT", w.Header().Get("Upload-Expires"))
+		assert.Equal(t, "1.0.0", w.Header().Get("Tus-Resumable"))
+	})
+
+	t.Run("请求头无效", func(t *testing.T) {
+		router := setupUploadTestRouter(NewUploadHandler(new(MockUploadService), 0), policy)
+		tus := map[string]string{"Tus-Resumable": "1.0.0"}
+
+		w := serve(router, http.MethodPost, "/api/v1/uploads", "", map[string]string{"Tus-Resumable": "1.0.0", "Upload-Length": "abc"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = serve(router, http.MethodPost, "/api/v1/uploads", "", map[string]string{"Tus-Resumable": "1.0.0", "Upload-Length": "1", "Upload-Metadata": "filename !!!"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = serve(router, http.MethodPatch, "/api/v1/uploads/"+id, "x", tus)
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+		w = serve(router, http.MethodPatch, "/api/v1/uploads/"+id, "x", map[string]string{"Tus-Resumable": "1.0.0", "Content-Type": "application/offset+octet-stream"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("上传完成后返回文件ID", func(t *testing.T) {
+		mockService := new(MockUploadService)
+		mockService.On("WriteChunk", mock.Anything, id, int64(4), int64(6), "efghij", policy).
+			Return(&storage.PartialUpload{ID: id, Length: 10, Offset: 10, FileID: 42, ExpiresAt: expiresAt}, nil)
+		mockService.On("GetUpload", mock.Anything, id).
+			Return(&storage.PartialUpload{ID: id, Length: 10, Offset: 10, FileID: 42, Metadata: map[string]string{"filename": "a.png"}, ExpiresAt: expiresAt}, nil)
+
+		router := setupUploadTestRouter(NewUploadHandler(mockService, 0), policy)
+		w := serve(router, http.MethodPatch, "/api/v1/uploads/"+id, "efghij", map[string]string{
+			"Tus-Resumable": "1.0.0",
+			"Upload-Offset": "4",
+			"Content-Type":  "application/offset+octet-stream",
+		})
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+		assert.Equal(t, "10", w.Header().Get("Upload-Offset"))
+		assert.Equal(t, "42", w.Header().Get("X-File-ID"))
+
+		w = serve(router, http.MethodHead, "/api/v1/uploads/"+id, "", map[string]string{"Tus-Resumable": "1.0.0"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "10", w.Header().Get("Upload-Length"))
+		assert.Equal(t, "filename YS5wbmc=", w.Header().Get("Upload-Metadata"))
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("取消上传", func(t *testing.T) {
+		mockService := new(MockUploadService)
+		mockService.On("TerminateUpload", mock.Anything, id).Return(nil)
+		router := setupUploadTestRouter(NewUploadHandler(mockService, 0), policy)
+
+		w := serve(router, http.MethodDelete, "/api/v1/uploads/"+id, "", map[string]string{"Tus-Resumable": "1.0.0"})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"net/http"
 	"strconv"
 
 	"gin/internal/api/response"
@@ -58,15 +59,17 @@ func (h *UserHandler) CreateUser() gin.HandlerFunc {
 
 // GetUser 获取用户
 // @Summary 获取单个用户
-// @Description 根据用户ID获取用户详细信息
+// @Description 根据用户ID获取用户详细信息（只能查看自己，管理员可以查看任何用户），ETag 响应头为用户的版本号
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param id path int true "用户ID"
 // @Success 200 {object} response.Response{data=models.User} "获取成功"
+// @Header 200 {string} ETag "用户版本号，更新时作为 If-Match 传回"
 // @Failure 400 {object} response.Response "请求参数错误"
 // @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "无权访问该用户"
 // @Failure 404 {object} response.Response "用户不存在"
 // @Failure 500 {object} response.Response "服务器内部错误"
 // @Router /api/v1/users/{id} [get]
@@ -85,45 +88,78 @@ func (h *UserHandler) GetUser() gin.HandlerFunc {
 			return
 		}
 
+		c.Header("ETag", versionETag(user.Version))
 		response.Success(c, i18n.UserMessage(i18n.UserGetSuccess), user)
 	}
 }
 
-// GetAllUsers 获取所有用户
-// @Summary 获取所有用户
-// @Description 获取系统中所有用户的列表
+// GetAllUsers 获取用户列表
+// @Summary 获取用户列表
+// @Description 分页获取用户列表，支持按名称、邮箱、角色、年龄和创建时间过滤；提供 cursor 时使用游标分页并忽略 offset
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} response.Response{data=[]models.User} "获取成功"
+// @Param name query string false "名称包含"
+// @Param email query string false "邮箱包含"
+// @Param role query string false "角色名"
+// @Param min_age query int false "最小年龄"
+// @Param max_age query int false "最大年龄"
+// @Param created_after query string false "创建时间不早于（RFC 3339）"
+// @Param created_before query string false "创建时间早于（RFC 3339）"
+// @Param limit query int false "每页条数（默认20，最大100）"
+// @Param offset query int false "偏移量"
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Param sort query string false "排序字段：id、name、email、age、created_at、updated_at，前缀 - 表示倒序（默认 -created_at）"
+// @Param deleted query bool false "只查询已删除的用户（需要 users:restore 权限）"
+// @Success 200 {object} response.PageResponse{data=[]models.User} "获取成功"
+// @Failure 400 {object} response.Response "查询参数错误"
 // @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
 // @Failure 500 {object} response.Response "服务器内部错误"
 // @Router /api/v1/users [get]
 func (h *UserHandler) GetAllUsers() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		users, err := h.userService.GetAllUsers(c.Request.Context())
+		var req models.ListUsersRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		page, err := h.userService.GetAllUsers(c.Request.Context(), &req)
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		response.Success(c, i18n.UserMessage(i18n.UserGetAllSuccess), users)
+		response.SuccessWithPage(c, i18n.UserMessage(i18n.UserGetAllSuccess), page.Items, response.Pagination{
+			Total:      page.Total,
+			Limit:      page.Limit,
+			Offset:     page.Offset,
+			NextCursor: page.NextCursor,
+			HasMore:    page.HasMore,
+		})
 	}
 }
 
 // UpdateUser 更新用户
 // @Summary 更新用户信息
-// @Description 根据用户ID更新用户信息
+// @Description 根据用户ID更新用户信息（只能修改自己，管理员可以修改任何用户）；提供 If-Match 时，用户在读取之后已被修改则拒绝更新
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param id path int true "用户ID"
+// @Param If-Match header string false "获取用户时返回的 ETag"
 // @Param user body models.UpdateUserRequest true "更新的用户信息"
 // @Success 200 {object} response.Response{data=models.User} "更新成功"
+// @Header 200 {string} ETag "更新后的用户版本号"
 // @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "无权访问该用户"
 // @Failure 404 {object} response.Response "用户不存在"
+// @Failure 409 {object} response.Response "更新时用户已被其他请求修改"
+// @Failure 412 {object} response.Response "If-Match 与用户当前的 ETag 不一致"
 // @Failure 500 {object} response.Response "服务器内部错误"
 // @Router /api/v1/users/{id} [put]
 func (h *UserHandler) UpdateUser() gin.HandlerFunc {
@@ -140,6 +176,10 @@ func (h *UserHandler) UpdateUser() gin.HandlerFunc {
 			c.Error(err)
 			return
 		}
+		if req.ExpectedVersion, err = ifMatchVersion(c); err != nil {
+			c.Error(err)
+			return
+		}
 
 		user, err := h.userService.UpdateUser(c.Request.Context(), id, &req)
 		if err != nil {
@@ -147,13 +187,66 @@ func (h *UserHandler) UpdateUser() gin.HandlerFunc {
 			return
 		}
 
+		c.Header("ETag", versionETag(user.Version))
+		response.Success(c, i18n.UserMessage(i18n.UserUpdateSuccess), user)
+	}
+}
+
+// PatchUser 部分更新用户
+// @Summary 部分更新用户信息
+// @Description 按 JSON Merge Patch（application/merge-patch+json）或 JSON Patch（application/json-patch+json）部分更新用户，能区分字段未提供、null 和零值；修改 role 需要 roles:assign 权限，password 只有管理员可以为其他用户设置
+// @Tags users
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param If-Match header string false "获取用户时返回的 ETag"
+// @Param patch body object true "补丁"
+// @Success 200 {object} response.Response{data=models.User} "更新成功"
+// @Header 200 {string} ETag "更新后的用户版本号"
+// @Failure 400 {object} response.Response "补丁无效或应用后的用户信息无效"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "无权访问该用户或修改角色"
+// @Failure 404 {object} response.Response "用户不存在"
+// @Failure 409 {object} response.Response "test 操作未通过或用户已被其他请求修改"
+// @Failure 412 {object} response.Response "If-Match 与用户当前的 ETag 不一致"
+// @Failure 415 {object} response.Response "不支持的补丁格式"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/users/{id} [patch]
+func (h *UserHandler) PatchUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidID), idStr), err))
+			return
+		}
+
+		patch, err := c.GetRawData()
+		if err != nil {
+			c.Error(errors.NewBadRequestError(i18n.UserMessage(i18n.UserErrorBadRequest), err))
+			return
+		}
+		req := models.PatchUserRequest{ContentType: c.ContentType(), Patch: patch}
+		if req.ExpectedVersion, err = ifMatchVersion(c); err != nil {
+			c.Error(err)
+			return
+		}
+
+		user, err := h.userService.PatchUser(c.Request.Context(), id, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.Header("ETag", versionETag(user.Version))
 		response.Success(c, i18n.UserMessage(i18n.UserUpdateSuccess), user)
 	}
 }
 
 // DeleteUser 删除用户
 // @Summary 删除用户
-// @Description 根据用户ID删除用户
+// @Description 根据用户ID删除用户（软删除，保留期内管理员可以恢复）
 // @Tags users
 // @Accept json
 // @Produce json
@@ -184,6 +277,73 @@ func (h *UserHandler) DeleteUser() gin.HandlerFunc {
 	}
 }
 
+// RestoreUser 恢复已删除的用户
+// @Summary 恢复已删除的用户
+// @Description 恢复保留期内已软删除的用户（需要 users:restore 权限），恢复后用户需要重新登录
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response{data=models.User} "恢复成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 404 {object} response.Response "用户不存在或未被删除"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/users/{id}/restore [post]
+func (h *UserHandler) RestoreUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidID), idStr), err))
+			return
+		}
+
+		user, err := h.userService.RestoreUser(c.Request.Context(), id)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserRestoreSuccess), user)
+	}
+}
+
+// PurgeUser 彻底删除用户
+// @Summary 彻底删除用户
+// @Description 彻底删除已软删除的用户（需要 users:purge 权限），删除后不可恢复
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 404 {object} response.Response "用户不存在或未被删除"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/users/{id}/purge [delete]
+func (h *UserHandler) PurgeUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			c.Error(errors.NewBadRequestError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorInvalidID), idStr), err))
+			return
+		}
+
+		if err := h.userService.PurgeUser(c.Request.Context(), id); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserPurgeSuccess), nil)
+	}
+}
+
 // Register 用户注册
 // @Summary 用户注册
 // @Description 用户注册新账户
@@ -215,14 +375,16 @@ func (h *UserHandler) Register() gin.HandlerFunc {
 
 // Login 用户登录
 // @Summary 用户登录
-// @Description 用户登录并获取JWT令牌
+// @Description 用户登录并获取JWT令牌；账户启用了两步验证时返回 mfa_token，需调用 /api/v1/auth/mfa/verify 完成登录
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param login body models.LoginRequest true "登录信息"
 // @Success 200 {object} response.Response{data=models.LoginResponse} "登录成功"
+// @Success 202 {object} response.Response{data=models.MFAChallengeResponse} "需要两步验证"
 // @Failure 400 {object} response.Response "请求参数错误"
 // @Failure 401 {object} response.Response "邮箱或密码错误"
+// @Failure 429 {object} response.Response "登录失败次数过多，请稍后重试"
 // @Failure 500 {object} response.Response "服务器内部错误"
 // @Router /api/v1/auth/login [post]
 func (h *UserHandler) Login() gin.HandlerFunc {
@@ -233,12 +395,18 @@ func (h *UserHandler) Login() gin.HandlerFunc {
 			return
 		}
 
-		resp, err := h.userService.Login(c.Request.Context(), &req)
+		resp, challenge, err := h.userService.Login(c.Request.Context(), &req)
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
+		// 密码正确但还需要两步验证
+		if challenge != nil {
+			response.SuccessWithCode(c, http.StatusAccepted, i18n.UserMessage(i18n.UserLoginMFARequired), challenge)
+			return
+		}
+
 		response.Success(c, i18n.UserMessage(i18n.UserLoginSuccess), resp)
 	}
 }
@@ -272,3 +440,32 @@ func (h *UserHandler) RefreshToken() gin.HandlerFunc {
 		response.Success(c, i18n.UserMessage(i18n.UserRefreshTokenSuccess), resp)
 	}
 }
+
+// Logout 退出登录
+// @Summary 退出登录
+// @Description 撤销刷新令牌所属的会话（all=true 时撤销该用户的所有会话）
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param logout body models.LogoutRequest true "退出登录请求"
+// @Success 200 {object} response.Response "退出成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 401 {object} response.Response "无效的刷新令牌"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/auth/logout [post]
+func (h *UserHandler) Logout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.LogoutRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := h.userService.Logout(c.Request.Context(), &req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Success(c, i18n.UserMessage(i18n.UserLogoutSuccess), nil)
+	}
+}
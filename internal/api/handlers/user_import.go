@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gin/internal/api/response"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBodySize 导入文件的最大字节数
+const maxImportBodySize = 10 << 20
+
+// userFileContentTypes 导入导出文件格式对应的 Content-Type
+var userFileContentTypes = map[string]string{
+	models.UserFileFormatCSV:    "text/csv",
+	models.UserFileFormatNDJSON: "application/x-ndjson",
+}
+
+// userFileFormat 返回请求指定的文件格式：优先使用 format 参数，否则按 Content-Type 识别
+func userFileFormat(format, contentType string) string {
+	if format != "" {
+		return format
+	}
+	for f, ct := range userFileContentTypes {
+		if contentType == ct {
+			return f
+		}
+	}
+	return ""
+}
+
+// ImportUsers 批量导入用户
+// @Summary 批量导入用户
+// @Description 请求体为 CSV（第一行为表头，需要 name、email、password 列，age、role 可选）或 NDJSON（每行一个用户）文件；返回每一行的校验与导入结果。atomic 模式任一行失败时不导入任何用户，best_effort 模式跳过失败的行；dry_run 只校验不写入
+// @Tags users
+// @Accept text/csv,application/x-ndjson
+// @Produce json
+// @Security ApiKeyAuth
+// @Param format query string false "文件格式：csv、ndjson（默认按 Content-Type 识别）"
+// @Param mode query string false "导入模式：atomic（默认）、best_effort"
+// @Param dry_run query bool false "只校验不写入"
+// @Param file body string true "导入文件"
+// @Success 200 {object} response.Response{data=models.ImportUsersResult} "导入结果"
+// @Failure 400 {object} response.Response "文件格式错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Failure 413 {object} response.Response "文件过大"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/users/import [post]
+func (h *UserHandler) ImportUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var opts models.ImportUsersOptions
+		if err := c.ShouldBindQuery(&opts); err != nil {
+			c.Error(err)
+			return
+		}
+		if opts.Format = userFileFormat(opts.Format, c.ContentType()); opts.Format == "" {
+			c.Error(errors.NewBadRequestError(i18n.UserMessage(i18n.UserErrorImportFormat), fmt.Errorf("unknown content type: %q", c.ContentType())))
+			return
+		}
+
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodySize)
+		result, err := h.userService.ImportUsers(c.Request.Context(), body, opts)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if stderrors.As(err, &tooLarge) {
+				err = errors.NewRequestEntityTooLargeError(fmt.Sprintf(i18n.UserMessage(i18n.UserErrorImportTooLarge), maxImportBodySize>>20), err)
+			}
+			c.Error(err)
+			return
+		}
+
+		message := i18n.UserMessage(i18n.UserImportSuccess)
+		switch {
+		case result.DryRun:
+			message = i18n.UserMessage(i18n.UserImportDryRun)
+		case !result.Committed && result.Failed > 0:
+			message = i18n.UserMessage(i18n.UserImportRejected)
+		}
+		response.Success(c, message, result)
+	}
+}
+
+// ExportUsers 批量导出用户
+// @Summary 批量导出用户
+// @Description 按过滤条件以 CSV 或 NDJSON 格式流式导出用户（不含密码），按 ID 排序
+// @Tags users
+// @Produce text/csv,application/x-ndjson
+// @Security ApiKeyAuth
+// @Param format query string false "文件格式：csv（默认）、ndjson"
+// @Param name query string false "名称包含"
+// @Param email query string false "邮箱包含"
+// @Param role query string false "角色名"
+// @Param min_age query int false "最小年龄"
+// @Param max_age query int false "最大年龄"
+// @Param created_after query string false "创建时间不早于（RFC 3339）"
+// @Param created_before query string false "创建时间早于（RFC 3339）"
+// @Param deleted query bool false "只导出已删除的用户（需要 users:restore 权限）"
+// @Success 200 {file} file "导出文件"
+// @Failure 400 {object} response.Response "查询参数错误"
+// @Failure 401 {object} response.Response "未授权"
+// @Failure 403 {object} response.Response "权限不足"
+// @Router /api/v1/users/export [get]
+func (h *UserHandler) ExportUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter models.UserFilter
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			c.Error(err)
+			return
+		}
+		format := c.DefaultQuery("format", models.UserFileFormatCSV)
+		contentType, ok := userFileContentTypes[format]
+		if !ok {
+			c.Error(errors.NewBadRequestError(i18n.UserMessage(i18n.UserErrorImportFormat), fmt.Errorf("invalid export format: %q", format)))
+			return
+		}
+
+		// 写入第一块数据之前出错（如权限错误）时仍按统一格式返回错误；开始写入后出错只能中断响应
+		w := &lazyHeaderWriter{ResponseWriter: c.Writer, writeHeader: func() {
+			c.Header("Content-Type", contentType)
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="users-%s.%s"`, time.Now().Format("20060102150405"), format))
+			c.Status(http.StatusOK)
+		}}
+		if err := h.userService.ExportUsers(c.Request.Context(), w, format, filter); err != nil {
+			c.Error(err)
+			return
+		}
+		if !w.written {
+			w.writeHeader()
+		}
+	}
+}
+
+// lazyHeaderWriter 在第一次写入时才设置响应头，出错时可以改为返回错误响应
+type lazyHeaderWriter struct {
+	gin.ResponseWriter
+	writeHeader func()
+	written     bool
+}
+
+// Write 写入数据，第一次写入前先设置响应头
+func (w *lazyHeaderWriter) Write(data []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		w.writeHeader()
+	}
+	return w.ResponseWriter.Write(data)
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"gin/internal/errors"
 	"gin/internal/models"
@@ -46,12 +49,12 @@ func (m *MockUserService) GetUserByEmail(ctx context.Context, email string) (*mo
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
-	args := m.Called(ctx)
+func (m *MockUserService) GetAllUsers(ctx context.Context, req *models.ListUsersRequest) (*models.Page[*models.User], error) {
+	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]*models.User), args.Error(1)
+	return args.Get(0).(*models.Page[*models.User]), args.Error(1)
 }
 
 func (m *MockUserService) UpdateUser(ctx context.Context, id int64, req *models.UpdateUserRequest) (*models.User, error) {
@@ -62,12 +65,62 @@ func (m *MockUserService) UpdateUser(ctx context.Context, id int64, req *models.
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserService) PatchUser(ctx context.Context, id int64, req *models.PatchUserRequest) (*models.User, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) ImportUsers(ctx context.Context, r io.Reader, opts models.ImportUsersOptions) (*models.ImportUsersResult, error) {
+	data, _ := io.ReadAll(r)
+	args := m.Called(ctx, string(data), opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportUsersResult), args.Error(1)
+}
+
+func (m *MockUserService) ExportUsers(ctx context.Context, w io.Writer, format string, filter models.UserFilter) error {
+	args := m.Called(ctx, format, filter)
+	if data := args.String(0); data != "" {
+		_, _ = io.WriteString(w, data)
+	}
+	return args.Error(1)
+}
+
 func (m *MockUserService) DeleteUser(ctx context.Context, id int64) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockUserService) Login(ctx context.Context, req *models.LoginRequest) (*models.LoginResponse, error) {
+func (m *MockUserService) RestoreUser(ctx context.Context, id int64) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) PurgeUser(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserService) Login(ctx context.Context, req *models.LoginRequest) (*models.LoginResponse, *models.MFAChallengeResponse, error) {
+	args := m.Called(ctx, req)
+	resp, _ := args.Get(0).(*models.LoginResponse)
+	challenge, _ := args.Get(1).(*models.MFAChallengeResponse)
+	return resp, challenge, args.Error(2)
+}
+
+func (m *MockUserService) VerifyMFA(ctx context.Context, req *models.MFAVerifyRequest) (*models.LoginResponse, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -75,6 +128,64 @@ func (m *MockUserService) Login(ctx context.Context, req *models.LoginRequest) (
 	return args.Get(0).(*models.LoginResponse), args.Error(1)
 }
 
+func (m *MockUserService) GetMFAStatus(ctx context.Context, userID int64) (*models.MFAStatusResponse, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFAStatusResponse), args.Error(1)
+}
+
+func (m *MockUserService) SetupMFA(ctx context.Context, userID int64) (*models.MFASetupResponse, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFASetupResponse), args.Error(1)
+}
+
+func (m *MockUserService) EnableMFA(ctx context.Context, userID int64, req *models.MFACodeRequest) (*models.MFARecoveryCodesResponse, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFARecoveryCodesResponse), args.Error(1)
+}
+
+func (m *MockUserService) DisableMFA(ctx context.Context, userID int64, req *models.MFADisableRequest) error {
+	args := m.Called(ctx, userID, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) GetMe(ctx context.Context, userID int64) (*models.Me, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Me), args.Error(1)
+}
+
+func (m *MockUserService) PatchMe(ctx context.Context, userID int64, req *models.PatchMeRequest) (*models.Me, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Me), args.Error(1)
+}
+
+func (m *MockUserService) DeleteMe(ctx context.Context, userID int64, req *models.DeleteMeRequest) error {
+	args := m.Called(ctx, userID, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) RegenerateRecoveryCodes(ctx context.Context, userID int64, req *models.MFACodeRequest) (*models.MFARecoveryCodesResponse, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFARecoveryCodesResponse), args.Error(1)
+}
+
 func (m *MockUserService) RefreshToken(ctx context.Context, req *models.RefreshTokenRequest) (*models.RefreshTokenResponse, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
@@ -83,6 +194,49 @@ func (m *MockUserService) RefreshToken(ctx context.Context, req *models.RefreshT
 	return args.Get(0).(*models.RefreshTokenResponse), args.Error(1)
 }
 
+func (m *MockUserService) Logout(ctx context.Context, req *models.LogoutRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]*models.Session, error) {
+	args := m.Called(ctx, userID, currentSessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *MockUserService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ForceLogout(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserService) UnlockUser(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ChangePassword(ctx context.Context, userID int64, req *models.ChangePasswordRequest) error {
+	args := m.Called(ctx, userID, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ForgotPassword(ctx context.Context, req *models.ForgotPasswordRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
 // setupTestRouter 设置测试路由
 func setupTestRouter(handler *UserHandler) *gin.Engine {
 	gin.SetMode(gin.TestMode)
@@ -96,9 +250,26 @@ func setupTestRouter(handler *UserHandler) *gin.Engine {
 	{
 		users.POST("", handler.CreateUser())
 		users.GET("", handler.GetAllUsers())
+		users.POST("/import", handler.ImportUsers())
+		users.GET("/export", handler.ExportUsers())
 		users.GET("/:id", handler.GetUser())
 		users.PUT("/:id", handler.UpdateUser())
+		users.PATCH("/:id", handler.PatchUser())
 		users.DELETE("/:id", handler.DeleteUser())
+		users.POST("/:id/restore", handler.RestoreUser())
+		users.DELETE("/:id/purge", handler.PurgeUser())
+	}
+
+	// 模拟认证中间件：X-User-ID 请求头作为当前用户
+	me := users.Group("/me", func(c *gin.Context) {
+		if id, err := strconv.ParseInt(c.GetHeader("X-User-ID"), 10, 64); err == nil {
+			c.Set("user_id", id)
+		}
+	})
+	{
+		me.GET("", handler.GetMe())
+		me.PATCH("", handler.PatchMe())
+		me.DELETE("", handler.DeleteMe())
 	}
 
 	return router
@@ -169,10 +340,11 @@ func TestUserHandler_GetUser(t *testing.T) {
 		router := setupTestRouter(handler)
 
 		expectedUser := &models.User{
-			ID:    1,
-			Name:  "张三",
-			Email: "zhangsan@example.com",
-			Age:   25,
+			ID:      1,
+			Name:    "张三",
+			Email:   "zhangsan@example.com",
+			Age:     25,
+			Version: 3,
 		}
 
 		mockService.On("GetUserByID", mock.Anything, int64(1)).Return(expectedUser, nil)
@@ -183,6 +355,7 @@ func TestUserHandler_GetUser(t *testing.T) {
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
 
 		var response map[string]interface{}
 		err := json.Unmarshal(w.Body.Bytes(), &response)
@@ -219,9 +392,11 @@ func TestUserHandler_GetAllUsers(t *testing.T) {
 			{ID: 2, Name: "用户2", Email: "user2@example.com", Age: 25},
 		}
 
-		mockService.On("GetAllUsers", mock.Anything).Return(expectedUsers, nil)
+		mockService.On("GetAllUsers", mock.Anything, mock.MatchedBy(func(req *models.ListUsersRequest) bool {
+			return req.Role == "user" && req.MinAge != nil && *req.MinAge == 18 && req.Limit == 2 && req.Sort == "-age"
+		})).Return(&models.Page[*models.User]{Items: expectedUsers, Total: 5, Limit: 2, NextCursor: "next", HasMore: true}, nil)
 
-		req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users?role=user&min_age=18&limit=2&sort=-age", nil)
 		w := httptest.NewRecorder()
 
 		router.ServeHTTP(w, req)
@@ -232,9 +407,30 @@ func TestUserHandler_GetAllUsers(t *testing.T) {
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		require.NoError(t, err)
 		assert.Equal(t, float64(200), response["code"])
+		assert.Len(t, response["data"], 2)
+
+		pagination, ok := response["pagination"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, float64(5), pagination["total"])
+		assert.Equal(t, "next", pagination["next_cursor"])
+		assert.Equal(t, true, pagination["has_more"])
 
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("无效的查询参数", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users?limit=1000", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "GetAllUsers")
+	})
 }
 
 // TestUserHandler_UpdateUser 测试更新用户处理器
@@ -274,6 +470,192 @@ func TestUserHandler_UpdateUser(t *testing.T) {
 
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("If-Match版本号传递给服务并返回新的ETag", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		updatedUser := &models.User{ID: 1, Name: "更新后的名称", Version: 4}
+		mockService.On("UpdateUser", mock.Anything, int64(1), mock.MatchedBy(func(req *models.UpdateUserRequest) bool {
+			return req.ExpectedVersion == 3
+		})).Return(updatedUser, nil)
+
+		body, _ := json.Marshal(models.UpdateUserRequest{Name: "更新后的名称"})
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/users/1", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"3"`)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("无效的If-Match应该返回412", func(t *testing.T) {
+		for _, ifMatch := range []string{`W/"3"`, `"3", "4"`, `3`, `"abc"`} {
+			mockService := new(MockUserService)
+			handler := NewUserHandler(mockService)
+			router := setupTestRouter(handler)
+
+			body, _ := json.Marshal(models.UpdateUserRequest{Name: "更新后的名称"})
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/users/1", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", ifMatch)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusPreconditionFailed, w.Code, ifMatch)
+			mockService.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+}
+
+// TestUserHandler_PatchUser 测试部分更新用户处理器
+func TestUserHandler_PatchUser(t *testing.T) {
+	t.Run("补丁原样传递给服务并返回新的ETag", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		patch := `{"age":0,"name":null}`
+		mockService.On("PatchUser", mock.Anything, int64(1), mock.MatchedBy(func(req *models.PatchUserRequest) bool {
+			return req.ContentType == "application/merge-patch+json" && string(req.Patch) == patch && req.ExpectedVersion == 3
+		})).Return(&models.User{ID: 1, Name: "张三", Version: 4}, nil)
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/1", bytes.NewBufferString(patch))
+		req.Header.Set("Content-Type", "application/merge-patch+json; charset=utf-8")
+		req.Header.Set("If-Match", `"3"`)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("无效的If-Match应该返回412", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/1", bytes.NewBufferString(`{"age":0}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", `W/"3"`)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		mockService.AssertNotCalled(t, "PatchUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// TestUserHandler_Me 测试当前用户处理器
+func TestUserHandler_Me(t *testing.T) {
+	t.Run("获取当前用户并返回ETag", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		mockService.On("GetMe", mock.Anything, int64(7)).Return(&models.Me{
+			User:    models.User{ID: 7, Name: "张三", Version: 2},
+			Profile: &models.UserProfile{Bio: "你好", Preferences: json.RawMessage(`{"theme":"dark"}`)},
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		req.Header.Set("X-User-ID", "7")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, float64(7), response.Data["id"])
+		assert.Equal(t, map[string]interface{}{"theme": "dark"}, response.Data["profile"].(map[string]interface{})["preferences"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("未认证应该返回401", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertNotCalled(t, "GetMe", mock.Anything, mock.Anything)
+	})
+
+	t.Run("补丁和当前密码原样传递给服务并返回新的ETag", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		patch := `{"email":"new@example.com","profile":{"bio":"新简介"}}`
+		mockService.On("PatchMe", mock.Anything, int64(7), mock.MatchedBy(func(req *models.PatchMeRequest) bool {
+			return req.ContentType == "application/merge-patch+json" && string(req.Patch) == patch && req.ExpectedVersion == 2 &&
+				req.CurrentPassword == "password123"
+		})).Return(&models.Me{User: models.User{ID: 7, Version: 3}, Profile: &models.UserProfile{Bio: "新简介"}}, nil)
+
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/me", bytes.NewBufferString(patch))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", `"2"`)
+		req.Header.Set(CurrentPasswordHeader, "password123")
+		req.Header.Set("X-User-ID", "7")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("注销需要密码", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "7")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "DeleteMe", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("成功注销", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		mockService.On("DeleteMe", mock.Anything, int64(7), &models.DeleteMeRequest{Password: "password123"}).Return(nil)
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me", bytes.NewBufferString(`{"password":"password123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "7")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
 }
 
 // TestUserHandler_DeleteUser 测试删除用户处理器
@@ -300,3 +682,121 @@ func TestUserHandler_DeleteUser(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 }
+
+// TestUserHandler_RestoreAndPurgeUser 测试恢复和彻底删除用户处理器
+func TestUserHandler_RestoreAndPurgeUser(t *testing.T) {
+	t.Run("成功恢复用户", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		mockService.On("RestoreUser", mock.Anything, int64(1)).Return(&models.User{ID: 1, Name: "张三", Email: "zhangsan@example.com"}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/1/restore", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, "用户已恢复", response["message"])
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("彻底删除未删除的用户返回404", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		mockService.On("PurgeUser", mock.Anything, int64(1)).Return(errors.NewNotFoundError("用户不存在或未被删除", nil))
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/1/purge", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestUserHandler_ImportExportUsers 测试批量导入导出用户处理器
+func TestUserHandler_ImportExportUsers(t *testing.T) {
+	t.Run("按Content-Type识别导入格式", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		body := "name,email,password\n张三,zhangsan@example.com,Passw0rd!\n"
+		opts := models.ImportUsersOptions{Format: models.UserFileFormatCSV, Mode: models.ImportModeBestEffort, DryRun: true}
+		mockService.On("ImportUsers", mock.Anything, body, opts).Return(&models.ImportUsersResult{
+			Mode: models.ImportModeBestEffort, DryRun: true, Total: 1,
+			Rows: []models.ImportRowResult{{Line: 2, Email: "zhangsan@example.com", Status: models.ImportRowValid}},
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/import?mode=best_effort&dry_run=true", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("无法识别导入格式返回400", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/import", bytes.NewBufferString(`[]`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "ImportUsers", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("导出时设置Content-Type和附件文件名", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		mockService.On("ExportUsers", mock.Anything, models.UserFileFormatNDJSON, models.UserFilter{Role: "user"}).
+			Return(`{"id":1,"name":"张三"}`+"\n", nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/export?format=ndjson&role=user", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".ndjson")
+		assert.Equal(t, `{"id":1,"name":"张三"}`+"\n", w.Body.String())
+	})
+
+	t.Run("开始写入前出错时返回JSON错误", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+		router := setupTestRouter(handler)
+
+		mockService.On("ExportUsers", mock.Anything, models.UserFileFormatCSV, models.UserFilter{Deleted: true}).
+			Return("", errors.NewForbiddenError("权限不足", nil))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/export?deleted=true", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+
+	"gin/internal/errors"
+	"gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditRecorder 记录审计事件（由 service.AuditService 实现）
+type AuditRecorder interface {
+	Record(ctx context.Context, entry *models.AuditLog)
+}
+
+// AuditMiddleware 审计中间件
+// 记录认证中间件拒绝的请求（401）和所有权限不足的请求（403，包括 service 层的资源级鉴权）；
+// 需注册在 RequestIDMiddleware 之后，业务操作的审计日志由 service 层记录
+func AuditMiddleware(recorder AuditRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		var entry *models.AuditLog
+		switch responseStatus(c) {
+		case http.StatusUnauthorized:
+			// 只记录被认证中间件中止的请求，登录失败等由 service 层记录
+			if !c.IsAborted() {
+				return
+			}
+			entry = &models.AuditLog{Action: models.AuditAccessUnauthorized, Outcome: models.AuditOutcomeFailure}
+		case http.StatusForbidden:
+			entry = &models.AuditLog{Action: models.AuditAccessDenied, Outcome: models.AuditOutcomeDenied}
+		default:
+			return
+		}
+
+		entry.Detail = c.Request.Method + " " + c.Request.URL.Path
+		// 认证通过后 request context 中带有操作者，由记录器读取
+		recorder.Record(c.Request.Context(), entry)
+	}
+}
+
+// responseStatus 返回请求的响应状态码
+// 处理器通过 c.Error 返回的错误由外层的 ErrorHandler 写入响应，此时按错误的状态码计算
+func responseStatus(c *gin.Context) int {
+	if !c.Writer.Written() && len(c.Errors) > 0 {
+		var appErr *errors.AppError
+		if stderrors.As(c.Errors.Last().Err, &appErr) {
+			return appErr.Code
+		}
+	}
+	return c.Writer.Status()
+}
@@ -1,21 +1,26 @@
 package middleware
 
 import (
-	"strings"
-	"time"
+	stderrors "errors"
+	"log"
+	"slices"
 
 	"gin/internal/api/response"
 	"gin/internal/auth"
 	"gin/internal/config"
 	"gin/internal/i18n"
 	"gin/internal/logger"
+	"gin/internal/repository"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 // AuthMiddleware 认证中间件
-func AuthMiddleware(jwtConfig *auth.JWTConfig) gin.HandlerFunc {
+// 按顺序尝试各认证方式（如访问令牌、API密钥），使用第一个在请求中找到凭据的认证方式；
+// 认证通过后写入相同的上下文键（user_id、role 等）；RequirePermission 按API密钥的权限范围限制，
+// RequireRole / RequireSession 不接受API密钥
+func AuthMiddleware(authenticators ...Authenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取请求ID（如果存在）
 		requestID, _ := c.Get("request_id")
@@ -27,10 +32,40 @@ func AuthMiddleware(jwtConfig *auth.JWTConfig) gin.HandlerFunc {
 		path := c.Request.URL.Path
 		method := c.Request.Method
 
-		// 从请求头获取Authorization
-		authHeader := c.GetHeader("Authorization")
+		var identity *Identity
+		for _, authenticator := range authenticators {
+			found, err := authenticator.Authenticate(c)
+			if err != nil {
+				var authErr *AuthError
+				if stderrors.As(err, &authErr) {
+					fields := append([]zap.Field{
+						zap.String("request_id", requestIDStr),
+						zap.String("path", path),
+						zap.String("method", method),
+					}, authErr.Fields...)
+					logger.Log.Warn(i18n.LogMessage(authErr.LogKey), fields...)
+					response.Unauthorized(c, i18n.UserMessage(authErr.UserKey), nil)
+					c.Abort()
+					return
+				}
 
-		if authHeader == "" {
+				logger.Log.Error(i18n.LogMessage(i18n.LogInternalError),
+					zap.String("request_id", requestIDStr),
+					zap.String("path", path),
+					zap.String("method", method),
+					zap.Error(err),
+				)
+				response.InternalServerError(c, i18n.UserMessage(i18n.UserErrorInternal), err)
+				c.Abort()
+				return
+			}
+			if found != nil {
+				identity = found
+				break
+			}
+		}
+
+		if identity == nil {
 			logger.Log.Warn(i18n.LogMessage(i18n.LogAuthFailedNoToken),
 				zap.String("request_id", requestIDStr),
 				zap.String("path", path),
@@ -41,56 +76,77 @@ func AuthMiddleware(jwtConfig *auth.JWTConfig) gin.HandlerFunc {
 			return
 		}
 
-		// 提取Bearer令牌
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			logger.Log.Warn(i18n.LogMessage(i18n.LogAuthFailedInvalidFmt),
-				zap.String("request_id", requestIDStr),
-				zap.String("path", path),
-				zap.String("method", method),
-				zap.String("token_prefix", parts[0]),
-			)
-			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalidFmt), nil)
-			c.Abort()
-			return
+		// 将用户信息存储在请求上下文中
+		c.Set("user_id", identity.UserID)
+		c.Set("email", identity.Email)
+		c.Set("name", identity.Name)
+		c.Set("role", identity.Role)
+		c.Set("session_id", identity.SessionID)
+		c.Set("token_id", identity.TokenID)
+		if identity.APIKeyID != 0 {
+			c.Set("api_key_id", identity.APIKeyID)
 		}
-
-		tokenString := parts[1]
-
-		// 验证令牌
-		claims, err := jwtConfig.ParseToken(tokenString)
-		if err != nil {
-			logger.Log.Warn(i18n.LogMessage(i18n.LogAuthFailedInvalid),
-				zap.String("request_id", requestIDStr),
-				zap.String("path", path),
-				zap.String("method", method),
-				zap.Error(err),
-			)
-			response.Unauthorized(c, i18n.UserMessage(i18n.UserAuthInvalid), nil)
-			c.Abort()
-			return
+		if identity.Scopes != nil {
+			c.Set("scopes", identity.Scopes)
 		}
-
-		// 将用户信息存储在请求上下文中
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("name", claims.Name)
-		c.Set("role", claims.Role)
+		// 同时写入 request context，供 service 层做资源级鉴权
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{
+			UserID: identity.UserID,
+			Role:   identity.Role,
+		}))
 
 		logger.Log.Debug(i18n.LogMessage(i18n.LogAuthSuccess),
 			zap.String("request_id", requestIDStr),
 			zap.String("path", path),
 			zap.String("method", method),
-			zap.Int64("user_id", claims.UserID),
-			zap.String("email", claims.Email),
-			zap.String("role", claims.Role.String()),
+			zap.Int64("user_id", identity.UserID),
+			zap.String("email", identity.Email),
+			zap.String("role", identity.Role.String()),
+			zap.Int64("api_key_id", identity.APIKeyID),
 		)
 
 		c.Next()
 	}
 }
 
+// RequireSession 要求通过访问令牌（用户登录会话）认证的中间件
+// 用于修改密码、两步验证、会话管理等账户自身的敏感操作：这些操作不对应任何权限，无法用权限范围约束，
+// 因此不接受API密钥，避免只读的密钥也能接管账户
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rejectAPIKey(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// rejectAPIKey 当前请求使用API密钥认证时返回403并中止请求
+func rejectAPIKey(c *gin.Context) bool {
+	if _, exists := c.Get("api_key_id"); !exists {
+		return false
+	}
+
+	// 获取请求ID（如果存在）
+	requestID, _ := c.Get("request_id")
+	requestIDStr := ""
+	if id, ok := requestID.(string); ok {
+		requestIDStr = id
+	}
+
+	logger.Log.Warn(i18n.LogMessage(i18n.LogPermissionDeniedAPIKey),
+		zap.String("request_id", requestIDStr),
+		zap.String("path", c.Request.URL.Path),
+		zap.String("method", c.Request.Method),
+		zap.Int64("api_key_id", c.GetInt64("api_key_id")),
+	)
+	response.Forbidden(c, i18n.UserMessage(i18n.UserPermissionDenied), nil)
+	c.Abort()
+	return true
+}
+
 // RequireRole 要求特定角色的中间件
+// 角色无法用API密钥的权限范围约束，使用API密钥认证时直接拒绝，API密钥可访问的路由应使用 RequirePermission
 func RequireRole(requiredRole auth.Role) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取请求ID（如果存在）
@@ -100,6 +156,10 @@ func RequireRole(requiredRole auth.Role) gin.HandlerFunc {
 			requestIDStr = id
 		}
 
+		if rejectAPIKey(c) {
+			return
+		}
+
 		// 从上下文中获取用户角色
 		roleValue, exists := c.Get("role")
 		if !exists {
@@ -155,14 +215,83 @@ func RequireAdmin() gin.HandlerFunc {
 	return RequireRole(auth.RoleAdmin)
 }
 
+// RequirePermission 要求角色被授予指定权限的中间件（如 "users:delete"）
+// 授权关系来自数据库并缓存在 auth.Permissions 中；使用API密钥认证时，权限还须在密钥的权限范围内
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 获取请求ID（如果存在）
+		requestID, _ := c.Get("request_id")
+		requestIDStr := ""
+		if id, ok := requestID.(string); ok {
+			requestIDStr = id
+		}
+
+		// 从上下文中获取用户角色
+		roleValue, exists := c.Get("role")
+		if !exists {
+			logger.Log.Warn(i18n.LogMessage(i18n.LogPermissionDeniedNoRole),
+				zap.String("request_id", requestIDStr),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("method", c.Request.Method),
+			)
+			response.Forbidden(c, i18n.UserMessage(i18n.UserPermissionDenied), nil)
+			c.Abort()
+			return
+		}
+
+		role, ok := roleValue.(auth.Role)
+		if !ok {
+			logger.Log.Warn(i18n.LogMessage(i18n.LogPermissionDeniedInvalidRole),
+				zap.String("request_id", requestIDStr),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("method", c.Request.Method),
+			)
+			response.Forbidden(c, i18n.UserMessage(i18n.UserPermissionDenied), nil)
+			c.Abort()
+			return
+		}
+
+		// 检查角色是否被授予该权限
+		if !role.HasPermission(permission) {
+			logger.Log.Warn(i18n.LogMessage(i18n.LogPermissionDeniedNoGrant),
+				zap.String("request_id", requestIDStr),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("method", c.Request.Method),
+				zap.String("user_role", role.String()),
+				zap.String("required_permission", permission),
+			)
+			response.Forbidden(c, i18n.UserMessage(i18n.UserPermissionDenied), nil)
+			c.Abort()
+			return
+		}
+
+		// API密钥只能使用其权限范围内的权限
+		if scopesValue, exists := c.Get("scopes"); exists {
+			if scopes, ok := scopesValue.([]string); ok && !slices.Contains(scopes, permission) {
+				logger.Log.Warn(i18n.LogMessage(i18n.LogPermissionDeniedScope),
+					zap.String("request_id", requestIDStr),
+					zap.String("path", c.Request.URL.Path),
+					zap.String("method", c.Request.Method),
+					zap.Int64("api_key_id", c.GetInt64("api_key_id")),
+					zap.String("required_permission", permission),
+				)
+				response.Forbidden(c, i18n.UserMessage(i18n.UserPermissionDenied), nil)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
 // NewAuthMiddleware 创建认证中间件实例
-func NewAuthMiddleware() gin.HandlerFunc {
-	// 从配置获取JWT密钥和过期时间
-	cfg := config.GetConfig()
-	jwtConfig := auth.NewJWTConfig(
-		cfg.JWT.SecretKey,
-		time.Duration(cfg.JWT.ExpiresIn)*time.Hour,
-	)
+// 按配置加载JWT签名密钥，密钥配置错误时直接退出
+func NewAuthMiddleware(revocationStore repository.TokenRevocationStore) gin.HandlerFunc {
+	jwtConfig, err := auth.LoadJWTConfig(&config.GetConfig().JWT)
+	if err != nil {
+		log.Fatalf("加载JWT密钥失败: %v", err)
+	}
 
-	return AuthMiddleware(jwtConfig)
+	return AuthMiddleware(NewJWTAuthenticator(jwtConfig, revocationStore))
 }
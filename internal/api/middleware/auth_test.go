@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/errors"
+	"gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAPIKeyVerifier 只认识一个密钥的 APIKeyVerifier
+type stubAPIKeyVerifier struct {
+	key  string
+	user *models.User
+	spec *models.APIKey
+}
+
+func (v *stubAPIKeyVerifier) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, *models.User, error) {
+	if key != v.key {
+		return nil, nil, errors.NewUnauthorizedError("API密钥无效", nil)
+	}
+	return v.spec, v.user, nil
+}
+
+// TestAuthMiddleware_Chain 测试访问令牌与API密钥两种认证方式
+func TestAuthMiddleware_Chain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	auth.Permissions.Load(map[auth.Role][]string{
+		auth.RoleUser: {auth.PermissionUsersRead, auth.PermissionUsersCreate},
+	})
+	defer auth.Permissions.Load(nil)
+
+	jwtConfig := auth.NewJWTConfig("test-secret", time.Hour)
+	verifier := &stubAPIKeyVerifier{
+		key:  auth.APIKeyPrefix + "valid",
+		user: &models.User{ID: 7, Email: "bot@example.com", Name: "机器人", Role: auth.RoleUser},
+		spec: &models.APIKey{ID: 3, UserID: 7, Scopes: []string{auth.PermissionUsersRead}},
+	}
+
+	router := gin.New()
+	authMiddleware := AuthMiddleware(NewJWTAuthenticator(jwtConfig, nil), NewAPIKeyAuthenticator(verifier))
+	router.GET("/read", authMiddleware, RequirePermission(auth.PermissionUsersRead), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetInt64("user_id"), "role": c.MustGet("role")})
+	})
+	router.POST("/create", authMiddleware, RequirePermission(auth.PermissionUsersCreate), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/user-only", authMiddleware, RequireRole(auth.RoleUser), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.POST("/session-only", authMiddleware, RequireSession(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("未提供凭据", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/read", nil).Code)
+	})
+
+	t.Run("访问令牌不受权限范围限制", func(t *testing.T) {
+		token, err := jwtConfig.GenerateToken(1, "zhangsan@example.com", "张三", auth.RoleUser, "sid")
+		require.NoError(t, err)
+		bearer := map[string]string{"Authorization": "Bearer " + token}
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/read", bearer).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/create", bearer).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/user-only", bearer).Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/session-only", bearer).Code)
+	})
+
+	t.Run("API密钥以所属用户的身份认证", func(t *testing.T) {
+		w := serve(http.MethodGet, "/read", map[string]string{APIKeyHeader: verifier.key})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"user_id":7,"role":"user"}`, w.Body.String())
+	})
+
+	t.Run("API密钥不能访问只按角色或只接受登录会话的路由", func(t *testing.T) {
+		// 角色和账户自身的敏感操作无法用权限范围约束
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/user-only", map[string]string{APIKeyHeader: verifier.key}).Code)
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/session-only", map[string]string{APIKeyHeader: verifier.key}).Code)
+	})
+
+	t.Run("API密钥不能使用权限范围以外的权限", func(t *testing.T) {
+		w := serve(http.MethodPost, "/create", map[string]string{APIKeyHeader: verifier.key})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("无效的凭据", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/read", map[string]string{APIKeyHeader: "gsk_wrong"}).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/read", map[string]string{"Authorization": "Bearer bad"}).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/read", map[string]string{"Authorization": "Basic abc"}).Code)
+	})
+}
+
+// recordingAuditRecorder 保存记录的审计事件
+type recordingAuditRecorder struct {
+	entries []*models.AuditLog
+	actors  []int64
+}
+
+func (r *recordingAuditRecorder) Record(ctx context.Context, entry *models.AuditLog) {
+	principal, _ := auth.PrincipalFromContext(ctx)
+	r.entries = append(r.entries, entry)
+	r.actors = append(r.actors, principal.UserID)
+}
+
+// TestAuditMiddleware 测试记录认证失败和权限不足的请求
+func TestAuditMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	jwtConfig := auth.NewJWTConfig("test-secret", time.Hour)
+	recorder := &recordingAuditRecorder{}
+
+	router := gin.New()
+	router.Use(errors.ErrorHandler(), AuditMiddleware(recorder))
+	authMiddleware := AuthMiddleware(NewJWTAuthenticator(jwtConfig, nil))
+	router.GET("/admin", authMiddleware, RequireRole(auth.RoleAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/owner", authMiddleware, func(c *gin.Context) {
+		c.Error(errors.NewForbiddenError("无权访问该用户", nil))
+	})
+	router.POST("/login", func(c *gin.Context) {
+		c.Error(errors.NewUnauthorizedError("邮箱或密码错误", nil))
+	})
+
+	token, err := jwtConfig.GenerateToken(5, "zhangsan@example.com", "张三", auth.RoleUser, "sid")
+	require.NoError(t, err)
+
+	serve := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/admin", ""))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/admin", token))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/owner", token))
+	// 登录失败由 service 层记录
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/login", ""))
+
+	require.Len(t, recorder.entries, 3)
+	assert.Equal(t, models.AuditAccessUnauthorized, recorder.entries[0].Action)
+	assert.Equal(t, "GET /admin", recorder.entries[0].Detail)
+	assert.Equal(t, int64(0), recorder.actors[0])
+	assert.Equal(t, models.AuditAccessDenied, recorder.entries[1].Action)
+	assert.Equal(t, models.AuditOutcomeDenied, recorder.entries[1].Outcome)
+	assert.Equal(t, int64(5), recorder.actors[1])
+	assert.Equal(t, "GET /owner", recorder.entries[2].Detail)
+}
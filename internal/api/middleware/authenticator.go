@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"gin/internal/auth"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/models"
+	"gin/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// APIKeyHeader 机器客户端传递API密钥的请求头
+const APIKeyHeader = "X-API-Key"
+
+// Identity 认证通过的调用者
+type Identity struct {
+	UserID    int64
+	Email     string
+	Name      string
+	Role      auth.Role
+	SessionID string // 仅访问令牌
+	TokenID   string // 仅访问令牌
+	APIKeyID  int64  // 仅API密钥
+	// Scopes API密钥允许使用的权限，为 nil 时不额外限制（访问令牌）
+	Scopes []string
+}
+
+// Authenticator 一种认证方式，由 AuthMiddleware 按顺序尝试
+type Authenticator interface {
+	// Authenticate 从请求中识别调用者
+	// 请求中没有本认证方式的凭据时返回 (nil, nil)，交给下一个认证方式；
+	// 凭据无效时返回 *AuthError（401），其他错误按服务器内部错误处理
+	Authenticate(c *gin.Context) (*Identity, error)
+}
+
+// AuthError 认证失败，LogKey 写入日志，UserKey 返回给客户端
+type AuthError struct {
+	LogKey  i18n.MessageKey
+	UserKey i18n.MessageKey
+	Fields  []zap.Field
+}
+
+// Error 实现error接口
+func (e *AuthError) Error() string {
+	return i18n.LogMessage(e.LogKey)
+}
+
+// jwtAuthenticator 通过 Authorization: Bearer <jwt> 认证
+type jwtAuthenticator struct {
+	jwtConfig       *auth.JWTConfig
+	revocationStore repository.TokenRevocationStore
+}
+
+// NewJWTAuthenticator 创建访问令牌认证方式
+// revocationStore 不为空时，签名有效的令牌还需通过撤销检查（退出登录、被删除、被强制下线）
+func NewJWTAuthenticator(jwtConfig *auth.JWTConfig, revocationStore repository.TokenRevocationStore) Authenticator {
+	return &jwtAuthenticator{jwtConfig: jwtConfig, revocationStore: revocationStore}
+}
+
+// Authenticate 校验访问令牌
+func (a *jwtAuthenticator) Authenticate(c *gin.Context) (*Identity, error) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return nil, nil
+	}
+
+	// 提取Bearer令牌
+	parts := strings.SplitN(authHeader, " ", 2)
+	if !(len(parts) == 2 && parts[0] == "Bearer") {
+		return nil, &AuthError{
+			LogKey:  i18n.LogAuthFailedInvalidFmt,
+			UserKey: i18n.UserAuthInvalidFmt,
+			Fields:  []zap.Field{zap.String("token_prefix", parts[0])},
+		}
+	}
+
+	// 验证令牌
+	claims, err := a.jwtConfig.ParseToken(parts[1])
+	if err != nil {
+		return nil, &AuthError{
+			LogKey:  i18n.LogAuthFailedInvalid,
+			UserKey: i18n.UserAuthInvalid,
+			Fields:  []zap.Field{zap.Error(err)},
+		}
+	}
+
+	// 检查令牌是否已被撤销
+	if a.revocationStore != nil {
+		revoked, err := a.revocationStore.IsRevoked(c.Request.Context(), claims)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, &AuthError{
+				LogKey:  i18n.LogAuthFailedRevoked,
+				UserKey: i18n.UserAuthRevoked,
+				Fields: []zap.Field{
+					zap.Int64("user_id", claims.UserID),
+					zap.String("jti", claims.ID),
+					zap.String("sid", claims.SessionID),
+				},
+			}
+		}
+	}
+
+	return &Identity{
+		UserID:    claims.UserID,
+		Email:     claims.Email,
+		Name:      claims.Name,
+		Role:      claims.Role,
+		SessionID: claims.SessionID,
+		TokenID:   claims.ID,
+	}, nil
+}
+
+// APIKeyVerifier 校验API密钥明文（由 service.APIKeyService 实现）
+type APIKeyVerifier interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, *models.User, error)
+}
+
+// apiKeyAuthenticator 通过 X-API-Key 请求头认证
+type apiKeyAuthenticator struct {
+	verifier APIKeyVerifier
+}
+
+// NewAPIKeyAuthenticator 创建API密钥认证方式
+// 请求以密钥所属用户的身份和当前角色执行，可使用的权限还受密钥的权限范围限制
+func NewAPIKeyAuthenticator(verifier APIKeyVerifier) Authenticator {
+	return &apiKeyAuthenticator{verifier: verifier}
+}
+
+// Authenticate 校验API密钥
+func (a *apiKeyAuthenticator) Authenticate(c *gin.Context) (*Identity, error) {
+	plain := c.GetHeader(APIKeyHeader)
+	if plain == "" {
+		return nil, nil
+	}
+
+	key, user, err := a.verifier.AuthenticateAPIKey(c.Request.Context(), plain)
+	if err != nil {
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) && appErr.Code == http.StatusUnauthorized {
+			return nil, &AuthError{
+				LogKey:  i18n.LogAuthFailedAPIKey,
+				UserKey: i18n.UserAuthAPIKeyInvalid,
+				Fields:  []zap.Field{zap.Error(err)},
+			}
+		}
+		return nil, err
+	}
+
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return &Identity{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Name:     user.Name,
+		Role:     user.Role,
+		APIKeyID: key.ID,
+		Scopes:   scopes,
+	}, nil
+}
@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
 	"time"
 
 	"gin/internal/i18n"
@@ -41,16 +44,26 @@ func StatCost() gin.HandlerFunc {
 	}
 }
 
+// maxLoggedBodySize 记录的响应体最大字节数，文件下载等较大的响应只记录开头
+const maxLoggedBodySize = 64 << 10
+
 type BodyLogWriter struct {
 	gin.ResponseWriter               // 嵌入gin框架ResponseWriter
 	body               *bytes.Buffer // 记录用的response
 }
 
 func (w BodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	if remaining := maxLoggedBodySize - w.body.Len(); remaining > 0 {
+		w.body.Write(b[:min(len(b), remaining)])
+	}
 	return w.ResponseWriter.Write(b)
 }
 
+// Unwrap 返回被包装的 ResponseWriter，http.ResponseController 通过它设置读写超时
+func (w BodyLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // GinBodyLogMiddleware 记录响应体的中间件
 func GinBodyLogMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -69,8 +82,8 @@ func GinBodyLogMiddleware() gin.HandlerFunc {
 		}
 
 		// 记录响应体（仅在调试模式下或错误响应时记录）
-		bodyStr := bodyLogWriter.body.String()
 		if c.Writer.Status() >= 400 || logger.Log.Core().Enabled(zap.DebugLevel) {
+			bodyStr := redactResponseBody(c.Writer.Header().Get("Content-Type"), bodyLogWriter.body.Bytes())
 			logger.Log.Debug(i18n.LogMessage(i18n.LogResponseBody),
 				zap.String("request_id", requestIDStr),
 				zap.String("path", c.Request.URL.Path),
@@ -81,3 +94,61 @@ func GinBodyLogMiddleware() gin.HandlerFunc {
 		}
 	}
 }
+
+// redactedValue 响应体日志中敏感字段的替换值
+const redactedValue = "[REDACTED]"
+
+// sensitiveBodyFields 响应体日志中需要脱敏的 JSON 字段：令牌、API密钥明文、两步验证密钥、恢复码和文件签名链接
+var sensitiveBodyFields = map[string]bool{
+	"access_token":     true,
+	"refresh_token":    true,
+	"mfa_token":        true,
+	"token":            true,
+	"key":              true,
+	"secret":           true,
+	"provisioning_uri": true,
+	"qr_payload":       true,
+	"recovery_codes":   true,
+	"password":         true,
+	"url":              true,
+}
+
+// redactResponseBody 返回脱敏后的响应体，JSON 响应中的敏感字段替换为 [REDACTED]
+// 无法解析的 JSON 响应（如超过 maxLoggedBodySize 被截断）不记录内容，避免敏感字段漏过脱敏
+func redactResponseBody(contentType string, body []byte) string {
+	if !strings.Contains(contentType, "json") {
+		return string(body)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return "[OMITTED]"
+	}
+
+	redacted, err := json.Marshal(redactFields(data))
+	if err != nil {
+		return "[OMITTED]"
+	}
+	return string(redacted)
+}
+
+// redactFields 递归替换对象中的敏感字段
+func redactFields(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for field, value := range v {
+			if sensitiveBodyFields[field] {
+				v[field] = redactedValue
+				continue
+			}
+			v[field] = redactFields(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactFields(value)
+		}
+	}
+	return data
+}
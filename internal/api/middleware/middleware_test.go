@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gin/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestGinBodyLogMiddleware_Redact 测试响应体日志中的敏感字段被脱敏
+func TestGinBodyLogMiddleware_Redact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	original := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = original })
+
+	router := gin.New()
+	router.Use(GinBodyLogMiddleware())
+	router.POST("/api-keys", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"data": gin.H{
+			"api_key": gin.H{"id": 1, "name": "CI"},
+			"key":     "gsk_plaintext",
+		}})
+	})
+	router.POST("/mfa/setup", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{
+			"secret":           "JBSWY3DPEHPK3PXP",
+			"provisioning_uri": "otpauth://totp/app:user?secret=JBSWY3DPEHPK3PXP",
+			"qr_payload":       "otpauth://totp/app:user?secret=JBSWY3DPEHPK3PXP",
+		}})
+	})
+	router.POST("/mfa/recovery-codes", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{"recovery_codes": []string{"AAAA-BBBB", "CCCC-DDDD"}}})
+	})
+	router.POST("/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{"access_token": "eyJhbGciOi", "refresh_token": "opaque-refresh"}})
+	})
+	router.GET("/text", func(c *gin.Context) {
+		c.String(http.StatusOK, "plain text")
+	})
+
+	body := func(method, path string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		return entries[0].ContextMap()["body"].(string)
+	}
+
+	t.Run("API密钥明文", func(t *testing.T) {
+		logged := body(http.MethodPost, "/api-keys")
+		assert.NotContains(t, logged, "gsk_plaintext")
+		assert.Contains(t, logged, `"name":"CI"`, "非敏感字段保留")
+	})
+
+	t.Run("两步验证密钥", func(t *testing.T) {
+		logged := body(http.MethodPost, "/mfa/setup")
+		assert.NotContains(t, logged, "JBSWY3DPEHPK3PXP")
+		assert.Contains(t, logged, redactedValue)
+	})
+
+	t.Run("恢复码", func(t *testing.T) {
+		assert.NotContains(t, body(http.MethodPost, "/mfa/recovery-codes"), "AAAA-BBBB")
+	})
+
+	t.Run("访问令牌和刷新令牌", func(t *testing.T) {
+		logged := body(http.MethodPost, "/login")
+		assert.NotContains(t, logged, "eyJhbGciOi")
+		assert.NotContains(t, logged, "opaque-refresh")
+	})
+
+	t.Run("非JSON响应原样记录", func(t *testing.T) {
+		assert.Equal(t, "plain text", body(http.MethodGet, "/text"))
+	})
+
+	t.Run("无法解析的JSON响应不记录内容", func(t *testing.T) {
+		assert.Equal(t, "[OMITTED]", redactResponseBody("application/json; charset=utf-8", []byte(`{"key":"gsk_trunc`)))
+	})
+}
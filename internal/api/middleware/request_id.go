@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"gin/internal/requestctx"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -23,6 +25,13 @@ func RequestIDMiddleware() gin.HandlerFunc {
 		// 将请求ID添加到响应头
 		c.Header("X-Request-ID", requestID)
 
+		// 将请求元数据写入 request context，service 层可通过 requestctx 读取
+		c.Request = c.Request.WithContext(requestctx.WithMetadata(c.Request.Context(), requestctx.Metadata{
+			RequestID: requestID,
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}))
+
 		c.Next()
 	}
 }
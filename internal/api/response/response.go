@@ -50,6 +50,35 @@ func Created(c *gin.Context, message string, data interface{}) {
 	})
 }
 
+// Pagination 分页信息
+type Pagination struct {
+	Total      int64  `json:"total"`                 // 满足过滤条件的总数
+	Limit      int    `json:"limit"`                 // 每页条数
+	Offset     int    `json:"offset"`                // 偏移量（游标分页时为 0）
+	NextCursor string `json:"next_cursor,omitempty"` // 下一页的游标，没有下一页时为空
+	HasMore    bool   `json:"has_more"`              // 是否还有下一页
+}
+
+// PageResponse 分页响应：在统一响应结构上增加分页信息，data 为当前页的数据
+type PageResponse struct {
+	Response
+	Pagination Pagination `json:"pagination"`
+}
+
+// SuccessWithPage 分页成功响应
+func SuccessWithPage(c *gin.Context, message string, data interface{}, pagination Pagination) {
+	c.JSON(http.StatusOK, PageResponse{
+		Response: Response{
+			Code:      http.StatusOK,
+			Message:   message,
+			Data:      data,
+			Timestamp: time.Now().Unix(),
+			RequestID: getRequestID(c),
+		},
+		Pagination: pagination,
+	})
+}
+
 // NoContent 无内容响应（204）
 func NoContent(c *gin.Context) {
 	c.Status(http.StatusNoContent)
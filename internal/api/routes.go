@@ -4,10 +4,12 @@ import (
 	"gin/internal/api/handlers"
 	"gin/internal/api/middleware"
 	apimiddleware "gin/internal/api/middleware"
+	"gin/internal/auth"
 	_ "gin/internal/docs" // 导入Swagger文档
 	"gin/internal/errors"
 	"gin/internal/metrics"
 	appmiddleware "gin/internal/middleware"
+	"gin/internal/models"
 	"net/http"
 	"path/filepath"
 	"runtime"
@@ -63,12 +65,6 @@ func SetupRouter() *gin.Engine {
 	router.GET("/loginHeader", handlers.LoginHeaderHandler())
 	router.GET("/loginUri/:id", handlers.LoginUriHandler())
 
-	// 文件上传相关路由
-	router.GET("/upload/page", handlers.UploadPageHandler())
-	router.POST("/upload", handlers.UploadHandler())
-	router.GET("/upload/multi/page", handlers.UploadMultiPageHandler())
-	router.POST("/upload/multi", handlers.UploadMultiHandler())
-
 	// 重定向相关路由
 	router.GET("/http/redirect", handlers.HTTPRedirectHandler())
 	router.GET("/path/redirect", handlers.PathRedirectHandler(router))
@@ -86,8 +82,45 @@ func SetupRouter() *gin.Engine {
 	return router
 }
 
+// fileUploadPolicy 通用文件上传限制：图片、PDF 和纯文本，单个文件不超过 10 MB，一次最多 10 个
+var fileUploadPolicy = models.UploadPolicy{
+	MaxSize:      10 << 20,
+	MaxFiles:     10,
+	AllowedTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf", "text/plain"},
+}
+
+// resumableUploadPolicy 断点续传上传限制：类型与普通上传相同，单个文件不超过 1 GB
+var resumableUploadPolicy = models.UploadPolicy{
+	MaxSize:      1 << 30,
+	MaxFiles:     1,
+	AllowedTypes: fileUploadPolicy.AllowedTypes,
+}
+
+// RouterDeps SetupRouterWithDI 所需的依赖
+type RouterDeps struct {
+	UserHandler   *handlers.UserHandler
+	RoleHandler   *handlers.RoleHandler
+	APIKeyHandler *handlers.APIKeyHandler
+	AuditHandler  *handlers.AuditHandler
+	FileHandler   *handlers.FileHandler
+	UploadHandler *handlers.UploadHandler
+	// AuthMiddleware 认证中间件，需与 service 层共用同一个JWT配置和令牌撤销存储
+	// 同时接受 Authorization: Bearer <jwt> 和 X-API-Key
+	AuthMiddleware gin.HandlerFunc
+	// JWTConfig 用于发布 JWKS 公钥
+	JWTConfig *auth.JWTConfig
+	// AuditRecorder 记录认证失败和权限不足的请求（为 nil 时不记录）
+	AuditRecorder middleware.AuditRecorder
+}
+
 // SetupRouterWithDI 设置路由（带依赖注入）
-func SetupRouterWithDI(userHandler *handlers.UserHandler) *gin.Engine {
+func SetupRouterWithDI(deps RouterDeps) *gin.Engine {
+	userHandler, roleHandler, authMiddleware := deps.UserHandler, deps.RoleHandler, deps.AuthMiddleware
+	apiKeyHandler, auditHandler, fileHandler := deps.APIKeyHandler, deps.AuditHandler, deps.FileHandler
+	uploadHandler := deps.UploadHandler
+	// 账户自身的敏感操作（修改密码、两步验证、会话管理、修改和注销当前用户）只接受访问令牌，不接受API密钥
+	sessionOnly := middleware.RequireSession()
+
 	router := gin.Default()
 	basePath := getCurrentPath()
 	basePath = filepath.Dir(filepath.Dir(basePath))
@@ -101,6 +134,11 @@ func SetupRouterWithDI(userHandler *handlers.UserHandler) *gin.Engine {
 	// 添加请求ID中间件（全局）
 	router.Use(middleware.RequestIDMiddleware())
 
+	// 添加审计中间件（全局，依赖请求ID）
+	if deps.AuditRecorder != nil {
+		router.Use(middleware.AuditMiddleware(deps.AuditRecorder))
+	}
+
 	// 模板和静态文件设置
 	handlers.SetupTemplates(router, basePath)
 
@@ -110,33 +148,116 @@ func SetupRouterWithDI(userHandler *handlers.UserHandler) *gin.Engine {
 	// Swagger 文档路由
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// JWKS 公钥（供其他服务验证访问令牌）
+	router.GET("/.well-known/jwks.json", handlers.JWKSHandler(deps.JWTConfig))
+
 	// API 路由组
 	apiGroup := router.Group("/api/v1")
 	{
 		// 认证路由（不需要认证）
-		auth := apiGroup.Group("/auth")
+		authGroup := apiGroup.Group("/auth")
+		{
+			authGroup.POST("/register", userHandler.Register())    // POST /api/v1/auth/register
+			authGroup.POST("/login", userHandler.Login())          // POST /api/v1/auth/login
+			authGroup.POST("/refresh", userHandler.RefreshToken()) // POST /api/v1/auth/refresh
+			authGroup.POST("/logout", userHandler.Logout())        // POST /api/v1/auth/logout
+
+			// 密码管理
+			authGroup.POST("/password/forgot", userHandler.ForgotPassword())                              // POST /api/v1/auth/password/forgot
+			authGroup.POST("/password/reset", userHandler.ResetPassword())                                // POST /api/v1/auth/password/reset
+			authGroup.POST("/password/change", authMiddleware, sessionOnly, userHandler.ChangePassword()) // POST /api/v1/auth/password/change（需要登录会话）
+
+			// 两步验证
+			authGroup.POST("/mfa/verify", userHandler.VerifyMFA())                                                    // POST /api/v1/auth/mfa/verify（登录第二步）
+			authGroup.GET("/mfa", authMiddleware, sessionOnly, userHandler.GetMFAStatus())                            // GET /api/v1/auth/mfa（需要登录会话）
+			authGroup.POST("/mfa/setup", authMiddleware, sessionOnly, userHandler.SetupMFA())                         // POST /api/v1/auth/mfa/setup（需要登录会话）
+			authGroup.POST("/mfa/enable", authMiddleware, sessionOnly, userHandler.EnableMFA())                       // POST /api/v1/auth/mfa/enable（需要登录会话）
+			authGroup.POST("/mfa/disable", authMiddleware, sessionOnly, userHandler.DisableMFA())                     // POST /api/v1/auth/mfa/disable（需要登录会话）
+			authGroup.POST("/mfa/recovery-codes", authMiddleware, sessionOnly, userHandler.RegenerateRecoveryCodes()) // POST /api/v1/auth/mfa/recovery-codes（需要登录会话）
+		}
+
+		// 会话管理路由（需要登录会话，不接受API密钥）
+		sessions := apiGroup.Group("/sessions")
+		sessions.Use(authMiddleware, sessionOnly)
 		{
-			auth.POST("/register", userHandler.Register())    // POST /api/v1/auth/register
-			auth.POST("/login", userHandler.Login())          // POST /api/v1/auth/login
-			auth.POST("/refresh", userHandler.RefreshToken()) // POST /api/v1/auth/refresh
+			sessions.GET("", userHandler.ListSessions())         // GET /api/v1/sessions
+			sessions.DELETE("/:id", userHandler.RevokeSession()) // DELETE /api/v1/sessions/:id
 		}
 
 		// 用户相关路由（需要认证）
 		users := apiGroup.Group("/users")
-		users.Use(middleware.NewAuthMiddleware()) // 应用认证中间件
+		users.Use(authMiddleware) // 应用认证中间件
+		{
+			// 当前用户（按令牌中的 user_id 解析，不需要额外权限；修改和注销只接受登录会话）
+			users.GET("/me", userHandler.GetMe())                    // GET /api/v1/users/me
+			users.PATCH("/me", sessionOnly, userHandler.PatchMe())   // PATCH /api/v1/users/me
+			users.DELETE("/me", sessionOnly, userHandler.DeleteMe()) // DELETE /api/v1/users/me
+
+			// 按权限控制的路由（权限在 /api/v1/roles 中管理）
+			users.POST("", middleware.RequirePermission(auth.PermissionUsersCreate), userHandler.CreateUser())                 // POST /api/v1/users
+			users.POST("/import", middleware.RequirePermission(auth.PermissionUsersImport), userHandler.ImportUsers())         // POST /api/v1/users/import
+			users.GET("/export", middleware.RequirePermission(auth.PermissionUsersExport), userHandler.ExportUsers())          // GET /api/v1/users/export
+			users.DELETE("/:id", middleware.RequirePermission(auth.PermissionUsersDelete), userHandler.DeleteUser())           // DELETE /api/v1/users/:id
+			users.POST("/:id/restore", middleware.RequirePermission(auth.PermissionUsersRestore), userHandler.RestoreUser())   // POST /api/v1/users/:id/restore
+			users.DELETE("/:id/purge", middleware.RequirePermission(auth.PermissionUsersPurge), userHandler.PurgeUser())       // DELETE /api/v1/users/:id/purge
+			users.POST("/:id/logout", middleware.RequirePermission(auth.PermissionUsersLogout), userHandler.ForceLogoutUser()) // POST /api/v1/users/:id/logout
+			users.POST("/:id/unlock", middleware.RequirePermission(auth.PermissionUsersUnlock), userHandler.UnlockUser())      // POST /api/v1/users/:id/unlock
+			users.PUT("/:id/role", middleware.RequirePermission(auth.PermissionRolesAssign), roleHandler.AssignRole())         // PUT /api/v1/users/:id/role
+			users.GET("", middleware.RequirePermission(auth.PermissionUsersRead), userHandler.GetAllUsers())                   // GET /api/v1/users
+			users.GET("/:id", middleware.RequirePermission(auth.PermissionUsersRead), userHandler.GetUser())                   // GET /api/v1/users/:id
+			users.PUT("/:id", middleware.RequirePermission(auth.PermissionUsersUpdate), userHandler.UpdateUser())              // PUT /api/v1/users/:id
+			users.PATCH("/:id", middleware.RequirePermission(auth.PermissionUsersUpdate), userHandler.PatchUser())             // PATCH /api/v1/users/:id
+		}
+
+		// 角色与权限管理路由（需要认证）
+		roles := apiGroup.Group("/roles")
+		roles.Use(authMiddleware)
+		{
+			roles.GET("", middleware.RequirePermission(auth.PermissionRolesRead), roleHandler.ListRoles())           // GET /api/v1/roles
+			roles.GET("/:id", middleware.RequirePermission(auth.PermissionRolesRead), roleHandler.GetRole())         // GET /api/v1/roles/:id
+			roles.POST("", middleware.RequirePermission(auth.PermissionRolesManage), roleHandler.CreateRole())       // POST /api/v1/roles
+			roles.PUT("/:id", middleware.RequirePermission(auth.PermissionRolesManage), roleHandler.UpdateRole())    // PUT /api/v1/roles/:id
+			roles.DELETE("/:id", middleware.RequirePermission(auth.PermissionRolesManage), roleHandler.DeleteRole()) // DELETE /api/v1/roles/:id
+		}
+		apiGroup.GET("/permissions", authMiddleware, middleware.RequirePermission(auth.PermissionRolesRead), roleHandler.ListPermissions()) // GET /api/v1/permissions
+
+		// API密钥管理路由（需要认证）
+		apiKeys := apiGroup.Group("/api-keys")
+		apiKeys.Use(authMiddleware, middleware.RequirePermission(auth.PermissionAPIKeysManage))
+		{
+			apiKeys.POST("", apiKeyHandler.CreateAPIKey())       // POST /api/v1/api-keys
+			apiKeys.GET("", apiKeyHandler.ListAPIKeys())         // GET /api/v1/api-keys
+			apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey()) // DELETE /api/v1/api-keys/:id
+		}
+
+		// 审计日志路由（需要认证）
+		audit := apiGroup.Group("/audit")
+		audit.Use(authMiddleware, middleware.RequirePermission(auth.PermissionAuditRead))
+		{
+			audit.GET("", auditHandler.ListAuditLogs())           // GET /api/v1/audit
+			audit.GET("/verify", auditHandler.VerifyAuditChain()) // GET /api/v1/audit/verify
+		}
+
+		// 文件路由
+		files := apiGroup.Group("/files")
+		{
+			// 上传需要 files:upload 权限；下载按文件归属在 service 层检查（本人、管理员或 files:read）
+			files.POST("", authMiddleware, middleware.RequirePermission(auth.PermissionFilesUpload), fileHandler.UploadFile(fileUploadPolicy))        // POST /api/v1/files
+			files.POST("/batch", authMiddleware, middleware.RequirePermission(auth.PermissionFilesUpload), fileHandler.UploadFiles(fileUploadPolicy)) // POST /api/v1/files/batch
+			files.GET("/:id", authMiddleware, fileHandler.DownloadFile())                                                                             // GET /api/v1/files/:id
+			files.POST("/:id/signed-url", authMiddleware, fileHandler.CreateSignedURL())                                                              // POST /api/v1/files/:id/signed-url
+			files.GET("/:id/shared", fileHandler.DownloadSignedFile())                                                                                // GET /api/v1/files/:id/shared（签名链接，不需要认证）
+		}
+
+		// 断点续传路由（tus 1.0 协议，OPTIONS 不需要认证）
+		apiGroup.OPTIONS("/uploads", uploadHandler.TusResumable(), uploadHandler.Options(resumableUploadPolicy)) // OPTIONS /api/v1/uploads
+		uploads := apiGroup.Group("/uploads")
+		uploads.Use(uploadHandler.TusResumable(), authMiddleware, middleware.RequirePermission(auth.PermissionFilesUpload))
 		{
-			// 需要管理员权限的路由
-			adminUsers := users.Group("")
-			adminUsers.Use(middleware.RequireAdmin()) // 应用管理员权限检查
-			{
-				adminUsers.POST("", userHandler.CreateUser())       // POST /api/v1/users（仅管理员）
-				adminUsers.DELETE("/:id", userHandler.DeleteUser()) // DELETE /api/v1/users/:id（仅管理员）
-			}
-
-			// 普通用户和管理员都可以访问的路由
-			users.GET("", userHandler.GetAllUsers())    // GET /api/v1/users
-			users.GET("/:id", userHandler.GetUser())    // GET /api/v1/users/:id
-			users.PUT("/:id", userHandler.UpdateUser()) // PUT /api/v1/users/:id
+			uploads.POST("", uploadHandler.CreateUpload(resumableUploadPolicy))     // POST /api/v1/uploads
+			uploads.HEAD("/:id", uploadHandler.HeadUpload())                        // HEAD /api/v1/uploads/:id
+			uploads.PATCH("/:id", uploadHandler.PatchUpload(resumableUploadPolicy)) // PATCH /api/v1/uploads/:id
+			uploads.DELETE("/:id", uploadHandler.DeleteUpload())                    // DELETE /api/v1/uploads/:id
 		}
 	}
 
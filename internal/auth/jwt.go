@@ -5,20 +5,25 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // JWTConfig JWT配置
 type JWTConfig struct {
 	SecretKey string
 	ExpiresIn time.Duration
+	Keys      *KeySet // 非空时使用密钥集合签名与验签，SecretKey 不再生效
 }
 
 // UserClaims 用户JWT声明
 type UserClaims struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
-	Name   string `json:"name"`
-	Role   Role   `json:"role"` // 用户角色
+	UserID    int64  `json:"user_id"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	Role      Role   `json:"role"`          // 用户角色
+	SessionID string `json:"sid,omitempty"` // 会话ID（即刷新令牌族ID），用于按会话撤销
+
+	// RegisteredClaims.ID 即 jti，用于撤销单个令牌
 	jwt.RegisteredClaims
 }
 
@@ -30,54 +35,41 @@ func NewJWTConfig(secretKey string, expiresIn time.Duration) *JWTConfig {
 	}
 }
 
-// GenerateToken 生成访问令牌（Access Token）
-func (j *JWTConfig) GenerateToken(userID int64, email, name string, role Role) (string, error) {
-	// 创建声明
-	claims := UserClaims{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpiresIn)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
-	}
-
-	// 创建令牌
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// 签名令牌
-	tokenString, err := token.SignedString([]byte(j.SecretKey))
-	if err != nil {
-		return "", err
+// NewJWTConfigWithKeys 创建使用密钥集合（支持 kid 轮换）的JWT配置
+func NewJWTConfigWithKeys(keys *KeySet, expiresIn time.Duration) *JWTConfig {
+	return &JWTConfig{
+		Keys:      keys,
+		ExpiresIn: expiresIn,
 	}
-
-	return tokenString, nil
 }
 
-// GenerateRefreshToken 生成刷新令牌（Refresh Token）
-// 刷新令牌使用更长的过期时间
-func (j *JWTConfig) GenerateRefreshToken(userID int64, email, name string, role Role, refreshExpiresIn time.Duration) (string, error) {
+// GenerateToken 生成访问令牌（Access Token）
+// 每个令牌都带有唯一的 jti，sessionID 为空表示不属于任何会话
+func (j *JWTConfig) GenerateToken(userID int64, email, name string, role Role, sessionID string) (string, error) {
 	// 创建声明
 	claims := UserClaims{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Name:      name,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(refreshExpiresIn)),
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpiresIn)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
 	// 创建令牌
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	key := j.signingKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
 
 	// 签名令牌
-	tokenString, err := token.SignedString([]byte(j.SecretKey))
+	tokenString, err := token.SignedString(key.SignKey)
 	if err != nil {
 		return "", err
 	}
@@ -89,11 +81,16 @@ func (j *JWTConfig) GenerateRefreshToken(userID int64, email, name string, role
 func (j *JWTConfig) ParseToken(tokenString string) (*UserClaims, error) {
 	// 解析令牌
 	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名算法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, err := j.verificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		// 验证签名算法，必须与密钥的算法一致，防止算法混淆攻击
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(j.SecretKey), nil
+		return key.VerifyKey, nil
 	})
 
 	if err != nil {
@@ -108,6 +105,22 @@ func (j *JWTConfig) ParseToken(tokenString string) (*UserClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// signingKey 返回当前签名密钥，未配置密钥集合时使用 SecretKey（HS256，不带 kid）
+func (j *JWTConfig) signingKey() *SigningKey {
+	if j.Keys != nil {
+		return j.Keys.SigningKey()
+	}
+	return NewHMACKey("", j.SecretKey)
+}
+
+// verificationKey 根据 kid 查找验签密钥
+func (j *JWTConfig) verificationKey(kid string) (*SigningKey, error) {
+	if j.Keys != nil {
+		return j.Keys.VerificationKey(kid, time.Now())
+	}
+	return NewHMACKey("", j.SecretKey), nil
+}
+
 // IsValidToken 检查令牌是否有效
 func (j *JWTConfig) IsValidToken(tokenString string) bool {
 	_, err := j.ParseToken(tokenString)
@@ -0,0 +1,304 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"gin/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrUnknownKeyID 令牌头部的 kid 不在密钥集合中
+	ErrUnknownKeyID = errors.New("unknown key id")
+	// ErrKeyExpired 密钥已退役且超过宽限期
+	ErrKeyExpired = errors.New("signing key retired")
+)
+
+// SigningKey JWT签名密钥
+// 对称密钥（HS256）的 SignKey 和 VerifyKey 相同；非对称密钥只有持有私钥时才能签名
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   interface{} // []byte / *rsa.PrivateKey / *ecdsa.PrivateKey / ed25519.PrivateKey，仅验签的密钥为 nil
+	VerifyKey interface{} // []byte / *rsa.PublicKey / *ecdsa.PublicKey / ed25519.PublicKey
+	RetiredAt time.Time   // 退役时间，零值表示未退役
+	NotAfter  time.Time   // 验签截止时间（退役时间 + 宽限期），零值表示一直有效
+}
+
+// NewHMACKey 创建 HS256 对称密钥
+func NewHMACKey(id, secret string) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		SignKey:   []byte(secret),
+		VerifyKey: []byte(secret),
+	}
+}
+
+// NewAsymmetricKey 根据私钥或公钥创建签名密钥，算法由密钥类型决定：
+// RSA → RS256，ECDSA P-256 → ES256，Ed25519 → EdDSA
+func NewAsymmetricKey(id string, privateKey crypto.Signer, publicKey crypto.PublicKey) (*SigningKey, error) {
+	if privateKey != nil {
+		publicKey = privateKey.Public()
+	}
+	if publicKey == nil {
+		return nil, fmt.Errorf("密钥 %q 缺少公钥或私钥", id)
+	}
+
+	key := &SigningKey{ID: id, VerifyKey: publicKey}
+	if privateKey != nil {
+		key.SignKey = privateKey
+	}
+
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("密钥 %q 的椭圆曲线不受支持，ES256 需要 P-256", id)
+		}
+		key.Method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("密钥 %q 的类型 %T 不受支持", id, publicKey)
+	}
+	return key, nil
+}
+
+// CanSign 是否可用于签名
+func (k *SigningKey) CanSign() bool {
+	return k.SignKey != nil
+}
+
+// IsUsableAt 指定时间是否仍可用于验签
+func (k *SigningKey) IsUsableAt(now time.Time) bool {
+	return k.NotAfter.IsZero() || now.Before(k.NotAfter)
+}
+
+// KeySet JWT签名密钥集合
+// 同一时间只有一个密钥用于签名，其余密钥按 kid 用于验签，退役密钥在宽限期内仍可验签
+type KeySet struct {
+	signing *SigningKey
+	keys    map[string]*SigningKey
+	order   []string
+}
+
+// NewKeySet 创建密钥集合，signingKeyID 为空时使用第一个可签名且未退役的密钥
+func NewKeySet(signingKeyID string, keys ...*SigningKey) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*SigningKey, len(keys))}
+	for _, key := range keys {
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("密钥ID %q 重复", key.ID)
+		}
+		ks.keys[key.ID] = key
+		ks.order = append(ks.order, key.ID)
+
+		if ks.signing != nil || !key.CanSign() {
+			continue
+		}
+		if (signingKeyID == "" && key.RetiredAt.IsZero()) || key.ID == signingKeyID {
+			ks.signing = key
+		}
+	}
+
+	if ks.signing == nil {
+		if signingKeyID != "" {
+			return nil, fmt.Errorf("签名密钥 %q 不存在或缺少私钥", signingKeyID)
+		}
+		return nil, errors.New("没有可用于签名的密钥")
+	}
+	return ks, nil
+}
+
+// SigningKey 返回当前用于签名的密钥
+func (ks *KeySet) SigningKey() *SigningKey {
+	return ks.signing
+}
+
+// VerificationKey 根据 kid 返回验签密钥
+func (ks *KeySet) VerificationKey(kid string, now time.Time) (*SigningKey, error) {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	if !key.IsUsableAt(now) {
+		return nil, ErrKeyExpired
+	}
+	return key, nil
+}
+
+// JSONWebKey JWKS 中的单个公钥（RFC 7517）
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet JWKS 文档
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS 返回当前仍可验签的非对称公钥，对称密钥永远不会公开
+func (ks *KeySet) JWKS(now time.Time) JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, id := range ks.order {
+		key := ks.keys[id]
+		if !key.IsUsableAt(now) {
+			continue
+		}
+		if jwk, ok := toJSONWebKey(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// toJSONWebKey 将公钥转换为 JWK 格式
+func toJSONWebKey(key *SigningKey) (JSONWebKey, bool) {
+	jwk := JSONWebKey{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch pub := key.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(pub.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = pub.Curve.Params().Name
+		jwk.X = encode(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(pub)
+	default:
+		return JSONWebKey{}, false
+	}
+	return jwk, true
+}
+
+// LoadSigningKey 从 PEM 文件加载签名密钥，配置了私钥时公钥路径可为空
+func LoadSigningKey(id, privateKeyPath, publicKeyPath string) (*SigningKey, error) {
+	var privateKey crypto.Signer
+	var publicKey crypto.PublicKey
+
+	if privateKeyPath != "" {
+		data, err := os.ReadFile(privateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥文件失败: %w", err)
+		}
+		if privateKey, err = ParsePrivateKeyPEM(data); err != nil {
+			return nil, fmt.Errorf("解析私钥 %s 失败: %w", privateKeyPath, err)
+		}
+	} else if publicKeyPath != "" {
+		data, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取公钥文件失败: %w", err)
+		}
+		if publicKey, err = ParsePublicKeyPEM(data); err != nil {
+			return nil, fmt.Errorf("解析公钥 %s 失败: %w", publicKeyPath, err)
+		}
+	}
+
+	return NewAsymmetricKey(id, privateKey, publicKey)
+}
+
+// ParsePrivateKeyPEM 解析 PEM 格式私钥（PKCS#8、PKCS#1 RSA 或 SEC1 EC）
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("无效的 PEM 数据")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("不支持的私钥类型 %T", key)
+	}
+	return signer, nil
+}
+
+// ParsePublicKeyPEM 解析 PEM 格式公钥（PKIX 或 PKCS#1 RSA）
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("无效的 PEM 数据")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// LoadJWTConfig 根据配置创建JWT配置
+// 未配置 keys 时沿用 secret_key 的 HS256 签名；配置了 keys 时按 kid 使用非对称密钥签名与验签
+func LoadJWTConfig(cfg *config.JWTConfig) (*JWTConfig, error) {
+	expiresIn := time.Duration(cfg.ExpiresIn) * time.Hour
+	if len(cfg.Keys) == 0 {
+		return NewJWTConfig(cfg.SecretKey, expiresIn), nil
+	}
+
+	grace := time.Duration(cfg.RotationGracePeriod) * time.Hour
+	if grace == 0 {
+		grace = expiresIn // 默认保证退役前签发的令牌都能在过期前通过验签
+	}
+
+	keys := make([]*SigningKey, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		if kc.ID == "" {
+			return nil, errors.New("JWT 密钥必须配置 id")
+		}
+		key, err := LoadSigningKey(kc.ID, kc.PrivateKeyPath, kc.PublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		if kc.RetiredAt != "" {
+			retiredAt, err := time.Parse(time.RFC3339, kc.RetiredAt)
+			if err != nil {
+				return nil, fmt.Errorf("密钥 %q 的 retired_at 格式错误: %w", kc.ID, err)
+			}
+			key.RetiredAt = retiredAt
+			key.NotAfter = retiredAt.Add(grace)
+		}
+		keys = append(keys, key)
+	}
+
+	keySet, err := NewKeySet(cfg.SigningKeyID, keys...)
+	if err != nil {
+		return nil, err
+	}
+	return NewJWTConfigWithKeys(keySet, expiresIn), nil
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generateSigner 生成测试用私钥
+func generateSigner(t *testing.T, alg string) crypto.Signer {
+	t.Helper()
+	var (
+		signer crypto.Signer
+		err    error
+	)
+	switch alg {
+	case "RS256":
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	}
+	require.NoError(t, err)
+	return signer
+}
+
+// writePEM 将私钥以 PKCS#8 PEM 格式写入临时文件
+func writePEM(t *testing.T, dir, name string, signer crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+// TestJWTConfig_AsymmetricSigning 测试 RS256 / ES256 / EdDSA 签名与验签
+func TestJWTConfig_AsymmetricSigning(t *testing.T) {
+	for _, alg := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := NewAsymmetricKey("k1", generateSigner(t, alg), nil)
+			require.NoError(t, err)
+			assert.Equal(t, alg, key.Method.Alg())
+
+			keySet, err := NewKeySet("", key)
+			require.NoError(t, err)
+			jwtConfig := NewJWTConfigWithKeys(keySet, time.Hour)
+
+			tokenString, err := jwtConfig.GenerateToken(1, "zhangsan@example.com", "张三", RoleAdmin, "sid")
+			require.NoError(t, err)
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenString, &UserClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, "k1", token.Header["kid"])
+			assert.Equal(t, alg, token.Header["alg"])
+
+			claims, err := jwtConfig.ParseToken(tokenString)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), claims.UserID)
+			assert.Equal(t, RoleAdmin, claims.Role)
+
+			// 只持有公钥的一方也能验签
+			publicOnly, err := NewAsymmetricKey("k1", nil, key.VerifyKey)
+			require.NoError(t, err)
+			assert.False(t, publicOnly.CanSign())
+			verifier := &JWTConfig{Keys: &KeySet{signing: key, keys: map[string]*SigningKey{"k1": publicOnly}}}
+			_, err = verifier.ParseToken(tokenString)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// TestJWTConfig_KeyRotation 测试按 kid 轮换密钥与宽限期
+func TestJWTConfig_KeyRotation(t *testing.T) {
+	oldKey, err := NewAsymmetricKey("old", generateSigner(t, "ES256"), nil)
+	require.NoError(t, err)
+	newKey, err := NewAsymmetricKey("new", generateSigner(t, "EdDSA"), nil)
+	require.NoError(t, err)
+
+	// 轮换前：旧密钥签发令牌
+	before, err := NewKeySet("old", oldKey)
+	require.NoError(t, err)
+	oldToken, err := NewJWTConfigWithKeys(before, time.Hour).GenerateToken(1, "a@example.com", "a", RoleUser, "")
+	require.NoError(t, err)
+
+	t.Run("宽限期内旧令牌仍可验签", func(t *testing.T) {
+		oldKey.RetiredAt = time.Now().Add(-time.Minute)
+		oldKey.NotAfter = time.Now().Add(time.Hour)
+		keySet, err := NewKeySet("", oldKey, newKey)
+		require.NoError(t, err)
+		assert.Equal(t, "new", keySet.SigningKey().ID, "退役密钥不再用于签名")
+
+		jwtConfig := NewJWTConfigWithKeys(keySet, time.Hour)
+		_, err = jwtConfig.ParseToken(oldToken)
+		assert.NoError(t, err)
+		assert.Len(t, keySet.JWKS(time.Now()).Keys, 2)
+	})
+
+	t.Run("宽限期结束后旧令牌被拒绝", func(t *testing.T) {
+		oldKey.NotAfter = time.Now().Add(-time.Second)
+		keySet, err := NewKeySet("new", oldKey, newKey)
+		require.NoError(t, err)
+
+		_, err = NewJWTConfigWithKeys(keySet, time.Hour).ParseToken(oldToken)
+		assert.ErrorIs(t, err, ErrKeyExpired)
+
+		jwks := keySet.JWKS(time.Now())
+		require.Len(t, jwks.Keys, 1)
+		assert.Equal(t, "new", jwks.Keys[0].KeyID)
+		assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+	})
+
+	t.Run("未知 kid 被拒绝", func(t *testing.T) {
+		keySet, err := NewKeySet("", newKey)
+		require.NoError(t, err)
+		_, err = NewJWTConfigWithKeys(keySet, time.Hour).ParseToken(oldToken)
+		assert.ErrorIs(t, err, ErrUnknownKeyID)
+	})
+}
+
+// TestJWTConfig_RejectsAlgorithmConfusion 测试拒绝与密钥算法不一致的令牌
+func TestJWTConfig_RejectsAlgorithmConfusion(t *testing.T) {
+	key, err := NewAsymmetricKey("k1", generateSigner(t, "RS256"), nil)
+	require.NoError(t, err)
+	keySet, err := NewKeySet("", key)
+	require.NoError(t, err)
+
+	// 用公钥字节作为 HMAC 密钥伪造令牌
+	der, err := x509.MarshalPKIXPublicKey(key.VerifyKey)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaims{UserID: 1, Role: RoleAdmin})
+	forged.Header["kid"] = "k1"
+	tokenString, err := forged.SignedString(der)
+	require.NoError(t, err)
+
+	_, err = NewJWTConfigWithKeys(keySet, time.Hour).ParseToken(tokenString)
+	assert.Error(t, err)
+}
+
+// TestLoadJWTConfig 测试从配置加载 PEM 密钥
+func TestLoadJWTConfig(t *testing.T) {
+	dir := t.TempDir()
+	current := writePEM(t, dir, "current.pem", generateSigner(t, "RS256"))
+	previous := generateSigner(t, "ES256")
+	pubDER, err := x509.MarshalPKIXPublicKey(previous.Public())
+	require.NoError(t, err)
+	previousPub := filepath.Join(dir, "previous.pub.pem")
+	require.NoError(t, os.WriteFile(previousPub, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644))
+
+	t.Run("未配置密钥时使用 HS256", func(t *testing.T) {
+		jwtConfig, err := LoadJWTConfig(&config.JWTConfig{SecretKey: "secret", ExpiresIn: 1})
+		require.NoError(t, err)
+		assert.Nil(t, jwtConfig.Keys)
+		assert.Equal(t, time.Hour, jwtConfig.ExpiresIn)
+	})
+
+	t.Run("加载私钥与仅验签的公钥", func(t *testing.T) {
+		retiredAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		jwtConfig, err := LoadJWTConfig(&config.JWTConfig{
+			ExpiresIn:           2,
+			RotationGracePeriod: 24,
+			Keys: []config.JWTKeyConfig{
+				{ID: "previous", PublicKeyPath: previousPub, RetiredAt: retiredAt},
+				{ID: "current", PrivateKeyPath: current},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "current", jwtConfig.Keys.SigningKey().ID)
+
+		previousKey, err := jwtConfig.Keys.VerificationKey("previous", time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, "ES256", previousKey.Method.Alg())
+		assert.WithinDuration(t, time.Now().Add(23*time.Hour), previousKey.NotAfter, time.Minute)
+	})
+
+	t.Run("配置错误", func(t *testing.T) {
+		_, err := LoadJWTConfig(&config.JWTConfig{Keys: []config.JWTKeyConfig{{ID: "k", PrivateKeyPath: filepath.Join(dir, "missing.pem")}}})
+		assert.Error(t, err)
+
+		_, err = LoadJWTConfig(&config.JWTConfig{Keys: []config.JWTKeyConfig{{ID: "k", PublicKeyPath: previousPub}}})
+		assert.Error(t, err, "只有公钥时无法签名")
+
+		_, err = LoadJWTConfig(&config.JWTConfig{SigningKeyID: "other", Keys: []config.JWTKeyConfig{{ID: "k", PrivateKeyPath: current}}})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"gin/internal/config"
+)
+
+// maxPasswordBytes bcrypt 只使用前 72 个字节，超出部分会被忽略
+const maxPasswordBytes = 72
+
+// ErrWeakPassword 密码不符合密码策略
+var ErrWeakPassword = errors.New("密码不符合安全策略")
+
+//go:embed breached_passwords.txt
+var builtinBreachedPasswords string
+
+// PasswordPolicy 密码策略
+// 所有会调用 HashPassword 写入新密码的地方（注册、修改、重置密码）都必须先通过 Validate
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	breached map[string]struct{}
+}
+
+// DefaultPasswordPolicy 默认密码策略：至少8个字符，包含小写字母和数字，且不在内置泄露密码列表中
+func DefaultPasswordPolicy() *PasswordPolicy {
+	p := &PasswordPolicy{
+		MinLength:    8,
+		RequireLower: true,
+		RequireDigit: true,
+		breached:     make(map[string]struct{}),
+	}
+	_ = p.LoadBreachedList(strings.NewReader(builtinBreachedPasswords))
+	return p
+}
+
+// LoadPasswordPolicy 根据配置创建密码策略
+// 内置泄露密码列表始终生效，配置了 breached_list 时追加文件中的密码
+func LoadPasswordPolicy(cfg *config.PasswordConfig) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{
+		MinLength:     cfg.MinLength,
+		RequireUpper:  cfg.RequireUpper,
+		RequireLower:  cfg.RequireLower,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+		breached:      make(map[string]struct{}),
+	}
+	_ = p.LoadBreachedList(strings.NewReader(builtinBreachedPasswords))
+
+	if cfg.BreachedList != "" {
+		f, err := os.Open(cfg.BreachedList)
+		if err != nil {
+			return nil, fmt.Errorf("打开泄露密码列表失败: %w", err)
+		}
+		defer f.Close()
+		if err := p.LoadBreachedList(f); err != nil {
+			return nil, fmt.Errorf("读取泄露密码列表失败: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+// LoadBreachedList 追加泄露密码列表（每行一个密码，忽略空行和 # 开头的注释行）
+func (p *PasswordPolicy) LoadBreachedList(r io.Reader) error {
+	if p.breached == nil {
+		p.breached = make(map[string]struct{})
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.breached[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Validate 检查密码是否符合策略，不符合时返回包含所有原因的 ErrWeakPassword
+func (p *PasswordPolicy) Validate(password string) error {
+	var reasons []string
+
+	if len([]rune(password)) < p.MinLength {
+		reasons = append(reasons, fmt.Sprintf("长度至少为%d个字符", p.MinLength))
+	}
+	if len(password) > maxPasswordBytes {
+		reasons = append(reasons, fmt.Sprintf("长度不能超过%d个字节", maxPasswordBytes))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		reasons = append(reasons, "必须包含大写字母")
+	}
+	if p.RequireLower && !hasLower {
+		reasons = append(reasons, "必须包含小写字母")
+	}
+	if p.RequireDigit && !hasDigit {
+		reasons = append(reasons, "必须包含数字")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		reasons = append(reasons, "必须包含特殊字符")
+	}
+
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		reasons = append(reasons, "该密码过于常见或已经泄露")
+	}
+
+	if len(reasons) > 0 {
+		return fmt.Errorf("%w：%s", ErrWeakPassword, strings.Join(reasons, "；"))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gin/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPasswordPolicy_Validate 测试密码策略校验
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	cases := []struct {
+		name     string
+		password string
+		reasons  []string
+	}{
+		{name: "符合策略", password: "Correct-Horse-9"},
+		{name: "长度不足", password: "Ab1!", reasons: []string{"长度至少为10个字符"}},
+		{name: "按字符而不是字节计算长度", password: "密码密码密码密码密码Aa1!"},
+		{name: "缺少多类字符", password: "onlylowercase", reasons: []string{"必须包含大写字母", "必须包含数字", "必须包含特殊字符"}},
+		{name: "超过bcrypt长度限制", password: "Aa1!" + string(make([]byte, 80)), reasons: []string{"长度不能超过72个字节"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Validate(tc.password)
+			if len(tc.reasons) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrWeakPassword)
+			for _, reason := range tc.reasons {
+				assert.Contains(t, err.Error(), reason)
+			}
+		})
+	}
+}
+
+// TestDefaultPasswordPolicy 测试默认密码策略与内置泄露密码列表
+func TestDefaultPasswordPolicy(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+
+	assert.NoError(t, policy.Validate("zhangsan2024"))
+	assert.ErrorIs(t, policy.Validate("abc12"), ErrWeakPassword)
+
+	// 泄露密码不区分大小写
+	err := policy.Validate("Password123")
+	require.ErrorIs(t, err, ErrWeakPassword)
+	assert.Contains(t, err.Error(), "过于常见")
+}
+
+// TestLoadPasswordPolicy 测试从配置加载密码策略
+func TestLoadPasswordPolicy(t *testing.T) {
+	t.Run("追加泄露密码列表文件", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		require.NoError(t, os.WriteFile(path, []byte("# 注释\n\ncompany2024\n"), 0644))
+
+		policy, err := LoadPasswordPolicy(&config.PasswordConfig{MinLength: 8, BreachedList: path})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, policy.Validate("Company2024"), ErrWeakPassword)
+		// 内置列表仍然生效
+		assert.ErrorIs(t, policy.Validate("password123"), ErrWeakPassword)
+		assert.NoError(t, policy.Validate("company2025"))
+	})
+
+	t.Run("泄露密码列表文件不存在", func(t *testing.T) {
+		_, err := LoadPasswordPolicy(&config.PasswordConfig{BreachedList: filepath.Join(t.TempDir(), "missing.txt")})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,26 @@
+package auth
+
+import "context"
+
+// Principal 当前请求的调用者身份（由认证中间件写入 request context，供 service 层鉴权）
+type Principal struct {
+	UserID int64
+	Role   Role
+}
+
+// principalKey context 键类型（避免与其他包冲突）
+type principalKey struct{}
+
+// WithPrincipal 将调用者身份写入 context
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext 从 context 中读取调用者身份，未认证时返回 false
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	if ctx == nil {
+		return Principal{}, false
+	}
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
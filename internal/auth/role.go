@@ -1,41 +1,50 @@
 package auth
 
-// Role 角色类型
-type Role int
+import "sync"
 
+// Role 角色类型（对应 roles 表中的角色名）
+type Role string
+
+const (
+	// RoleUser 普通用户（内置角色）
+	RoleUser Role = "user"
+	// RoleAdmin 超级管理员（内置角色）
+	RoleAdmin Role = "admin"
+	// 其他角色由管理员通过 /api/v1/roles 在数据库中创建
+)
+
+// 内置权限
 const (
-	// RoleUser 普通用户
-	RoleUser Role = iota
-	// RoleAdmin 超级管理员
-	RoleAdmin
-	// 后续可以扩展更多角色，例如：
-	// RoleModerator  // 版主
-	// RoleEditor     // 编辑
-	// RoleViewer     // 查看者
+	PermissionUsersRead     = "users:read"
+	PermissionUsersCreate   = "users:create"
+	PermissionUsersUpdate   = "users:update"
+	PermissionUsersDelete   = "users:delete"
+	PermissionUsersLogout   = "users:logout"
+	PermissionUsersUnlock   = "users:unlock"
+	PermissionUsersRestore  = "users:restore"
+	PermissionUsersPurge    = "users:purge"
+	PermissionUsersImport   = "users:import"
+	PermissionUsersExport   = "users:export"
+	PermissionRolesRead     = "roles:read"
+	PermissionRolesManage   = "roles:manage"
+	PermissionRolesAssign   = "roles:assign"
+	PermissionAPIKeysManage = "apikeys:manage"
+	PermissionAuditRead     = "audit:read"
+	PermissionFilesUpload   = "files:upload"
+	PermissionFilesRead     = "files:read"
 )
 
 // String 返回角色的字符串表示
 func (r Role) String() string {
-	switch r {
-	case RoleAdmin:
-		return "admin"
-	case RoleUser:
-		return "user"
-	default:
-		return "unknown"
-	}
+	return string(r)
 }
 
 // ParseRole 从字符串解析角色
 func ParseRole(s string) Role {
-	switch s {
-	case "admin":
-		return RoleAdmin
-	case "user":
-		return RoleUser
-	default:
+	if s == "" {
 		return RoleUser // 默认为普通用户
 	}
+	return Role(s)
 }
 
 // IsAdmin 检查是否为管理员
@@ -43,20 +52,46 @@ func (r Role) IsAdmin() bool {
 	return r == RoleAdmin
 }
 
-// HasPermission 检查角色是否有权限（可扩展用于更复杂的权限检查）
+// HasPermission 检查角色是否有权限（基于全局权限缓存 Permissions）
 func (r Role) HasPermission(permission string) bool {
-	// 管理员拥有所有权限
-	if r.IsAdmin() {
-		return true
-	}
+	return Permissions.HasPermission(r, permission)
+}
 
-	// 普通用户的权限检查（可根据业务需求扩展）
-	switch permission {
-	case "read":
-		return true
-	case "write":
-		return false // 普通用户默认无写权限
-	default:
-		return false
+// PermissionCache 角色权限缓存
+// 授权关系保存在数据库中，启动时及角色变更后整体加载到内存，鉴权时不访问数据库
+type PermissionCache struct {
+	mu     sync.RWMutex
+	grants map[Role]map[string]struct{}
+}
+
+// Permissions 全局权限缓存
+var Permissions = NewPermissionCache()
+
+// NewPermissionCache 创建权限缓存
+func NewPermissionCache() *PermissionCache {
+	return &PermissionCache{grants: make(map[Role]map[string]struct{})}
+}
+
+// Load 用新的授权关系整体替换缓存
+func (c *PermissionCache) Load(grants map[Role][]string) {
+	loaded := make(map[Role]map[string]struct{}, len(grants))
+	for role, permissions := range grants {
+		set := make(map[string]struct{}, len(permissions))
+		for _, permission := range permissions {
+			set[permission] = struct{}{}
+		}
+		loaded[role] = set
 	}
+
+	c.mu.Lock()
+	c.grants = loaded
+	c.mu.Unlock()
+}
+
+// HasPermission 检查角色是否被授予了指定权限
+func (c *PermissionCache) HasPermission(role Role, permission string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.grants[role][permission]
+	return ok
 }
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHMACSigner 测试 HMAC 签名
+func TestHMACSigner(t *testing.T) {
+	signer, err := NewHMACSigner("secret")
+	require.NoError(t, err)
+
+	sig := signer.Sign("file:1:1700000000")
+	assert.True(t, signer.Verify("file:1:1700000000", sig))
+	assert.False(t, signer.Verify("file:2:1700000000", sig), "签名与内容绑定")
+	assert.False(t, signer.Verify("file:1:1700000000", sig[:len(sig)-1]))
+	assert.False(t, signer.Verify("file:1:1700000000", "!!!"))
+
+	other, err := NewHMACSigner("other")
+	require.NoError(t, err)
+	assert.False(t, other.Verify("file:1:1700000000", sig), "不同密钥的签名无效")
+
+	random1, err := NewHMACSigner("")
+	require.NoError(t, err)
+	random2, err := NewHMACSigner("")
+	require.NoError(t, err)
+	assert.NotEqual(t, random1.Sign("a"), random2.Sign("a"), "未配置密钥时随机生成")
+}
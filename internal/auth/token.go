@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// opaqueTokenBytes 不透明令牌的随机字节数
+const opaqueTokenBytes = 32
+
+// GenerateOpaqueToken 生成不透明令牌（用于刷新令牌等服务端存储的令牌）
+// 令牌本身不携带任何信息，只能通过服务端存储的哈希值校验
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken 计算令牌的SHA-256哈希（数据库只保存哈希，不保存令牌明文）
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix API密钥的固定前缀，便于在日志和代码仓库中识别泄露的密钥
+const APIKeyPrefix = "gsk_"
+
+// apiKeyDisplayLength 密钥列表中展示的前缀长度（固定前缀 + 8 个字符）
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// GenerateAPIKey 生成API密钥，返回密钥明文和用于展示识别的前缀
+// 明文只在创建时返回一次，数据库只保存 HashToken(明文)
+func GenerateAPIKey() (key, displayPrefix string, err error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	key = APIKeyPrefix + token
+	return key, key[:apiKeyDisplayLength], nil
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHOTP_RFC6238 使用 RFC 6238 附录 B 的 SHA1 测试向量验证算法实现
+func TestHOTP_RFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.code, hotp(key, uint64(tc.unix/TOTPPeriod), 8), "T=%d", tc.unix)
+	}
+}
+
+// TestTOTP 测试验证码生成与校验
+func TestTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32, "20字节密钥的Base32编码长度为32")
+
+	now := time.Unix(1700000000, 0)
+	code, err := TOTPCode(secret, now)
+	require.NoError(t, err)
+	assert.Len(t, code, TOTPDigits)
+
+	t.Run("当前时间步校验通过", func(t *testing.T) {
+		step, ok := ValidateTOTP(secret, code, now, 1)
+		assert.True(t, ok)
+		assert.Equal(t, TOTPStep(now), step)
+	})
+
+	t.Run("允许前后一个时间步的时钟误差", func(t *testing.T) {
+		step, ok := ValidateTOTP(secret, code, now.Add(TOTPPeriod*time.Second), 1)
+		assert.True(t, ok)
+		assert.Equal(t, TOTPStep(now), step, "应返回验证码实际所在的时间步")
+
+		_, ok = ValidateTOTP(secret, code, now.Add(2*TOTPPeriod*time.Second), 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("密钥不区分大小写", func(t *testing.T) {
+		_, ok := ValidateTOTP(strings.ToLower(secret), code, now, 0)
+		assert.True(t, ok)
+	})
+
+	t.Run("错误的验证码", func(t *testing.T) {
+		_, ok := ValidateTOTP(secret, "12345", now, 1)
+		assert.False(t, ok)
+		_, ok = ValidateTOTP(secret, "abcdef", now, 1)
+		assert.False(t, ok)
+		_, ok = ValidateTOTP("not-base32!", code, now, 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("与标准Base32编码的密钥兼容", func(t *testing.T) {
+		padded := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+		code, err := TOTPCode(padded, time.Unix(59, 0))
+		require.NoError(t, err)
+		assert.Equal(t, "287082", code)
+	})
+}
+
+// TestTOTPProvisioningURI 测试配置地址格式
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Go Startup", "admin@example.com", "JBSWY3DPEHPK3PXP")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Go%20Startup:admin@example.com?"), uri)
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Go+Startup")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
+
+// TestRecoveryCodes 测试恢复码生成与规范化
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+		assert.False(t, seen[code], "恢复码不应重复")
+		seen[code] = true
+	}
+
+	assert.Equal(t, NormalizeRecoveryCode(codes[0]), NormalizeRecoveryCode(strings.ToUpper(codes[0])))
+	assert.Equal(t, "abcdefghij", NormalizeRecoveryCode(" ABCDE-fghij "))
+}
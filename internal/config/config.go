@@ -12,6 +12,14 @@ type Config struct {
 	Database DatabaseConfig `mapstructure:"database"`
 	Logging  LoggingConfig  `mapstructure:"logging"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
+	Password PasswordConfig `mapstructure:"password"`
+	Notifier NotifierConfig `mapstructure:"notifier"`
+	Login    LoginConfig    `mapstructure:"login"`
+	MFA      MFAConfig      `mapstructure:"mfa"`
+	APIKey   APIKeyConfig   `mapstructure:"api_key"`
+	Users    UsersConfig    `mapstructure:"users"`
+	Storage  StorageConfig  `mapstructure:"storage"`
+	Images   ImagesConfig   `mapstructure:"images"`
 }
 
 // ServerConfig 服务器配置
@@ -23,8 +31,22 @@ type ServerConfig struct {
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Driver string `mapstructure:"driver"` // 数据库驱动: mysql, sqlite3
-	DSN    string `mapstructure:"dsn"`    // 数据库连接字符串
+	Driver      string `mapstructure:"driver"`       // 数据库驱动: mysql, postgres, sqlite3
+	DSN         string `mapstructure:"dsn"`          // 数据库连接字符串
+	AutoMigrate bool   `mapstructure:"auto_migrate"` // 服务启动时自动执行未执行的迁移
+
+	QueryTimeout       int `mapstructure:"query_timeout"`        // 单条SQL的默认超时时间（秒），0 表示不限制
+	SlowQueryThreshold int `mapstructure:"slow_query_threshold"` // 慢查询阈值（毫秒），执行时间达到阈值的SQL记录警告日志，0 表示不记录
+
+	// 连接池（SQLite 忽略这些配置）
+	MaxOpenConns    int `mapstructure:"max_open_conns"`     // 最大打开连接数，0 表示不限制
+	MaxIdleConns    int `mapstructure:"max_idle_conns"`     // 最大空闲连接数，0 表示不保留空闲连接
+	ConnMaxLifetime int `mapstructure:"conn_max_lifetime"`  // 连接最长存活时间（秒），0 表示不限制
+	ConnMaxIdleTime int `mapstructure:"conn_max_idle_time"` // 连接最长空闲时间（秒），0 表示不限制
+
+	// 只读副本
+	Replicas             []string `mapstructure:"replicas"`               // 只读副本DSN，为空时读写都使用主库
+	ReplicaCheckInterval int      `mapstructure:"replica_check_interval"` // 只读副本健康检查间隔（秒）
 }
 
 // LoggingConfig 日志配置
@@ -38,6 +60,126 @@ type JWTConfig struct {
 	SecretKey        string `mapstructure:"secret_key"`
 	ExpiresIn        int    `mapstructure:"expires_in"`         // 访问令牌过期时间（小时）
 	RefreshExpiresIn int    `mapstructure:"refresh_expires_in"` // 刷新令牌过期时间（小时）
+
+	// 非对称签名配置：Keys 为空时使用 SecretKey 进行 HS256 签名
+	Keys                []JWTKeyConfig `mapstructure:"keys"`
+	SigningKeyID        string         `mapstructure:"signing_key_id"`        // 当前用于签名的密钥 kid，为空时使用第一个带私钥且未退役的密钥
+	RotationGracePeriod int            `mapstructure:"rotation_grace_period"` // 密钥退役后仍可验签的宽限期（小时），默认等于访问令牌有效期
+}
+
+// JWTKeyConfig JWT签名密钥配置（RS256 / ES256 / EdDSA，算法由密钥类型决定）
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"id"`          // 密钥ID，写入令牌头部的 kid
+	PrivateKeyPath string `mapstructure:"private_key"` // PEM 私钥路径（仅验签的旧密钥可不配置）
+	PublicKeyPath  string `mapstructure:"public_key"`  // PEM 公钥路径（配置了私钥时可省略）
+	RetiredAt      string `mapstructure:"retired_at"`  // 退役时间（RFC3339），退役后只在宽限期内用于验签
+}
+
+// PasswordConfig 密码策略与找回密码配置
+type PasswordConfig struct {
+	MinLength     int    `mapstructure:"min_length"`      // 最小长度
+	RequireUpper  bool   `mapstructure:"require_upper"`   // 必须包含大写字母
+	RequireLower  bool   `mapstructure:"require_lower"`   // 必须包含小写字母
+	RequireDigit  bool   `mapstructure:"require_digit"`   // 必须包含数字
+	RequireSymbol bool   `mapstructure:"require_symbol"`  // 必须包含特殊字符
+	BreachedList  string `mapstructure:"breached_list"`   // 泄露密码列表文件（每行一个），在内置列表基础上追加
+	ResetTokenTTL int    `mapstructure:"reset_token_ttl"` // 重置密码令牌有效期（分钟）
+	ResetURL      string `mapstructure:"reset_url"`       // 重置密码链接，{token} 会被替换为重置令牌
+}
+
+// NotifierConfig 通知配置（找回密码等场景向用户发送消息）
+type NotifierConfig struct {
+	Type string `mapstructure:"type"` // log：写入应用日志；file：追加到文件
+	File string `mapstructure:"file"` // type 为 file 时的文件路径
+}
+
+// LoginConfig 登录防暴力破解配置
+// 按账户（邮箱）和客户端IP分别统计连续失败次数：每次失败后需要等待指数增长的退避时间才能再次尝试，
+// 失败次数达到上限后临时锁定
+type LoginConfig struct {
+	MaxAccountFailures int `mapstructure:"max_account_failures"` // 账户连续失败多少次后锁定，0 表示不锁定
+	MaxIPFailures      int `mapstructure:"max_ip_failures"`      // 同一IP连续失败多少次后锁定，0 表示不锁定
+	LockoutDuration    int `mapstructure:"lockout_duration"`     // 锁定时长（分钟）
+	FailureWindow      int `mapstructure:"failure_window"`       // 距上次失败超过该时长（分钟）后重新计数
+	BackoffBase        int `mapstructure:"backoff_base"`         // 第一次失败后的退避时间（秒），之后每次翻倍，0 表示不退避
+	BackoffMax         int `mapstructure:"backoff_max"`          // 退避时间上限（秒）
+}
+
+// MFAConfig 两步验证（TOTP）配置
+type MFAConfig struct {
+	Issuer          string `mapstructure:"issuer"`            // 身份验证器中显示的服务名称
+	RequireForAdmin bool   `mapstructure:"require_for_admin"` // 管理员必须启用两步验证（未启用时登录后需先完成设置）
+	ChallengeTTL    int    `mapstructure:"challenge_ttl"`     // 登录时两步验证令牌的有效期（分钟）
+	MaxAttempts     int    `mapstructure:"max_attempts"`      // 每个两步验证令牌允许输错验证码的次数
+	RecoveryCodes   int    `mapstructure:"recovery_codes"`    // 生成的恢复码数量
+	Skew            int    `mapstructure:"skew"`              // 允许前后偏差的时间步数（每步30秒）
+}
+
+// APIKeyConfig API密钥配置
+type APIKeyConfig struct {
+	DefaultTTL int `mapstructure:"default_ttl"` // 创建时未指定有效期时的默认有效期（天）
+	MaxTTL     int `mapstructure:"max_ttl"`     // 有效期上限（天）
+}
+
+// UsersConfig 用户数据配置
+type UsersConfig struct {
+	DeletedRetention int `mapstructure:"deleted_retention"` // 已删除用户的保留天数，过后彻底删除，0 表示不自动清除
+}
+
+// StorageConfig 上传文件的存储配置
+type StorageConfig struct {
+	Type  string             `mapstructure:"type"` // local：本地磁盘；s3：S3 兼容的对象存储（AWS S3、MinIO 等）
+	Local LocalStorageConfig `mapstructure:"local"`
+	S3    S3StorageConfig    `mapstructure:"s3"`
+	Tus   TusConfig          `mapstructure:"tus"`
+	// SignedURL 下载文件的签名链接
+	SignedURL SignedURLConfig `mapstructure:"signed_url"`
+}
+
+// LocalStorageConfig 本地磁盘存储配置
+type LocalStorageConfig struct {
+	Dir string `mapstructure:"dir"` // 文件保存目录
+}
+
+// S3StorageConfig S3 兼容对象存储配置
+type S3StorageConfig struct {
+	Endpoint        string `mapstructure:"endpoint"`          // 服务地址，如 https://s3.us-east-1.amazonaws.com、http://localhost:9000
+	Region          string `mapstructure:"region"`            // 区域，MinIO 使用 us-east-1
+	Bucket          string `mapstructure:"bucket"`            // 存储桶（需提前创建）
+	AccessKeyID     string `mapstructure:"access_key_id"`     // 访问密钥ID
+	SecretAccessKey string `mapstructure:"secret_access_key"` // 访问密钥
+	PathStyle       bool   `mapstructure:"path_style"`        // 使用 endpoint/bucket/key 形式的地址（MinIO 需要开启），否则使用 bucket.endpoint/key
+}
+
+// TusConfig 断点续传（tus 协议）配置
+type TusConfig struct {
+	Dir          string `mapstructure:"dir"`           // 未完成的上传保存目录，服务重启后可以继续上传
+	Expiration   int    `mapstructure:"expiration"`    // 上传创建后的有效期（小时），过期未完成的上传会被清理
+	ChunkTimeout int    `mapstructure:"chunk_timeout"` // 接收一个分片的读写超时（秒），覆盖 server.read_timeout / write_timeout
+}
+
+// SignedURLConfig 文件签名链接配置
+type SignedURLConfig struct {
+	Secret     string `mapstructure:"secret"`      // HMAC 签名密钥，为空时每次启动随机生成（重启后已签发的链接失效）
+	DefaultTTL int    `mapstructure:"default_ttl"` // 未指定有效期时的默认有效期（秒）
+	MaxTTL     int    `mapstructure:"max_ttl"`     // 有效期上限（秒）
+}
+
+// ImagesConfig 上传图片的处理配置
+type ImagesConfig struct {
+	CacheDir     string            `mapstructure:"cache_dir"`     // 缩略图等派生图片的缓存目录，可以随时清空
+	MaxPixels    int               `mapstructure:"max_pixels"`    // 允许处理的最大像素数（宽 × 高），防止解码超大图片耗尽内存
+	MaxDimension int               `mapstructure:"max_dimension"` // 变换参数 w / h 的上限
+	JPEGQuality  int               `mapstructure:"jpeg_quality"`  // 生成 JPEG 的质量（1-100）
+	Thumbnails   []ThumbnailConfig `mapstructure:"thumbnails"`    // 上传图片后预先生成的缩略图
+}
+
+// ThumbnailConfig 缩略图尺寸
+type ThumbnailConfig struct {
+	Name   string `mapstructure:"name"` // 名称，下载时通过 ?thumbnail=名称 获取
+	Width  int    `mapstructure:"width"`
+	Height int    `mapstructure:"height"`
+	Fit    string `mapstructure:"fit"` // cover：裁剪填满；contain：完整显示在范围内；fill：拉伸
 }
 
 // AppConfig 提供一个全局可访问的配置实例
@@ -60,9 +202,55 @@ func LoadConfig() *Config {
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("database.driver", "sqlite3")
 	viper.SetDefault("database.dsn", "./data/app.db")
+	viper.SetDefault("database.auto_migrate", true)
+	viper.SetDefault("database.query_timeout", 5)
+	viper.SetDefault("database.slow_query_threshold", 200)
+	viper.SetDefault("database.max_open_conns", 25)
+	viper.SetDefault("database.max_idle_conns", 5)
+	viper.SetDefault("database.conn_max_lifetime", 300)
+	viper.SetDefault("database.conn_max_idle_time", 0)
+	viper.SetDefault("database.replica_check_interval", 10)
 	viper.SetDefault("jwt.secret_key", "your-secret-key-change-in-production")
 	viper.SetDefault("jwt.expires_in", 24)          // 默认24小时过期（访问令牌）
 	viper.SetDefault("jwt.refresh_expires_in", 168) // 默认7天过期（刷新令牌）
+	viper.SetDefault("password.min_length", 8)
+	viper.SetDefault("password.require_lower", true)
+	viper.SetDefault("password.require_digit", true)
+	viper.SetDefault("password.reset_token_ttl", 30) // 默认30分钟
+	viper.SetDefault("password.reset_url", "http://localhost:8080/reset-password?token={token}")
+	viper.SetDefault("notifier.type", "log")
+	viper.SetDefault("login.max_account_failures", 5)
+	viper.SetDefault("login.max_ip_failures", 20)
+	viper.SetDefault("login.lockout_duration", 15)
+	viper.SetDefault("login.failure_window", 15)
+	viper.SetDefault("login.backoff_base", 1)
+	viper.SetDefault("login.backoff_max", 30)
+	viper.SetDefault("mfa.issuer", "Go Startup")
+	viper.SetDefault("mfa.require_for_admin", false)
+	viper.SetDefault("mfa.challenge_ttl", 5)
+	viper.SetDefault("mfa.max_attempts", 5)
+	viper.SetDefault("mfa.recovery_codes", 10)
+	viper.SetDefault("mfa.skew", 1)
+	viper.SetDefault("api_key.default_ttl", 90)
+	viper.SetDefault("api_key.max_ttl", 365)
+	viper.SetDefault("users.deleted_retention", 30)
+	viper.SetDefault("storage.type", "local")
+	viper.SetDefault("storage.local.dir", "./data/files")
+	viper.SetDefault("storage.s3.region", "us-east-1")
+	viper.SetDefault("storage.s3.path_style", true)
+	viper.SetDefault("storage.tus.dir", "./data/uploads")
+	viper.SetDefault("storage.tus.expiration", 24)
+	viper.SetDefault("storage.tus.chunk_timeout", 60)
+	viper.SetDefault("storage.signed_url.default_ttl", 3600)
+	viper.SetDefault("storage.signed_url.max_ttl", 7*24*3600)
+	viper.SetDefault("images.cache_dir", "./data/image-cache")
+	viper.SetDefault("images.max_pixels", 40_000_000)
+	viper.SetDefault("images.max_dimension", 2048)
+	viper.SetDefault("images.jpeg_quality", 85)
+	viper.SetDefault("images.thumbnails", []map[string]any{
+		{"name": "small", "width": 128, "height": 128, "fit": "cover"},
+		{"name": "medium", "width": 512, "height": 512, "fit": "contain"},
+	})
 
 	if err := viper.ReadInConfig(); err != nil { // 读取配置
 		log.Printf("无法读取配置文件: %v, 将使用默认值", err)
@@ -3,18 +3,26 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // DB 数据库接口
+// 仓库应通过 Conn 执行SQL，使客户端断开和服务关闭能够取消查询，并应用查询超时和慢查询日志
 type DB interface {
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
 	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	Begin() (*sql.Tx, error)
 	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 	PingContext(ctx context.Context) error
@@ -23,28 +31,186 @@ type DB interface {
 
 var Database DB
 
+// Option 数据库连接可选配置
+type Option func(*sqlDB)
+
+// WithQueryTimeout 指定单条SQL的默认超时时间（ctx 已有更早的截止时间时不生效），0 表示不限制
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(db *sqlDB) {
+		db.queryOptions.timeout = timeout
+	}
+}
+
+// WithSlowQueryThreshold 指定慢查询阈值，执行时间达到阈值的SQL会记录警告日志，0 表示不记录
+func WithSlowQueryThreshold(threshold time.Duration) Option {
+	return func(db *sqlDB) {
+		db.queryOptions.slowThreshold = threshold
+	}
+}
+
+// WithPool 指定连接池配置（默认使用 DefaultPoolConfig），只读副本使用相同的配置
+func WithPool(pool PoolConfig) Option {
+	return func(db *sqlDB) {
+		db.pool = pool
+	}
+}
+
+// WithReplicas 指定只读副本DSN和健康检查间隔
+// ReadConn 的查询会轮询发送到健康的只读副本，所有副本都不可用时回退到主库
+func WithReplicas(dsns []string, checkInterval time.Duration) Option {
+	return func(db *sqlDB) {
+		db.replicaDSNs = dsns
+		db.replicaCheckInterval = checkInterval
+	}
+}
+
+// WithDialect 指定SQL方言（默认按驱动选择）
+// 用于兼容其他数据库SQL写法的驱动，例如测试中用 SQLite 执行 PostgreSQL 方言的SQL
+func WithDialect(dialect Dialect) Option {
+	return func(db *sqlDB) {
+		db.queryOptions.dialect = dialect
+	}
+}
+
+// PoolConfig 连接池配置
+type PoolConfig struct {
+	MaxOpenConns    int           // 最大打开连接数，0 表示不限制
+	MaxIdleConns    int           // 最大空闲连接数，0 表示不保留空闲连接
+	ConnMaxLifetime time.Duration // 连接最长存活时间，0 表示不限制
+	ConnMaxIdleTime time.Duration // 连接最长空闲时间，0 表示不限制
+}
+
+// DefaultPoolConfig 默认连接池配置
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    25,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 5 * time.Minute,
+	}
+}
+
+// sqlDB 数据库连接，保存通过 Conn 执行SQL时使用的查询超时、慢查询配置、方言和只读副本
+type sqlDB struct {
+	*sql.DB
+	queryOptions queryOptions
+	pool         PoolConfig
+
+	replicaDSNs          []string
+	replicaCheckInterval time.Duration
+	replicas             []*replica
+	next                 atomic.Uint64
+	stopHealthCheck      context.CancelFunc
+	healthCheckDone      chan struct{}
+}
+
 // InitDB 初始化数据库连接
-func InitDB(driver, dsn string) (DB, error) {
-	db, err := sql.Open(driver, dsn)
-	if err != nil {
-		return nil, fmt.Errorf("无法打开数据库连接: %w", err)
+func InitDB(driver, dsn string, opts ...Option) (DB, error) {
+	db := &sqlDB{pool: DefaultPoolConfig(), replicaCheckInterval: 10 * time.Second}
+	for _, opt := range opts {
+		opt(db)
+	}
+	if db.queryOptions.dialect == nil {
+		dialect, err := DialectFor(driver)
+		if err != nil {
+			return nil, err
+		}
+		db.queryOptions.dialect = dialect
 	}
 
-	// 设置连接池参数（SQLite 不需要这些，但保留兼容性）
-	if driver != "sqlite3" {
-		db.SetMaxOpenConns(25)
-		db.SetMaxIdleConns(5)
-		db.SetConnMaxLifetime(5 * time.Minute)
+	conn, err := openDB(driver, dsn, db.pool)
+	if err != nil {
+		return nil, err
 	}
 
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := db.PingContext(ctx); err != nil {
+	if err := conn.PingContext(ctx); err != nil {
 		return nil, fmt.Errorf("无法连接到数据库: %w", err)
 	}
+	db.DB = conn
+
+	// 只读副本不可用时不影响启动，由健康检查在恢复后重新启用
+	for i, replicaDSN := range db.replicaDSNs {
+		replicaConn, err := openDB(driver, replicaDSN, db.pool)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("无法打开只读副本连接: %w", err)
+		}
+		r := &replica{name: fmt.Sprintf("replica-%d", i), db: replicaConn}
+		r.healthy.Store(true) // 启动时只在不可用时记录日志
+		r.check(ctx)
+		db.replicas = append(db.replicas, r)
+	}
+	if len(db.replicas) > 0 {
+		db.startHealthCheck()
+	}
 
 	Database = db
 	return db, nil
 }
+
+// openDB 打开数据库连接并设置连接池参数
+func openDB(driver, dsn string, pool PoolConfig) (*sql.DB, error) {
+	if driver == "sqlite3" {
+		dsn = sqliteForeignKeysDSN(dsn)
+	}
+	conn, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开数据库连接: %w", err)
+	}
+
+	// 设置连接池参数（SQLite 不需要这些；内存数据库的连接被回收后数据会丢失）
+	if driver != "sqlite3" {
+		conn.SetMaxOpenConns(pool.MaxOpenConns)
+		conn.SetMaxIdleConns(pool.MaxIdleConns)
+		conn.SetConnMaxLifetime(pool.ConnMaxLifetime)
+		conn.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	}
+	return conn, nil
+}
+
+// sqliteForeignKeysDSN 为 SQLite 连接开启外键约束
+// SQLite 默认不检查外键，ON DELETE CASCADE 不会生效；通过 DSN 参数开启后驱动会在每个新连接上执行
+// PRAGMA foreign_keys = ON，DSN 中已显式设置时保持不变
+func sqliteForeignKeysDSN(dsn string) string {
+	if strings.Contains(dsn, "_foreign_keys=") || strings.Contains(dsn, "_fk=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=1"
+	}
+	return dsn + "?_foreign_keys=1"
+}
+
+// Close 停止只读副本健康检查并关闭所有连接
+func (db *sqlDB) Close() error {
+	if db.stopHealthCheck != nil {
+		db.stopHealthCheck()
+		<-db.healthCheckDone
+	}
+
+	var errs []error
+	for _, r := range db.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	if db.DB != nil {
+		errs = append(errs, db.DB.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Pools 返回数据库的所有连接池（主库为 primary，只读副本为 replica-N），用于导出连接池指标
+func Pools(db DB) map[string]*sql.DB {
+	s, ok := db.(*sqlDB)
+	if !ok {
+		return nil
+	}
+
+	pools := map[string]*sql.DB{"primary": s.DB}
+	for _, r := range s.replicas {
+		pools[r.name] = r.db
+	}
+	return pools
+}
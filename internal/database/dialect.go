@@ -0,0 +1,191 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dialect 数据库方言，屏蔽不同数据库在占位符、自增ID、upsert 和建表语句上的差异
+// 仓库统一使用 ? 占位符编写SQL，通过 Conn 执行时按方言自动转换
+type Dialect interface {
+	// Name 方言名称，与驱动名和迁移文件目录名一致
+	Name() string
+	// Rebind 将查询中的 ? 占位符转换为数据库使用的占位符（字符串字面量中的 ? 保持不变）
+	Rebind(query string) string
+	// SupportsReturning 是否通过 INSERT ... RETURNING id 获取自增ID，否则使用 LastInsertId
+	SupportsReturning() bool
+	// Upsert 返回追加在 INSERT ... VALUES (...) 之后、插入冲突时改为更新的子句
+	// conflictColumns 为冲突的唯一约束列，assignments 为 "列 = 表达式"，表达式中用 Excluded 引用待插入的值
+	Upsert(conflictColumns []string, assignments ...string) string
+	// Excluded 在 Upsert 的更新表达式中引用待插入行的列值
+	Excluded(column string) string
+	// SchemaMigrationsDDL schema_migrations 记录表的建表语句
+	SchemaMigrationsDDL() string
+	// MigrationLockSQL 返回获取和释放迁移锁的语句，防止多个实例同时执行迁移；不需要时返回空字符串
+	// 锁属于数据库会话，两条语句需要在同一个连接上执行；获取锁的语句阻塞等待，成功时返回一行 1
+	MigrationLockSQL() (lock, unlock string)
+}
+
+// dialects 支持的数据库方言，按驱动名索引
+var dialects = map[string]Dialect{
+	"sqlite3":  sqliteDialect{},
+	"mysql":    mysqlDialect{},
+	"postgres": postgresDialect{},
+}
+
+// DialectFor 返回驱动对应的方言
+func DialectFor(driver string) (Dialect, error) {
+	dialect, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("不支持的数据库驱动: %s", driver)
+	}
+	return dialect, nil
+}
+
+// DialectOf 返回数据库连接使用的方言（不是 InitDB 创建的连接时按 SQLite 处理）
+func DialectOf(db DB) Dialect {
+	if s, ok := db.(*sqlDB); ok && s.queryOptions.dialect != nil {
+		return s.queryOptions.dialect
+	}
+	return sqliteDialect{}
+}
+
+// SupportedDrivers 返回支持的数据库驱动
+func SupportedDrivers() []string {
+	drivers := make([]string, 0, len(dialects))
+	for driver := range dialects {
+		drivers = append(drivers, driver)
+	}
+	sort.Strings(drivers)
+	return drivers
+}
+
+// sqliteDialect SQLite 方言
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite3" }
+
+func (sqliteDialect) Rebind(query string) string { return query }
+
+func (sqliteDialect) SupportsReturning() bool { return false }
+
+func (sqliteDialect) Upsert(conflictColumns []string, assignments ...string) string {
+	return onConflictUpsert(conflictColumns, assignments)
+}
+
+func (sqliteDialect) Excluded(column string) string { return "excluded." + column }
+
+func (sqliteDialect) SchemaMigrationsDDL() string {
+	return `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at DATETIME NOT NULL
+		)
+	`
+}
+
+// MigrationLockSQL SQLite 的写事务按数据库文件加锁，迁移与迁移记录在同一个事务中提交，
+// 并发执行同一个迁移时后提交的事务因主键冲突整体回滚，不需要额外的锁
+func (sqliteDialect) MigrationLockSQL() (lock, unlock string) { return "", "" }
+
+// mysqlDialect MySQL 方言
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Rebind(query string) string { return query }
+
+func (mysqlDialect) SupportsReturning() bool { return false }
+
+// Upsert MySQL 在任意唯一约束冲突时更新，不需要指定冲突列
+func (mysqlDialect) Upsert(conflictColumns []string, assignments ...string) string {
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+func (mysqlDialect) Excluded(column string) string { return "VALUES(" + column + ")" }
+
+func (mysqlDialect) SchemaMigrationsDDL() string {
+	return `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at DATETIME NOT NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4
+	`
+}
+
+// MigrationLockSQL 使用命名锁，最多等待 migrationLockTimeout 秒，超时返回 0
+func (mysqlDialect) MigrationLockSQL() (lock, unlock string) {
+	return fmt.Sprintf("SELECT GET_LOCK('%s', %d)", migrationLockName, migrationLockTimeout),
+		fmt.Sprintf("SELECT RELEASE_LOCK('%s')", migrationLockName)
+}
+
+// postgresDialect PostgreSQL 方言
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+// Rebind 将 ? 依次替换为 $1、$2 ...
+func (postgresDialect) Rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte // 当前所在字符串字面量或带引号标识符的引号，0 表示不在引号中
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0 // 转义的引号（'' 或 ""）会先关闭再重新打开，结果一致
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func (postgresDialect) SupportsReturning() bool { return true }
+
+func (postgresDialect) Upsert(conflictColumns []string, assignments ...string) string {
+	return onConflictUpsert(conflictColumns, assignments)
+}
+
+func (postgresDialect) Excluded(column string) string { return "excluded." + column }
+
+func (postgresDialect) SchemaMigrationsDDL() string {
+	return `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL
+		)
+	`
+}
+
+// MigrationLockSQL 使用会话级咨询锁，等待期间可以通过 context 取消
+func (postgresDialect) MigrationLockSQL() (lock, unlock string) {
+	return fmt.Sprintf("SELECT 1 FROM pg_advisory_lock(%d)", migrationLockKey),
+		fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationLockKey)
+}
+
+// onConflictUpsert SQLite 与 PostgreSQL 共用的 ON CONFLICT ... DO UPDATE 子句
+func onConflictUpsert(conflictColumns []string, assignments []string) string {
+	return " ON CONFLICT (" + strings.Join(conflictColumns, ", ") + ") DO UPDATE SET " + strings.Join(assignments, ", ")
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDialect 测试各数据库方言的SQL差异
+func TestDialect(t *testing.T) {
+	sqlite, err := DialectFor("sqlite3")
+	require.NoError(t, err)
+	mysql, err := DialectFor("mysql")
+	require.NoError(t, err)
+	postgres, err := DialectFor("postgres")
+	require.NoError(t, err)
+
+	_, err = DialectFor("oracle")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"mysql", "postgres", "sqlite3"}, SupportedDrivers())
+
+	t.Run("占位符转换", func(t *testing.T) {
+		query := "SELECT id FROM users WHERE name = ? AND email LIKE ? ESCAPE '!'"
+		assert.Equal(t, query, sqlite.Rebind(query))
+		assert.Equal(t, query, mysql.Rebind(query))
+		assert.Equal(t, "SELECT id FROM users WHERE name = $1 AND email LIKE $2 ESCAPE '!'", postgres.Rebind(query))
+
+		// 字符串字面量和带引号的标识符中的 ? 不是占位符
+		assert.Equal(t,
+			`SELECT '?', 'it''s ?', "col?" FROM t WHERE a = $1`,
+			postgres.Rebind(`SELECT '?', 'it''s ?', "col?" FROM t WHERE a = ?`),
+		)
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		assert.Equal(t,
+			" ON CONFLICT (k) DO UPDATE SET v = excluded.v",
+			sqlite.Upsert([]string{"k"}, "v = "+sqlite.Excluded("v")),
+		)
+		assert.Equal(t,
+			" ON CONFLICT (k) DO UPDATE SET v = excluded.v",
+			postgres.Upsert([]string{"k"}, "v = "+postgres.Excluded("v")),
+		)
+		assert.Equal(t,
+			" ON DUPLICATE KEY UPDATE v = VALUES(v)",
+			mysql.Upsert([]string{"k"}, "v = "+mysql.Excluded("v")),
+		)
+	})
+
+	t.Run("迁移锁", func(t *testing.T) {
+		lock, unlock := sqlite.MigrationLockSQL()
+		assert.Empty(t, lock+unlock, "SQLite 依靠事务防止重复迁移")
+
+		lock, unlock = mysql.MigrationLockSQL()
+		assert.Equal(t, "SELECT GET_LOCK('gin_schema_migrations', 600)", lock)
+		assert.Equal(t, "SELECT RELEASE_LOCK('gin_schema_migrations')", unlock)
+
+		lock, unlock = postgres.MigrationLockSQL()
+		assert.Equal(t, "SELECT 1 FROM pg_advisory_lock(7346921480113)", lock)
+		assert.Equal(t, "SELECT pg_advisory_unlock(7346921480113)", unlock)
+	})
+
+	t.Run("PostgreSQL方言通过RETURNING获取自增ID", func(t *testing.T) {
+		// SQLite 同样支持 $n 占位符和 RETURNING，可以代替 PostgreSQL 验证
+		db, err := InitDB("sqlite3", ":memory:", WithDialect(postgres))
+		require.NoError(t, err)
+		defer db.Close()
+		assert.Equal(t, postgres, DialectOf(db))
+
+		ctx := context.Background()
+		_, err = Conn(ctx, db).ExecContext(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL UNIQUE, hits INTEGER NOT NULL)")
+		require.NoError(t, err)
+
+		for i, name := range []string{"a", "b"} {
+			id, err := Conn(ctx, db).InsertContext(ctx, "INSERT INTO items (name, hits) VALUES (?, ?)", name, 1)
+			require.NoError(t, err)
+			assert.Equal(t, int64(i+1), id)
+		}
+
+		upsert := "INSERT INTO items (name, hits) VALUES (?, ?)" + postgres.Upsert([]string{"name"}, "hits = items.hits + "+postgres.Excluded("hits"))
+		_, err = Conn(ctx, db).ExecContext(ctx, upsert, "a", 5)
+		require.NoError(t, err)
+
+		var hits int
+		require.NoError(t, Conn(ctx, db).QueryRowContext(ctx, "SELECT hits FROM items WHERE name = ?", "a").Scan(&hits))
+		assert.Equal(t, 6, hits)
+	})
+}
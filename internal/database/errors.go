@@ -0,0 +1,33 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// mysqlErrDupEntry MySQL 唯一键冲突错误码（ER_DUP_ENTRY）
+const mysqlErrDupEntry = 1062
+
+// postgresUniqueViolation PostgreSQL 唯一约束冲突错误码（unique_violation）
+const postgresUniqueViolation = "23505"
+
+// IsUniqueViolation 判断错误是否为唯一约束冲突
+// 并发写入时"先查询再插入"的检查无法完全避免冲突，需要根据唯一约束错误返回明确的业务错误
+func IsUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrDupEntry
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == postgresUniqueViolation
+	}
+	return false
+}
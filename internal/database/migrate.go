@@ -2,45 +2,478 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// InitSchema 初始化数据库表结构
-func InitSchema(db DB) error {
-	ctx := context.Background()
+// migrationFiles 内置的迁移文件，按驱动分目录存放：migrations/<driver>/<version>_<name>.<up|down>.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
 
-	// 创建 users 表
-	createUsersTable := `
-		CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL,
-			email TEXT NOT NULL UNIQUE,
-			password TEXT NOT NULL,
-			age INTEGER NOT NULL DEFAULT 0,
-			role INTEGER NOT NULL DEFAULT 0,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-		)
-	`
+// MigrationsDir 迁移文件在源码中的默认目录（供 migrate create 使用）
+const MigrationsDir = "./internal/database/migrations"
 
-	_, err := db.Exec(createUsersTable)
+// migrationFilePattern 迁移文件名格式，例如 0001_create_users_table.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migrationNamePattern 迁移名称格式
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// 迁移锁：多个实例同时启动并自动迁移时，只有一个实例执行迁移，其余实例等待后发现已无需执行
+const (
+	migrationLockName    = "gin_schema_migrations" // MySQL 命名锁
+	migrationLockKey     = 7_346_921_480_113       // PostgreSQL 咨询锁
+	migrationLockTimeout = 600                     // MySQL 等待锁的秒数
+)
+
+// ErrChecksumMismatch 已执行的迁移文件被修改
+var ErrChecksumMismatch = errors.New("迁移文件校验和不一致")
+
+// Migration 单个版本的迁移
+type Migration struct {
+	Version  int64
+	Name     string
+	UpSQL    string
+	DownSQL  string
+	Checksum string // up 脚本的 sha256，用于发现已执行迁移被修改
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // 已执行，但文件内容与执行时不一致
+	Missing   bool // 已执行，但迁移文件已不存在
+}
+
+// Migrator 版本化 SQL 迁移执行器
+type Migrator struct {
+	db     DB
+	driver string
+	source fs.FS
+}
+
+// NewMigrator 使用内置迁移文件创建迁移执行器
+func NewMigrator(db DB, driver string) *Migrator {
+	source, _ := embeddedMigrations(driver)
+	return NewMigratorWithSource(db, driver, source)
+}
+
+// embeddedMigrations 返回指定驱动的内置迁移文件
+func embeddedMigrations(driver string) (fs.FS, error) {
+	return fs.Sub(migrationFiles, "migrations/"+driver)
+}
+
+// NewMigratorWithSource 使用指定的迁移文件来源创建迁移执行器
+// source 根目录下直接存放当前驱动的迁移文件
+func NewMigratorWithSource(db DB, driver string, source fs.FS) *Migrator {
+	return &Migrator{
+		db:     db,
+		driver: driver,
+		source: source,
+	}
+}
+
+// Up 执行所有未执行的迁移，返回本次执行的迁移
+// 执行期间持有迁移锁，多个实例可以同时调用
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	migrations, applied, err := m.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已执行的迁移被修改时拒绝继续，避免库结构与文件不一致
+	for _, migration := range migrations {
+		if record, ok := applied[migration.Version]; ok && record.checksum != migration.Checksum {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+
+	var executed []*Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.apply(ctx, migration); err != nil {
+			return executed, err
+		}
+		executed = append(executed, migration)
+	}
+
+	return executed, nil
+}
+
+// Down 回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("回滚步数必须大于0: %d", steps)
+	}
+
+	unlock, err := m.lock(ctx)
 	if err != nil {
-		return fmt.Errorf("创建 users 表失败: %w", err)
+		return nil, err
 	}
+	defer unlock()
 
-	// 创建索引
-	createIndex := `
-		CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)
-	`
-	_, err = db.Exec(createIndex)
+	migrations, applied, err := m.prepare(ctx)
 	if err != nil {
-		return fmt.Errorf("创建索引失败: %w", err)
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	var rolledBack []*Migration
+	for i := 0; i < steps && i < len(versions); i++ {
+		migration, ok := byVersion[versions[i]]
+		if !ok {
+			return rolledBack, fmt.Errorf("迁移文件不存在，无法回滚版本 %04d", versions[i])
+		}
+		if applied[migration.Version].checksum != migration.Checksum {
+			return rolledBack, fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+		if err := m.revert(ctx, migration); err != nil {
+			return rolledBack, err
+		}
+		rolledBack = append(rolledBack, migration)
+	}
+
+	return rolledBack, nil
+}
+
+// Status 返回所有迁移（包括已执行但文件丢失的迁移）的状态，按版本升序
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	migrations, applied, err := m.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]bool, len(migrations))
+	var statuses []*MigrationStatus
+	for _, migration := range migrations {
+		known[migration.Version] = true
+		status := &MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.appliedAt
+			status.Modified = record.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, record := range applied {
+		if known[version] {
+			continue
+		}
+		statuses = append(statuses, &MigrationStatus{
+			Version:   version,
+			Name:      record.name,
+			Applied:   true,
+			AppliedAt: record.appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// appliedMigration schema_migrations 中的一条记录
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// lock 在单独的连接上获取迁移锁，返回释放锁并归还连接的函数
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	dialect, err := DialectFor(m.driver)
+	if err != nil {
+		return nil, err
+	}
+	lockSQL, unlockSQL := dialect.MigrationLockSQL()
+	if lockSQL == "" {
+		return func() {}, nil
+	}
+
+	pool, ok := m.db.(interface {
+		Conn(ctx context.Context) (*sql.Conn, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("数据库连接不支持获取迁移锁")
+	}
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取迁移锁失败: %w", err)
+	}
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, lockSQL).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("获取迁移锁失败: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		conn.Close()
+		return nil, fmt.Errorf("获取迁移锁失败: 等待其他实例执行迁移超时")
+	}
+
+	return func() {
+		// 迁移被取消时同样需要释放锁；释放失败时关闭连接，数据库随会话结束释放锁
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), unlockSQL); err != nil {
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
+}
+
+// prepare 确保记录表存在，并加载迁移文件和已执行记录
+func (m *Migrator) prepare(ctx context.Context) ([]*Migration, map[int64]appliedMigration, error) {
+	dialect, err := DialectFor(m.driver)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := m.db.ExecContext(ctx, dialect.SchemaMigrationsDDL()); err != nil {
+		return nil, nil, fmt.Errorf("创建 schema_migrations 表失败: %w", err)
+	}
+
+	migrations, err := LoadMigrations(m.source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询迁移记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var version int64
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, nil, fmt.Errorf("扫描迁移记录失败: %w", err)
+		}
+		applied[version] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("遍历迁移记录失败: %w", err)
+	}
+
+	return migrations, applied, nil
+}
+
+// apply 在事务中执行单个迁移并写入记录
+// 注意：MySQL 的 DDL 会隐式提交事务，失败时可能需要手动清理
+func (m *Migrator) apply(ctx context.Context, migration *Migration) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range splitStatements(migration.UpSQL) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("执行迁移 %04d_%s 失败: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		DialectOf(m.db).Rebind("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+		migration.Version, migration.Name, migration.Checksum, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("写入迁移记录失败: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// revert 在事务中回滚单个迁移并删除记录
+func (m *Migrator) revert(ctx context.Context, migration *Migration) error {
+	if strings.TrimSpace(migration.DownSQL) == "" {
+		return fmt.Errorf("迁移 %04d_%s 没有 down 脚本", migration.Version, migration.Name)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range splitStatements(migration.DownSQL) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("回滚迁移 %04d_%s 失败: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, DialectOf(m.db).Rebind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version); err != nil {
+		return fmt.Errorf("删除迁移记录失败: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// LoadMigrations 从迁移文件来源加载所有迁移，按版本升序
+func LoadMigrations(source fs.FS) ([]*Migration, error) {
+	if source == nil {
+		return nil, nil
+	}
+
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取迁移目录失败: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的迁移版本 %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移文件 %s 失败: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("迁移版本 %04d 重复: %s 与 %s", version, migration.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			migration.UpSQL = string(content)
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.UpSQL == "" {
+			return nil, fmt.Errorf("迁移 %04d_%s 缺少 up 脚本", migration.Version, migration.Name)
+		}
+		sum := sha256.Sum256([]byte(migration.UpSQL))
+		migration.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// CreateMigration 在 dir 下为每个驱动生成下一个版本的 up/down 空迁移文件，返回生成的文件路径
+func CreateMigration(dir, name string, drivers []string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+	if !migrationNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("迁移名称只能包含字母、数字和下划线: %s", name)
+	}
+
+	// 所有驱动共用同一个版本号，取各目录中的最大版本 + 1
+	var next int64 = 1
+	for _, driver := range drivers {
+		migrations, err := LoadMigrations(os.DirFS(filepath.Join(dir, driver)))
+		if err != nil {
+			return nil, err
+		}
+		if n := len(migrations); n > 0 && migrations[n-1].Version >= next {
+			next = migrations[n-1].Version + 1
+		}
+	}
+
+	var created []string
+	for _, driver := range drivers {
+		driverDir := filepath.Join(dir, driver)
+		if err := os.MkdirAll(driverDir, 0755); err != nil {
+			return created, fmt.Errorf("创建迁移目录失败: %w", err)
+		}
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(driverDir, fmt.Sprintf("%04d_%s.%s.sql", next, name, direction))
+			content := fmt.Sprintf("-- %04d_%s (%s, %s)\n", next, name, driver, direction)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				return created, fmt.Errorf("写入迁移文件失败: %w", err)
+			}
+			created = append(created, path)
+		}
+	}
+
+	return created, nil
+}
+
+// splitStatements 将迁移脚本按分号拆分为单条语句（MySQL 驱动默认不支持一次执行多条语句）
+// 以 "--" 开头的整行注释会被忽略；语句需以行尾分号结束
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmt := strings.TrimSpace(current.String())
+			stmt = strings.TrimSpace(strings.TrimSuffix(stmt, ";"))
+			if stmt != "" {
+				statements = append(statements, stmt)
+			}
+			current.Reset()
+		}
 	}
 
-	// 测试连接
-	if err := db.PingContext(ctx); err != nil {
-		return fmt.Errorf("数据库连接测试失败: %w", err)
+	if stmt := strings.TrimSpace(current.String()); stmt != "" {
+		statements = append(statements, stmt)
 	}
 
-	return nil
+	return statements
 }
@@ -0,0 +1,405 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupMigrateTestDB 创建迁移测试用的内存数据库
+func setupMigrateTestDB(t *testing.T) DB {
+	db, err := InitDB("sqlite3", ":memory:")
+	require.NoError(t, err, "应该能创建测试数据库")
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testMigrations 测试用迁移文件
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_items.up.sql": {Data: []byte(`
+			-- 测试表
+			CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+			CREATE INDEX idx_items_name ON items(name);
+		`)},
+		"0001_create_items.down.sql":    {Data: []byte("DROP TABLE items;")},
+		"0002_add_items_price.up.sql":   {Data: []byte("ALTER TABLE items ADD COLUMN price INTEGER NOT NULL DEFAULT 0;")},
+		"0002_add_items_price.down.sql": {Data: []byte("ALTER TABLE items DROP COLUMN price;")},
+		"README.md":                     {Data: []byte("非迁移文件会被忽略")},
+	}
+}
+
+// TestMigrator_UpDown 测试执行与回滚迁移
+func TestMigrator_UpDown(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	ctx := context.Background()
+	migrator := NewMigratorWithSource(db, "sqlite3", testMigrations())
+
+	t.Run("执行所有迁移", func(t *testing.T) {
+		executed, err := migrator.Up(ctx)
+		require.NoError(t, err)
+		require.Len(t, executed, 2)
+		assert.Equal(t, int64(1), executed[0].Version)
+		assert.Equal(t, "add_items_price", executed[1].Name)
+
+		_, err = db.Exec("INSERT INTO items (name, price) VALUES (?, ?)", "apple", 3)
+		assert.NoError(t, err)
+	})
+
+	t.Run("重复执行不会再次迁移", func(t *testing.T) {
+		executed, err := migrator.Up(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, executed)
+	})
+
+	t.Run("回滚最近一个迁移", func(t *testing.T) {
+		rolledBack, err := migrator.Down(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, rolledBack, 1)
+		assert.Equal(t, int64(2), rolledBack[0].Version)
+
+		_, err = db.Exec("INSERT INTO items (name, price) VALUES (?, ?)", "pear", 3)
+		assert.Error(t, err, "price 列应该已被删除")
+
+		statuses, err := migrator.Status(ctx)
+		require.NoError(t, err)
+		require.Len(t, statuses, 2)
+		assert.True(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+	})
+}
+
+// TestMigrator_ChecksumMismatch 测试已执行迁移被修改时拒绝执行
+func TestMigrator_ChecksumMismatch(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	ctx := context.Background()
+
+	source := testMigrations()
+	_, err := NewMigratorWithSource(db, "sqlite3", source).Up(ctx)
+	require.NoError(t, err)
+
+	source["0001_create_items.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE items (id INTEGER PRIMARY KEY);")}
+	migrator := NewMigratorWithSource(db, "sqlite3", source)
+
+	_, err = migrator.Up(ctx)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Modified)
+	assert.False(t, statuses[1].Modified)
+}
+
+// TestMigrator_FailedMigrationRollsBack 测试迁移失败时不写入记录
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	ctx := context.Background()
+
+	source := fstest.MapFS{
+		"0001_broken.up.sql": {Data: []byte("CREATE TABLE ok_table (id INTEGER);\nTHIS IS NOT SQL;")},
+	}
+	migrator := NewMigratorWithSource(db, "sqlite3", source)
+
+	_, err := migrator.Up(ctx)
+	require.Error(t, err)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.False(t, statuses[0].Applied)
+}
+
+// TestMigrator_Embedded 测试内置迁移文件在各驱动下都可加载，且版本和名称一致
+func TestMigrator_Embedded(t *testing.T) {
+	var expected []string
+	for _, driver := range SupportedDrivers() {
+		source, err := embeddedMigrations(driver)
+		require.NoError(t, err)
+		migrations, err := LoadMigrations(source)
+		require.NoError(t, err, driver)
+		assert.NotEmpty(t, migrations, driver)
+
+		var names []string
+		for _, migration := range migrations {
+			names = append(names, fmt.Sprintf("%04d_%s", migration.Version, migration.Name))
+		}
+		if expected == nil {
+			expected = names
+		}
+		assert.Equal(t, expected, names, driver)
+	}
+
+	db := setupMigrateTestDB(t)
+	_, err := NewMigrator(db, "sqlite3").Up(context.Background())
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO users (name, email, password) VALUES (?, ?, ?)", "张三", "zhangsan@example.com", "hash")
+	assert.NoError(t, err)
+}
+
+// TestMigrator_LegacyRoles 测试已有用户的 role 0/1 迁移到内置的 user/admin 角色
+func TestMigrator_LegacyRoles(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	ctx := context.Background()
+
+	// 先执行角色迁移之前的版本
+	source, err := embeddedMigrations("sqlite3")
+	require.NoError(t, err)
+	legacy := fstest.MapFS{}
+	entries, err := fs.ReadDir(source, ".")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		if entry.Name() < "0004" {
+			data, err := fs.ReadFile(source, entry.Name())
+			require.NoError(t, err)
+			legacy[entry.Name()] = &fstest.MapFile{Data: data}
+		}
+	}
+	_, err = NewMigratorWithSource(db, "sqlite3", legacy).Up(ctx)
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO users (name, email, password, role) VALUES (?, ?, ?, ?), (?, ?, ?, ?)",
+		"普通用户", "user@example.com", "hash", 0,
+		"管理员", "admin@example.com", "hash", 1,
+	)
+	require.NoError(t, err)
+
+	executed, err := NewMigrator(db, "sqlite3").Up(ctx)
+	require.NoError(t, err)
+
+	var role string
+	require.NoError(t, db.QueryRow("SELECT r.name FROM users u JOIN roles r ON r.id = u.role_id WHERE u.email = ?", "admin@example.com").Scan(&role))
+	assert.Equal(t, "admin", role)
+	require.NoError(t, db.QueryRow("SELECT r.name FROM users u JOIN roles r ON r.id = u.role_id WHERE u.email = ?", "user@example.com").Scan(&role))
+	assert.Equal(t, "user", role)
+
+	// 回滚后恢复旧的 role 列
+	_, err = NewMigrator(db, "sqlite3").Down(ctx, len(executed))
+	require.NoError(t, err)
+	var legacyRole int
+	require.NoError(t, db.QueryRow("SELECT role FROM users WHERE email = ?", "admin@example.com").Scan(&legacyRole))
+	assert.Equal(t, 1, legacyRole)
+}
+
+// TestCreateMigration 测试生成迁移文件
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sqlite3"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sqlite3", "0003_existing.up.sql"), []byte("SELECT 1;"), 0644))
+
+	created, err := CreateMigration(dir, "Add Users-Phone", []string{"mysql", "sqlite3"})
+	require.NoError(t, err)
+	assert.Len(t, created, 4)
+	assert.FileExists(t, filepath.Join(dir, "mysql", "0004_add_users_phone.up.sql"))
+	assert.FileExists(t, filepath.Join(dir, "sqlite3", "0004_add_users_phone.down.sql"))
+
+	_, err = CreateMigration(dir, "bad/name", []string{"sqlite3"})
+	assert.Error(t, err)
+}
+
+// TestSplitStatements 测试迁移脚本拆分
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements(`
+		-- 注释
+		CREATE TABLE a (
+			id INTEGER
+		);
+
+		INSERT INTO a VALUES (1);
+		SELECT 1
+	`)
+	require.Len(t, statements, 3)
+	assert.Contains(t, statements[0], "CREATE TABLE a")
+	assert.Equal(t, "INSERT INTO a VALUES (1)", statements[1])
+	assert.Equal(t, "SELECT 1", statements[2])
+}
+
+// TestMigrator_EmbeddedSchemaConsistent 测试各驱动的内置迁移在每个版本执行 up / down 后得到相同的表、列和权限
+// 测试默认只在 SQLite 上执行迁移，MySQL / PostgreSQL 的迁移文件只在设置 TEST_MYSQL_DSN / TEST_POSTGRES_DSN 时
+// 由仓库测试执行；这里解析迁移语句比较结构，避免某个方言漏改或写错表名、列名
+func TestMigrator_EmbeddedSchemaConsistent(t *testing.T) {
+	migrationsByDriver := make(map[string][]*Migration)
+	for _, driver := range SupportedDrivers() {
+		source, err := embeddedMigrations(driver)
+		require.NoError(t, err)
+		migrations, err := LoadMigrations(source)
+		require.NoError(t, err, driver)
+		migrationsByDriver[driver] = migrations
+	}
+
+	reference := migrationsByDriver["sqlite3"]
+	for i, migration := range reference {
+		version := fmt.Sprintf("%04d_%s", migration.Version, migration.Name)
+		before := make(map[string]schemaSnapshot)
+		after := make(map[string]schemaSnapshot)
+		for driver, migrations := range migrationsByDriver {
+			require.Len(t, migrations, len(reference), driver)
+			require.NotEmpty(t, migrations[i].DownSQL, "%s %s 缺少 down 脚本", driver, version)
+
+			snapshot := newSchemaSnapshot()
+			for _, previous := range migrations[:i] {
+				snapshot.apply(t, driver, previous.UpSQL)
+			}
+			before[driver] = snapshot.clone()
+			snapshot.apply(t, driver, migrations[i].UpSQL)
+			after[driver] = snapshot.clone()
+
+			// down 脚本应恢复到执行 up 之前的结构
+			snapshot.apply(t, driver, migrations[i].DownSQL)
+			assert.Equal(t, before[driver], snapshot, "%s %s 的 down 脚本没有恢复迁移前的结构", driver, version)
+		}
+		for driver := range migrationsByDriver {
+			assert.Equal(t, after["sqlite3"], after[driver], "%s %s 执行后的结构与 sqlite3 不一致", driver, version)
+		}
+	}
+}
+
+// schemaSnapshot 从迁移语句推导出的数据库结构：表及其列、permissions 表中的权限
+type schemaSnapshot struct {
+	Tables      map[string]map[string]bool
+	Permissions map[string]bool
+}
+
+func newSchemaSnapshot() schemaSnapshot {
+	return schemaSnapshot{Tables: map[string]map[string]bool{}, Permissions: map[string]bool{}}
+}
+
+func (s schemaSnapshot) clone() schemaSnapshot {
+	cloned := newSchemaSnapshot()
+	for table, columns := range s.Tables {
+		cloned.Tables[table] = make(map[string]bool, len(columns))
+		for column := range columns {
+			cloned.Tables[table][column] = true
+		}
+	}
+	for permission := range s.Permissions {
+		cloned.Permissions[permission] = true
+	}
+	return cloned
+}
+
+var (
+	createTablePattern  = regexp.MustCompile(`(?is)^CREATE TABLE (?:IF NOT EXISTS )?(\w+)\s*\((.*)\)[^)]*$`)
+	alterTablePattern   = regexp.MustCompile(`(?is)^ALTER TABLE (\w+)\s+(.*)$`)
+	dropTablePattern    = regexp.MustCompile(`(?i)^DROP TABLE (?:IF EXISTS )?(\w+)$`)
+	indexPattern        = regexp.MustCompile(`(?i)^(CREATE (UNIQUE )?INDEX|DROP INDEX) `)
+	permissionPattern   = regexp.MustCompile(`'(\w+:\w+)'`)
+	dataStatementPrefix = regexp.MustCompile(`(?i)^(INSERT|UPDATE|DELETE|SELECT) `)
+)
+
+// apply 按顺序执行迁移脚本中的语句；索引和外键不参与比较（SQLite 使用未命名的内联约束）
+func (s schemaSnapshot) apply(t *testing.T, driver, script string) {
+	for _, statement := range splitStatements(script) {
+		statement = strings.Join(strings.Fields(stripLineComments(statement)), " ")
+
+		switch {
+		case createTablePattern.MatchString(statement):
+			matches := createTablePattern.FindStringSubmatch(statement)
+			columns := make(map[string]bool)
+			for _, definition := range splitTopLevel(matches[2]) {
+				if column, ok := columnName(definition); ok {
+					columns[column] = true
+				}
+			}
+			s.Tables[strings.ToLower(matches[1])] = columns
+
+		case alterTablePattern.MatchString(statement):
+			matches := alterTablePattern.FindStringSubmatch(statement)
+			columns, ok := s.Tables[strings.ToLower(matches[1])]
+			if !assert.True(t, ok, "%s: 修改不存在的表: %s", driver, statement) {
+				continue
+			}
+			for _, action := range splitTopLevel(matches[2]) {
+				words := strings.Fields(strings.ToUpper(action))
+				original := strings.Fields(action)
+				switch {
+				case len(words) >= 3 && words[0] == "ADD" && words[1] == "COLUMN":
+					columns[strings.ToLower(original[2])] = true
+				case len(words) >= 3 && words[0] == "DROP" && words[1] == "COLUMN":
+					assert.True(t, columns[strings.ToLower(original[2])], "%s: 删除不存在的列: %s", driver, statement)
+					delete(columns, strings.ToLower(original[2]))
+				case len(words) >= 2 && (words[0] == "ADD" || words[0] == "DROP") &&
+					(words[1] == "KEY" || words[1] == "INDEX" || words[1] == "UNIQUE" || words[1] == "CONSTRAINT" || words[1] == "FOREIGN"):
+				default:
+					t.Errorf("%s: 无法解析的 ALTER TABLE 操作: %s", driver, action)
+				}
+			}
+
+		case dropTablePattern.MatchString(statement):
+			table := strings.ToLower(dropTablePattern.FindStringSubmatch(statement)[1])
+			delete(s.Tables, table)
+			if table == "permissions" {
+				clear(s.Permissions)
+			}
+
+		case indexPattern.MatchString(statement):
+
+		case dataStatementPrefix.MatchString(statement):
+			upper := strings.ToUpper(statement)
+			for _, matches := range permissionPattern.FindAllStringSubmatch(statement, -1) {
+				switch {
+				case strings.HasPrefix(upper, "INSERT INTO PERMISSIONS "):
+					s.Permissions[matches[1]] = true
+				case strings.HasPrefix(upper, "DELETE FROM PERMISSIONS "):
+					delete(s.Permissions, matches[1])
+				}
+			}
+
+		default:
+			t.Errorf("%s: 无法解析的迁移语句: %s", driver, statement)
+		}
+	}
+}
+
+// stripLineComments 去掉语句中每行 "--" 之后的行尾注释
+func stripLineComments(statement string) string {
+	lines := strings.Split(statement, "\n")
+	for i, line := range lines {
+		if idx := strings.Index(line, "--"); idx >= 0 {
+			lines[i] = line[:idx]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitTopLevel 按不在括号内的逗号拆分
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// columnName 返回建表语句中列定义的列名，表级约束和索引返回 false
+func columnName(definition string) (string, bool) {
+	fields := strings.Fields(definition)
+	if len(fields) == 0 {
+		return "", false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "PRIMARY", "FOREIGN", "CONSTRAINT", "UNIQUE", "KEY", "INDEX", "CHECK":
+		return "", false
+	}
+	return strings.ToLower(strings.Trim(fields[0], "`\"")), true
+}
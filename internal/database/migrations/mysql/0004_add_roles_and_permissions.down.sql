@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN role INT NOT NULL DEFAULT 0;
+UPDATE users SET role = CASE WHEN role_id = 2 THEN 1 ELSE 0 END;
+ALTER TABLE users
+    DROP FOREIGN KEY fk_users_role,
+    DROP KEY idx_users_role_id,
+    DROP COLUMN role_id;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,53 @@
+-- 角色、权限及授权关系，built_in 为内置角色（不可删除、不可改名）
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    built_in TINYINT(1) NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_roles_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    UNIQUE KEY idx_permissions_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL,
+    permission_id BIGINT NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 内置角色，ID 固定以便迁移已有用户
+INSERT INTO roles (id, name, description, built_in) VALUES
+    (1, 'user', '普通用户', 1),
+    (2, 'admin', '超级管理员', 1);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', '查看用户'),
+    ('users:create', '创建用户'),
+    ('users:update', '更新用户'),
+    ('users:delete', '删除用户'),
+    ('users:logout', '强制用户下线'),
+    ('roles:read', '查看角色与权限'),
+    ('roles:manage', '管理角色'),
+    ('roles:assign', '为用户分配角色');
+
+-- 管理员拥有全部权限，普通用户保留原有的查看与更新权限
+INSERT INTO role_permissions (role_id, permission_id) SELECT 2, id FROM permissions;
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT 1, id FROM permissions WHERE name IN ('users:read', 'users:update');
+
+-- 已有用户按旧的 role 值迁移：0 → user，1 → admin
+ALTER TABLE users ADD COLUMN role_id BIGINT NOT NULL DEFAULT 1;
+UPDATE users SET role_id = CASE role WHEN 1 THEN 2 ELSE 1 END;
+ALTER TABLE users
+    DROP COLUMN role,
+    ADD KEY idx_users_role_id (role_id),
+    ADD CONSTRAINT fk_users_role FOREIGN KEY (role_id) REFERENCES roles(id);
@@ -0,0 +1,4 @@
+DELETE rp FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id WHERE p.name = 'users:unlock';
+DELETE FROM permissions WHERE name = 'users:unlock';
+
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,4 @@
+DELETE rp FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id WHERE p.name = 'apikeys:manage';
+DELETE FROM permissions WHERE name = 'apikeys:manage';
+
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,21 @@
+-- 机器客户端使用的API密钥（只保存哈希，scopes 为空格分隔的权限名）
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(32) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    last_used_at DATETIME NULL,
+    revoked_at DATETIME NULL,
+    created_by BIGINT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_api_keys_key_hash (key_hash),
+    KEY idx_api_keys_user_id (user_id),
+    CONSTRAINT fk_api_keys_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 管理API密钥权限（授予管理员）
+INSERT INTO permissions (name, description) VALUES ('apikeys:manage', '创建、查看和吊销API密钥');
+INSERT INTO role_permissions (role_id, permission_id) SELECT 2, id FROM permissions WHERE name = 'apikeys:manage';
@@ -0,0 +1,6 @@
+DELETE rp FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id WHERE p.name IN ('users:restore', 'users:purge');
+DELETE FROM permissions WHERE name IN ('users:restore', 'users:purge');
+
+ALTER TABLE users
+    DROP KEY idx_users_deleted_at,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,2 @@
+DELETE rp FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id WHERE p.name IN ('users:import', 'users:export');
+DELETE FROM permissions WHERE name IN ('users:import', 'users:export');
@@ -0,0 +1,4 @@
+-- 批量导入、导出用户的权限（授予管理员）
+INSERT INTO permissions (name, description) VALUES ('users:import', '从 CSV / NDJSON 批量导入用户');
+INSERT INTO permissions (name, description) VALUES ('users:export', '以 CSV / NDJSON 批量导出用户');
+INSERT INTO role_permissions (role_id, permission_id) SELECT 2, id FROM permissions WHERE name IN ('users:import', 'users:export');
@@ -0,0 +1,26 @@
+-- 审计日志（只追加；hash = SHA-256(prev_hash + 本条内容)，prev_hash 唯一保证哈希链不分叉）
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    actor_id BIGINT NOT NULL DEFAULT 0,
+    actor_role VARCHAR(50) NOT NULL DEFAULT '',
+    action VARCHAR(50) NOT NULL,
+    outcome VARCHAR(20) NOT NULL,
+    target_type VARCHAR(50) NOT NULL DEFAULT '',
+    target_id VARCHAR(100) NOT NULL DEFAULT '',
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(500) NOT NULL DEFAULT '',
+    changes TEXT NOT NULL,
+    detail VARCHAR(500) NOT NULL DEFAULT '',
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY idx_audit_logs_prev_hash (prev_hash),
+    KEY idx_audit_logs_created_at (created_at),
+    KEY idx_audit_logs_actor_id (actor_id),
+    KEY idx_audit_logs_target (target_type, target_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 查看审计日志权限（授予管理员）
+INSERT INTO permissions (name, description) VALUES ('audit:read', '查看和校验审计日志');
+INSERT INTO role_permissions (role_id, permission_id) SELECT 2, id FROM permissions WHERE name = 'audit:read';
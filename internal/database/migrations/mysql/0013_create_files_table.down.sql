@@ -0,0 +1,4 @@
+DELETE rp FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id WHERE p.name = 'files:upload';
+DELETE FROM permissions WHERE name = 'files:upload';
+
+DROP TABLE IF EXISTS files;
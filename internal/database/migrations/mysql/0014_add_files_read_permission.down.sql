@@ -0,0 +1,2 @@
+DELETE rp FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id WHERE p.name = 'files:read';
+DELETE FROM permissions WHERE name = 'files:read';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS token_revocations;
+
+ALTER TABLE refresh_tokens
+    DROP COLUMN ip,
+    DROP COLUMN user_agent;
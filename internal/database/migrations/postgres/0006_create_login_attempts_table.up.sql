@@ -0,0 +1,13 @@
+-- 登录失败计数：attempt_key 为 account:<邮箱> 或 ip:<客户端IP>
+CREATE TABLE IF NOT EXISTS login_attempts (
+    attempt_key VARCHAR(320) NOT NULL PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failed_at ON login_attempts(last_failed_at);
+
+-- 解锁账户权限（授予管理员）
+INSERT INTO permissions (name, description) VALUES ('users:unlock', '解锁被锁定的账户');
+INSERT INTO role_permissions (role_id, permission_id) SELECT 2, id FROM permissions WHERE name = 'users:unlock';
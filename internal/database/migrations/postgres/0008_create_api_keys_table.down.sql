@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'apikeys:manage');
+DELETE FROM permissions WHERE name = 'apikeys:manage';
+
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,9 @@
+-- 用户软删除：deleted_at 不为空表示已删除，保留期过后由定时任务彻底清除
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at);
+
+-- 恢复与彻底删除用户的权限（授予管理员）
+INSERT INTO permissions (name, description) VALUES ('users:restore', '查看和恢复已删除的用户');
+INSERT INTO permissions (name, description) VALUES ('users:purge', '彻底删除已删除的用户');
+INSERT INTO role_permissions (role_id, permission_id) SELECT 2, id FROM permissions WHERE name IN ('users:restore', 'users:purge');
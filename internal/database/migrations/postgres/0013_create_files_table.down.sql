@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'files:upload');
+DELETE FROM permissions WHERE name = 'files:upload';
+
+DROP TABLE IF EXISTS files;
@@ -0,0 +1,3 @@
+-- 下载任意用户文件的权限（授予管理员，文件所有者始终可以下载自己的文件）
+INSERT INTO permissions (name, description) VALUES ('files:read', '下载任意用户上传的文件');
+INSERT INTO role_permissions (role_id, permission_id) SELECT 2, id FROM permissions WHERE name = 'files:read';
@@ -0,0 +1,12 @@
+-- 用户扩展资料（头像、简介、语言、时区和偏好设置），没有记录时表示使用默认值
+-- avatar_file_id 指向已上传的图片文件；preferences 为 JSON 对象
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id BIGINT NOT NULL PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    avatar_file_id BIGINT NULL REFERENCES files(id) ON DELETE SET NULL,
+    bio VARCHAR(500) NOT NULL DEFAULT '',
+    locale VARCHAR(35) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    preferences TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_token_revocations_expires_at;
+DROP INDEX IF EXISTS idx_token_revocations_kind_value;
+DROP TABLE IF EXISTS token_revocations;
+
+ALTER TABLE refresh_tokens DROP COLUMN ip;
+ALTER TABLE refresh_tokens DROP COLUMN user_agent;
@@ -0,0 +1,16 @@
+-- 刷新令牌记录客户端信息，用于会话列表展示
+ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN ip TEXT NOT NULL DEFAULT '';
+
+-- 访问令牌撤销记录：kind 为 token(jti) / session(sid) / user(user_id)
+-- expires_at 之后该记录影响的令牌都已自然过期，可以清理
+CREATE TABLE IF NOT EXISTS token_revocations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    value TEXT NOT NULL,
+    revoked_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_revocations_kind_value ON token_revocations(kind, value);
+CREATE INDEX IF NOT EXISTS idx_token_revocations_expires_at ON token_revocations(expires_at);
@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'users:unlock');
+DELETE FROM permissions WHERE name = 'users:unlock';
+
+DROP INDEX IF EXISTS idx_login_attempts_last_failed_at;
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,2 @@
+-- 用户乐观锁版本号：每次更新加一，通过 ETag / If-Match 防止并发修改互相覆盖
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'files:upload');
+DELETE FROM permissions WHERE name = 'files:upload';
+
+DROP INDEX IF EXISTS idx_files_owner_id;
+DROP TABLE IF EXISTS files;
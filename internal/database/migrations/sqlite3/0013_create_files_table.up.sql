@@ -0,0 +1,18 @@
+-- 上传文件的元数据（内容保存在配置的文件存储中，storage_key 由服务端生成）
+CREATE TABLE IF NOT EXISTS files (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    storage_key TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    checksum TEXT NOT NULL,
+    owner_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_files_owner_id ON files(owner_id);
+
+-- 上传文件权限（授予普通用户和管理员）
+INSERT INTO permissions (name, description) VALUES ('files:upload', '上传文件');
+INSERT INTO role_permissions (role_id, permission_id) SELECT 1, id FROM permissions WHERE name = 'files:upload';
+INSERT INTO role_permissions (role_id, permission_id) SELECT 2, id FROM permissions WHERE name = 'files:upload';
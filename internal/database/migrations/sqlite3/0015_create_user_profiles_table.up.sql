@@ -0,0 +1,14 @@
+-- 用户扩展资料（头像、简介、语言、时区和偏好设置），没有记录时表示使用默认值
+-- avatar_file_id 指向已上传的图片文件；preferences 为 JSON 对象
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id INTEGER PRIMARY KEY,
+    avatar_file_id INTEGER NULL,
+    bio TEXT NOT NULL DEFAULT '',
+    locale TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT '',
+    preferences TEXT NOT NULL DEFAULT '{}',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (avatar_file_id) REFERENCES files(id) ON DELETE SET NULL
+);
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"gin/internal/i18n"
+	"gin/internal/logger"
+	"gin/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// queryOptions 查询超时、慢查询配置与数据库方言
+type queryOptions struct {
+	timeout       time.Duration
+	slowThreshold time.Duration
+	dialect       Dialect
+}
+
+// sqlExecutor *sql.DB 与 *sql.Tx 共同的执行SQL方法
+type sqlExecutor interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Rows 查询结果集
+// 查询在 Close 时才算结束：默认超时覆盖整个读取过程，慢查询按读取完成的时间计算
+type Rows struct {
+	*sql.Rows
+	finish func(err error)
+}
+
+// Close 关闭结果集并结束查询
+func (r *Rows) Close() error {
+	rowsErr := r.Rows.Err()
+	err := r.Rows.Close()
+	r.finish(rowsErr)
+	return err
+}
+
+// Row 单行查询结果，查询在 Scan 时才算结束（必须调用 Scan）
+type Row struct {
+	row    *sql.Row
+	finish func(err error)
+}
+
+// Scan 读取结果并结束查询，没有结果时返回 sql.ErrNoRows
+func (r *Row) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	r.finish(err)
+	return err
+}
+
+// Err 返回查询错误
+func (r *Row) Err() error {
+	return r.row.Err()
+}
+
+// queryExecutor 按方言转换占位符，为每条SQL添加默认超时，并记录慢查询和超时日志
+type queryExecutor struct {
+	exec    sqlExecutor
+	options queryOptions
+}
+
+// QueryContext 执行查询，调用方必须关闭返回的结果集
+func (e *queryExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	query = e.rebind(query)
+	queryCtx, finish := e.begin(ctx, query)
+	rows, err := e.exec.QueryContext(queryCtx, query, args...)
+	if err != nil {
+		finish(err)
+		return nil, err
+	}
+	return &Rows{Rows: rows, finish: finish}, nil
+}
+
+// QueryRowContext 执行单行查询
+func (e *queryExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	query = e.rebind(query)
+	queryCtx, finish := e.begin(ctx, query)
+	return &Row{row: e.exec.QueryRowContext(queryCtx, query, args...), finish: finish}
+}
+
+// ExecContext 执行写操作
+func (e *queryExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query = e.rebind(query)
+	queryCtx, finish := e.begin(ctx, query)
+	result, err := e.exec.ExecContext(queryCtx, query, args...)
+	finish(err)
+	return result, err
+}
+
+// InsertContext 执行 INSERT 并返回新行的自增ID（id 列）
+// 支持 RETURNING 的数据库（PostgreSQL）追加 RETURNING id，其他数据库使用 LastInsertId
+func (e *queryExecutor) InsertContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	if e.options.dialect != nil && e.options.dialect.SupportsReturning() {
+		var id int64
+		err := e.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := e.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// rebind 按方言转换占位符
+func (e *queryExecutor) rebind(query string) string {
+	if e.options.dialect == nil {
+		return query
+	}
+	return e.options.dialect.Rebind(query)
+}
+
+// begin 开始一次查询：设置默认超时（ctx 已有更早的截止时间时直接使用 ctx），
+// 返回的 finish 在查询结束时记录慢查询或超时日志并释放 context，多次调用只生效一次
+func (e *queryExecutor) begin(ctx context.Context, query string) (context.Context, func(err error)) {
+	queryCtx, cancel := ctx, context.CancelFunc(func() {})
+	if e.options.timeout > 0 {
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > e.options.timeout {
+			queryCtx, cancel = context.WithTimeout(ctx, e.options.timeout)
+		}
+	}
+
+	start := time.Now()
+	var once sync.Once
+	return queryCtx, func(err error) {
+		once.Do(func() {
+			timedOut := errors.Is(err, context.DeadlineExceeded) ||
+				(err != nil && errors.Is(queryCtx.Err(), context.DeadlineExceeded))
+			cancel()
+			e.observe(ctx, query, time.Since(start), timedOut)
+		})
+	}
+}
+
+// observe 记录慢查询和超时的SQL（不记录参数，避免泄露密码哈希、令牌等敏感数据）
+func (e *queryExecutor) observe(ctx context.Context, query string, elapsed time.Duration, timedOut bool) {
+	fields := []zap.Field{
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.String("query", compactQuery(query)),
+		zap.Duration("elapsed", elapsed),
+	}
+
+	switch {
+	case timedOut:
+		logger.Log.Warn(i18n.LogMessage(i18n.LogQueryTimeout), append(fields, zap.Duration("timeout", e.options.timeout))...)
+	case e.options.slowThreshold > 0 && elapsed >= e.options.slowThreshold:
+		logger.Log.Warn(i18n.LogMessage(i18n.LogSlowQuery), append(fields, zap.Duration("threshold", e.options.slowThreshold))...)
+	}
+}
+
+// compactQuery 将多行SQL压缩为单行，便于在日志中查看
+func compactQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
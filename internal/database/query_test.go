@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gin/internal/logger"
+	"gin/internal/requestctx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeLogs 将全局日志替换为可断言的日志
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.WarnLevel)
+	original := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = original })
+	return logs
+}
+
+// TestQueryExecutor 测试查询超时与慢查询日志
+func TestQueryExecutor(t *testing.T) {
+	ctx := requestctx.WithMetadata(context.Background(), requestctx.Metadata{RequestID: "req-123"})
+
+	t.Run("慢查询记录SQL与request_id", func(t *testing.T) {
+		logs := observeLogs(t)
+		db, err := InitDB("sqlite3", ":memory:", WithSlowQueryThreshold(time.Nanosecond))
+		require.NoError(t, err)
+		defer db.Close()
+
+		_, err = Conn(ctx, db).ExecContext(ctx, `
+			CREATE TABLE items (
+				id INTEGER PRIMARY KEY
+			)`)
+		require.NoError(t, err)
+
+		entries := logs.FilterMessage("Slow SQL query").All()
+		require.Len(t, entries, 1)
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "req-123", fields["request_id"])
+		assert.Equal(t, "CREATE TABLE items ( id INTEGER PRIMARY KEY )", fields["query"])
+	})
+
+	t.Run("未达到阈值不记录", func(t *testing.T) {
+		logs := observeLogs(t)
+		db, err := InitDB("sqlite3", ":memory:", WithSlowQueryThreshold(time.Hour))
+		require.NoError(t, err)
+		defer db.Close()
+
+		var n int
+		require.NoError(t, Conn(ctx, db).QueryRowContext(ctx, "SELECT 1").Scan(&n))
+		assert.Zero(t, logs.Len())
+	})
+
+	t.Run("超过默认超时时间的查询被取消", func(t *testing.T) {
+		logs := observeLogs(t)
+		db, err := InitDB("sqlite3", ":memory:", WithQueryTimeout(50*time.Millisecond))
+		require.NoError(t, err)
+		defer db.Close()
+
+		start := time.Now()
+		rows, err := Conn(ctx, db).QueryContext(ctx, `
+			WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000)
+			SELECT COUNT(*) FROM c`)
+		if err == nil {
+			for rows.Next() {
+			}
+			err = rows.Err()
+			rows.Close()
+		}
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.Equal(t, 1, logs.FilterMessage("SQL query timed out").Len())
+	})
+
+	t.Run("事务中的查询同样生效", func(t *testing.T) {
+		logs := observeLogs(t)
+		db, err := InitDB("sqlite3", ":memory:", WithSlowQueryThreshold(time.Nanosecond))
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = NewTxManager(db).WithinTx(ctx, func(ctx context.Context) error {
+			var n int
+			return Conn(ctx, db).QueryRowContext(ctx, "SELECT 1").Scan(&n)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, logs.FilterMessage("Slow SQL query").Len())
+	})
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"gin/internal/i18n"
+	"gin/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// replicaPingTimeout 只读副本健康检查的超时时间
+const replicaPingTimeout = 2 * time.Second
+
+// replica 只读副本
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// check 检查只读副本是否可用，状态变化时记录日志
+func (r *replica) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+	defer cancel()
+
+	err := r.db.PingContext(ctx)
+	healthy := err == nil
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		logger.Log.Info(i18n.LogMessage(i18n.LogReplicaRecovered), zap.String("replica", r.name))
+	} else {
+		logger.Log.Warn(i18n.LogMessage(i18n.LogReplicaUnhealthy), zap.String("replica", r.name), zap.Error(err))
+	}
+}
+
+// startHealthCheck 定期检查只读副本，Close 时停止
+func (db *sqlDB) startHealthCheck() {
+	interval := db.replicaCheckInterval
+	if interval <= 0 {
+		interval = 10 * time.Second // 默认10秒
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	db.stopHealthCheck = cancel
+	db.healthCheckDone = make(chan struct{})
+
+	go func() {
+		defer close(db.healthCheckDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for _, r := range db.replicas {
+					r.check(ctx)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// replica 轮询选择一个健康的只读副本，没有可用副本时返回 nil
+func (db *sqlDB) replica() *sql.DB {
+	n := len(db.replicas)
+	if n == 0 {
+		return nil
+	}
+
+	start := db.next.Add(1)
+	for i := 0; i < n; i++ {
+		r := db.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return nil
+}
+
+// primaryKey context 中要求读主库的键
+type primaryKey struct{}
+
+// WithPrimary 要求 ctx 中的只读查询读主库
+// 用于写入后立即读取等不能容忍只读副本复制延迟的场景
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadConn 返回只读查询使用的连接
+// ctx 中没有事务、也没有通过 WithPrimary 要求读主库时，使用健康的只读副本；否则与 Conn 相同
+// 只读副本存在复制延迟，只应用于可以接受短暂读到旧数据的查询（例如按ID查询、列表查询）
+func ReadConn(ctx context.Context, db DB) Executor {
+	s, ok := db.(*sqlDB)
+	if !ok {
+		return Conn(ctx, db)
+	}
+	if _, inTx := TxFromContext(ctx); inTx {
+		return Conn(ctx, db)
+	}
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return Conn(ctx, db)
+	}
+	if r := s.replica(); r != nil {
+		return &queryExecutor{exec: r, options: s.queryOptions}
+	}
+	return Conn(ctx, db)
+}
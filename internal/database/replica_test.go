@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSourceDB 创建一个只有一行数据的 SQLite 文件数据库，用 source 区分主库和只读副本
+func newSourceDB(t *testing.T, path, source string) {
+	db, err := InitDB("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, err = Conn(ctx, db).ExecContext(ctx, "CREATE TABLE source (name TEXT)")
+	require.NoError(t, err)
+	_, err = Conn(ctx, db).ExecContext(ctx, "INSERT INTO source (name) VALUES (?)", source)
+	require.NoError(t, err)
+}
+
+// readSource 读取查询实际使用的数据库
+func readSource(t *testing.T, ctx context.Context, conn Executor) string {
+	var name string
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT name FROM source").Scan(&name))
+	return name
+}
+
+// TestReadConn 测试只读查询路由到只读副本
+func TestReadConn(t *testing.T) {
+	dir := t.TempDir()
+	primaryDSN := filepath.Join(dir, "primary.db")
+	replicaDSN := filepath.Join(dir, "replica.db")
+	newSourceDB(t, primaryDSN, "primary")
+	newSourceDB(t, replicaDSN, "replica")
+
+	ctx := context.Background()
+
+	t.Run("只读查询使用只读副本", func(t *testing.T) {
+		db, err := InitDB("sqlite3", primaryDSN, WithReplicas([]string{replicaDSN}, 0))
+		require.NoError(t, err)
+		defer db.Close()
+
+		assert.Equal(t, "replica", readSource(t, ctx, ReadConn(ctx, db)))
+		assert.Equal(t, "primary", readSource(t, ctx, Conn(ctx, db)))
+		assert.Equal(t, "primary", readSource(t, WithPrimary(ctx), ReadConn(WithPrimary(ctx), db)))
+
+		pools := Pools(db)
+		assert.Len(t, pools, 2)
+		assert.Contains(t, pools, "primary")
+		assert.Contains(t, pools, "replica-0")
+	})
+
+	t.Run("事务中的查询使用主库", func(t *testing.T) {
+		db, err := InitDB("sqlite3", primaryDSN, WithReplicas([]string{replicaDSN}, 0))
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = NewTxManager(db).WithinTx(ctx, func(ctx context.Context) error {
+			assert.Equal(t, "primary", readSource(t, ctx, ReadConn(ctx, db)))
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("只读副本不可用时回退到主库", func(t *testing.T) {
+		logs := observeLogs(t)
+		missing := filepath.Join(dir, "missing", "replica.db")
+		db, err := InitDB("sqlite3", primaryDSN, WithReplicas([]string{missing}, 0))
+		require.NoError(t, err)
+		defer db.Close()
+
+		assert.Equal(t, "primary", readSource(t, ctx, ReadConn(ctx, db)))
+		assert.Equal(t, 1, logs.FilterMessage("Read replica unavailable, reads fall back to primary").Len())
+	})
+
+	t.Run("只读副本恢复后重新启用", func(t *testing.T) {
+		db, err := InitDB("sqlite3", primaryDSN, WithReplicas([]string{replicaDSN}, 0))
+		require.NoError(t, err)
+		defer db.Close()
+
+		r := db.(*sqlDB).replicas[0]
+		r.healthy.Store(false)
+		assert.Equal(t, "primary", readSource(t, ctx, ReadConn(ctx, db)))
+
+		r.check(ctx)
+		assert.True(t, r.healthy.Load())
+		assert.Equal(t, "replica", readSource(t, ctx, ReadConn(ctx, db)))
+	})
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Executor 执行SQL的接口，由 Conn 返回
+// SQL 统一使用 ? 占位符，执行时按数据库方言转换；每条SQL都会应用默认超时，执行时间超过阈值时记录慢查询日志
+type Executor interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	// InsertContext 执行 INSERT 并返回新行的自增ID，屏蔽 RETURNING 与 LastInsertId 的差异
+	InsertContext(ctx context.Context, query string, args ...interface{}) (int64, error)
+}
+
+// txKey context 中保存事务的键
+type txKey struct{}
+
+// ContextWithTx 将事务保存到 context 中
+func ContextWithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext 从 context 中获取事务
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok && tx != nil
+}
+
+// Conn 返回执行SQL使用的连接：context 中有事务时使用该事务，否则使用 db
+// 仓库的每个查询都应该通过 Conn 执行，才能加入调用方开启的事务，并应用 InitDB 配置的查询超时和慢查询日志
+func Conn(ctx context.Context, db DB) Executor {
+	var options queryOptions
+	if s, ok := db.(*sqlDB); ok {
+		options = s.queryOptions
+	}
+	if tx, ok := TxFromContext(ctx); ok {
+		return &queryExecutor{exec: tx, options: options}
+	}
+	return &queryExecutor{exec: db, options: options}
+}
+
+// TxManager 事务管理器（Unit of Work）
+type TxManager interface {
+	// WithinTx 在事务中执行 fn，fn 中的仓库调用需要使用传入的 ctx
+	// fn 返回错误或 panic 时回滚，否则提交；ctx 中已有事务时直接加入该事务，由最外层负责提交或回滚
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// sqlTxManager 基于数据库事务的事务管理器
+type sqlTxManager struct {
+	db DB
+}
+
+// NewTxManager 创建事务管理器
+func NewTxManager(db DB) TxManager {
+	return &sqlTxManager{db: db}
+}
+
+// WithinTx 在事务中执行 fn
+func (m *sqlTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = fn(ContextWithTx(ctx, tx)); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// noopTxManager 不开启事务的事务管理器
+type noopTxManager struct{}
+
+// NewNoopTxManager 创建不开启事务的事务管理器（用于内存仓库和测试，fn 直接执行）
+func NewNoopTxManager() TxManager {
+	return noopTxManager{}
+}
+
+// WithinTx 直接执行 fn
+func (noopTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTxManager_WithinTx 测试事务的提交、回滚与嵌套
+func TestTxManager_WithinTx(t *testing.T) {
+	db := setupMigrateTestDB(t)
+	ctx := context.Background()
+	_, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE)")
+	require.NoError(t, err)
+
+	manager := NewTxManager(db)
+	insert := func(ctx context.Context, name string) error {
+		_, err := Conn(ctx, db).ExecContext(ctx, "INSERT INTO items (name) VALUES (?)", name)
+		return err
+	}
+	count := func(t *testing.T) int {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM items").Scan(&n))
+		return n
+	}
+
+	t.Run("成功时提交", func(t *testing.T) {
+		err := manager.WithinTx(ctx, func(ctx context.Context) error {
+			_, ok := TxFromContext(ctx)
+			assert.True(t, ok, "fn 的 ctx 中应该有事务")
+			return insert(ctx, "a")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count(t))
+	})
+
+	t.Run("返回错误时回滚", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		err := manager.WithinTx(ctx, func(ctx context.Context) error {
+			require.NoError(t, insert(ctx, "b"))
+			return errBoom
+		})
+		assert.ErrorIs(t, err, errBoom)
+		assert.Equal(t, 1, count(t))
+	})
+
+	t.Run("panic 时回滚并继续 panic", func(t *testing.T) {
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = manager.WithinTx(ctx, func(ctx context.Context) error {
+				require.NoError(t, insert(ctx, "c"))
+				panic("boom")
+			})
+		})
+		assert.Equal(t, 1, count(t))
+	})
+
+	t.Run("嵌套调用加入外层事务", func(t *testing.T) {
+		err := manager.WithinTx(ctx, func(ctx context.Context) error {
+			outer, _ := TxFromContext(ctx)
+			require.NoError(t, manager.WithinTx(ctx, func(ctx context.Context) error {
+				inner, _ := TxFromContext(ctx)
+				assert.Same(t, outer, inner)
+				return insert(ctx, "d")
+			}))
+			// 外层失败时内层的写入一并回滚
+			return insert(ctx, "a")
+		})
+		assert.True(t, IsUniqueViolation(err), "重复插入应该违反唯一约束")
+		assert.Equal(t, 1, count(t))
+	})
+
+	t.Run("不开启事务的事务管理器直接执行", func(t *testing.T) {
+		err := NewNoopTxManager().WithinTx(ctx, func(ctx context.Context) error {
+			_, ok := TxFromContext(ctx)
+			assert.False(t, ok)
+			return insert(ctx, "e")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, count(t))
+	})
+}
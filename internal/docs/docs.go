@@ -583,10 +583,10 @@ const docTemplate = `{
     },
     "definitions": {
         "auth.Role": {
-            "type": "integer",
+            "type": "string",
             "enum": [
-                0,
-                1
+                "user",
+                "admin"
             ],
             "x-enum-varnames": [
                 "RoleUser",
@@ -66,6 +66,69 @@ func NewUnauthorizedError(msg string, err error) *AppError {
 	}
 }
 
+// NewForbiddenError 创建403错误
+func NewForbiddenError(msg string, err error) *AppError {
+	return &AppError{
+		Code:    http.StatusForbidden,
+		Message: msg,
+		Err:     err,
+	}
+}
+
+// NewConflictError 创建409错误（例如更新时资源已被其他请求修改）
+func NewConflictError(msg string, err error) *AppError {
+	return &AppError{
+		Code:    http.StatusConflict,
+		Message: msg,
+		Err:     err,
+	}
+}
+
+// NewGoneError 创建410错误（例如资源已过期，不会再恢复）
+func NewGoneError(msg string, err error) *AppError {
+	return &AppError{
+		Code:    http.StatusGone,
+		Message: msg,
+		Err:     err,
+	}
+}
+
+// NewPreconditionFailedError 创建412错误（例如 If-Match 与资源当前的 ETag 不一致）
+func NewPreconditionFailedError(msg string, err error) *AppError {
+	return &AppError{
+		Code:    http.StatusPreconditionFailed,
+		Message: msg,
+		Err:     err,
+	}
+}
+
+// NewRequestEntityTooLargeError 创建413错误（例如上传的文件超过大小限制）
+func NewRequestEntityTooLargeError(msg string, err error) *AppError {
+	return &AppError{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: msg,
+		Err:     err,
+	}
+}
+
+// NewUnsupportedMediaTypeError 创建415错误（例如 PATCH 请求使用了不支持的补丁格式）
+func NewUnsupportedMediaTypeError(msg string, err error) *AppError {
+	return &AppError{
+		Code:    http.StatusUnsupportedMediaType,
+		Message: msg,
+		Err:     err,
+	}
+}
+
+// NewTooManyRequestsError 创建429错误
+func NewTooManyRequestsError(msg string, err error) *AppError {
+	return &AppError{
+		Code:    http.StatusTooManyRequests,
+		Message: msg,
+		Err:     err,
+	}
+}
+
 // ErrorHandler 统一错误处理中间件
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
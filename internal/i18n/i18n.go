@@ -19,7 +19,9 @@ const (
 	LogAuthFailedNoToken    MessageKey = "log.auth.failed.no_token"
 	LogAuthFailedInvalidFmt MessageKey = "log.auth.failed.invalid_format"
 	LogAuthFailedInvalid    MessageKey = "log.auth.failed.invalid"
+	LogAuthFailedRevoked    MessageKey = "log.auth.failed.revoked"
 	LogAuthSuccess          MessageKey = "log.auth.success"
+	LogRefreshTokenReused   MessageKey = "log.auth.refresh_token.reused"
 
 	// 请求相关
 	LogRequestCost    MessageKey = "log.request.cost"
@@ -31,6 +33,59 @@ const (
 	LogPermissionDenied            MessageKey = "log.permission.denied"
 	LogPermissionDeniedNoRole      MessageKey = "log.permission.denied.no_role"
 	LogPermissionDeniedInvalidRole MessageKey = "log.permission.denied.invalid_role"
+	LogPermissionDeniedNoGrant     MessageKey = "log.permission.denied.no_grant"
+
+	// 通知相关
+	LogNotificationSent MessageKey = "log.notification.sent"
+
+	// 登录保护相关
+	LogLoginFailed     MessageKey = "log.login.failed"
+	LogLoginThrottled  MessageKey = "log.login.throttled"
+	LogAccountLocked   MessageKey = "log.login.account_locked"
+	LogIPLocked        MessageKey = "log.login.ip_locked"
+	LogAccountUnlocked MessageKey = "log.login.account_unlocked"
+
+	// 两步验证相关
+	LogMFAEnabled                  MessageKey = "log.mfa.enabled"
+	LogMFADisabled                 MessageKey = "log.mfa.disabled"
+	LogMFAVerifyFailed             MessageKey = "log.mfa.verify_failed"
+	LogMFARecoveryCodeUsed         MessageKey = "log.mfa.recovery_code_used"
+	LogMFARecoveryCodesRegenerated MessageKey = "log.mfa.recovery_codes_regenerated"
+
+	// API密钥相关
+	LogAuthFailedAPIKey       MessageKey = "log.auth.failed.api_key"
+	LogPermissionDeniedScope  MessageKey = "log.permission.denied.scope"
+	LogPermissionDeniedAPIKey MessageKey = "log.permission.denied.api_key"
+	LogAPIKeyCreated          MessageKey = "log.api_key.created"
+	LogAPIKeyRevoked          MessageKey = "log.api_key.revoked"
+
+	// 用户删除相关
+	LogUserDeleted           MessageKey = "log.user.deleted"
+	LogUserRestored          MessageKey = "log.user.restored"
+	LogUserPurged            MessageKey = "log.user.purged"
+	LogUserFileCleanupFailed MessageKey = "log.user.file_cleanup_failed"
+
+	// 批量导入导出相关
+	LogUsersImported MessageKey = "log.users.imported"
+	LogUsersExported MessageKey = "log.users.exported"
+
+	// 审计日志相关
+	LogAuditWriteFailed MessageKey = "log.audit.write_failed"
+	LogAuditChainBroken MessageKey = "log.audit.chain_broken"
+
+	// 文件上传相关
+	LogFileUploaded         MessageKey = "log.file.uploaded"
+	LogFileCleanupFailed    MessageKey = "log.file.cleanup_failed"
+	LogUploadCreated        MessageKey = "log.upload.created"
+	LogUploadTerminated     MessageKey = "log.upload.terminated"
+	LogImageThumbnailFailed MessageKey = "log.image.thumbnail_failed"
+
+	// 数据库相关
+	LogSlowQuery    MessageKey = "log.database.slow_query"
+	LogQueryTimeout MessageKey = "log.database.query_timeout"
+
+	LogReplicaUnhealthy MessageKey = "log.database.replica_unhealthy"
+	LogReplicaRecovered MessageKey = "log.database.replica_recovered"
 )
 
 // 用户消息键（中文，用于API响应）
@@ -39,6 +94,7 @@ const (
 	UserAuthNoToken    MessageKey = "user.auth.no_token"
 	UserAuthInvalidFmt MessageKey = "user.auth.invalid_format"
 	UserAuthInvalid    MessageKey = "user.auth.invalid"
+	UserAuthRevoked    MessageKey = "user.auth.revoked"
 
 	// 用户操作相关
 	UserCreateSuccess       MessageKey = "user.create.success"
@@ -49,12 +105,76 @@ const (
 	UserDeleteSuccess       MessageKey = "user.delete.success"
 	UserLoginSuccess        MessageKey = "user.login.success"
 	UserRefreshTokenSuccess MessageKey = "user.refresh_token.success"
+	UserLogoutSuccess       MessageKey = "user.logout.success"
+
+	// 会话相关
+	UserSessionListSuccess   MessageKey = "user.session.list.success"
+	UserSessionRevokeSuccess MessageKey = "user.session.revoke.success"
+	UserForceLogoutSuccess   MessageKey = "user.force_logout.success"
+
+	// 角色相关
+	UserRoleListSuccess       MessageKey = "user.role.list.success"
+	UserRoleGetSuccess        MessageKey = "user.role.get.success"
+	UserRoleCreateSuccess     MessageKey = "user.role.create.success"
+	UserRoleUpdateSuccess     MessageKey = "user.role.update.success"
+	UserRoleDeleteSuccess     MessageKey = "user.role.delete.success"
+	UserRoleAssignSuccess     MessageKey = "user.role.assign.success"
+	UserPermissionListSuccess MessageKey = "user.permission.list.success"
+	UserErrorInvalidRoleID    MessageKey = "user.error.invalid_role_id"
+
+	// 登录保护相关
+	UserUnlockSuccess MessageKey = "user.unlock.success"
+
+	// 两步验证相关
+	UserLoginMFARequired        MessageKey = "user.login.mfa_required"
+	UserMFAStatusSuccess        MessageKey = "user.mfa.status.success"
+	UserMFASetupSuccess         MessageKey = "user.mfa.setup.success"
+	UserMFAEnableSuccess        MessageKey = "user.mfa.enable.success"
+	UserMFADisableSuccess       MessageKey = "user.mfa.disable.success"
+	UserMFARecoveryCodesSuccess MessageKey = "user.mfa.recovery_codes.success"
+
+	// API密钥相关
+	UserAuthAPIKeyInvalid   MessageKey = "user.auth.api_key_invalid"
+	UserAPIKeyCreateSuccess MessageKey = "user.api_key.create.success"
+	UserAPIKeyListSuccess   MessageKey = "user.api_key.list.success"
+	UserAPIKeyRevokeSuccess MessageKey = "user.api_key.revoke.success"
+
+	// 用户删除相关
+	UserRestoreSuccess MessageKey = "user.restore.success"
+	UserPurgeSuccess   MessageKey = "user.purge.success"
+
+	// 批量导入相关
+	UserImportSuccess       MessageKey = "user.import.success"
+	UserImportDryRun        MessageKey = "user.import.dry_run"
+	UserImportRejected      MessageKey = "user.import.rejected"
+	UserErrorImportFormat   MessageKey = "user.error.import_format"
+	UserErrorImportTooLarge MessageKey = "user.error.import_too_large"
+
+	// 审计日志相关
+	UserAuditListSuccess   MessageKey = "user.audit.list.success"
+	UserAuditVerifySuccess MessageKey = "user.audit.verify.success"
+	UserAuditVerifyBroken  MessageKey = "user.audit.verify.broken"
+
+	// 文件上传相关
+	UserFileUploadSuccess     MessageKey = "user.file.upload.success"
+	UserErrorUploadTooLarge   MessageKey = "user.error.upload_too_large"
+	UserErrorUploadNoFile     MessageKey = "user.error.upload_no_file"
+	UserErrorTusVersion       MessageKey = "user.error.tus_version"
+	UserErrorUploadHeader     MessageKey = "user.error.upload_header"
+	UserFileSignedURLSuccess  MessageKey = "user.file.signed_url.success"
+	UserErrorSignedURLInvalid MessageKey = "user.error.signed_url_invalid"
+
+	// 密码相关
+	UserPasswordChangeSuccess MessageKey = "user.password.change.success"
+	UserPasswordForgotSuccess MessageKey = "user.password.forgot.success"
+	UserPasswordResetSuccess  MessageKey = "user.password.reset.success"
 
 	// 错误相关
 	UserErrorBadRequest MessageKey = "user.error.bad_request"
 	UserErrorInvalidID  MessageKey = "user.error.invalid_id"
 	UserErrorJSONFormat MessageKey = "user.error.json_format"
 	UserErrorInternal   MessageKey = "user.error.internal"
+	UserErrorIfMatch    MessageKey = "user.error.if_match"
 
 	// 权限相关
 	UserPermissionDenied MessageKey = "user.permission.denied"
@@ -78,10 +198,150 @@ var messages = map[MessageKey]map[Language]string{
 		LanguageEn: "Authentication failed: token validation failed",
 		LanguageZh: "认证失败：令牌验证失败",
 	},
+	LogAuthFailedRevoked: {
+		LanguageEn: "Authentication failed: token has been revoked",
+		LanguageZh: "认证失败：令牌已被撤销",
+	},
 	LogAuthSuccess: {
 		LanguageEn: "Authentication successful",
 		LanguageZh: "认证成功",
 	},
+	LogRefreshTokenReused: {
+		LanguageEn: "Refresh token reuse detected, token family revoked",
+		LanguageZh: "检测到刷新令牌复用，已撤销整个令牌族",
+	},
+	LogNotificationSent: {
+		LanguageEn: "Notification sent",
+		LanguageZh: "通知已发送",
+	},
+	LogLoginFailed: {
+		LanguageEn: "Login failed",
+		LanguageZh: "登录失败",
+	},
+	LogLoginThrottled: {
+		LanguageEn: "Login rejected: too many failed attempts",
+		LanguageZh: "登录被拒绝：失败次数过多",
+	},
+	LogAccountLocked: {
+		LanguageEn: "Account locked after too many failed login attempts",
+		LanguageZh: "登录失败次数过多，账户已锁定",
+	},
+	LogIPLocked: {
+		LanguageEn: "Client IP locked after too many failed login attempts",
+		LanguageZh: "登录失败次数过多，客户端IP已锁定",
+	},
+	LogAccountUnlocked: {
+		LanguageEn: "Account unlocked",
+		LanguageZh: "账户已解锁",
+	},
+	LogMFAEnabled: {
+		LanguageEn: "Two-factor authentication enabled",
+		LanguageZh: "两步验证已启用",
+	},
+	LogMFADisabled: {
+		LanguageEn: "Two-factor authentication disabled",
+		LanguageZh: "两步验证已关闭",
+	},
+	LogMFAVerifyFailed: {
+		LanguageEn: "Two-factor verification failed",
+		LanguageZh: "两步验证失败",
+	},
+	LogMFARecoveryCodeUsed: {
+		LanguageEn: "Recovery code used for two-factor authentication",
+		LanguageZh: "使用恢复码完成两步验证",
+	},
+	LogMFARecoveryCodesRegenerated: {
+		LanguageEn: "Recovery codes regenerated",
+		LanguageZh: "恢复码已重新生成",
+	},
+	LogAuthFailedAPIKey: {
+		LanguageEn: "Authentication failed: invalid API key",
+		LanguageZh: "认证失败：API密钥无效",
+	},
+	LogPermissionDeniedScope: {
+		LanguageEn: "Permission denied: permission not in API key scopes",
+		LanguageZh: "权限不足：API密钥的权限范围不包含该权限",
+	},
+	LogPermissionDeniedAPIKey: {
+		LanguageEn: "Permission denied: route requires a user session, API keys are not accepted",
+		LanguageZh: "权限不足：该接口需要用户登录会话，不接受API密钥",
+	},
+	LogAPIKeyCreated: {
+		LanguageEn: "API key created",
+		LanguageZh: "API密钥已创建",
+	},
+	LogAPIKeyRevoked: {
+		LanguageEn: "API key revoked",
+		LanguageZh: "API密钥已吊销",
+	},
+	LogUserDeleted: {
+		LanguageEn: "User soft-deleted",
+		LanguageZh: "用户已删除（可恢复）",
+	},
+	LogUserRestored: {
+		LanguageEn: "Deleted user restored",
+		LanguageZh: "已删除的用户已恢复",
+	},
+	LogUserPurged: {
+		LanguageEn: "User permanently deleted",
+		LanguageZh: "用户已彻底删除",
+	},
+	LogUserFileCleanupFailed: {
+		LanguageEn: "Failed to remove stored file of purged user",
+		LanguageZh: "删除已彻底删除用户的文件失败",
+	},
+	LogUsersImported: {
+		LanguageEn: "Users imported",
+		LanguageZh: "批量导入用户完成",
+	},
+	LogUsersExported: {
+		LanguageEn: "Users exported",
+		LanguageZh: "批量导出用户完成",
+	},
+	LogAuditWriteFailed: {
+		LanguageEn: "Failed to write audit log",
+		LanguageZh: "写入审计日志失败",
+	},
+	LogAuditChainBroken: {
+		LanguageEn: "Audit log hash chain verification failed",
+		LanguageZh: "审计日志哈希链校验失败",
+	},
+	LogFileUploaded: {
+		LanguageEn: "File uploaded",
+		LanguageZh: "文件上传成功",
+	},
+	LogFileCleanupFailed: {
+		LanguageEn: "Failed to remove stored file after upload failure",
+		LanguageZh: "上传失败后删除已保存的文件失败",
+	},
+	LogUploadCreated: {
+		LanguageEn: "Resumable upload created",
+		LanguageZh: "创建断点续传上传",
+	},
+	LogUploadTerminated: {
+		LanguageEn: "Resumable upload terminated",
+		LanguageZh: "断点续传上传已取消",
+	},
+	LogImageThumbnailFailed: {
+		LanguageEn: "Failed to generate image thumbnails",
+		LanguageZh: "生成图片缩略图失败",
+	},
+	LogSlowQuery: {
+		LanguageEn: "Slow SQL query",
+		LanguageZh: "SQL慢查询",
+	},
+	LogQueryTimeout: {
+		LanguageEn: "SQL query timed out",
+		LanguageZh: "SQL查询超时",
+	},
+	LogReplicaUnhealthy: {
+		LanguageEn: "Read replica unavailable, reads fall back to primary",
+		LanguageZh: "只读副本不可用，读请求回退到主库",
+	},
+	LogReplicaRecovered: {
+		LanguageEn: "Read replica recovered",
+		LanguageZh: "只读副本已恢复",
+	},
 	LogRequestCost: {
 		LanguageEn: "Request processing time",
 		LanguageZh: "请求处理耗时",
@@ -110,6 +370,10 @@ var messages = map[MessageKey]map[Language]string{
 		LanguageEn: "Permission denied: invalid role",
 		LanguageZh: "权限不足：无效的角色信息",
 	},
+	LogPermissionDeniedNoGrant: {
+		LanguageEn: "Permission denied: permission not granted to role",
+		LanguageZh: "权限不足：角色未被授予该权限",
+	},
 
 	// 用户消息（中文，用于API响应）
 	UserAuthNoToken: {
@@ -124,6 +388,10 @@ var messages = map[MessageKey]map[Language]string{
 		LanguageZh: "无效的认证令牌",
 		LanguageEn: "Invalid authentication token",
 	},
+	UserAuthRevoked: {
+		LanguageZh: "认证令牌已失效，请重新登录",
+		LanguageEn: "Authentication token has been revoked, please log in again",
+	},
 	UserCreateSuccess: {
 		LanguageZh: "创建成功",
 		LanguageEn: "Created successfully",
@@ -152,6 +420,182 @@ var messages = map[MessageKey]map[Language]string{
 		LanguageZh: "登录成功",
 		LanguageEn: "Login successful",
 	},
+	UserRefreshTokenSuccess: {
+		LanguageZh: "刷新成功",
+		LanguageEn: "Token refreshed successfully",
+	},
+	UserLogoutSuccess: {
+		LanguageZh: "退出登录成功",
+		LanguageEn: "Logout successful",
+	},
+	UserSessionListSuccess: {
+		LanguageZh: "获取成功",
+		LanguageEn: "Retrieved successfully",
+	},
+	UserSessionRevokeSuccess: {
+		LanguageZh: "会话已撤销",
+		LanguageEn: "Session revoked",
+	},
+	UserForceLogoutSuccess: {
+		LanguageZh: "用户已被强制下线",
+		LanguageEn: "User has been logged out from all sessions",
+	},
+	UserRoleListSuccess: {
+		LanguageZh: "获取成功",
+		LanguageEn: "Retrieved successfully",
+	},
+	UserRoleGetSuccess: {
+		LanguageZh: "获取成功",
+		LanguageEn: "Retrieved successfully",
+	},
+	UserRoleCreateSuccess: {
+		LanguageZh: "角色创建成功",
+		LanguageEn: "Role created successfully",
+	},
+	UserRoleUpdateSuccess: {
+		LanguageZh: "角色更新成功",
+		LanguageEn: "Role updated successfully",
+	},
+	UserRoleDeleteSuccess: {
+		LanguageZh: "角色删除成功",
+		LanguageEn: "Role deleted successfully",
+	},
+	UserRoleAssignSuccess: {
+		LanguageZh: "角色分配成功",
+		LanguageEn: "Role assigned successfully",
+	},
+	UserPermissionListSuccess: {
+		LanguageZh: "获取成功",
+		LanguageEn: "Retrieved successfully",
+	},
+	UserErrorInvalidRoleID: {
+		LanguageZh: "无效的角色ID: %s",
+		LanguageEn: "Invalid role ID: %s",
+	},
+	UserUnlockSuccess: {
+		LanguageZh: "账户已解锁",
+		LanguageEn: "Account unlocked",
+	},
+	UserLoginMFARequired: {
+		LanguageZh: "请输入两步验证码",
+		LanguageEn: "Two-factor authentication required",
+	},
+	UserMFAStatusSuccess: {
+		LanguageZh: "获取两步验证状态成功",
+		LanguageEn: "Two-factor authentication status retrieved",
+	},
+	UserMFASetupSuccess: {
+		LanguageZh: "请使用身份验证器扫描二维码，并输入验证码完成启用",
+		LanguageEn: "Scan the QR code with your authenticator app and enter the code to finish",
+	},
+	UserMFAEnableSuccess: {
+		LanguageZh: "两步验证已启用，请妥善保存恢复码",
+		LanguageEn: "Two-factor authentication enabled, keep your recovery codes safe",
+	},
+	UserMFADisableSuccess: {
+		LanguageZh: "两步验证已关闭",
+		LanguageEn: "Two-factor authentication disabled",
+	},
+	UserMFARecoveryCodesSuccess: {
+		LanguageZh: "恢复码已重新生成，之前的恢复码已失效",
+		LanguageEn: "Recovery codes regenerated, previous codes are no longer valid",
+	},
+	UserAuthAPIKeyInvalid: {
+		LanguageZh: "API密钥无效、已过期或已吊销",
+		LanguageEn: "API key is invalid, expired or revoked",
+	},
+	UserAPIKeyCreateSuccess: {
+		LanguageZh: "API密钥创建成功，请立即保存密钥，之后将无法再次查看",
+		LanguageEn: "API key created, store the key now as it cannot be shown again",
+	},
+	UserAPIKeyListSuccess: {
+		LanguageZh: "获取API密钥列表成功",
+		LanguageEn: "API keys retrieved successfully",
+	},
+	UserAPIKeyRevokeSuccess: {
+		LanguageZh: "API密钥已吊销",
+		LanguageEn: "API key revoked",
+	},
+	UserRestoreSuccess: {
+		LanguageZh: "用户已恢复",
+		LanguageEn: "User restored",
+	},
+	UserPurgeSuccess: {
+		LanguageZh: "用户已彻底删除",
+		LanguageEn: "User permanently deleted",
+	},
+	UserImportSuccess: {
+		LanguageZh: "导入完成",
+		LanguageEn: "Import completed",
+	},
+	UserImportDryRun: {
+		LanguageZh: "校验完成，未写入任何用户",
+		LanguageEn: "Validation completed, no users were written",
+	},
+	UserImportRejected: {
+		LanguageZh: "存在无效的行，未导入任何用户",
+		LanguageEn: "Some rows are invalid, no users were imported",
+	},
+	UserErrorImportFormat: {
+		LanguageZh: "无法识别文件格式，请通过 format 参数指定 csv 或 ndjson",
+		LanguageEn: "Unknown file format, specify csv or ndjson with the format parameter",
+	},
+	UserErrorImportTooLarge: {
+		LanguageZh: "导入文件不能超过 %d MB",
+		LanguageEn: "Import file must not exceed %d MB",
+	},
+	UserAuditListSuccess: {
+		LanguageZh: "获取审计日志成功",
+		LanguageEn: "Audit logs retrieved",
+	},
+	UserAuditVerifySuccess: {
+		LanguageZh: "审计日志哈希链完整",
+		LanguageEn: "Audit log hash chain is intact",
+	},
+	UserAuditVerifyBroken: {
+		LanguageZh: "审计日志哈希链校验失败，日志可能已被篡改",
+		LanguageEn: "Audit log hash chain is broken, logs may have been tampered with",
+	},
+	UserFileUploadSuccess: {
+		LanguageZh: "上传成功",
+		LanguageEn: "Upload completed",
+	},
+	UserErrorUploadTooLarge: {
+		LanguageZh: "上传的内容不能超过 %d MB",
+		LanguageEn: "Upload must not exceed %d MB",
+	},
+	UserErrorUploadNoFile: {
+		LanguageZh: "请选择要上传的文件（表单字段 %s）",
+		LanguageEn: "No file uploaded (form field %s)",
+	},
+	UserErrorTusVersion: {
+		LanguageZh: "不支持的 tus 协议版本，请使用 %s",
+		LanguageEn: "Unsupported tus protocol version, use %s",
+	},
+	UserErrorUploadHeader: {
+		LanguageZh: "请求头 %s 无效",
+		LanguageEn: "Invalid %s header",
+	},
+	UserFileSignedURLSuccess: {
+		LanguageZh: "签名链接已生成",
+		LanguageEn: "Signed URL created",
+	},
+	UserErrorSignedURLInvalid: {
+		LanguageZh: "签名链接无效",
+		LanguageEn: "Invalid signed URL",
+	},
+	UserPasswordChangeSuccess: {
+		LanguageZh: "密码修改成功，请重新登录",
+		LanguageEn: "Password changed, please log in again",
+	},
+	UserPasswordForgotSuccess: {
+		LanguageZh: "如果该邮箱已注册，重置密码的链接已发送",
+		LanguageEn: "If the email is registered, a password reset link has been sent",
+	},
+	UserPasswordResetSuccess: {
+		LanguageZh: "密码重置成功，请重新登录",
+		LanguageEn: "Password reset, please log in again",
+	},
 	UserErrorBadRequest: {
 		LanguageZh: "请求参数错误",
 		LanguageEn: "Bad request",
@@ -168,6 +612,10 @@ var messages = map[MessageKey]map[Language]string{
 		LanguageZh: "内部服务器错误",
 		LanguageEn: "Internal server error",
 	},
+	UserErrorIfMatch: {
+		LanguageZh: "If-Match 请求头无效，应为获取资源时返回的 ETag",
+		LanguageEn: "Invalid If-Match header, expected the ETag returned when fetching the resource",
+	},
 	UserPermissionDenied: {
 		LanguageZh: "权限不足",
 		LanguageEn: "Permission denied",
@@ -0,0 +1,359 @@
+// Package imaging 处理上传的图片
+//
+// 支持 JPEG、PNG、GIF 和 WebP，全部使用纯 Go 实现，不依赖 cgo：
+// 上传时去除 EXIF 等元数据并按拍摄方向旋转；下载时按参数缩放，派生图片（缩略图等）缓存在磁盘上。
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gin/internal/config"
+
+	_ "golang.org/x/image/webp"
+	"golang.org/x/sync/singleflight"
+)
+
+// MaxInputSize 可以处理的图片文件最大字节数（处理时需要把内容全部读入内存）
+const MaxInputSize = 64 << 20
+
+var (
+	// ErrUnsupported 不支持处理的文件类型
+	ErrUnsupported = errors.New("不支持处理的图片类型")
+	// ErrInvalidImage 图片内容无法解析
+	ErrInvalidImage = errors.New("图片无法解析")
+	// ErrTooLarge 图片文件或像素数超过限制
+	ErrTooLarge = errors.New("图片尺寸过大")
+	// ErrInvalidOptions 变换参数无效
+	ErrInvalidOptions = errors.New("图片变换参数无效")
+)
+
+// Fit 缩放方式
+type Fit string
+
+const (
+	FitContain Fit = "contain" // 等比缩放到完整显示在范围内，不放大（默认）
+	FitCover   Fit = "cover"   // 等比缩放并居中裁剪，填满范围
+	FitFill    Fit = "fill"    // 拉伸到指定宽高
+)
+
+// Options 变换参数，宽和高至少指定一个
+type Options struct {
+	Width  int
+	Height int
+	Fit    Fit
+}
+
+// thumbnailNamePattern 缩略图名称格式
+var thumbnailNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// strippers 去除元数据的函数，同时返回 EXIF 中的拍摄方向；GIF 没有 EXIF，不需要处理
+var strippers = map[string]func([]byte) ([]byte, int, error){
+	"image/jpeg": stripJPEG,
+	"image/png":  stripPNG,
+	"image/webp": stripWebP,
+}
+
+// thumbnail 配置的缩略图
+type thumbnail struct {
+	name string
+	opts Options
+}
+
+// Source 原图
+type Source struct {
+	Checksum    string                                           // 原图内容的 SHA-256，用于生成派生图片的缓存键
+	ContentType string                                           // 原图类型
+	Open        func(ctx context.Context) (io.ReadCloser, error) // 读取原图内容，只在缓存未命中时调用
+}
+
+// Variant 派生图片
+type Variant struct {
+	Key         string   // 缓存键，原图或变换参数不同时不同
+	ContentType string   // JPEG 原图输出 JPEG，其他输出 PNG
+	Size        int64    // 内容长度
+	Content     *os.File // 缓存文件，调用方负责关闭
+}
+
+// Processor 图片处理器
+type Processor struct {
+	cacheDir     string
+	maxPixels    int
+	maxDimension int
+	quality      int
+	thumbnails   []thumbnail
+	group        singleflight.Group // 同一派生图片同时只生成一次
+}
+
+// New 根据配置创建图片处理器
+func New(cfg config.ImagesConfig) (*Processor, error) {
+	if cfg.CacheDir == "" {
+		return nil, fmt.Errorf("images.cache_dir 不能为空")
+	}
+	p := &Processor{
+		cacheDir:     cfg.CacheDir,
+		maxPixels:    cfg.MaxPixels,
+		maxDimension: cfg.MaxDimension,
+		quality:      cfg.JPEGQuality,
+	}
+	if p.quality < 1 || p.quality > 100 {
+		p.quality = jpeg.DefaultQuality
+	}
+
+	seen := make(map[string]bool, len(cfg.Thumbnails))
+	for _, t := range cfg.Thumbnails {
+		if !thumbnailNamePattern.MatchString(t.Name) || seen[t.Name] {
+			return nil, fmt.Errorf("缩略图名称无效或重复: %q", t.Name)
+		}
+		seen[t.Name] = true
+		opts, err := p.normalize(Options{Width: t.Width, Height: t.Height, Fit: Fit(t.Fit)})
+		if err != nil {
+			return nil, fmt.Errorf("缩略图 %s 配置无效: %w", t.Name, err)
+		}
+		p.thumbnails = append(p.thumbnails, thumbnail{name: t.Name, opts: opts})
+	}
+	return p, nil
+}
+
+// Supported 是否支持处理该类型的图片
+func (p *Processor) Supported(contentType string) bool {
+	_, ok := strippers[contentType]
+	return ok || contentType == "image/gif"
+}
+
+// Thumbnail 返回配置的缩略图的变换参数
+func (p *Processor) Thumbnail(name string) (Options, error) {
+	for _, t := range p.thumbnails {
+		if t.name == name {
+			return t.opts, nil
+		}
+	}
+	return Options{}, fmt.Errorf("%w: 缩略图 %q 不存在", ErrInvalidOptions, name)
+}
+
+// Sanitize 去除图片中的 EXIF、XMP、文本注释等元数据，返回处理后的内容和类型
+// 带有拍摄方向的图片按方向旋转后重新编码（WebP 没有纯 Go 编码器，旋转后输出 PNG）；
+// 其他图片只去除元数据段，图像数据不重新编码，不损失质量
+func (p *Processor) Sanitize(data []byte, contentType string) ([]byte, string, error) {
+	if !p.Supported(contentType) {
+		return nil, "", ErrUnsupported
+	}
+	if err := p.checkSize(data); err != nil {
+		return nil, "", err
+	}
+	strip, ok := strippers[contentType]
+	if !ok {
+		return data, contentType, nil
+	}
+
+	clean, orientation, err := strip(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if orientation == 1 {
+		return clean, contentType, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(clean))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	outputType := contentType
+	if outputType == "image/webp" {
+		outputType = "image/png"
+	}
+	var buf bytes.Buffer
+	if err := p.encode(&buf, orient(toNRGBA(img), orientation), outputType); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), outputType, nil
+}
+
+// Variant 返回按参数缩放后的派生图片，优先使用磁盘缓存
+func (p *Processor) Variant(ctx context.Context, src Source, opts Options) (*Variant, error) {
+	if !p.Supported(src.ContentType) {
+		return nil, ErrUnsupported
+	}
+	opts, err := p.normalize(opts)
+	if err != nil {
+		return nil, err
+	}
+	key, path, contentType := p.variantPath(src, opts)
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// 发起请求的客户端断开时不中断生成，等待同一图片的其他请求可以继续使用
+		_, err, _ = p.group.Do(key, func() (any, error) {
+			img, err := p.load(context.WithoutCancel(ctx), src)
+			if err != nil {
+				return nil, err
+			}
+			return nil, p.write(path, resize(img, opts), contentType)
+		})
+		if err != nil {
+			return nil, err
+		}
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取缓存图片失败: %w", err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("读取缓存图片失败: %w", err)
+	}
+	return &Variant{Key: key, ContentType: contentType, Size: stat.Size(), Content: f}, nil
+}
+
+// GenerateThumbnails 生成配置的全部缩略图，已缓存的跳过；原图只读取和解码一次
+func (p *Processor) GenerateThumbnails(ctx context.Context, src Source) error {
+	if !p.Supported(src.ContentType) {
+		return ErrUnsupported
+	}
+
+	var img *image.NRGBA
+	var errs []error
+	for _, t := range p.thumbnails {
+		_, path, contentType := p.variantPath(src, t.opts)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if img == nil {
+			var err error
+			if img, err = p.load(ctx, src); err != nil {
+				return err
+			}
+		}
+		if err := p.write(path, resize(img, t.opts), contentType); err != nil {
+			errs = append(errs, fmt.Errorf("缩略图 %s: %w", t.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// normalize 检查变换参数，未指定缩放方式时使用 contain
+func (p *Processor) normalize(opts Options) (Options, error) {
+	switch opts.Fit {
+	case "":
+		opts.Fit = FitContain
+	case FitContain, FitCover, FitFill:
+	default:
+		return Options{}, fmt.Errorf("%w: 不支持的缩放方式 %q", ErrInvalidOptions, opts.Fit)
+	}
+	if opts.Width <= 0 && opts.Height <= 0 {
+		return Options{}, fmt.Errorf("%w: 宽和高至少指定一个", ErrInvalidOptions)
+	}
+	if opts.Width < 0 || opts.Height < 0 || (p.maxDimension > 0 && max(opts.Width, opts.Height) > p.maxDimension) {
+		return Options{}, fmt.Errorf("%w: 宽和高必须在 1 到 %d 之间", ErrInvalidOptions, p.maxDimension)
+	}
+	return opts, nil
+}
+
+// variantPath 返回派生图片的缓存键、缓存路径和类型
+// 缓存键由原图的 SHA-256 和变换参数计算，不包含文件ID，内容相同的文件共用缓存
+func (p *Processor) variantPath(src Source, opts Options) (key, path, contentType string) {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%d|%s|%d", src.Checksum, opts.Width, opts.Height, opts.Fit, p.quality))
+	key = hex.EncodeToString(sum[:])
+
+	contentType, ext := "image/png", ".png"
+	if src.ContentType == "image/jpeg" {
+		contentType, ext = "image/jpeg", ".jpg"
+	}
+	return key, filepath.Join(p.cacheDir, key[:2], key+ext), contentType
+}
+
+// load 读取并解码原图，按 EXIF 拍摄方向旋转（兼容去除元数据之前上传的图片）
+// GIF 动图只使用第一帧
+func (p *Processor) load(ctx context.Context, src Source) (*image.NRGBA, error) {
+	r, err := src.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxInputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取原图失败: %w", err)
+	}
+	if err := p.checkSize(data); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	orientation := 1
+	if strip, ok := strippers[src.ContentType]; ok {
+		if _, o, err := strip(data); err == nil {
+			orientation = o
+		}
+	}
+	return orient(toNRGBA(img), orientation), nil
+}
+
+// write 编码图片并写入缓存：先写临时文件再重命名，不会留下写了一半的缓存
+func (p *Processor) write(path string, img image.Image, contentType string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".variant-*")
+	if err != nil {
+		return fmt.Errorf("写入缓存图片失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = p.encode(tmp, img, contentType)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("写入缓存图片失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("写入缓存图片失败: %w", err)
+	}
+	return nil
+}
+
+// encode 按类型编码图片
+func (p *Processor) encode(w io.Writer, img image.Image, contentType string) error {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: p.quality})
+	case "image/png":
+		return png.Encode(w, img)
+	default:
+		return ErrUnsupported
+	}
+}
+
+// checkSize 检查文件大小和像素数，只解析图片头，不解码图像数据
+func (p *Processor) checkSize(data []byte) error {
+	if len(data) > MaxInputSize {
+		return fmt.Errorf("%w: 文件不能超过 %d MB", ErrTooLarge, MaxInputSize>>20)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if p.maxPixels > 0 && int64(cfg.Width)*int64(cfg.Height) > int64(p.maxPixels) {
+		return fmt.Errorf("%w: %d×%d 超过 %d 像素", ErrTooLarge, cfg.Width, cfg.Height, p.maxPixels)
+	}
+	return nil
+}
@@ -0,0 +1,444 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gin/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webpLossless 1×1 的无损 WebP 图片
+const webpLossless = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+// newTestProcessor 创建缓存在临时目录的处理器
+func newTestProcessor(t *testing.T, cfg config.ImagesConfig) *Processor {
+	t.Helper()
+	cfg.CacheDir = t.TempDir()
+	if cfg.MaxDimension == 0 {
+		cfg.MaxDimension = 2048
+	}
+	p, err := New(cfg)
+	require.NoError(t, err)
+	return p
+}
+
+// testImage 左上角为红色、其余为蓝色的图片，用于检查旋转方向
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.SetNRGBA(x, y, blue)
+		}
+	}
+	img.SetNRGBA(0, 0, red)
+	return img
+}
+
+// exifTIFF 只包含拍摄方向的 EXIF 数据（大端序）
+func exifTIFF(orientation int) []byte {
+	buf := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	buf = binary.BigEndian.AppendUint16(buf, 1)
+	buf = binary.BigEndian.AppendUint16(buf, 0x0112)
+	buf = binary.BigEndian.AppendUint16(buf, 3)
+	buf = binary.BigEndian.AppendUint32(buf, 1)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(orientation))
+	buf = binary.BigEndian.AppendUint16(buf, 0)
+	return binary.BigEndian.AppendUint32(buf, 0)
+}
+
+// jpegWithEXIF 编码 JPEG 并在文件头后插入 EXIF 和注释段
+func jpegWithEXIF(t *testing.T, img image.Image, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	encoded := buf.Bytes()
+
+	segment := func(marker byte, payload []byte) []byte {
+		seg := []byte{0xFF, marker}
+		seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+		return append(seg, payload...)
+	}
+	out := append([]byte{}, encoded[:2]...)
+	out = append(out, segment(0xE1, append([]byte("Exif\x00\x00"), exifTIFF(orientation)...))...)
+	out = append(out, segment(0xFE, []byte("GPS 39.9042N 116.4074E"))...)
+	return append(out, encoded[2:]...)
+}
+
+// pngChunk 生成 PNG 数据块
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(append([]byte(chunkType), data...)))
+}
+
+// pngWithEXIF 编码 PNG 并在 IHDR 后插入 eXIf 和 tEXt 块
+func pngWithEXIF(t *testing.T, img image.Image, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	encoded := buf.Bytes()
+
+	ihdrEnd := len(pngSignature) + 12 + 13
+	out := append([]byte{}, encoded[:ihdrEnd]...)
+	out = append(out, pngChunk("eXIf", exifTIFF(orientation))...)
+	out = append(out, pngChunk("tEXt", []byte("Author\x00secret"))...)
+	return append(out, encoded[ihdrEnd:]...)
+}
+
+// webpWithEXIF 把 1×1 的 WebP 包装为带有 EXIF 和 XMP 块的扩展格式
+func webpWithEXIF(t *testing.T, orientation int) []byte {
+	t.Helper()
+	simple, err := base64.StdEncoding.DecodeString(webpLossless)
+	require.NoError(t, err)
+
+	chunk := func(fourCC string, data []byte) []byte {
+		c := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	out := []byte("RIFF\x00\x00\x00\x00WEBP")
+	out = append(out, chunk("VP8X", []byte{webpMetadataFlags, 0, 0, 0, 0, 0, 0, 0, 0, 0})...)
+	out = append(out, simple[12:]...)
+	out = append(out, chunk("EXIF", append([]byte("Exif\x00\x00"), exifTIFF(orientation)...))...)
+	out = append(out, chunk("XMP ", []byte("<x:xmpmeta>secret</x:xmpmeta>"))...)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+// decode 解码图片
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, _, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+func TestProcessor_Sanitize(t *testing.T) {
+	p := newTestProcessor(t, config.ImagesConfig{})
+
+	t.Run("JPEG 去除 EXIF 和注释，方向正常时不重新编码", func(t *testing.T) {
+		data := jpegWithEXIF(t, testImage(8, 4), 1)
+
+		out, contentType, err := p.Sanitize(data, "image/jpeg")
+
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", contentType)
+		assert.NotContains(t, string(out), "Exif")
+		assert.NotContains(t, string(out), "GPS")
+		assert.Less(t, len(out), len(data))
+		_, orientation, err := stripJPEG(out)
+		require.NoError(t, err)
+		assert.Equal(t, 1, orientation)
+		// 图像数据原样保留
+		assert.True(t, bytes.HasSuffix(data, out[len(out)-100:]))
+	})
+
+	t.Run("JPEG 按拍摄方向旋转", func(t *testing.T) {
+		data := jpegWithEXIF(t, testImage(8, 4), 6)
+
+		out, contentType, err := p.Sanitize(data, "image/jpeg")
+
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", contentType)
+		assert.NotContains(t, string(out), "Exif")
+		assert.Equal(t, image.Rect(0, 0, 4, 8), decode(t, out).Bounds())
+	})
+
+	t.Run("PNG 去除元数据块并按拍摄方向旋转", func(t *testing.T) {
+		data := pngWithEXIF(t, testImage(3, 2), 8)
+
+		out, contentType, err := p.Sanitize(data, "image/png")
+
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", contentType)
+		assert.NotContains(t, string(out), "secret")
+		assert.NotContains(t, string(out), "eXIf")
+		img := decode(t, out)
+		assert.Equal(t, image.Rect(0, 0, 2, 3), img.Bounds())
+		// 逆时针旋转 90° 后左上角移到左下角
+		assert.Equal(t, red, color.NRGBAModel.Convert(img.At(0, 2)))
+		assert.Equal(t, blue, color.NRGBAModel.Convert(img.At(0, 0)))
+	})
+
+	t.Run("PNG 没有拍摄方向时只去除元数据", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, testImage(3, 2)))
+		data := append(append([]byte{}, buf.Bytes()[:33]...), pngChunk("tEXt", []byte("Comment\x00secret"))...)
+		data = append(data, buf.Bytes()[33:]...)
+
+		out, _, err := p.Sanitize(data, "image/png")
+
+		require.NoError(t, err)
+		assert.Equal(t, buf.Bytes(), out)
+	})
+
+	t.Run("WebP 去除 EXIF 和 XMP 块并清除标志位", func(t *testing.T) {
+		data := webpWithEXIF(t, 1)
+
+		out, contentType, err := p.Sanitize(data, "image/webp")
+
+		require.NoError(t, err)
+		assert.Equal(t, "image/webp", contentType)
+		assert.NotContains(t, string(out), "EXIF")
+		assert.NotContains(t, string(out), "secret")
+		assert.Equal(t, byte(0), out[20]&webpMetadataFlags)
+		assert.Equal(t, uint32(len(out)-8), binary.LittleEndian.Uint32(out[4:]))
+		assert.Equal(t, image.Rect(0, 0, 1, 1), decode(t, out).Bounds())
+	})
+
+	t.Run("WebP 需要旋转时输出 PNG", func(t *testing.T) {
+		out, contentType, err := p.Sanitize(webpWithEXIF(t, 3), "image/webp")
+
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", contentType)
+		assert.True(t, bytes.HasPrefix(out, pngSignature))
+	})
+
+	t.Run("GIF 原样返回", func(t *testing.T) {
+		data := []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")
+
+		out, contentType, err := p.Sanitize(data, "image/gif")
+
+		require.NoError(t, err)
+		assert.Equal(t, "image/gif", contentType)
+		assert.Equal(t, data, out)
+	})
+
+	t.Run("无法解析的图片", func(t *testing.T) {
+		_, _, err := p.Sanitize([]byte("\x89PNG\r\n\x1a\nbroken"), "image/png")
+		assert.ErrorIs(t, err, ErrInvalidImage)
+	})
+
+	t.Run("不支持的类型", func(t *testing.T) {
+		_, _, err := p.Sanitize([]byte("BM"), "image/bmp")
+		assert.ErrorIs(t, err, ErrUnsupported)
+	})
+
+	t.Run("像素数超过限制", func(t *testing.T) {
+		small := newTestProcessor(t, config.ImagesConfig{MaxPixels: 100})
+		_, _, err := small.Sanitize(pngWithEXIF(t, testImage(20, 20), 1), "image/png")
+		assert.ErrorIs(t, err, ErrTooLarge)
+	})
+}
+
+// countingSource 记录原图被读取的次数
+func countingSource(data []byte, contentType string, opens *int) Source {
+	return Source{
+		Checksum:    "checksum-" + contentType,
+		ContentType: contentType,
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			*opens++
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+}
+
+func TestProcessor_Variant(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(400, 200)))
+	source := buf.Bytes()
+
+	tests := []struct {
+		name string
+		opts Options
+		want image.Rectangle
+	}{
+		{"contain 等比缩放", Options{Width: 100, Height: 100, Fit: FitContain}, image.Rect(0, 0, 100, 50)},
+		{"未指定缩放方式时使用 contain", Options{Height: 50}, image.Rect(0, 0, 100, 50)},
+		{"contain 不放大", Options{Width: 800}, image.Rect(0, 0, 400, 200)},
+		{"cover 居中裁剪", Options{Width: 100, Height: 100, Fit: FitCover}, image.Rect(0, 0, 100, 100)},
+		{"fill 拉伸", Options{Width: 100, Height: 30, Fit: FitFill}, image.Rect(0, 0, 100, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProcessor(t, config.ImagesConfig{})
+			var opens int
+			src := countingSource(source, "image/png", &opens)
+
+			v, err := p.Variant(context.Background(), src, tt.opts)
+			require.NoError(t, err)
+			defer v.Content.Close()
+
+			assert.Equal(t, "image/png", v.ContentType)
+			data, err := io.ReadAll(v.Content)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(data)), v.Size)
+			assert.Equal(t, tt.want, decode(t, data).Bounds())
+			assert.Equal(t, 1, opens)
+		})
+	}
+
+	t.Run("命中缓存时不读取原图", func(t *testing.T) {
+		p := newTestProcessor(t, config.ImagesConfig{})
+		var opens int
+		src := countingSource(source, "image/png", &opens)
+
+		first, err := p.Variant(context.Background(), src, Options{Width: 100})
+		require.NoError(t, err)
+		first.Content.Close()
+		second, err := p.Variant(context.Background(), src, Options{Width: 100})
+		require.NoError(t, err)
+		second.Content.Close()
+
+		assert.Equal(t, 1, opens)
+		assert.Equal(t, first.Key, second.Key)
+		_, err = os.Stat(filepath.Join(p.cacheDir, first.Key[:2], first.Key+".png"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("参数不同时缓存键不同", func(t *testing.T) {
+		p := newTestProcessor(t, config.ImagesConfig{})
+		var opens int
+		src := countingSource(source, "image/png", &opens)
+
+		a, err := p.Variant(context.Background(), src, Options{Width: 100, Height: 100, Fit: FitCover})
+		require.NoError(t, err)
+		a.Content.Close()
+		b, err := p.Variant(context.Background(), src, Options{Width: 100, Height: 100, Fit: FitFill})
+		require.NoError(t, err)
+		b.Content.Close()
+
+		assert.NotEqual(t, a.Key, b.Key)
+		assert.Equal(t, 2, opens)
+	})
+
+	t.Run("JPEG 原图输出 JPEG 并按拍摄方向旋转", func(t *testing.T) {
+		p := newTestProcessor(t, config.ImagesConfig{})
+		var opens int
+		src := countingSource(jpegWithEXIF(t, testImage(80, 40), 6), "image/jpeg", &opens)
+
+		v, err := p.Variant(context.Background(), src, Options{Width: 20})
+		require.NoError(t, err)
+		defer v.Content.Close()
+
+		assert.Equal(t, "image/jpeg", v.ContentType)
+		img, format, err := image.Decode(v.Content)
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, image.Rect(0, 0, 20, 40), img.Bounds())
+	})
+
+	t.Run("WebP 原图", func(t *testing.T) {
+		p := newTestProcessor(t, config.ImagesConfig{})
+		var opens int
+		src := countingSource(webpWithEXIF(t, 1), "image/webp", &opens)
+
+		v, err := p.Variant(context.Background(), src, Options{Width: 10, Height: 10, Fit: FitFill})
+		require.NoError(t, err)
+		defer v.Content.Close()
+
+		assert.Equal(t, "image/png", v.ContentType)
+		img, _, err := image.Decode(v.Content)
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 10, 10), img.Bounds())
+	})
+
+	t.Run("参数无效", func(t *testing.T) {
+		p := newTestProcessor(t, config.ImagesConfig{MaxDimension: 500})
+		var opens int
+		src := countingSource(source, "image/png", &opens)
+
+		for _, opts := range []Options{{}, {Width: 501}, {Width: 100, Fit: "crop"}, {Width: -1, Height: 10}} {
+			_, err := p.Variant(context.Background(), src, opts)
+			assert.ErrorIs(t, err, ErrInvalidOptions, "%+v", opts)
+		}
+		assert.Zero(t, opens)
+	})
+
+	t.Run("不支持的类型", func(t *testing.T) {
+		p := newTestProcessor(t, config.ImagesConfig{})
+		var opens int
+
+		_, err := p.Variant(context.Background(), countingSource([]byte("%PDF-1.4"), "application/pdf", &opens), Options{Width: 10})
+
+		assert.ErrorIs(t, err, ErrUnsupported)
+	})
+
+	t.Run("原图无法解析时不写入缓存", func(t *testing.T) {
+		p := newTestProcessor(t, config.ImagesConfig{})
+		var opens int
+
+		_, err := p.Variant(context.Background(), countingSource([]byte("\x89PNG\r\n\x1a\nbroken"), "image/png", &opens), Options{Width: 10})
+
+		assert.ErrorIs(t, err, ErrInvalidImage)
+		entries, _ := os.ReadDir(p.cacheDir)
+		for _, entry := range entries {
+			files, _ := os.ReadDir(filepath.Join(p.cacheDir, entry.Name()))
+			assert.Empty(t, files)
+		}
+	})
+}
+
+func TestProcessor_GenerateThumbnails(t *testing.T) {
+	p := newTestProcessor(t, config.ImagesConfig{Thumbnails: []config.ThumbnailConfig{
+		{Name: "small", Width: 32, Height: 32, Fit: "cover"},
+		{Name: "medium", Width: 128, Height: 128, Fit: "contain"},
+	}})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(400, 200)))
+	var opens int
+	src := countingSource(buf.Bytes(), "image/png", &opens)
+
+	require.NoError(t, p.GenerateThumbnails(context.Background(), src))
+	assert.Equal(t, 1, opens, "原图只读取一次")
+
+	// 生成的缩略图与 Variant 使用同一缓存
+	small, err := p.Thumbnail("small")
+	require.NoError(t, err)
+	v, err := p.Variant(context.Background(), src, small)
+	require.NoError(t, err)
+	defer v.Content.Close()
+	img, _, err := image.Decode(v.Content)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 32, 32), img.Bounds())
+
+	require.NoError(t, p.GenerateThumbnails(context.Background(), src))
+	assert.Equal(t, 1, opens, "已生成的缩略图不再读取原图")
+
+	_, err = p.Thumbnail("large")
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ImagesConfig
+		want string
+	}{
+		{"缓存目录为空", config.ImagesConfig{}, "cache_dir"},
+		{"缩略图名称无效", config.ImagesConfig{CacheDir: "x", Thumbnails: []config.ThumbnailConfig{{Name: "../a", Width: 1}}}, "名称无效"},
+		{"缩略图名称重复", config.ImagesConfig{CacheDir: "x", Thumbnails: []config.ThumbnailConfig{{Name: "a", Width: 1}, {Name: "a", Width: 2}}}, "重复"},
+		{"缩略图尺寸无效", config.ImagesConfig{CacheDir: "x", Thumbnails: []config.ThumbnailConfig{{Name: "a"}}}, "配置无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			require.Error(t, err)
+			assert.True(t, strings.Contains(err.Error(), tt.want), err.Error())
+		})
+	}
+}
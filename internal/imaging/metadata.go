@@ -0,0 +1,204 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// exifHeader JPEG APP1 段中 EXIF 数据的前缀
+var exifHeader = []byte("Exif\x00\x00")
+
+// pngSignature PNG 文件头
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks 去除的 PNG 元数据块：EXIF、文本注释和修改时间
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// webpMetadataFlags VP8X 头中 EXIF 和 XMP 的标志位
+const webpMetadataFlags = 0x08 | 0x04
+
+// stripJPEG 去除 JPEG 中的元数据段，返回去除后的内容和 EXIF 中的拍摄方向（没有时为 1）
+// 保留 APP0（JFIF）、APP2（ICC 颜色配置）和 APP14（Adobe 颜色变换），其余 APPn 和注释段全部去除；
+// 图像数据原样保留，不重新编码
+func stripJPEG(data []byte) ([]byte, int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, 0, fmt.Errorf("%w: 不是 JPEG 文件", ErrInvalidImage)
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	orientation := 1
+	for i := 2; ; {
+		if i >= len(data) || data[i] != 0xFF {
+			return nil, 0, fmt.Errorf("%w: JPEG 段格式错误", ErrInvalidImage)
+		}
+		// 跳过段之间的填充字节
+		for i < len(data) && data[i] == 0xFF {
+			i++
+		}
+		if i >= len(data) {
+			return nil, 0, fmt.Errorf("%w: JPEG 文件不完整", ErrInvalidImage)
+		}
+		marker := data[i]
+		i++
+		if marker == 0xD9 {
+			return append(out, 0xFF, marker), orientation, nil
+		}
+		if i+2 > len(data) {
+			return nil, 0, fmt.Errorf("%w: JPEG 文件不完整", ErrInvalidImage)
+		}
+		length := int(binary.BigEndian.Uint16(data[i:]))
+		if length < 2 || i+length > len(data) {
+			return nil, 0, fmt.Errorf("%w: JPEG 段长度错误", ErrInvalidImage)
+		}
+		segment := data[i+2 : i+length]
+
+		switch {
+		case marker == 0xDA:
+			// 扫描数据开始，之后的内容原样保留
+			return append(append(out, 0xFF, marker), data[i:]...), orientation, nil
+		case marker == 0xE1:
+			if payload, ok := bytes.CutPrefix(segment, exifHeader); ok {
+				orientation = exifOrientation(payload)
+			}
+		case isJPEGMetadata(marker):
+			// 去除
+		default:
+			out = append(append(out, 0xFF, marker), data[i:i+length]...)
+		}
+		i += length
+	}
+}
+
+// isJPEGMetadata 是否为去除的 JPEG 段：APP0、APP2、APP14 以外的 APPn 和注释段
+func isJPEGMetadata(marker byte) bool {
+	if marker == 0xFE {
+		return true
+	}
+	return marker >= 0xE0 && marker <= 0xEF && marker != 0xE0 && marker != 0xE2 && marker != 0xEE
+}
+
+// stripPNG 去除 PNG 中的元数据块，返回去除后的内容和 eXIf 块中的拍摄方向（没有时为 1）
+func stripPNG(data []byte) ([]byte, int, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, 0, fmt.Errorf("%w: 不是 PNG 文件", ErrInvalidImage)
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	orientation := 1
+	for i := len(pngSignature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, 0, fmt.Errorf("%w: PNG 文件不完整", ErrInvalidImage)
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		end := i + 12 + length
+		if length < 0 || end > len(data) || end < i {
+			return nil, 0, fmt.Errorf("%w: PNG 数据块长度错误", ErrInvalidImage)
+		}
+		if chunkType == "eXIf" {
+			orientation = exifOrientation(bytes.TrimPrefix(data[i+8:i+8+length], exifHeader))
+		}
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out, orientation, nil
+}
+
+// stripWebP 去除 WebP 中的 EXIF 和 XMP 块，返回去除后的内容和 EXIF 中的拍摄方向（没有时为 1）
+func stripWebP(data []byte) ([]byte, int, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, 0, fmt.Errorf("%w: 不是 WebP 文件", ErrInvalidImage)
+	}
+	riffEnd := min(8+int(binary.LittleEndian.Uint32(data[4:])), len(data))
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+	orientation := 1
+	for i := 12; i < riffEnd; {
+		if i+8 > riffEnd {
+			return nil, 0, fmt.Errorf("%w: WebP 文件不完整", ErrInvalidImage)
+		}
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size
+		if size < 0 || end > riffEnd || end < i {
+			return nil, 0, fmt.Errorf("%w: WebP 数据块长度错误", ErrInvalidImage)
+		}
+		// 数据块按偶数字节对齐
+		if size&1 == 1 && end < riffEnd {
+			end++
+		}
+
+		switch fourCC {
+		case "EXIF":
+			orientation = exifOrientation(bytes.TrimPrefix(data[i+8:i+8+size], exifHeader))
+		case "XMP ":
+			// 去除
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if size > 0 {
+				out[start+8] &^= webpMetadataFlags
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, orientation, nil
+}
+
+// exifOrientation 读取 EXIF（TIFF 格式）IFD0 中的拍摄方向（标签 0x0112），没有或无效时返回 1
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := range count {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		// 类型 3 为 SHORT，值直接保存在条目的值字段中
+		if order.Uint16(tiff[entry:]) != 0x0112 || order.Uint16(tiff[entry+2:]) != 3 {
+			continue
+		}
+		if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+			return orientation
+		}
+		return 1
+	}
+	return 1
+}
@@ -0,0 +1,101 @@
+package imaging
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// toNRGBA 转换为 NRGBA 格式，便于按像素旋转和缩放
+func toNRGBA(src image.Image) *image.NRGBA {
+	if img, ok := src.(*image.NRGBA); ok && img.Rect.Min == (image.Point{}) {
+		return img
+	}
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// orient 按 EXIF 拍摄方向（1-8）旋转或翻转图片，使其按正常方向显示
+func orient(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// 5-8 需要交换宽高
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿左上-右下对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转 90°
+				dx, dy = h-1-y, x
+			case 7: // 沿右上-左下对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转 90°
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
+
+// resize 按选项缩放图片
+//   - contain：等比缩放到完整显示在 Width × Height 范围内，不放大
+//   - cover：等比缩放并居中裁剪，填满 Width × Height
+//   - fill：拉伸到 Width × Height
+//
+// 只指定宽或高时按原图比例计算另一边，三种方式都等同于 contain
+func resize(src *image.NRGBA, opts Options) *image.NRGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if opts.Width == 0 || opts.Height == 0 {
+		opts.Fit = FitContain
+	}
+
+	srcRect := src.Rect
+	var dw, dh int
+	switch opts.Fit {
+	case FitCover:
+		dw, dh = opts.Width, opts.Height
+		// 按目标比例从原图中心裁剪
+		scale := math.Max(float64(dw)/float64(w), float64(dh)/float64(h))
+		cw := min(w, max(1, int(math.Round(float64(dw)/scale))))
+		ch := min(h, max(1, int(math.Round(float64(dh)/scale))))
+		srcRect = image.Rect((w-cw)/2, (h-ch)/2, (w-cw)/2+cw, (h-ch)/2+ch)
+	case FitFill:
+		dw, dh = opts.Width, opts.Height
+	default:
+		scale := 1.0
+		if opts.Width > 0 {
+			scale = math.Min(scale, float64(opts.Width)/float64(w))
+		}
+		if opts.Height > 0 {
+			scale = math.Min(scale, float64(opts.Height)/float64(h))
+		}
+		dw = max(1, int(math.Round(float64(w)*scale)))
+		dh = max(1, int(math.Round(float64(h)*scale)))
+	}
+
+	if dw == w && dh == h && srcRect == src.Rect {
+		return src
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Src, nil)
+	return dst
+}
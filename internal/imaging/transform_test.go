@@ -0,0 +1,72 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrient(t *testing.T) {
+	// 3×2 的图片，左上角为红色；旋转或翻转后红色所在的位置
+	tests := []struct {
+		orientation int
+		size        image.Point
+		red         image.Point
+	}{
+		{1, image.Pt(3, 2), image.Pt(0, 0)},
+		{2, image.Pt(3, 2), image.Pt(2, 0)},
+		{3, image.Pt(3, 2), image.Pt(2, 1)},
+		{4, image.Pt(3, 2), image.Pt(0, 1)},
+		{5, image.Pt(2, 3), image.Pt(0, 0)},
+		{6, image.Pt(2, 3), image.Pt(1, 0)},
+		{7, image.Pt(2, 3), image.Pt(1, 2)},
+		{8, image.Pt(2, 3), image.Pt(0, 2)},
+	}
+	for _, tt := range tests {
+		img := orient(testImage(3, 2), tt.orientation)
+
+		assert.Equal(t, tt.size, img.Rect.Size(), "方向 %d", tt.orientation)
+		assert.Equal(t, red, img.NRGBAAt(tt.red.X, tt.red.Y), "方向 %d", tt.orientation)
+	}
+}
+
+func TestResize(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 300, 100))
+	// 左边三分之一为红色，cover 居中裁剪后不应出现红色
+	for y := range 100 {
+		for x := range 300 {
+			c := blue
+			if x < 100 {
+				c = red
+			}
+			src.SetNRGBA(x, y, c)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want image.Point
+	}{
+		{"contain 只指定宽", Options{Width: 150, Fit: FitContain}, image.Pt(150, 50)},
+		{"contain 只指定高", Options{Height: 20, Fit: FitContain}, image.Pt(60, 20)},
+		{"contain 宽高都指定", Options{Width: 60, Height: 60, Fit: FitContain}, image.Pt(60, 20)},
+		{"contain 不放大", Options{Width: 600, Height: 600, Fit: FitContain}, image.Pt(300, 100)},
+		{"cover 只指定宽时等同于 contain", Options{Width: 150, Fit: FitCover}, image.Pt(150, 50)},
+		{"cover", Options{Width: 50, Height: 50, Fit: FitCover}, image.Pt(50, 50)},
+		{"fill", Options{Width: 40, Height: 40, Fit: FitFill}, image.Pt(40, 40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resize(src, tt.opts).Rect.Size())
+		})
+	}
+
+	t.Run("cover 居中裁剪", func(t *testing.T) {
+		img := resize(src, Options{Width: 50, Height: 50, Fit: FitCover})
+		assert.Equal(t, color.NRGBA{B: 255, A: 255}, img.NRGBAAt(0, 25))
+		assert.Equal(t, color.NRGBA{B: 255, A: 255}, img.NRGBAAt(49, 25))
+	})
+}
@@ -0,0 +1,98 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMergePatch 测试 JSON Merge Patch（用例来自 RFC 7396 附录 A）
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"替换成员", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"添加成员", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null删除成员", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"零值不是删除", `{"age":25}`, `{"age":0}`, `{"age":0}`},
+		{"递归合并对象", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"数组整体替换", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"非对象补丁替换整个文档", `{"a":"foo"}`, `"bar"`, `"bar"`},
+		{"补丁中的null值不会写入新对象", `{"e":null}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}},"e":null}`},
+		{"大整数不损失精度", `{"id":9007199254740993}`, `{"name":"x"}`, `{"id":9007199254740993,"name":"x"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+
+	t.Run("无效的补丁", func(t *testing.T) {
+		_, err := MergePatch([]byte(`{}`), []byte(`{"a":`))
+		assert.ErrorIs(t, err, ErrInvalidPatch)
+	})
+}
+
+// TestApply 测试 JSON Patch（用例来自 RFC 6902 附录 A）
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"添加对象成员", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"插入数组元素", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"追加到数组末尾", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`},
+		{"删除对象成员", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"删除数组元素", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"替换值", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"替换为null", `{"age":25}`, `[{"op":"replace","path":"/age","value":null}]`, `{"age":null}`},
+		{"移动值", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"移动数组元素", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"复制值", `{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, `{"foo":{"bar":1},"baz":{"bar":2}}`},
+		{"test通过", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"转义的路径", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+		{"嵌套数组", `{"a":[[1,2],[3]]}`, `[{"op":"add","path":"/a/0/1","value":9},{"op":"remove","path":"/a/1/0"}]`, `{"a":[[1,9,2],[]]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(result))
+		})
+	}
+
+	errorTests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected error
+	}{
+		{"test未通过", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrTestFailed},
+		{"路径不存在", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, ErrPathNotFound},
+		{"添加到不存在的父节点", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ErrPathNotFound},
+		{"数组下标越界", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/2","value":"qux"}]`, ErrPathNotFound},
+		{"数组下标有前导零", `{"foo":["a","b"]}`, `[{"op":"remove","path":"/foo/01"}]`, ErrInvalidPatch},
+		{"缺少value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, ErrInvalidPatch},
+		{"不支持的操作", `{"foo":"bar"}`, `[{"op":"merge","path":"/foo","value":1}]`, ErrInvalidPatch},
+		{"路径不以斜杠开头", `{"foo":"bar"}`, `[{"op":"remove","path":"foo"}]`, ErrInvalidPatch},
+		{"移动到自身的子路径", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`, ErrInvalidPatch},
+		{"补丁不是数组", `{"foo":"bar"}`, `{"op":"remove","path":"/foo"}`, ErrInvalidPatch},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
@@ -9,7 +9,8 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-var Log *zap.Logger
+// Log 全局日志实例（InitLogger 之前为空操作日志，避免测试等场景下空指针）
+var Log = zap.NewNop()
 
 // InitLogger 初始化日志系统
 func InitLogger(cfg *config.LoggingConfig) *zap.Logger {
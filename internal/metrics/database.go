@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterDBStats 将数据库连接池状态（sql.DBStats）注册为 Prometheus 指标
+// 指标名为 go_sql_*，按 db_name 标签区分连接池（例如 primary、replica-0）
+func RegisterDBStats(pools map[string]*sql.DB) error {
+	for name, pool := range pools {
+		if err := prometheus.Register(collectors.NewDBStatsCollector(pool, name)); err != nil {
+			return fmt.Errorf("注册数据库连接池指标失败 %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// APIKey 机器客户端使用的API密钥（服务端只保存密钥哈希）
+// 密钥以所属用户的身份访问接口，可调用的接口还受 Scopes 限制
+type APIKey struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"` // 密钥所属用户，请求以该用户的身份和角色执行
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"` // 密钥前几位，用于识别密钥
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"` // 允许使用的权限，须是所属用户角色已被授予的权限
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedBy  int64      `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsRevoked 密钥是否已吊销
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// IsExpired 密钥是否已过期
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
+
+// HasScope 密钥是否允许使用指定权限
+func (k *APIKey) HasScope(permission string) bool {
+	return slices.Contains(k.Scopes, permission)
+}
+
+// CreateAPIKeyRequest 创建API密钥请求
+type CreateAPIKeyRequest struct {
+	UserID    int64    `json:"user_id" binding:"required,min=1"`
+	Name      string   `json:"name" binding:"required,min=1,max=100"`
+	Scopes    []string `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresIn int      `json:"expires_in" binding:"omitempty,min=1"` // 有效期（天），不填时使用配置的默认值
+}
+
+// CreateAPIKeyResponse 创建API密钥响应（密钥明文只返回这一次）
+type CreateAPIKeyResponse struct {
+	APIKey *APIKey `json:"api_key"`
+	Key    string  `json:"key"`
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// 审计事件类型
+const (
+	AuditUserCreate      = "user.create"       // 创建用户（包括注册和批量导入）
+	AuditUserUpdate      = "user.update"       // 更新用户（PUT / PATCH）
+	AuditUserDelete      = "user.delete"       // 软删除用户
+	AuditUserRestore     = "user.restore"      // 恢复已删除的用户
+	AuditUserPurge       = "user.purge"        // 彻底删除用户
+	AuditUserRoleAssign  = "user.role_assign"  // 为用户分配角色
+	AuditUserForceLogout = "user.force_logout" // 强制用户下线
+	AuditUserUnlock      = "user.unlock"       // 解锁被锁定的账户
+	AuditUserImport      = "user.import"       // 批量导入用户
+	AuditUserExport      = "user.export"       // 批量导出用户
+
+	AuditAuthLogin          = "auth.login"           // 登录（失败时 outcome 为 failure）
+	AuditAuthLogout         = "auth.logout"          // 退出登录
+	AuditAuthPasswordChange = "auth.password_change" // 修改密码
+	AuditAuthPasswordReset  = "auth.password_reset"  // 通过重置令牌设置新密码
+	AuditAuthMFAEnable      = "auth.mfa_enable"      // 开启两步验证
+	AuditAuthMFADisable     = "auth.mfa_disable"     // 关闭两步验证
+
+	AuditRoleCreate = "role.create" // 创建角色
+	AuditRoleUpdate = "role.update" // 更新角色及其权限
+	AuditRoleDelete = "role.delete" // 删除角色
+
+	AuditAPIKeyCreate = "apikey.create" // 创建API密钥
+	AuditAPIKeyRevoke = "apikey.revoke" // 吊销API密钥
+
+	AuditFileUpload = "file.upload" // 上传文件
+	AuditFileShare  = "file.share"  // 生成文件签名链接
+
+	AuditAccessUnauthorized = "access.unauthorized" // 认证失败（缺少或无效的凭据）
+	AuditAccessDenied       = "access.denied"       // 权限不足
+)
+
+// 审计事件结果
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+	AuditOutcomeDenied  = "denied"
+)
+
+// 审计对象类型
+const (
+	AuditTargetUser   = "user"
+	AuditTargetRole   = "role"
+	AuditTargetAPIKey = "api_key"
+	AuditTargetFile   = "file"
+)
+
+// AuditChange 一个字段修改前后的值（新建时 Before 为空，删除时 After 为空）
+type AuditChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// AuditLog 审计日志（只追加，不修改、不删除）
+// 每条日志的 Hash 由上一条日志的 Hash 和本条内容计算，修改或删除任一条日志都会使之后的哈希链校验失败
+type AuditLog struct {
+	ID         int64           `json:"id" db:"id"`
+	ActorID    int64           `json:"actor_id" db:"actor_id"` // 操作者，0 表示匿名（如登录失败、未认证的请求）
+	ActorRole  string          `json:"actor_role,omitempty" db:"actor_role"`
+	Action     string          `json:"action" db:"action"`
+	Outcome    string          `json:"outcome" db:"outcome"`
+	TargetType string          `json:"target_type,omitempty" db:"target_type"`
+	TargetID   string          `json:"target_id,omitempty" db:"target_id"`
+	RequestID  string          `json:"request_id,omitempty" db:"request_id"`
+	IP         string          `json:"ip,omitempty" db:"ip"`
+	UserAgent  string          `json:"user_agent,omitempty" db:"user_agent"`
+	Changes    json.RawMessage `json:"changes,omitempty" db:"changes"` // 修改的字段：{"字段": {"before": ..., "after": ...}}，敏感字段不记录值
+	Detail     string          `json:"detail,omitempty" db:"detail"`   // 补充说明，如被拒绝的请求路径
+	PrevHash   string          `json:"prev_hash" db:"prev_hash"`       // 上一条日志的哈希，第一条为空
+	Hash       string          `json:"hash" db:"hash"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// ComputeHash 计算日志的哈希：SHA-256(上一条哈希 + 本条内容)，不包括 ID 和 Hash 本身
+// 时间精确到秒并按 UTC 格式化，各数据库读出的时间计算结果一致
+func (l *AuditLog) ComputeHash() string {
+	content, _ := json.Marshal([]interface{}{
+		l.PrevHash,
+		l.ActorID,
+		l.ActorRole,
+		l.Action,
+		l.Outcome,
+		l.TargetType,
+		l.TargetID,
+		l.RequestID,
+		l.IP,
+		l.UserAgent,
+		string(l.Changes),
+		l.Detail,
+		l.CreatedAt.UTC().Format(time.RFC3339),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditFilter 审计日志过滤条件（为空的条件不生效）
+type AuditFilter struct {
+	ActorID    *int64     `form:"actor_id" binding:"omitempty,gte=0"`
+	Action     string     `form:"action"` // 事件类型，如 user.update；以 . 结尾时按前缀匹配，如 user.
+	Outcome    string     `form:"outcome" binding:"omitempty,oneof=success failure denied"`
+	TargetType string     `form:"target_type"`
+	TargetID   string     `form:"target_id"`
+	RequestID  string     `form:"request_id"`
+	IP         string     `form:"ip"`
+	Since      *time.Time `form:"since"` // 不早于（RFC 3339）
+	Until      *time.Time `form:"until"` // 早于（RFC 3339）
+}
+
+// ListAuditLogsRequest 审计日志列表请求（查询参数）
+type ListAuditLogsRequest struct {
+	AuditFilter
+	PageQuery
+}
+
+// AuditVerifyResult 哈希链校验结果
+type AuditVerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`             // 已校验的日志数
+	BrokenID int64  `json:"broken_id,omitempty"` // 第一条校验失败的日志
+	Reason   string `json:"reason,omitempty"`
+}
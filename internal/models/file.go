@@ -0,0 +1,84 @@
+package models
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File 上传文件的元数据（内容保存在配置的文件存储中）
+type File struct {
+	ID          int64     `json:"id" db:"id"`
+	StorageKey  string    `json:"-" db:"storage_key"`             // 文件存储中的键，由服务端生成
+	Name        string    `json:"name" db:"name"`                 // 上传时的文件名，只用于展示，不参与存储路径
+	ContentType string    `json:"content_type" db:"content_type"` // 按文件内容识别的类型
+	Size        int64     `json:"size" db:"size"`
+	Checksum    string    `json:"checksum" db:"checksum"` // 内容的 SHA-256（十六进制）
+	OwnerID     int64     `json:"owner_id" db:"owner_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// UploadPolicy 上传限制（按路由配置）
+type UploadPolicy struct {
+	MaxSize      int64    // 单个文件的最大字节数
+	MaxFiles     int      // 一次请求最多上传的文件数
+	AllowedTypes []string // 允许的类型，支持 image/* 形式的通配
+}
+
+// Allows 是否允许上传该类型的文件
+func (p UploadPolicy) Allows(contentType string) bool {
+	for _, allowed := range p.AllowedTypes {
+		if allowed == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateSignedURLRequest 生成文件签名链接请求
+// 图片变换参数与下载时的 w、h、fit、thumbnail 相同，参与签名，链接只能按指定的参数下载
+type CreateSignedURLRequest struct {
+	ExpiresIn int `json:"expires_in" binding:"omitempty,min=1"` // 有效期（秒），为空时使用默认有效期
+	ImageTransform
+}
+
+// SignedURL 文件签名链接，持有链接即可在有效期内下载文件，不需要登录
+type SignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ImageTransform 下载图片时的变换参数：指定宽高缩放，或使用配置的缩略图
+type ImageTransform struct {
+	Width     int    `json:"w,omitempty" form:"w" binding:"omitempty,min=1"`
+	Height    int    `json:"h,omitempty" form:"h" binding:"omitempty,min=1"`
+	Fit       string `json:"fit,omitempty" form:"fit" binding:"omitempty,oneof=cover contain fill"` // 缩放方式，默认 contain
+	Thumbnail string `json:"thumbnail,omitempty" form:"thumbnail"`                                  // 缩略图名称，不能与宽高同时指定
+}
+
+// IsZero 是否没有指定任何变换（下载原图）
+func (t ImageTransform) IsZero() bool {
+	return t == ImageTransform{}
+}
+
+// Values 返回变换参数对应的查询参数，只包含指定了的参数
+func (t ImageTransform) Values() url.Values {
+	values := url.Values{}
+	if t.Width > 0 {
+		values.Set("w", strconv.Itoa(t.Width))
+	}
+	if t.Height > 0 {
+		values.Set("h", strconv.Itoa(t.Height))
+	}
+	if t.Fit != "" {
+		values.Set("fit", t.Fit)
+	}
+	if t.Thumbnail != "" {
+		values.Set("thumbnail", t.Thumbnail)
+	}
+	return values
+}
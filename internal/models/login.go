@@ -14,9 +14,10 @@ type LoginRequest struct {
 
 // LoginResponse 登录响应结构体
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`  // 访问令牌
-	RefreshToken string `json:"refresh_token"` // 刷新令牌
-	User         User   `json:"user"`
+	AccessToken   string   `json:"access_token"`  // 访问令牌
+	RefreshToken  string   `json:"refresh_token"` // 刷新令牌
+	User          User     `json:"user"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // 登录时首次启用两步验证生成的恢复码（只返回这一次）
 }
 
 // RefreshTokenRequest 刷新令牌请求结构体
@@ -25,6 +26,8 @@ type RefreshTokenRequest struct {
 }
 
 // RefreshTokenResponse 刷新令牌响应结构体
+// 刷新令牌每次使用后都会轮换，客户端必须保存新的刷新令牌
 type RefreshTokenResponse struct {
-	AccessToken string `json:"access_token"` // 新的访问令牌
+	AccessToken  string `json:"access_token"`  // 新的访问令牌
+	RefreshToken string `json:"refresh_token"` // 新的刷新令牌（旧令牌立即失效）
 }
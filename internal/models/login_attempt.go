@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LoginAttempt 登录失败计数（按账户或客户端IP统计）
+type LoginAttempt struct {
+	Key          string     `json:"key" db:"attempt_key"` // account:<邮箱> 或 ip:<客户端IP>
+	Failures     int        `json:"failures" db:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at" db:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+}
+
+// IsLocked 在指定时间是否处于锁定状态
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// UserMFA 用户的两步验证（TOTP）设置
+// 获取密钥后处于待确认状态（Enabled 为 false），输入正确的验证码后才正式启用
+type UserMFA struct {
+	UserID       int64     `json:"user_id" db:"user_id"`
+	Secret       string    `json:"-" db:"secret"`
+	Enabled      bool      `json:"enabled" db:"enabled"`
+	LastUsedStep int64     `json:"-" db:"last_used_step"` // 最近一次使用的验证码所在时间步，用于拒绝重放
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MFAChallenge 登录时的两步验证令牌记录（服务端只保存令牌哈希，令牌只能使用一次）
+type MFAChallenge struct {
+	ID        int64      `json:"id" db:"id"`
+	UserID    int64      `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	Attempts  int        `json:"attempts" db:"attempts"` // 已尝试验证的次数
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsUsed 令牌是否已使用
+func (c *MFAChallenge) IsUsed() bool {
+	return c.UsedAt != nil
+}
+
+// IsExpired 令牌是否已过期
+func (c *MFAChallenge) IsExpired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// MFAChallengeResponse 需要两步验证时的登录响应
+// 客户端使用 mfa_token 和验证码调用 /api/v1/auth/mfa/verify 换取访问令牌
+type MFAChallengeResponse struct {
+	MFARequired   bool              `json:"mfa_required"`             // 固定为 true，用于区分普通的登录响应
+	MFAToken      string            `json:"mfa_token"`                // 两步验证令牌
+	ExpiresIn     int               `json:"expires_in"`               // 两步验证令牌有效期（秒）
+	SetupRequired bool              `json:"setup_required,omitempty"` // 账户必须启用两步验证但尚未设置
+	Setup         *MFASetupResponse `json:"setup,omitempty"`          // SetupRequired 为 true 时返回密钥
+}
+
+// MFASetupResponse 两步验证密钥
+type MFASetupResponse struct {
+	Secret          string `json:"secret"`           // Base32 密钥（无法扫码时手动输入）
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// 配置地址
+	QRPayload       string `json:"qr_payload"`       // 二维码内容，由客户端渲染为二维码供身份验证器扫描
+}
+
+// MFAStatusResponse 两步验证状态
+type MFAStatusResponse struct {
+	Enabled                bool `json:"enabled"`                  // 是否已启用
+	Required               bool `json:"required"`                 // 是否必须启用（管理员且开启了 mfa.require_for_admin）
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"` // 剩余可用的恢复码数量
+}
+
+// MFARecoveryCodesResponse 恢复码（只在生成时返回一次）
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFACodeRequest 提交验证码的请求结构体（启用两步验证、重新生成恢复码）
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFADisableRequest 关闭两步验证请求结构体（code 可以是验证码或恢复码）
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFAVerifyRequest 登录时的两步验证请求结构体（code 可以是验证码或恢复码）
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
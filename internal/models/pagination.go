@@ -0,0 +1,38 @@
+package models
+
+// 分页大小
+const (
+	DefaultPageLimit = 20  // 未指定 limit 时每页的条数
+	MaxPageLimit     = 100 // 每页条数上限
+)
+
+// PageQuery 分页与排序参数
+// 提供 cursor 时使用游标（keyset）分页并忽略 offset；否则使用 offset 分页
+type PageQuery struct {
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+	Cursor string `form:"cursor"`
+	Sort   string `form:"sort"` // 排序字段，前缀 - 表示倒序，如 -created_at
+}
+
+// Page 分页查询结果（handler 通过 response.SuccessWithPage 返回）
+type Page[T any] struct {
+	Items      []T
+	Total      int64  // 满足过滤条件的总数（不受分页影响）
+	Limit      int    // 本次使用的每页条数
+	Offset     int    // 本次使用的偏移量（游标分页时为 0）
+	NextCursor string // 下一页的游标，没有下一页时为空
+	HasMore    bool
+}
+
+// PageLimit 返回实际使用的每页条数（未指定时为默认值，不超过上限）
+func (q PageQuery) PageLimit() int {
+	switch {
+	case q.Limit <= 0:
+		return DefaultPageLimit
+	case q.Limit > MaxPageLimit:
+		return MaxPageLimit
+	default:
+		return q.Limit
+	}
+}
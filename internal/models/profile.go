@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MaxPreferencesSize 偏好设置 JSON 的最大字节数
+const MaxPreferencesSize = 16 << 10
+
+// UserProfile 用户扩展资料（与用户一对一，没有保存过时各字段为默认值）
+type UserProfile struct {
+	UserID       int64           `json:"-" db:"user_id"`
+	AvatarFileID *int64          `json:"avatar_file_id" db:"avatar_file_id"` // 头像（本人上传的图片文件ID），通过 /api/v1/files/{id} 下载；为空表示未设置
+	Bio          string          `json:"bio" db:"bio" binding:"max=500"`
+	Locale       string          `json:"locale" db:"locale" binding:"omitempty,bcp47_language_tag"` // 语言（BCP 47，如 zh-CN）
+	Timezone     string          `json:"timezone" db:"timezone" binding:"omitempty,timezone"`       // 时区（IANA 时区名，如 Asia/Shanghai）
+	Preferences  json.RawMessage `json:"preferences" db:"preferences"`                              // 客户端自定义的偏好设置（JSON 对象）
+	UpdatedAt    *time.Time      `json:"updated_at" db:"updated_at"`                                // 没有保存过时为空
+}
+
+// Me 当前登录用户及其扩展资料（/api/v1/users/me）
+type Me struct {
+	User
+	Profile *UserProfile `json:"profile"`
+}
+
+// PatchMeRequest 部分更新当前用户请求（PATCH /api/v1/users/me）
+// Patch 按 ContentType 作为 JSON Merge Patch 或 JSON Patch 应用到 Me 的 JSON 表示；
+// 可以修改 name、email、age 以及 profile 中的 avatar_file_id、bio、locale、timezone、preferences，其余字段只读
+type PatchMeRequest struct {
+	ContentType string
+	Patch       []byte
+
+	// ExpectedVersion 客户端读取时的版本号（来自 If-Match 请求头），与当前版本不一致时拒绝更新；0 表示不校验
+	ExpectedVersion int64
+	// CurrentPassword 当前密码（来自 X-Current-Password 请求头），修改邮箱时必须提供
+	CurrentPassword string
+}
+
+// DeleteMeRequest 注销当前用户请求，需要验证当前密码
+type DeleteMeRequest struct {
+	Password string `json:"password" binding:"required"`
+}
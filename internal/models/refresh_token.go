@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// RefreshToken 刷新令牌记录（服务端只保存令牌哈希）
+// 同一次登录产生的刷新令牌属于同一个令牌族（FamilyID），每次刷新都会轮换出新令牌
+type RefreshToken struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	FamilyID   string     `json:"family_id" db:"family_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	ReplacedBy *int64     `json:"replaced_by,omitempty" db:"replaced_by"` // 轮换后的新令牌ID
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IP         string     `json:"ip" db:"ip"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsRevoked 令牌是否已被撤销或轮换
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// IsExpired 令牌是否已过期
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// LogoutRequest 退出登录请求结构体
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	All          bool   `json:"all"` // 是否退出该用户的所有会话
+}
+
+// Session 登录会话（一个令牌族即一个会话，其当前有效的刷新令牌代表会话状态）
+type Session struct {
+	ID         string    `json:"id"`           // 会话ID（令牌族ID）
+	UserAgent  string    `json:"user_agent"`   // 最近一次登录/刷新的客户端
+	IP         string    `json:"ip"`           // 最近一次登录/刷新的IP
+	LastUsedAt time.Time `json:"last_used_at"` // 最近一次登录/刷新时间
+	ExpiresAt  time.Time `json:"expires_at"`   // 不再刷新时的会话过期时间
+	Current    bool      `json:"current"`      // 是否为当前请求所属的会话
+}
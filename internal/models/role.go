@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Role 角色
+type Role struct {
+	ID          int64     `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	BuiltIn     bool      `json:"built_in" db:"built_in"` // 内置角色不可删除、不可改名
+	Permissions []string  `json:"permissions"`            // 授予该角色的权限名
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Permission 权限（名称格式为 资源:操作，如 users:delete）
+type Permission struct {
+	ID          int64  `json:"id" db:"id"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+}
+
+// CreateRoleRequest 创建角色请求
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,min=2,max=64"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest 更新角色请求（Permissions 为 nil 时不修改授权）
+type UpdateRoleRequest struct {
+	Name        string   `json:"name" binding:"omitempty,min=2,max=64"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// AssignRoleRequest 为用户分配角色请求
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -8,14 +8,16 @@ import (
 
 // User 用户模型
 type User struct {
-	ID        int64     `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name" binding:"required,min=2,max=50"`
-	Email     string    `json:"email" db:"email" binding:"required,email"`
-	Password  string    `json:"password,omitempty" db:"password" binding:"required,min=6"`
-	Age       int       `json:"age" db:"age" binding:"gte=0,lte=150"`
-	Role      auth.Role `json:"role" db:"role"` // 角色：0=普通用户，1=超级管理员
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID        int64      `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name" binding:"required,min=2,max=50"`
+	Email     string     `json:"email" db:"email" binding:"required,email"`
+	Password  string     `json:"password,omitempty" db:"password" binding:"required,min=6"`
+	Age       int        `json:"age" db:"age" binding:"gte=0,lte=150"`
+	Role      auth.Role  `json:"role" db:"role"`       // 角色名：user / admin / 自定义角色
+	Version   int64      `json:"version" db:"version"` // 乐观锁版本号，每次更新加一（GET 以 ETag 返回，更新时通过 If-Match 校验）
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"` // 软删除时间，未删除时为空
 }
 
 // CreateUserRequest 创建用户请求
@@ -30,6 +32,38 @@ type CreateUserRequest struct {
 type UpdateUserRequest struct {
 	Name     string `json:"name" binding:"omitempty,min=2,max=50"`
 	Email    string `json:"email" binding:"omitempty,email"`
-	Password string `json:"password" binding:"omitempty,min=6"`
+	Password string `json:"password" binding:"omitempty,min=6"` // 仅管理员可为其他用户设置；修改自己的密码请使用 /api/v1/auth/password/change
 	Age      int    `json:"age" binding:"omitempty,gte=0,lte=150"`
+
+	// ExpectedVersion 客户端读取时的版本号（来自 If-Match 请求头），与当前版本不一致时拒绝更新；0 表示不校验
+	ExpectedVersion int64 `json:"-"`
+}
+
+// PatchUserRequest 部分更新用户请求（PATCH）
+// Patch 按 ContentType 作为 JSON Merge Patch（application/merge-patch+json）或 JSON Patch（application/json-patch+json）
+// 应用到用户的 JSON 表示；可以修改 name、email、age，有权限时还可以修改 role 和 password，其余字段只读
+type PatchUserRequest struct {
+	ContentType string
+	Patch       []byte
+
+	// ExpectedVersion 客户端读取时的版本号（来自 If-Match 请求头），与当前版本不一致时拒绝更新；0 表示不校验
+	ExpectedVersion int64
+}
+
+// UserFilter 用户列表过滤条件（为空的条件不生效）
+type UserFilter struct {
+	Name          string     `form:"name"`  // 名称包含
+	Email         string     `form:"email"` // 邮箱包含
+	Role          string     `form:"role"`  // 角色名
+	MinAge        *int       `form:"min_age" binding:"omitempty,gte=0"`
+	MaxAge        *int       `form:"max_age" binding:"omitempty,gte=0"`
+	CreatedAfter  *time.Time `form:"created_after"`  // 创建时间不早于（RFC 3339）
+	CreatedBefore *time.Time `form:"created_before"` // 创建时间早于（RFC 3339）
+	Deleted       bool       `form:"deleted"`        // 只列出已删除的用户（需要 users:restore 权限）
+}
+
+// ListUsersRequest 用户列表请求（查询参数）
+type ListUsersRequest struct {
+	UserFilter
+	PageQuery
 }
@@ -0,0 +1,57 @@
+package models
+
+// 批量导入导出的文件格式
+const (
+	UserFileFormatCSV    = "csv"    // 第一行为表头
+	UserFileFormatNDJSON = "ndjson" // 每行一个 JSON 对象
+)
+
+// 批量导入模式
+const (
+	ImportModeAtomic     = "atomic"      // 全部行校验通过才导入，在一个事务中写入；任一行失败时不导入任何用户
+	ImportModeBestEffort = "best_effort" // 导入校验通过的行，跳过失败的行
+)
+
+// 导入结果中每一行的状态
+const (
+	ImportRowCreated = "created" // 已创建
+	ImportRowValid   = "valid"   // 校验通过但未写入（试运行，或 atomic 模式下有其他行失败）
+	ImportRowFailed  = "failed"  // 校验或创建失败
+)
+
+// ImportUsersOptions 批量导入选项
+type ImportUsersOptions struct {
+	Format string `form:"format" binding:"omitempty,oneof=csv ndjson"`
+	Mode   string `form:"mode" binding:"omitempty,oneof=atomic best_effort"` // 默认 atomic
+	DryRun bool   `form:"dry_run"`                                           // 只校验，不写入
+}
+
+// ImportUserRecord 导入文件中的一行
+// CSV 按表头匹配列（name、email、password 必须提供，age、role 可选，其他列忽略），NDJSON 按 JSON 字段匹配
+type ImportUserRecord struct {
+	Name     string `json:"name" binding:"required,min=2,max=50"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+	Age      int    `json:"age" binding:"gte=0,lte=150"`
+	Role     string `json:"role"` // 为空时为普通用户，其他角色需要 roles:assign 权限
+}
+
+// ImportRowResult 一行的导入结果
+type ImportRowResult struct {
+	Line   int    `json:"line"` // 在文件中的行号（从 1 开始，CSV 的表头为第 1 行）
+	Email  string `json:"email,omitempty"`
+	Status string `json:"status"` // created / valid / failed
+	UserID int64  `json:"user_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportUsersResult 批量导入结果
+type ImportUsersResult struct {
+	Mode      string            `json:"mode"`
+	DryRun    bool              `json:"dry_run"`
+	Committed bool              `json:"committed"` // 是否写入了数据库
+	Total     int               `json:"total"`
+	Created   int               `json:"created"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
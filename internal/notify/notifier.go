@@ -0,0 +1,100 @@
+// Package notify 向用户发送通知（找回密码等）
+//
+// 目前提供写日志和写文件两种实现，便于本地开发时查看消息内容；
+// 接入邮件、短信等渠道时实现 Notifier 接口即可。
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gin/internal/config"
+	"gin/internal/i18n"
+	"gin/internal/logger"
+	"gin/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// Message 通知消息
+type Message struct {
+	To      string // 收件人（邮箱）
+	Subject string
+	Body    string
+}
+
+// Notifier 通知发送接口
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New 根据配置创建通知发送器
+func New(cfg *config.NotifierConfig) (Notifier, error) {
+	switch cfg.Type {
+	case "", "log":
+		return NewLogNotifier(), nil
+	case "file":
+		if cfg.File == "" {
+			return nil, fmt.Errorf("notifier.file 不能为空")
+		}
+		return NewFileNotifier(cfg.File), nil
+	default:
+		return nil, fmt.Errorf("不支持的通知类型: %s", cfg.Type)
+	}
+}
+
+// logNotifier 将消息写入应用日志（消息中可能包含重置令牌等敏感信息，仅用于本地开发）
+type logNotifier struct{}
+
+// NewLogNotifier 创建日志通知发送器
+func NewLogNotifier() Notifier {
+	return logNotifier{}
+}
+
+// Send 将消息写入日志
+func (logNotifier) Send(ctx context.Context, msg Message) error {
+	logger.Log.Info(i18n.LogMessage(i18n.LogNotificationSent),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
+
+// fileNotifier 将消息追加到文件
+type fileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileNotifier 创建文件通知发送器
+func NewFileNotifier(path string) Notifier {
+	return &fileNotifier{path: path}
+}
+
+// Send 将消息追加到文件末尾
+func (n *fileNotifier) Send(ctx context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.path), 0o755); err != nil {
+		return fmt.Errorf("创建通知目录失败: %w", err)
+	}
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("打开通知文件失败: %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	if err != nil {
+		return fmt.Errorf("写入通知文件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gin/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFileNotifier 测试文件通知发送器
+func TestFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail", "notifications.log")
+	notifier := NewFileNotifier(path)
+
+	ctx := context.Background()
+	require.NoError(t, notifier.Send(ctx, Message{To: "a@example.com", Subject: "主题1", Body: "内容1"}))
+	require.NoError(t, notifier.Send(ctx, Message{To: "b@example.com", Subject: "主题2", Body: "内容2"}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "To: a@example.com\nSubject: 主题1\n\n内容1")
+	assert.Contains(t, string(data), "To: b@example.com\nSubject: 主题2\n\n内容2")
+}
+
+// TestNew 测试根据配置创建通知发送器
+func TestNew(t *testing.T) {
+	t.Run("默认使用日志通知", func(t *testing.T) {
+		notifier, err := New(&config.NotifierConfig{})
+		require.NoError(t, err)
+		assert.NoError(t, notifier.Send(context.Background(), Message{To: "a@example.com"}))
+	})
+
+	t.Run("文件通知必须配置路径", func(t *testing.T) {
+		_, err := New(&config.NotifierConfig{Type: "file"})
+		assert.Error(t, err)
+	})
+
+	t.Run("不支持的类型", func(t *testing.T) {
+		_, err := New(&config.NotifierConfig{Type: "sms"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,57 @@
+// Package policy 资源级访问控制
+//
+// 路由上的 RequirePermission 只判断角色能否执行某类操作，
+// 本包在 service 层进一步判断调用者能否操作某个具体资源（例如只能修改自己的资料）。
+// 调用者身份从 context 中读取（见 auth.WithPrincipal）。
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	"gin/internal/auth"
+	"gin/internal/errors"
+)
+
+// Rule 访问规则：判断调用者能否访问属于 ownerID 的资源
+type Rule func(caller auth.Principal, ownerID int64) bool
+
+// Self 调用者是资源的所有者
+func Self(caller auth.Principal, ownerID int64) bool {
+	return caller.UserID > 0 && caller.UserID == ownerID
+}
+
+// Admin 调用者是超级管理员
+func Admin(caller auth.Principal, ownerID int64) bool {
+	return caller.Role.IsAdmin()
+}
+
+// Permission 调用者的角色被授予了指定权限
+func Permission(permission string) Rule {
+	return func(caller auth.Principal, ownerID int64) bool {
+		return caller.Role.HasPermission(permission)
+	}
+}
+
+// Authorize 检查调用者能否访问属于 ownerID 的资源，满足任一规则即允许
+// context 中没有调用者身份时返回 401，所有规则都不满足时返回 403
+func Authorize(ctx context.Context, ownerID int64, rules ...Rule) error {
+	caller, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return errors.NewUnauthorizedError("未认证", fmt.Errorf("no principal in context"))
+	}
+
+	for _, rule := range rules {
+		if rule(caller, ownerID) {
+			return nil
+		}
+	}
+
+	return errors.NewForbiddenError("无权访问该资源",
+		fmt.Errorf("user %d (role %s) cannot access resource owned by user %d", caller.UserID, caller.Role, ownerID))
+}
+
+// SelfOrAdmin 只允许资源所有者本人或超级管理员访问
+func SelfOrAdmin(ctx context.Context, ownerID int64) error {
+	return Authorize(ctx, ownerID, Self, Admin)
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+// ErrAPIKeyNotFound API密钥不存在或已吊销
+var ErrAPIKeyNotFound = errors.New("API密钥不存在或已吊销")
+
+// APIKeyRepository API密钥仓库接口
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	FindByID(ctx context.Context, id int64) (*models.APIKey, error)
+	// FindByHash 根据密钥哈希查找（包括已吊销、已过期的密钥，由调用方判断是否可用）
+	FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	// List 按创建时间倒序列出密钥，userID 为 0 时列出所有用户的密钥
+	List(ctx context.Context, userID int64) ([]*models.APIKey, error)
+	// Revoke 吊销密钥；密钥不存在或已吊销时返回 ErrAPIKeyNotFound
+	Revoke(ctx context.Context, id int64) error
+	// TouchLastUsed 更新最近使用时间；距上次更新不足 interval 时跳过，避免每个请求都写数据库
+	TouchLastUsed(ctx context.Context, id int64, now time.Time, interval time.Duration) error
+}
+
+// apiKeyColumns 查询API密钥时的列
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_by, created_at"
+
+// apiKeyRepository API密钥仓库实现
+type apiKeyRepository struct {
+	db database.DB
+}
+
+// NewAPIKeyRepository 创建API密钥仓库
+func NewAPIKeyRepository(db database.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create 保存API密钥
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	key.CreatedAt = time.Now()
+
+	id, err := database.Conn(ctx, r.db).InsertContext(ctx,
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		key.UserID, key.Name, key.Prefix, key.KeyHash, encodeScopes(key.Scopes), key.ExpiresAt, key.CreatedBy, key.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("保存API密钥失败: %w", err)
+	}
+	key.ID = id
+
+	return key, nil
+}
+
+// FindByID 根据ID查找API密钥
+func (r *apiKeyRepository) FindByID(ctx context.Context, id int64) (*models.APIKey, error) {
+	return r.findOne(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE id = ?", id)
+}
+
+// FindByHash 根据密钥哈希查找API密钥
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	return r.findOne(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?", keyHash)
+}
+
+// List 列出API密钥
+func (r *apiKeyRepository) List(ctx context.Context, userID int64) ([]*models.APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys"
+	var args []interface{}
+	if userID > 0 {
+		query += " WHERE user_id = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := database.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询API密钥失败: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取API密钥失败: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历API密钥失败: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke 吊销API密钥
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL",
+		time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("吊销API密钥失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed 更新最近使用时间
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int64, now time.Time, interval time.Duration) error {
+	_, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at <= ?)",
+		now, id, now.Add(-interval),
+	)
+	if err != nil {
+		return fmt.Errorf("更新API密钥使用时间失败: %w", err)
+	}
+	return nil
+}
+
+// findOne 查询单个API密钥
+func (r *apiKeyRepository) findOne(ctx context.Context, query string, args ...interface{}) (*models.APIKey, error) {
+	key, err := scanAPIKey(database.Conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("查询API密钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// apiKeyScanner 兼容 *sql.Row 和 *sql.Rows
+type apiKeyScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey 读取一行API密钥
+func scanAPIKey(row apiKeyScanner) (*models.APIKey, error) {
+	var scopes string
+	var lastUsedAt, revokedAt sql.NullTime
+	key := &models.APIKey{}
+	if err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&key.ExpiresAt, &lastUsedAt, &revokedAt, &key.CreatedBy, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+	key.Scopes = decodeScopes(scopes)
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
+
+// encodeScopes 将权限列表保存为空格分隔的字符串
+func encodeScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// decodeScopes 解析空格分隔的权限列表
+func decodeScopes(scopes string) []string {
+	return strings.Fields(scopes)
+}
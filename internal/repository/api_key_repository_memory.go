@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gin/internal/models"
+)
+
+// memoryAPIKeyRepository 基于内存的API密钥仓库（用于测试和无数据库场景，重启后数据丢失）
+type memoryAPIKeyRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	keys   map[int64]*models.APIKey
+}
+
+// NewMemoryAPIKeyRepository 创建内存API密钥仓库
+func NewMemoryAPIKeyRepository() APIKeyRepository {
+	return &memoryAPIKeyRepository{
+		keys: make(map[int64]*models.APIKey),
+	}
+}
+
+// Create 保存API密钥
+func (r *memoryAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.KeyHash == key.KeyHash {
+			return nil, fmt.Errorf("保存API密钥失败: 密钥已存在")
+		}
+	}
+
+	r.nextID++
+	key.ID = r.nextID
+	key.CreatedAt = time.Now()
+	r.keys[key.ID] = copyAPIKey(key)
+
+	return key, nil
+}
+
+// FindByID 根据ID查找API密钥
+func (r *memoryAPIKeyRepository) FindByID(ctx context.Context, id int64) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return copyAPIKey(key), nil
+}
+
+// FindByHash 根据密钥哈希查找API密钥
+func (r *memoryAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			return copyAPIKey(key), nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+// List 列出API密钥
+func (r *memoryAPIKeyRepository) List(ctx context.Context, userID int64) ([]*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]*models.APIKey, 0)
+	for _, key := range r.keys {
+		if userID > 0 && key.UserID != userID {
+			continue
+		}
+		keys = append(keys, copyAPIKey(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return keys[i].ID > keys[j].ID
+	})
+	return keys, nil
+}
+
+// Revoke 吊销API密钥
+func (r *memoryAPIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.IsRevoked() {
+		return ErrAPIKeyNotFound
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return nil
+}
+
+// TouchLastUsed 更新最近使用时间
+func (r *memoryAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64, now time.Time, interval time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil
+	}
+	if key.LastUsedAt == nil || !key.LastUsedAt.After(now.Add(-interval)) {
+		key.LastUsedAt = &now
+	}
+	return nil
+}
+
+// copyAPIKey 复制API密钥，避免调用方修改仓库内的数据
+func copyAPIKey(key *models.APIKey) *models.APIKey {
+	copied := *key
+	copied.Scopes = slices.Clone(key.Scopes)
+	return &copied
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIKeyRepository 测试API密钥仓库（SQL 与内存实现行为一致）
+func TestAPIKeyRepository(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		ctx := context.Background()
+		userRepo := NewUserRepository(db)
+
+		repos := map[string]APIKeyRepository{
+			"sql":    NewAPIKeyRepository(db),
+			"memory": NewMemoryAPIKeyRepository(),
+		}
+
+		for name, repo := range repos {
+			t.Run(name, func(t *testing.T) {
+				owner, err := userRepo.Create(ctx, &models.User{
+					Name:     "服务账户",
+					Email:    "apikey-" + name + "@example.com",
+					Password: "hashed_password",
+				})
+				require.NoError(t, err)
+				other, err := userRepo.Create(ctx, &models.User{
+					Name:     "其他账户",
+					Email:    "apikey-other-" + name + "@example.com",
+					Password: "hashed_password",
+				})
+				require.NoError(t, err)
+
+				created, err := repo.Create(ctx, &models.APIKey{
+					UserID:    owner.ID,
+					Name:      "CI",
+					Prefix:    "gsk_abcdefgh",
+					KeyHash:   "hash-1-" + name,
+					Scopes:    []string{"users:read", "users:create"},
+					ExpiresAt: time.Now().Add(24 * time.Hour),
+					CreatedBy: owner.ID,
+				})
+				require.NoError(t, err)
+				assert.NotZero(t, created.ID)
+
+				_, err = repo.Create(ctx, &models.APIKey{
+					UserID:    other.ID,
+					Name:      "报表",
+					Prefix:    "gsk_12345678",
+					KeyHash:   "hash-2-" + name,
+					Scopes:    []string{"users:read"},
+					ExpiresAt: time.Now().Add(24 * time.Hour),
+					CreatedBy: owner.ID,
+				})
+				require.NoError(t, err)
+
+				found, err := repo.FindByHash(ctx, "hash-1-"+name)
+				require.NoError(t, err)
+				assert.Equal(t, created.ID, found.ID)
+				assert.Equal(t, []string{"users:read", "users:create"}, found.Scopes)
+				assert.Nil(t, found.LastUsedAt)
+				assert.False(t, found.IsRevoked())
+
+				_, err = repo.FindByHash(ctx, "not-exist")
+				assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+				// 按用户过滤
+				keys, err := repo.List(ctx, owner.ID)
+				require.NoError(t, err)
+				require.Len(t, keys, 1)
+				assert.Equal(t, "CI", keys[0].Name)
+
+				all, err := repo.List(ctx, 0)
+				require.NoError(t, err)
+				assert.GreaterOrEqual(t, len(all), 2)
+
+				// 最近使用时间在间隔内只更新一次
+				first := time.Now().Truncate(time.Second)
+				require.NoError(t, repo.TouchLastUsed(ctx, created.ID, first, time.Minute))
+				require.NoError(t, repo.TouchLastUsed(ctx, created.ID, first.Add(30*time.Second), time.Minute))
+				found, err = repo.FindByID(ctx, created.ID)
+				require.NoError(t, err)
+				require.NotNil(t, found.LastUsedAt)
+				assert.True(t, found.LastUsedAt.Equal(first))
+
+				require.NoError(t, repo.TouchLastUsed(ctx, created.ID, first.Add(2*time.Minute), time.Minute))
+				found, err = repo.FindByID(ctx, created.ID)
+				require.NoError(t, err)
+				assert.True(t, found.LastUsedAt.Equal(first.Add(2*time.Minute)))
+
+				// 只能吊销一次
+				require.NoError(t, repo.Revoke(ctx, created.ID))
+				assert.ErrorIs(t, repo.Revoke(ctx, created.ID), ErrAPIKeyNotFound)
+				assert.ErrorIs(t, repo.Revoke(ctx, 9999), ErrAPIKeyNotFound)
+
+				found, err = repo.FindByHash(ctx, "hash-1-"+name)
+				require.NoError(t, err)
+				assert.True(t, found.IsRevoked())
+			})
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+// ErrAuditChainConflict 并发追加审计日志时多次与其他写入冲突
+var ErrAuditChainConflict = errors.New("审计日志哈希链写入冲突")
+
+// auditAppendRetries 追加审计日志与其他实例冲突时的最大尝试次数
+const auditAppendRetries = 5
+
+// AuditLogRepository 审计日志仓库接口（只追加，不提供修改和删除）
+type AuditLogRepository interface {
+	// Append 追加一条审计日志：读取最后一条日志的哈希作为 PrevHash，计算本条的 Hash 后写入；
+	// prev_hash 唯一约束保证多个实例并发写入时哈希链不分叉，冲突时重新读取后重试
+	Append(ctx context.Context, entry *models.AuditLog) (*models.AuditLog, error)
+	// List 按过滤条件分页查询审计日志，支持 offset 与游标两种分页方式
+	List(ctx context.Context, filter models.AuditFilter, page models.PageQuery) (*models.Page[*models.AuditLog], error)
+	// ListAfter 按 ID 顺序返回 ID 大于 afterID 的至多 limit 条日志（用于校验哈希链）
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]*models.AuditLog, error)
+}
+
+// auditLogColumns 查询审计日志时的列
+const auditLogColumns = "id, actor_id, actor_role, action, outcome, target_type, target_id, request_id, ip, user_agent, changes, detail, prev_hash, hash, created_at"
+
+// auditLogRepository 审计日志仓库实现
+type auditLogRepository struct {
+	db database.DB
+	// mu 串行化本实例内的追加，避免同一实例内的请求互相冲突重试
+	mu sync.Mutex
+}
+
+// NewAuditLogRepository 创建审计日志仓库
+func NewAuditLogRepository(db database.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Append 追加审计日志
+// 在事务中调用时冲突无法重试（PostgreSQL 的事务在出错后不可继续使用），应在业务事务提交后记录
+func (r *auditLogRepository) Append(ctx context.Context, entry *models.AuditLog) (*models.AuditLog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, inTx := database.TxFromContext(ctx)
+	// 时间精确到秒，与各数据库的存储精度一致，读出后重新计算的哈希不变
+	entry.CreatedAt = time.Now().Truncate(time.Second)
+
+	for attempt := 1; ; attempt++ {
+		var prevHash string
+		err := database.Conn(ctx, r.db).QueryRowContext(ctx, "SELECT hash FROM audit_logs ORDER BY id DESC LIMIT 1").Scan(&prevHash)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("查询最后一条审计日志失败: %w", err)
+		}
+		entry.PrevHash = prevHash
+		entry.Hash = entry.ComputeHash()
+
+		id, err := database.Conn(ctx, r.db).InsertContext(ctx,
+			"INSERT INTO audit_logs (actor_id, actor_role, action, outcome, target_type, target_id, request_id, ip, user_agent, changes, detail, prev_hash, hash, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			entry.ActorID, entry.ActorRole, entry.Action, entry.Outcome, entry.TargetType, entry.TargetID,
+			entry.RequestID, entry.IP, entry.UserAgent, string(entry.Changes), entry.Detail,
+			entry.PrevHash, entry.Hash, entry.CreatedAt,
+		)
+		if err == nil {
+			entry.ID = id
+			return entry, nil
+		}
+		if !database.IsUniqueViolation(err) {
+			return nil, fmt.Errorf("保存审计日志失败: %w", err)
+		}
+		// 其他实例已使用同一条日志作为 PrevHash
+		if inTx || attempt >= auditAppendRetries {
+			return nil, ErrAuditChainConflict
+		}
+	}
+}
+
+// auditLogSortFields 审计日志允许排序的字段
+var auditLogSortFields = map[string]SortField{
+	"id":         {Column: "id"},
+	"created_at": {Column: "created_at", Time: true},
+}
+
+// defaultAuditLogSort 默认按写入顺序倒序
+const defaultAuditLogSort = "-id"
+
+// List 分页查询审计日志
+func (r *auditLogRepository) List(ctx context.Context, filter models.AuditFilter, page models.PageQuery) (*models.Page[*models.AuditLog], error) {
+	sort, err := ParseSort(page.Sort, auditLogSortFields, defaultAuditLogSort)
+	if err != nil {
+		return nil, err
+	}
+
+	qb := NewQueryBuilder("FROM audit_logs")
+	if filter.ActorID != nil {
+		qb.Where("actor_id = ?", *filter.ActorID)
+	}
+	if prefix, ok := strings.CutSuffix(filter.Action, "."); ok && prefix != "" {
+		qb.WhereHasPrefix("action", prefix+".")
+	} else if filter.Action != "" {
+		qb.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		qb.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.TargetType != "" {
+		qb.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		qb.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		qb.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.IP != "" {
+		qb.Where("ip = ?", filter.IP)
+	}
+	// 时间以服务器本地时区写入，转换为本地时间后比较（SQLite 按字符串比较时间）
+	if filter.Since != nil {
+		qb.Where("created_at >= ?", filter.Since.Local())
+	}
+	if filter.Until != nil {
+		qb.Where("created_at < ?", filter.Until.Local())
+	}
+
+	var total int64
+	countQuery, countArgs := qb.CountQuery()
+	if err := database.ReadConn(ctx, r.db).QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("统计审计日志数量失败: %w", err)
+	}
+
+	query, args, err := qb.SelectPage(auditLogColumns, sort, "id", page)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := r.query(ctx, database.ReadConn(ctx, r.db), query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildPage(logs, total, sort, page, func(log *models.AuditLog) (interface{}, int64) {
+		if sort.Name == "created_at" {
+			return log.CreatedAt, log.ID
+		}
+		return log.ID, log.ID
+	}), nil
+}
+
+// ListAfter 按 ID 顺序读取日志（从主库读取，避免副本延迟导致哈希链看起来断开）
+func (r *auditLogRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]*models.AuditLog, error) {
+	return r.query(ctx, database.Conn(ctx, r.db),
+		"SELECT "+auditLogColumns+" FROM audit_logs WHERE id > ? ORDER BY id LIMIT ?", afterID, limit)
+}
+
+// query 查询多条审计日志
+func (r *auditLogRepository) query(ctx context.Context, conn database.Executor, query string, args ...interface{}) ([]*models.AuditLog, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询审计日志失败: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*models.AuditLog
+	for rows.Next() {
+		var changes string
+		log := &models.AuditLog{}
+		if err := rows.Scan(&log.ID, &log.ActorID, &log.ActorRole, &log.Action, &log.Outcome, &log.TargetType, &log.TargetID,
+			&log.RequestID, &log.IP, &log.UserAgent, &changes, &log.Detail, &log.PrevHash, &log.Hash, &log.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取审计日志失败: %w", err)
+		}
+		if changes != "" {
+			log.Changes = json.RawMessage(changes)
+		}
+		logs = append(logs, log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历审计日志失败: %w", err)
+	}
+
+	return logs, nil
+}
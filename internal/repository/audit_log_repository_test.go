@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuditLogRepository 测试审计日志仓库
+func TestAuditLogRepository(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewAuditLogRepository(db)
+		ctx := context.Background()
+
+		entries := []*models.AuditLog{
+			{ActorID: 1, ActorRole: "admin", Action: models.AuditUserCreate, Outcome: models.AuditOutcomeSuccess, TargetType: models.AuditTargetUser, TargetID: "7",
+				RequestID: "req-1", IP: "10.0.0.1", Changes: json.RawMessage(`{"name":{"after":"张三"}}`)},
+			{ActorID: 1, ActorRole: "admin", Action: models.AuditUserDelete, Outcome: models.AuditOutcomeSuccess, TargetType: models.AuditTargetUser, TargetID: "7", RequestID: "req-2"},
+			{Action: models.AuditAuthLogin, Outcome: models.AuditOutcomeFailure, Detail: "密码错误, email=zhangsan@example.com", IP: "10.0.0.2"},
+		}
+		for _, entry := range entries {
+			_, err := repo.Append(ctx, entry)
+			require.NoError(t, err)
+		}
+
+		t.Run("追加的日志组成哈希链", func(t *testing.T) {
+			logs, err := repo.ListAfter(ctx, 0, 10)
+			require.NoError(t, err)
+			require.Len(t, logs, 3)
+
+			prevHash := ""
+			for _, log := range logs {
+				assert.Equal(t, prevHash, log.PrevHash)
+				assert.Equal(t, log.ComputeHash(), log.Hash, "读出后重新计算的哈希应该不变")
+				prevHash = log.Hash
+			}
+			assert.JSONEq(t, `{"name":{"after":"张三"}}`, string(logs[0].Changes))
+			assert.Nil(t, logs[1].Changes)
+		})
+
+		t.Run("按条件过滤", func(t *testing.T) {
+			page, err := repo.List(ctx, models.AuditFilter{Action: "user."}, models.PageQuery{})
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), page.Total)
+			assert.Equal(t, models.AuditUserDelete, page.Items[0].Action, "默认按写入顺序倒序")
+
+			anonymous := int64(0)
+			page, err = repo.List(ctx, models.AuditFilter{ActorID: &anonymous, Outcome: models.AuditOutcomeFailure}, models.PageQuery{})
+			require.NoError(t, err)
+			require.Len(t, page.Items, 1)
+			assert.Equal(t, "10.0.0.2", page.Items[0].IP)
+
+			since := time.Now().Add(time.Hour)
+			page, err = repo.List(ctx, models.AuditFilter{Since: &since}, models.PageQuery{})
+			require.NoError(t, err)
+			assert.Zero(t, page.Total)
+		})
+
+		t.Run("游标分页", func(t *testing.T) {
+			first, err := repo.List(ctx, models.AuditFilter{}, models.PageQuery{Limit: 2})
+			require.NoError(t, err)
+			require.True(t, first.HasMore)
+
+			second, err := repo.List(ctx, models.AuditFilter{}, models.PageQuery{Limit: 2, Cursor: first.NextCursor})
+			require.NoError(t, err)
+			require.Len(t, second.Items, 1)
+			assert.Equal(t, models.AuditUserCreate, second.Items[0].Action)
+		})
+
+		t.Run("修改日志后哈希不一致", func(t *testing.T) {
+			_, err := db.ExecContext(ctx, "UPDATE audit_logs SET detail = ? WHERE id = ?", "已篡改", entries[1].ID)
+			require.NoError(t, err)
+
+			logs, err := repo.ListAfter(ctx, entries[0].ID, 1)
+			require.NoError(t, err)
+			require.Len(t, logs, 1)
+			assert.NotEqual(t, logs[0].ComputeHash(), logs[0].Hash)
+		})
+	})
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+// ErrFileNotFound 文件不存在
+var ErrFileNotFound = errors.New("文件不存在")
+
+// FileRepository 文件元数据仓库接口
+type FileRepository interface {
+	Create(ctx context.Context, file *models.File) (*models.File, error)
+	// FindByID 根据ID查找文件，不存在时返回 ErrFileNotFound
+	FindByID(ctx context.Context, id int64) (*models.File, error)
+	// Delete 删除文件元数据，不存在时返回 ErrFileNotFound
+	Delete(ctx context.Context, id int64) error
+	// ListByOwner 查询用户上传的所有文件（彻底删除用户时用于删除文件内容）
+	ListByOwner(ctx context.Context, ownerID int64) ([]*models.File, error)
+	// DeleteByOwner 删除用户上传的所有文件元数据，返回删除数量
+	DeleteByOwner(ctx context.Context, ownerID int64) (int64, error)
+}
+
+// fileColumns 查询文件时的列
+const fileColumns = "id, storage_key, name, content_type, size, checksum, owner_id, created_at"
+
+// fileRepository 文件元数据仓库实现
+type fileRepository struct {
+	db database.DB
+}
+
+// NewFileRepository 创建文件元数据仓库
+func NewFileRepository(db database.DB) FileRepository {
+	return &fileRepository{db: db}
+}
+
+// Create 保存文件元数据
+func (r *fileRepository) Create(ctx context.Context, file *models.File) (*models.File, error) {
+	file.CreatedAt = time.Now()
+
+	id, err := database.Conn(ctx, r.db).InsertContext(ctx,
+		"INSERT INTO files (storage_key, name, content_type, size, checksum, owner_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		file.StorageKey, file.Name, file.ContentType, file.Size, file.Checksum, file.OwnerID, file.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("保存文件信息失败: %w", err)
+	}
+	file.ID = id
+
+	return file, nil
+}
+
+// FindByID 根据ID查找文件
+func (r *fileRepository) FindByID(ctx context.Context, id int64) (*models.File, error) {
+	file := &models.File{}
+	err := database.ReadConn(ctx, r.db).QueryRowContext(ctx, "SELECT "+fileColumns+" FROM files WHERE id = ?", id).Scan(
+		&file.ID, &file.StorageKey, &file.Name, &file.ContentType, &file.Size, &file.Checksum, &file.OwnerID, &file.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("查询文件信息失败: %w", err)
+	}
+	return file, nil
+}
+
+// Delete 删除文件元数据
+func (r *fileRepository) Delete(ctx context.Context, id int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM files WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("删除文件信息失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrFileNotFound
+	}
+	return nil
+}
+
+// ListByOwner 查询用户上传的所有文件
+func (r *fileRepository) ListByOwner(ctx context.Context, ownerID int64) ([]*models.File, error) {
+	rows, err := database.Conn(ctx, r.db).QueryContext(ctx, "SELECT "+fileColumns+" FROM files WHERE owner_id = ? ORDER BY id", ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件信息失败: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*models.File
+	for rows.Next() {
+		file := &models.File{}
+		if err := rows.Scan(&file.ID, &file.StorageKey, &file.Name, &file.ContentType, &file.Size, &file.Checksum, &file.OwnerID, &file.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描文件信息失败: %w", err)
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历文件信息失败: %w", err)
+	}
+	return files, nil
+}
+
+// DeleteByOwner 删除用户上传的所有文件元数据
+func (r *fileRepository) DeleteByOwner(ctx context.Context, ownerID int64) (int64, error) {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM files WHERE owner_id = ?", ownerID)
+	if err != nil {
+		return 0, fmt.Errorf("删除文件信息失败: %w", err)
+	}
+	return result.RowsAffected()
+}
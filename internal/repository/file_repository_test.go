@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gin/internal/database"
+	"gin/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFileRepository 测试文件元数据仓库
+func TestFileRepository(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewFileRepository(db)
+		ctx := context.Background()
+
+		created, err := repo.Create(ctx, &models.File{
+			StorageKey:  "2024/01/02/abc.png",
+			Name:        "头像.png",
+			ContentType: "image/png",
+			Size:        1024,
+			Checksum:    "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			OwnerID:     7,
+		})
+		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
+
+		t.Run("根据ID查找", func(t *testing.T) {
+			found, err := repo.FindByID(ctx, created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "2024/01/02/abc.png", found.StorageKey)
+			assert.Equal(t, "头像.png", found.Name)
+			assert.Equal(t, int64(1024), found.Size)
+			assert.Equal(t, int64(7), found.OwnerID)
+
+			_, err = repo.FindByID(ctx, created.ID+100)
+			assert.ErrorIs(t, err, ErrFileNotFound)
+		})
+
+		t.Run("存储键不能重复", func(t *testing.T) {
+			_, err := repo.Create(ctx, &models.File{StorageKey: "2024/01/02/abc.png", Name: "a", ContentType: "image/png", Checksum: "x"})
+			assert.Error(t, err)
+		})
+
+		t.Run("按上传者查询和删除", func(t *testing.T) {
+			other, err := repo.Create(ctx, &models.File{StorageKey: "2024/01/02/other.png", Name: "b", ContentType: "image/png", Checksum: "y", OwnerID: 8})
+			require.NoError(t, err)
+			_, err = repo.Create(ctx, &models.File{StorageKey: "2024/01/02/def.png", Name: "c", ContentType: "image/png", Checksum: "z", OwnerID: 7})
+			require.NoError(t, err)
+
+			files, err := repo.ListByOwner(ctx, 7)
+			require.NoError(t, err)
+			require.Len(t, files, 2)
+			assert.Equal(t, "2024/01/02/abc.png", files[0].StorageKey)
+			assert.Equal(t, "2024/01/02/def.png", files[1].StorageKey)
+
+			deleted, err := repo.DeleteByOwner(ctx, 8)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), deleted)
+			_, err = repo.FindByID(ctx, other.ID)
+			assert.ErrorIs(t, err, ErrFileNotFound)
+			_, err = repo.FindByID(ctx, created.ID)
+			assert.NoError(t, err, "其他用户的文件不受影响")
+		})
+
+		t.Run("删除", func(t *testing.T) {
+			require.NoError(t, repo.Delete(ctx, created.ID))
+			assert.ErrorIs(t, repo.Delete(ctx, created.ID), ErrFileNotFound)
+		})
+	})
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+// LoginAttemptStore 登录失败计数存储
+type LoginAttemptStore interface {
+	// Get 获取计数，不存在时返回失败次数为 0 的记录
+	Get(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// RecordFailure 原子地累加一次失败；距上次失败超过 window 时从 1 重新计数
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginAttempt, error)
+	// Lock 锁定到指定时间
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset 清除计数与锁定（登录成功、管理员解锁）
+	Reset(ctx context.Context, key string) error
+	// PurgeStale 清理最后一次失败早于 before 且未处于锁定状态的记录，返回清理数量
+	PurgeStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+// loginAttemptStore 基于数据库的登录失败计数存储
+type loginAttemptStore struct {
+	db database.DB
+}
+
+// NewLoginAttemptStore 创建基于数据库的登录失败计数存储
+func NewLoginAttemptStore(db database.DB) LoginAttemptStore {
+	return &loginAttemptStore{db: db}
+}
+
+// Get 获取计数
+func (s *loginAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var lockedUntil sql.NullTime
+	attempt := &models.LoginAttempt{Key: key}
+	err := database.Conn(ctx, s.db).QueryRowContext(ctx,
+		"SELECT failures, last_failed_at, locked_until FROM login_attempts WHERE attempt_key = ?", key,
+	).Scan(&attempt.Failures, &attempt.LastFailedAt, &lockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return attempt, nil
+		}
+		return nil, fmt.Errorf("查询登录失败计数失败: %w", err)
+	}
+	if lockedUntil.Valid {
+		attempt.LockedUntil = &lockedUntil.Time
+	}
+	return attempt, nil
+}
+
+// RecordFailure 累加一次失败
+// 通过 upsert 在一条语句中插入或累加，并发的失败不会互相覆盖
+func (s *loginAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	dialect := database.DialectOf(s.db)
+	query := "INSERT INTO login_attempts (attempt_key, failures, last_failed_at) VALUES (?, 1, ?)" + dialect.Upsert(
+		[]string{"attempt_key"},
+		"failures = CASE WHEN login_attempts.last_failed_at < ? THEN 1 ELSE login_attempts.failures + 1 END",
+		"last_failed_at = "+dialect.Excluded("last_failed_at"),
+	)
+	if _, err := database.Conn(ctx, s.db).ExecContext(ctx, query, key, now, now.Add(-window)); err != nil {
+		return nil, fmt.Errorf("记录登录失败失败: %w", err)
+	}
+	return s.Get(ctx, key)
+}
+
+// Lock 锁定到指定时间
+func (s *loginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	if _, err := database.Conn(ctx, s.db).ExecContext(ctx, "UPDATE login_attempts SET locked_until = ? WHERE attempt_key = ?", until, key); err != nil {
+		return fmt.Errorf("锁定失败: %w", err)
+	}
+	return nil
+}
+
+// Reset 清除计数与锁定
+func (s *loginAttemptStore) Reset(ctx context.Context, key string) error {
+	if _, err := database.Conn(ctx, s.db).ExecContext(ctx, "DELETE FROM login_attempts WHERE attempt_key = ?", key); err != nil {
+		return fmt.Errorf("清除登录失败计数失败: %w", err)
+	}
+	return nil
+}
+
+// PurgeStale 清理过期的计数
+func (s *loginAttemptStore) PurgeStale(ctx context.Context, before time.Time) (int64, error) {
+	result, err := database.Conn(ctx, s.db).ExecContext(ctx,
+		"DELETE FROM login_attempts WHERE last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)",
+		before, time.Now(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("清理登录失败计数失败: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gin/internal/models"
+)
+
+// memoryLoginAttemptStore 基于内存的登录失败计数存储（单实例部署或测试使用，重启后数据丢失）
+type memoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*models.LoginAttempt
+}
+
+// NewMemoryLoginAttemptStore 创建内存登录失败计数存储
+func NewMemoryLoginAttemptStore() LoginAttemptStore {
+	return &memoryLoginAttemptStore{
+		attempts: make(map[string]*models.LoginAttempt),
+	}
+}
+
+// Get 获取计数
+func (s *memoryLoginAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		found := *attempt
+		return &found, nil
+	}
+	return &models.LoginAttempt{Key: key}, nil
+}
+
+// RecordFailure 累加一次失败
+func (s *memoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &models.LoginAttempt{Key: key}
+		s.attempts[key] = attempt
+	}
+	if attempt.LastFailedAt.Before(now.Add(-window)) {
+		attempt.Failures = 1
+	} else {
+		attempt.Failures++
+	}
+	attempt.LastFailedAt = now
+
+	found := *attempt
+	return &found, nil
+}
+
+// Lock 锁定到指定时间
+func (s *memoryLoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		attempt.LockedUntil = &until
+	}
+	return nil
+}
+
+// Reset 清除计数与锁定
+func (s *memoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// PurgeStale 清理过期的计数
+func (s *memoryLoginAttemptStore) PurgeStale(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var purged int64
+	for key, attempt := range s.attempts {
+		if attempt.LastFailedAt.Before(before) && !attempt.IsLocked(now) {
+			delete(s.attempts, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
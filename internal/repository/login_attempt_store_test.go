@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gin/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoginAttemptStore 测试登录失败计数存储（SQL 与内存实现行为一致）
+func TestLoginAttemptStore(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		ctx := context.Background()
+		stores := map[string]LoginAttemptStore{
+			"sql":    NewLoginAttemptStore(db),
+			"memory": NewMemoryLoginAttemptStore(),
+		}
+
+		for name, store := range stores {
+			t.Run(name, func(t *testing.T) {
+				key := "account:" + name + "@example.com"
+				now := time.Now()
+				window := 15 * time.Minute
+
+				empty, err := store.Get(ctx, key)
+				require.NoError(t, err)
+				assert.Zero(t, empty.Failures)
+				assert.False(t, empty.IsLocked(now))
+
+				for i := 1; i <= 3; i++ {
+					attempt, err := store.RecordFailure(ctx, key, now.Add(time.Duration(i)*time.Second), window)
+					require.NoError(t, err)
+					assert.Equal(t, i, attempt.Failures)
+				}
+
+				// 超过统计窗口后重新计数
+				attempt, err := store.RecordFailure(ctx, key, now.Add(window+time.Minute), window)
+				require.NoError(t, err)
+				assert.Equal(t, 1, attempt.Failures)
+
+				require.NoError(t, store.Lock(ctx, key, now.Add(time.Hour)))
+				locked, err := store.Get(ctx, key)
+				require.NoError(t, err)
+				assert.True(t, locked.IsLocked(now))
+
+				// 锁定中的记录不会被清理
+				purged, err := store.PurgeStale(ctx, now.Add(2*window))
+				require.NoError(t, err)
+				assert.Zero(t, purged)
+
+				require.NoError(t, store.Reset(ctx, key))
+				reset, err := store.Get(ctx, key)
+				require.NoError(t, err)
+				assert.Zero(t, reset.Failures)
+				assert.Nil(t, reset.LockedUntil)
+
+				// 清理过期的计数
+				_, err = store.RecordFailure(ctx, "ip:"+name, now.Add(-2*window), window)
+				require.NoError(t, err)
+				purged, err = store.PurgeStale(ctx, now.Add(-window))
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), purged)
+			})
+		}
+	})
+}
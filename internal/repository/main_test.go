@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gin/internal/database"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTarget 仓库测试使用的数据库
+type testTarget struct {
+	name string
+	// open 返回已执行全部迁移的空数据库
+	open func(t *testing.T) database.DB
+}
+
+// testTargets 返回本地可用的测试数据库：
+//   - sqlite3：SQLite 内存数据库
+//   - postgres-sqlite：使用 PostgreSQL 方言的 SQLite 内存数据库，不依赖外部服务即可验证 $n 占位符、RETURNING 和 ON CONFLICT 写法
+//   - postgres / mysql：设置 TEST_POSTGRES_DSN / TEST_MYSQL_DSN 时，在真实数据库中为每个测试创建独立的 schema / 库
+func testTargets() []testTarget {
+	targets := []testTarget{
+		{name: "sqlite3", open: openSQLite},
+		{name: "postgres-sqlite", open: openPostgresSQLite},
+	}
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		targets = append(targets, testTarget{name: "postgres", open: func(t *testing.T) database.DB { return openPostgres(t, dsn) }})
+	}
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		targets = append(targets, testTarget{name: "mysql", open: func(t *testing.T) database.DB { return openMySQL(t, dsn) }})
+	}
+	return targets
+}
+
+// forEachTestDB 在每个测试数据库上以子测试（子测试名为数据库名）运行 fn，fn 返回后关闭数据库
+func forEachTestDB(t *testing.T, fn func(t *testing.T, db database.DB)) {
+	for _, target := range testTargets() {
+		t.Run(target.name, func(t *testing.T) {
+			t.Logf("测试数据库: %s", target.name)
+			db := target.open(t)
+			defer teardownTestDB(t, db)
+			fn(t, db)
+		})
+	}
+}
+
+// teardownTestDB 清理测试数据库
+func teardownTestDB(t *testing.T, db database.DB) {
+	if db != nil {
+		err := db.Close()
+		assert.NoError(t, err, "应该能关闭数据库")
+	}
+}
+
+// migrate 执行迁移初始化表结构
+func migrate(t *testing.T, db database.DB, driver string) database.DB {
+	_, err := database.NewMigrator(db, driver).Up(context.Background())
+	require.NoError(t, err, "应该能执行数据库迁移")
+	return db
+}
+
+// openSQLite 使用内存数据库进行测试
+func openSQLite(t *testing.T) database.DB {
+	db, err := database.InitDB("sqlite3", ":memory:")
+	require.NoError(t, err, "应该能创建测试数据库")
+	return migrate(t, db, "sqlite3")
+}
+
+// openPostgresSQLite 使用 PostgreSQL 方言的 SQLite 内存数据库（表结构使用 SQLite 的迁移文件）
+// PostgreSQL 迁移文件只在设置 TEST_POSTGRES_DSN 时执行，各方言迁移的结构一致性由 database 包的 TestMigrator_EmbeddedSchemaConsistent 检查
+func openPostgresSQLite(t *testing.T) database.DB {
+	dialect, err := database.DialectFor("postgres")
+	require.NoError(t, err)
+	db, err := database.InitDB("sqlite3", ":memory:", database.WithDialect(dialect))
+	require.NoError(t, err, "应该能创建测试数据库")
+	return migrate(t, db, "sqlite3")
+}
+
+// testSchemaSeq 真实数据库中测试 schema / 库名的序号
+var testSchemaSeq atomic.Int64
+
+// testSchemaName 生成本次测试独占的 schema / 库名
+func testSchemaName() string {
+	return fmt.Sprintf("gin_test_%d_%d", os.Getpid(), testSchemaSeq.Add(1))
+}
+
+// openPostgres 在 PostgreSQL 中创建独立的 schema，测试结束后删除
+func openPostgres(t *testing.T, dsn string) database.DB {
+	admin, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+
+	schema := testSchemaName()
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err, "应该能创建测试 schema")
+	t.Cleanup(func() {
+		_, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		assert.NoError(t, err)
+	})
+
+	// search_path 作为连接参数传入，连接池中的每个连接都使用该 schema
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		require.NoError(t, err)
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		dsn = u.String()
+	} else {
+		dsn += " search_path=" + schema
+	}
+
+	db, err := database.InitDB("postgres", dsn)
+	require.NoError(t, err, "应该能连接测试数据库")
+	return migrate(t, db, "postgres")
+}
+
+// openMySQL 在 MySQL 中创建独立的库，测试结束后删除
+func openMySQL(t *testing.T, dsn string) database.DB {
+	admin, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+
+	name := testSchemaName()
+	_, err = admin.Exec("CREATE DATABASE " + name + " DEFAULT CHARSET utf8mb4")
+	require.NoError(t, err, "应该能创建测试库")
+	t.Cleanup(func() {
+		_, err := admin.Exec("DROP DATABASE " + name)
+		assert.NoError(t, err)
+	})
+
+	cfg, err := mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	cfg.DBName = name
+	cfg.ParseTime = true
+
+	db, err := database.InitDB("mysql", cfg.FormatDSN())
+	require.NoError(t, err, "应该能连接测试数据库")
+	return migrate(t, db, "mysql")
+}
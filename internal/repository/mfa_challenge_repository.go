@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+// ErrMFAChallengeUsed 两步验证令牌已使用或尝试次数已用完
+var ErrMFAChallengeUsed = errors.New("两步验证令牌已失效")
+
+// MFAChallengeRepository 登录两步验证令牌仓库接口
+type MFAChallengeRepository interface {
+	Create(ctx context.Context, challenge *models.MFAChallenge) (*models.MFAChallenge, error)
+	FindByHash(ctx context.Context, tokenHash string) (*models.MFAChallenge, error)
+	// IncrementAttempts 累加一次尝试次数；令牌已使用或已尝试 maxAttempts 次时返回 ErrMFAChallengeUsed
+	IncrementAttempts(ctx context.Context, id int64, maxAttempts int) error
+	// MarkUsed 将未使用的令牌标记为已使用；令牌已被使用时返回 ErrMFAChallengeUsed
+	MarkUsed(ctx context.Context, id int64) error
+	// PurgeExpired 清理已过期的令牌，返回清理数量
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// mfaChallengeRepository 登录两步验证令牌仓库实现
+type mfaChallengeRepository struct {
+	db database.DB
+}
+
+// NewMFAChallengeRepository 创建登录两步验证令牌仓库
+func NewMFAChallengeRepository(db database.DB) MFAChallengeRepository {
+	return &mfaChallengeRepository{db: db}
+}
+
+// Create 保存两步验证令牌
+func (r *mfaChallengeRepository) Create(ctx context.Context, challenge *models.MFAChallenge) (*models.MFAChallenge, error) {
+	challenge.CreatedAt = time.Now()
+
+	id, err := database.Conn(ctx, r.db).InsertContext(ctx,
+		"INSERT INTO mfa_challenges (user_id, token_hash, attempts, expires_at, created_at) VALUES (?, ?, 0, ?, ?)",
+		challenge.UserID, challenge.TokenHash, challenge.ExpiresAt, challenge.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("保存两步验证令牌失败: %w", err)
+	}
+	challenge.ID = id
+	challenge.Attempts = 0
+
+	return challenge, nil
+}
+
+// FindByHash 根据令牌哈希查找两步验证令牌
+func (r *mfaChallengeRepository) FindByHash(ctx context.Context, tokenHash string) (*models.MFAChallenge, error) {
+	var usedAt sql.NullTime
+	challenge := &models.MFAChallenge{}
+	err := database.Conn(ctx, r.db).QueryRowContext(ctx,
+		"SELECT id, user_id, token_hash, attempts, expires_at, used_at, created_at FROM mfa_challenges WHERE token_hash = ?",
+		tokenHash,
+	).Scan(&challenge.ID, &challenge.UserID, &challenge.TokenHash, &challenge.Attempts, &challenge.ExpiresAt, &usedAt, &challenge.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("两步验证令牌不存在: %w", err)
+		}
+		return nil, fmt.Errorf("查询两步验证令牌失败: %w", err)
+	}
+	if usedAt.Valid {
+		challenge.UsedAt = &usedAt.Time
+	}
+
+	return challenge, nil
+}
+
+// IncrementAttempts 累加一次尝试次数
+// 在校验验证码之前调用，通过 attempts < ? 条件保证并发请求也不能超过尝试次数上限
+func (r *mfaChallengeRepository) IncrementAttempts(ctx context.Context, id int64, maxAttempts int) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = ? AND used_at IS NULL AND attempts < ?",
+		id, maxAttempts,
+	)
+	if err != nil {
+		return fmt.Errorf("更新两步验证令牌失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrMFAChallengeUsed
+	}
+	return nil
+}
+
+// MarkUsed 标记令牌已使用
+// 通过 used_at IS NULL 条件保证并发验证时只有一个请求能换取访问令牌
+func (r *mfaChallengeRepository) MarkUsed(ctx context.Context, id int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE mfa_challenges SET used_at = ? WHERE id = ? AND used_at IS NULL",
+		time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("更新两步验证令牌失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrMFAChallengeUsed
+	}
+	return nil
+}
+
+// PurgeExpired 清理已过期的令牌
+func (r *mfaChallengeRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM mfa_challenges WHERE expires_at <= ?", time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("清理两步验证令牌失败: %w", err)
+	}
+	return result.RowsAffected()
+}
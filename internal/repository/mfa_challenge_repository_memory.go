@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gin/internal/models"
+)
+
+// memoryMFAChallengeRepository 基于内存的登录两步验证令牌仓库（用于测试和无数据库场景，重启后数据丢失）
+type memoryMFAChallengeRepository struct {
+	mu         sync.Mutex
+	nextID     int64
+	challenges map[int64]*models.MFAChallenge
+}
+
+// NewMemoryMFAChallengeRepository 创建内存登录两步验证令牌仓库
+func NewMemoryMFAChallengeRepository() MFAChallengeRepository {
+	return &memoryMFAChallengeRepository{
+		challenges: make(map[int64]*models.MFAChallenge),
+	}
+}
+
+// Create 保存两步验证令牌
+func (r *memoryMFAChallengeRepository) Create(ctx context.Context, challenge *models.MFAChallenge) (*models.MFAChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.challenges {
+		if existing.TokenHash == challenge.TokenHash {
+			return nil, fmt.Errorf("保存两步验证令牌失败: 令牌已存在")
+		}
+	}
+
+	r.nextID++
+	challenge.ID = r.nextID
+	challenge.Attempts = 0
+	challenge.CreatedAt = time.Now()
+	stored := *challenge
+	r.challenges[challenge.ID] = &stored
+
+	return challenge, nil
+}
+
+// FindByHash 根据令牌哈希查找两步验证令牌
+func (r *memoryMFAChallengeRepository) FindByHash(ctx context.Context, tokenHash string) (*models.MFAChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, challenge := range r.challenges {
+		if challenge.TokenHash == tokenHash {
+			found := *challenge
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("两步验证令牌不存在")
+}
+
+// IncrementAttempts 累加一次尝试次数
+func (r *memoryMFAChallengeRepository) IncrementAttempts(ctx context.Context, id int64, maxAttempts int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.challenges[id]
+	if !ok || challenge.IsUsed() || challenge.Attempts >= maxAttempts {
+		return ErrMFAChallengeUsed
+	}
+	challenge.Attempts++
+	return nil
+}
+
+// MarkUsed 标记令牌已使用
+func (r *memoryMFAChallengeRepository) MarkUsed(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.challenges[id]
+	if !ok || challenge.IsUsed() {
+		return ErrMFAChallengeUsed
+	}
+	now := time.Now()
+	challenge.UsedAt = &now
+	return nil
+}
+
+// PurgeExpired 清理已过期的令牌
+func (r *memoryMFAChallengeRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var purged int64
+	for id, challenge := range r.challenges {
+		if challenge.IsExpired(now) {
+			delete(r.challenges, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+var (
+	// ErrMFANotFound 用户未设置两步验证
+	ErrMFANotFound = errors.New("未设置两步验证")
+	// ErrMFACodeReplayed 验证码所在时间步已被使用过
+	ErrMFACodeReplayed = errors.New("验证码已使用")
+	// ErrRecoveryCodeInvalid 恢复码不存在或已使用
+	ErrRecoveryCodeInvalid = errors.New("恢复码无效")
+)
+
+// MFARepository 两步验证仓库接口（TOTP 密钥与恢复码）
+type MFARepository interface {
+	// FindByUserID 查询用户的两步验证设置；未设置时返回 ErrMFANotFound
+	FindByUserID(ctx context.Context, userID int64) (*models.UserMFA, error)
+	// SaveSecret 保存待确认的密钥（覆盖之前未确认的密钥）；已启用时返回错误
+	SaveSecret(ctx context.Context, userID int64, secret string) error
+	// Enable 确认启用两步验证
+	Enable(ctx context.Context, userID int64) error
+	// Delete 删除用户的两步验证设置和恢复码
+	Delete(ctx context.Context, userID int64) error
+	// MarkStepUsed 记录已使用的时间步；该时间步不晚于上次使用的时间步时返回 ErrMFACodeReplayed
+	MarkStepUsed(ctx context.Context, userID int64, step int64) error
+	// ReplaceRecoveryCodes 用新的恢复码替换用户所有恢复码
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	// UseRecoveryCode 使用一个恢复码；不存在或已使用时返回 ErrRecoveryCodeInvalid
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+	// CountRecoveryCodes 统计用户未使用的恢复码数量
+	CountRecoveryCodes(ctx context.Context, userID int64) (int, error)
+}
+
+// mfaRepository 两步验证仓库实现
+type mfaRepository struct {
+	db database.DB
+}
+
+// NewMFARepository 创建两步验证仓库
+func NewMFARepository(db database.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+// FindByUserID 查询用户的两步验证设置
+func (r *mfaRepository) FindByUserID(ctx context.Context, userID int64) (*models.UserMFA, error) {
+	mfa := &models.UserMFA{}
+	err := database.Conn(ctx, r.db).QueryRowContext(ctx,
+		"SELECT user_id, secret, enabled, last_used_step, created_at, updated_at FROM user_mfa WHERE user_id = ?",
+		userID,
+	).Scan(&mfa.UserID, &mfa.Secret, &mfa.Enabled, &mfa.LastUsedStep, &mfa.CreatedAt, &mfa.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMFANotFound
+		}
+		return nil, fmt.Errorf("查询两步验证设置失败: %w", err)
+	}
+	return mfa, nil
+}
+
+// SaveSecret 保存待确认的密钥
+// 只删除未启用的记录，已启用时插入会因主键冲突失败，避免覆盖正在使用的密钥
+func (r *mfaRepository) SaveSecret(ctx context.Context, userID int64, secret string) error {
+	if _, err := database.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM user_mfa WHERE user_id = ? AND enabled = ?", userID, false); err != nil {
+		return fmt.Errorf("保存两步验证密钥失败: %w", err)
+	}
+
+	now := time.Now()
+	_, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"INSERT INTO user_mfa (user_id, secret, enabled, last_used_step, created_at, updated_at) VALUES (?, ?, ?, 0, ?, ?)",
+		userID, secret, false, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("保存两步验证密钥失败: %w", err)
+	}
+	return nil
+}
+
+// Enable 确认启用两步验证
+func (r *mfaRepository) Enable(ctx context.Context, userID int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, "UPDATE user_mfa SET enabled = ?, updated_at = ? WHERE user_id = ?", true, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("启用两步验证失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrMFANotFound
+	}
+	return nil
+}
+
+// Delete 删除用户的两步验证设置和恢复码
+func (r *mfaRepository) Delete(ctx context.Context, userID int64) error {
+	if _, err := database.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除恢复码失败: %w", err)
+	}
+	if _, err := database.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM user_mfa WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除两步验证设置失败: %w", err)
+	}
+	return nil
+}
+
+// MarkStepUsed 记录已使用的时间步
+// 通过 last_used_step < ? 条件保证同一验证码（以及更早的验证码）只能使用一次
+func (r *mfaRepository) MarkStepUsed(ctx context.Context, userID int64, step int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE user_mfa SET last_used_step = ?, updated_at = ? WHERE user_id = ? AND last_used_step < ?",
+		step, time.Now(), userID, step,
+	)
+	if err != nil {
+		return fmt.Errorf("记录验证码使用失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrMFACodeReplayed
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes 用新的恢复码替换用户所有恢复码
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	if _, err := database.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除恢复码失败: %w", err)
+	}
+
+	now := time.Now()
+	for _, hash := range codeHashes {
+		if _, err := database.Conn(ctx, r.db).ExecContext(ctx,
+			"INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)",
+			userID, hash, now,
+		); err != nil {
+			return fmt.Errorf("保存恢复码失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode 使用一个恢复码
+// 通过 used_at IS NULL 条件保证并发请求中只有一个能使用成功
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE mfa_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		time.Now(), userID, codeHash,
+	)
+	if err != nil {
+		return fmt.Errorf("使用恢复码失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+// CountRecoveryCodes 统计用户未使用的恢复码数量
+func (r *mfaRepository) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := database.Conn(ctx, r.db).QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = ? AND used_at IS NULL", userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("统计恢复码失败: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gin/internal/models"
+)
+
+// memoryRecoveryCode 内存中的恢复码
+type memoryRecoveryCode struct {
+	hash string
+	used bool
+}
+
+// memoryMFARepository 基于内存的两步验证仓库（用于测试和无数据库场景，重启后数据丢失）
+type memoryMFARepository struct {
+	mu            sync.Mutex
+	settings      map[int64]*models.UserMFA
+	recoveryCodes map[int64][]*memoryRecoveryCode
+}
+
+// NewMemoryMFARepository 创建内存两步验证仓库
+func NewMemoryMFARepository() MFARepository {
+	return &memoryMFARepository{
+		settings:      make(map[int64]*models.UserMFA),
+		recoveryCodes: make(map[int64][]*memoryRecoveryCode),
+	}
+}
+
+// FindByUserID 查询用户的两步验证设置
+func (r *memoryMFARepository) FindByUserID(ctx context.Context, userID int64) (*models.UserMFA, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mfa, ok := r.settings[userID]
+	if !ok {
+		return nil, ErrMFANotFound
+	}
+	found := *mfa
+	return &found, nil
+}
+
+// SaveSecret 保存待确认的密钥
+func (r *memoryMFARepository) SaveSecret(ctx context.Context, userID int64, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.settings[userID]; ok && existing.Enabled {
+		return fmt.Errorf("保存两步验证密钥失败: 两步验证已启用")
+	}
+
+	now := time.Now()
+	r.settings[userID] = &models.UserMFA{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return nil
+}
+
+// Enable 确认启用两步验证
+func (r *memoryMFARepository) Enable(ctx context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mfa, ok := r.settings[userID]
+	if !ok {
+		return ErrMFANotFound
+	}
+	mfa.Enabled = true
+	mfa.UpdatedAt = time.Now()
+	return nil
+}
+
+// Delete 删除用户的两步验证设置和恢复码
+func (r *memoryMFARepository) Delete(ctx context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.settings, userID)
+	delete(r.recoveryCodes, userID)
+	return nil
+}
+
+// MarkStepUsed 记录已使用的时间步
+func (r *memoryMFARepository) MarkStepUsed(ctx context.Context, userID int64, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mfa, ok := r.settings[userID]
+	if !ok || mfa.LastUsedStep >= step {
+		return ErrMFACodeReplayed
+	}
+	mfa.LastUsedStep = step
+	mfa.UpdatedAt = time.Now()
+	return nil
+}
+
+// ReplaceRecoveryCodes 用新的恢复码替换用户所有恢复码
+func (r *memoryMFARepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	codes := make([]*memoryRecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, &memoryRecoveryCode{hash: hash})
+	}
+	r.recoveryCodes[userID] = codes
+	return nil
+}
+
+// UseRecoveryCode 使用一个恢复码
+func (r *memoryMFARepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, code := range r.recoveryCodes[userID] {
+		if code.hash == codeHash && !code.used {
+			code.used = true
+			return nil
+		}
+	}
+	return ErrRecoveryCodeInvalid
+}
+
+// CountRecoveryCodes 统计用户未使用的恢复码数量
+func (r *memoryMFARepository) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, code := range r.recoveryCodes[userID] {
+		if !code.used {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMFARepository 测试两步验证仓库（SQL 与内存实现行为一致）
+func TestMFARepository(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		ctx := context.Background()
+		userRepo := NewUserRepository(db)
+
+		repos := map[string]MFARepository{
+			"sql":    NewMFARepository(db),
+			"memory": NewMemoryMFARepository(),
+		}
+
+		for name, repo := range repos {
+			t.Run(name, func(t *testing.T) {
+				user, err := userRepo.Create(ctx, &models.User{
+					Name:     "两步验证用户",
+					Email:    "mfa-" + name + "@example.com",
+					Password: "hashed_password",
+				})
+				require.NoError(t, err)
+
+				_, err = repo.FindByUserID(ctx, user.ID)
+				assert.ErrorIs(t, err, ErrMFANotFound)
+
+				// 未确认的密钥可以被覆盖
+				require.NoError(t, repo.SaveSecret(ctx, user.ID, "SECRET1"))
+				require.NoError(t, repo.SaveSecret(ctx, user.ID, "SECRET2"))
+				mfa, err := repo.FindByUserID(ctx, user.ID)
+				require.NoError(t, err)
+				assert.Equal(t, "SECRET2", mfa.Secret)
+				assert.False(t, mfa.Enabled)
+
+				// 启用后不能再覆盖密钥
+				require.NoError(t, repo.Enable(ctx, user.ID))
+				assert.Error(t, repo.SaveSecret(ctx, user.ID, "SECRET3"))
+				mfa, err = repo.FindByUserID(ctx, user.ID)
+				require.NoError(t, err)
+				assert.True(t, mfa.Enabled)
+				assert.Equal(t, "SECRET2", mfa.Secret)
+
+				// 同一时间步和更早的时间步不能重复使用
+				require.NoError(t, repo.MarkStepUsed(ctx, user.ID, 100))
+				assert.ErrorIs(t, repo.MarkStepUsed(ctx, user.ID, 100), ErrMFACodeReplayed)
+				assert.ErrorIs(t, repo.MarkStepUsed(ctx, user.ID, 99), ErrMFACodeReplayed)
+				require.NoError(t, repo.MarkStepUsed(ctx, user.ID, 101))
+
+				// 恢复码只能使用一次
+				require.NoError(t, repo.ReplaceRecoveryCodes(ctx, user.ID, []string{"hash-a", "hash-b"}))
+				count, err := repo.CountRecoveryCodes(ctx, user.ID)
+				require.NoError(t, err)
+				assert.Equal(t, 2, count)
+
+				require.NoError(t, repo.UseRecoveryCode(ctx, user.ID, "hash-a"))
+				assert.ErrorIs(t, repo.UseRecoveryCode(ctx, user.ID, "hash-a"), ErrRecoveryCodeInvalid)
+				assert.ErrorIs(t, repo.UseRecoveryCode(ctx, user.ID, "hash-x"), ErrRecoveryCodeInvalid)
+				count, err = repo.CountRecoveryCodes(ctx, user.ID)
+				require.NoError(t, err)
+				assert.Equal(t, 1, count)
+
+				// 重新生成后旧恢复码失效
+				require.NoError(t, repo.ReplaceRecoveryCodes(ctx, user.ID, []string{"hash-c"}))
+				assert.ErrorIs(t, repo.UseRecoveryCode(ctx, user.ID, "hash-b"), ErrRecoveryCodeInvalid)
+
+				// 关闭两步验证时一并删除恢复码
+				require.NoError(t, repo.Delete(ctx, user.ID))
+				_, err = repo.FindByUserID(ctx, user.ID)
+				assert.ErrorIs(t, err, ErrMFANotFound)
+				count, err = repo.CountRecoveryCodes(ctx, user.ID)
+				require.NoError(t, err)
+				assert.Zero(t, count)
+				assert.ErrorIs(t, repo.Enable(ctx, user.ID), ErrMFANotFound)
+			})
+		}
+	})
+}
+
+// TestMFAChallengeRepository 测试登录两步验证令牌仓库（SQL 与内存实现行为一致）
+func TestMFAChallengeRepository(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		ctx := context.Background()
+		user, err := NewUserRepository(db).Create(ctx, &models.User{
+			Name:     "两步验证用户",
+			Email:    "challenge@example.com",
+			Password: "hashed_password",
+		})
+		require.NoError(t, err)
+
+		repos := map[string]MFAChallengeRepository{
+			"sql":    NewMFAChallengeRepository(db),
+			"memory": NewMemoryMFAChallengeRepository(),
+		}
+
+		for name, repo := range repos {
+			t.Run(name, func(t *testing.T) {
+				first, err := repo.Create(ctx, &models.MFAChallenge{
+					UserID:    user.ID,
+					TokenHash: "challenge-1-" + name,
+					ExpiresAt: time.Now().Add(5 * time.Minute),
+				})
+				require.NoError(t, err)
+				assert.NotZero(t, first.ID)
+
+				// 尝试次数达到上限后令牌失效
+				require.NoError(t, repo.IncrementAttempts(ctx, first.ID, 2))
+				require.NoError(t, repo.IncrementAttempts(ctx, first.ID, 2))
+				assert.ErrorIs(t, repo.IncrementAttempts(ctx, first.ID, 2), ErrMFAChallengeUsed)
+
+				found, err := repo.FindByHash(ctx, "challenge-1-"+name)
+				require.NoError(t, err)
+				assert.Equal(t, 2, found.Attempts)
+				assert.False(t, found.IsUsed())
+
+				// 令牌只能使用一次
+				require.NoError(t, repo.MarkUsed(ctx, first.ID))
+				assert.ErrorIs(t, repo.MarkUsed(ctx, first.ID), ErrMFAChallengeUsed)
+				assert.ErrorIs(t, repo.IncrementAttempts(ctx, first.ID, 5), ErrMFAChallengeUsed)
+
+				used, err := repo.FindByHash(ctx, "challenge-1-"+name)
+				require.NoError(t, err)
+				assert.True(t, used.IsUsed())
+
+				// 清理过期令牌
+				_, err = repo.Create(ctx, &models.MFAChallenge{
+					UserID:    user.ID,
+					TokenHash: "challenge-2-" + name,
+					ExpiresAt: time.Now().Add(-time.Minute),
+				})
+				require.NoError(t, err)
+				purged, err := repo.PurgeExpired(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), purged)
+				_, err = repo.FindByHash(ctx, "challenge-2-"+name)
+				assert.Error(t, err)
+
+				_, err = repo.FindByHash(ctx, "not-exist")
+				assert.Error(t, err)
+			})
+		}
+	})
+}
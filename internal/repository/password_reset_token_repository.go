@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+// ErrPasswordResetTokenUsed 重置密码令牌已被使用或已作废
+var ErrPasswordResetTokenUsed = errors.New("重置密码令牌已使用")
+
+// PasswordResetTokenRepository 重置密码令牌仓库接口
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) (*models.PasswordResetToken, error)
+	FindByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+	// MarkUsed 将未使用的令牌标记为已使用；令牌已被使用时返回 ErrPasswordResetTokenUsed
+	MarkUsed(ctx context.Context, id int64) error
+	// InvalidateByUserID 作废用户所有未使用的令牌
+	InvalidateByUserID(ctx context.Context, userID int64) error
+	// PurgeExpired 清理已过期的令牌，返回清理数量
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// passwordResetTokenRepository 重置密码令牌仓库实现
+type passwordResetTokenRepository struct {
+	db database.DB
+}
+
+// NewPasswordResetTokenRepository 创建重置密码令牌仓库
+func NewPasswordResetTokenRepository(db database.DB) PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{db: db}
+}
+
+// Create 保存重置密码令牌
+func (r *passwordResetTokenRepository) Create(ctx context.Context, token *models.PasswordResetToken) (*models.PasswordResetToken, error) {
+	token.CreatedAt = time.Now()
+
+	id, err := database.Conn(ctx, r.db).InsertContext(ctx,
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?)",
+		token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("保存重置密码令牌失败: %w", err)
+	}
+	token.ID = id
+
+	return token, nil
+}
+
+// FindByHash 根据令牌哈希查找重置密码令牌
+func (r *passwordResetTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var usedAt sql.NullTime
+	token := &models.PasswordResetToken{}
+	err := database.Conn(ctx, r.db).QueryRowContext(ctx,
+		"SELECT id, user_id, token_hash, expires_at, used_at, created_at FROM password_reset_tokens WHERE token_hash = ?",
+		tokenHash,
+	).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &usedAt, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("重置密码令牌不存在: %w", err)
+		}
+		return nil, fmt.Errorf("查询重置密码令牌失败: %w", err)
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	return token, nil
+}
+
+// MarkUsed 标记令牌已使用
+// 通过 used_at IS NULL 条件保证并发重置时只有一个请求能使用成功
+func (r *passwordResetTokenRepository) MarkUsed(ctx context.Context, id int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE password_reset_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL",
+		time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("更新重置密码令牌失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrPasswordResetTokenUsed
+	}
+
+	return nil
+}
+
+// InvalidateByUserID 作废用户所有未使用的令牌
+func (r *passwordResetTokenRepository) InvalidateByUserID(ctx context.Context, userID int64) error {
+	_, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE password_reset_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL",
+		time.Now(), userID,
+	)
+	if err != nil {
+		return fmt.Errorf("作废重置密码令牌失败: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired 清理已过期的令牌
+func (r *passwordResetTokenRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE expires_at <= ?", time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("清理重置密码令牌失败: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gin/internal/models"
+)
+
+// memoryPasswordResetTokenRepository 基于内存的重置密码令牌仓库（用于测试和无数据库场景，重启后数据丢失）
+type memoryPasswordResetTokenRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	tokens map[int64]*models.PasswordResetToken
+}
+
+// NewMemoryPasswordResetTokenRepository 创建内存重置密码令牌仓库
+func NewMemoryPasswordResetTokenRepository() PasswordResetTokenRepository {
+	return &memoryPasswordResetTokenRepository{
+		tokens: make(map[int64]*models.PasswordResetToken),
+	}
+}
+
+// Create 保存重置密码令牌
+func (r *memoryPasswordResetTokenRepository) Create(ctx context.Context, token *models.PasswordResetToken) (*models.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return nil, fmt.Errorf("保存重置密码令牌失败: 令牌已存在")
+		}
+	}
+
+	r.nextID++
+	token.ID = r.nextID
+	token.CreatedAt = time.Now()
+	stored := *token
+	r.tokens[token.ID] = &stored
+
+	return token, nil
+}
+
+// FindByHash 根据令牌哈希查找重置密码令牌
+func (r *memoryPasswordResetTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("重置密码令牌不存在")
+}
+
+// MarkUsed 标记令牌已使用
+func (r *memoryPasswordResetTokenRepository) MarkUsed(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.IsUsed() {
+		return ErrPasswordResetTokenUsed
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return nil
+}
+
+// InvalidateByUserID 作废用户所有未使用的令牌
+func (r *memoryPasswordResetTokenRepository) InvalidateByUserID(ctx context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range r.tokens {
+		if token.UserID == userID && !token.IsUsed() {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// PurgeExpired 清理已过期的令牌
+func (r *memoryPasswordResetTokenRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var purged int64
+	for id, token := range r.tokens {
+		if token.IsExpired(now) {
+			delete(r.tokens, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
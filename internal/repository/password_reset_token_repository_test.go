@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPasswordResetTokenRepository 测试重置密码令牌仓库（SQL 与内存实现行为一致）
+func TestPasswordResetTokenRepository(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		ctx := context.Background()
+		user, err := NewUserRepository(db).Create(ctx, &models.User{
+			Name:     "重置用户",
+			Email:    "reset@example.com",
+			Password: "hashed_password",
+		})
+		require.NoError(t, err)
+
+		repos := map[string]PasswordResetTokenRepository{
+			"sql":    NewPasswordResetTokenRepository(db),
+			"memory": NewMemoryPasswordResetTokenRepository(),
+		}
+
+		for name, repo := range repos {
+			t.Run(name, func(t *testing.T) {
+				first, err := repo.Create(ctx, &models.PasswordResetToken{
+					UserID:    user.ID,
+					TokenHash: "reset-1-" + name,
+					ExpiresAt: time.Now().Add(time.Hour),
+				})
+				require.NoError(t, err)
+				assert.NotZero(t, first.ID)
+
+				found, err := repo.FindByHash(ctx, "reset-1-"+name)
+				require.NoError(t, err)
+				assert.Equal(t, first.ID, found.ID)
+				assert.False(t, found.IsUsed())
+
+				// 令牌只能使用一次
+				require.NoError(t, repo.MarkUsed(ctx, first.ID))
+				assert.ErrorIs(t, repo.MarkUsed(ctx, first.ID), ErrPasswordResetTokenUsed)
+
+				used, err := repo.FindByHash(ctx, "reset-1-"+name)
+				require.NoError(t, err)
+				assert.True(t, used.IsUsed())
+
+				// 作废用户所有未使用的令牌
+				second, err := repo.Create(ctx, &models.PasswordResetToken{
+					UserID:    user.ID,
+					TokenHash: "reset-2-" + name,
+					ExpiresAt: time.Now().Add(time.Hour),
+				})
+				require.NoError(t, err)
+				require.NoError(t, repo.InvalidateByUserID(ctx, user.ID))
+				assert.ErrorIs(t, repo.MarkUsed(ctx, second.ID), ErrPasswordResetTokenUsed)
+
+				// 清理过期令牌
+				_, err = repo.Create(ctx, &models.PasswordResetToken{
+					UserID:    user.ID,
+					TokenHash: "reset-3-" + name,
+					ExpiresAt: time.Now().Add(-time.Minute),
+				})
+				require.NoError(t, err)
+				purged, err := repo.PurgeExpired(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), purged)
+				_, err = repo.FindByHash(ctx, "reset-3-"+name)
+				assert.Error(t, err)
+
+				_, err = repo.FindByHash(ctx, "not-exist")
+				assert.Error(t, err)
+			})
+		}
+	})
+}
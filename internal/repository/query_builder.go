@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gin/internal/models"
+)
+
+var (
+	// ErrInvalidSort 排序字段不在白名单中
+	ErrInvalidSort = errors.New("不支持的排序字段")
+	// ErrInvalidCursor 分页游标无法解析或与排序方式不匹配
+	ErrInvalidCursor = errors.New("分页游标无效")
+)
+
+// likeEscape LIKE 模式中的转义字符（MySQL、SQLite 与 PostgreSQL 中写法一致，避免使用反斜杠）
+const likeEscape = "!"
+
+// SortField 允许排序的字段
+type SortField struct {
+	Column string // SQL 列名（可带表别名），只能来自代码中的白名单
+	Time   bool   // 时间列：游标中的值按 RFC 3339 解析
+}
+
+// Sort 解析后的排序方式
+type Sort struct {
+	Name  string // 请求中的字段名（不含 - 前缀）
+	Field SortField
+	Desc  bool
+}
+
+// String 返回排序参数的字符串形式（倒序时带 - 前缀）
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Name
+	}
+	return s.Name
+}
+
+// ParseSort 按白名单解析排序参数，sort 为空时使用 defaultSort
+func ParseSort(sort string, fields map[string]SortField, defaultSort string) (Sort, error) {
+	if sort == "" {
+		sort = defaultSort
+	}
+	desc := strings.HasPrefix(sort, "-")
+	name := strings.TrimPrefix(sort, "-")
+
+	field, ok := fields[name]
+	if !ok {
+		return Sort{}, fmt.Errorf("%w: %s", ErrInvalidSort, name)
+	}
+	return Sort{Name: name, Field: field, Desc: desc}, nil
+}
+
+// pageCursor 游标内容：上一页最后一行的排序字段值和ID
+// 游标与排序方式绑定，换了排序方式后旧游标不再可用
+type pageCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    int64       `json:"id"`
+}
+
+// EncodeCursor 生成游标（base64url 编码的 JSON，客户端应将其视为不透明字符串）
+func EncodeCursor(sort Sort, value interface{}, id int64) string {
+	if t, ok := value.(time.Time); ok {
+		value = t.Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(pageCursor{Sort: sort.String(), Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游标，返回排序字段值和ID
+func decodeCursor(encoded string, sort Sort) (interface{}, int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var c pageCursor
+	if err := decoder.Decode(&c); err != nil || c.Sort != sort.String() {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	switch v := c.Value.(type) {
+	case string:
+		if !sort.Field.Time {
+			return v, c.ID, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return t, c.ID, nil
+	case json.Number:
+		if sort.Field.Time {
+			return nil, 0, ErrInvalidCursor
+		}
+		if n, err := v.Int64(); err == nil {
+			return n, c.ID, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return f, c.ID, nil
+	default:
+		return nil, 0, ErrInvalidCursor
+	}
+}
+
+// QueryBuilder 拼接带过滤条件、排序和分页的查询
+// 条件值全部通过占位符传入；列名只能来自代码（排序列来自 SortField 白名单），不能来自请求
+type QueryBuilder struct {
+	from  string
+	conds []string
+	args  []interface{}
+}
+
+// NewQueryBuilder 创建查询构建器，from 为 FROM 子句（可包含 JOIN），如 "FROM users u JOIN roles r ON r.id = u.role_id"
+func NewQueryBuilder(from string) *QueryBuilder {
+	return &QueryBuilder{from: from}
+}
+
+// Where 追加一个 AND 条件
+func (b *QueryBuilder) Where(cond string, args ...interface{}) *QueryBuilder {
+	b.conds = append(b.conds, cond)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// WhereContains 追加"列包含指定文本"条件（不区分大小写，转义 LIKE 通配符）
+// PostgreSQL 的 LIKE 区分大小写，统一比较小写形式，使各数据库的结果一致
+func (b *QueryBuilder) WhereContains(column, value string) *QueryBuilder {
+	return b.Where("LOWER("+column+") LIKE ? ESCAPE '"+likeEscape+"'", "%"+escapeLike(strings.ToLower(value))+"%")
+}
+
+// WhereHasPrefix 追加"列以指定文本开头"条件（转义 LIKE 通配符），用于取值为小写标识符的列
+func (b *QueryBuilder) WhereHasPrefix(column, prefix string) *QueryBuilder {
+	return b.Where(column+" LIKE ? ESCAPE '"+likeEscape+"'", escapeLike(prefix)+"%")
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(value string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(value)
+}
+
+// CountQuery 生成统计满足条件的总行数的查询
+func (b *QueryBuilder) CountQuery() (string, []interface{}) {
+	return "SELECT COUNT(*) " + b.from + b.where(b.conds), b.args
+}
+
+// SelectPage 生成分页查询
+// 按 sort 排序，idColumn 作为唯一的次排序列保证顺序稳定；
+// 提供游标时追加 keyset 条件，否则使用 OFFSET；多取一行用于判断是否还有下一页
+func (b *QueryBuilder) SelectPage(columns string, sort Sort, idColumn string, page models.PageQuery) (string, []interface{}, error) {
+	conds := append([]string(nil), b.conds...)
+	args := append([]interface{}(nil), b.args...)
+
+	op, dir := ">", "ASC"
+	if sort.Desc {
+		op, dir = "<", "DESC"
+	}
+
+	if page.Cursor != "" {
+		value, id, err := decodeCursor(page.Cursor, sort)
+		if err != nil {
+			return "", nil, err
+		}
+		col := sort.Field.Column
+		conds = append(conds, fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", col, op, col, idColumn, op))
+		args = append(args, value, value, id)
+	}
+
+	query := "SELECT " + columns + " " + b.from + b.where(conds) +
+		fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT ?", sort.Field.Column, dir, idColumn, dir)
+	args = append(args, page.PageLimit()+1)
+	if page.Cursor == "" && page.Offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, page.Offset)
+	}
+
+	return query, args, nil
+}
+
+// where 拼接 WHERE 子句
+func (b *QueryBuilder) where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// buildPage 组装分页结果：根据多取的一行判断是否还有下一页，并用本页最后一行生成下一页游标
+// key 返回一行的排序字段值和ID
+func buildPage[T any](items []T, total int64, sort Sort, page models.PageQuery, key func(T) (interface{}, int64)) *models.Page[T] {
+	limit := page.PageLimit()
+	result := &models.Page[T]{
+		Items: items,
+		Total: total,
+		Limit: limit,
+	}
+	if page.Cursor == "" {
+		result.Offset = page.Offset
+	}
+
+	if len(items) > limit {
+		result.Items = items[:limit]
+		result.HasMore = true
+		value, id := key(result.Items[limit-1])
+		result.NextCursor = EncodeCursor(sort, value, id)
+	}
+	if result.Items == nil {
+		result.Items = make([]T, 0)
+	}
+	return result
+}
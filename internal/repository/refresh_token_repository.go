@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+// ErrRefreshTokenRevoked 刷新令牌已被撤销或已轮换（再次使用即视为令牌复用）
+var ErrRefreshTokenRevoked = errors.New("刷新令牌已被撤销")
+
+// RefreshTokenRepository 刷新令牌仓库接口
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error)
+	FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// FindActiveByUserID 查找用户所有未撤销且未过期的刷新令牌（每个会话一个）
+	FindActiveByUserID(ctx context.Context, userID int64) ([]*models.RefreshToken, error)
+	// MarkReplaced 将未撤销的令牌标记为已被 replacedBy 轮换；令牌已撤销时返回 ErrRefreshTokenRevoked
+	MarkReplaced(ctx context.Context, id, replacedBy int64) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeByUserID(ctx context.Context, userID int64) error
+}
+
+// refreshTokenRepository 刷新令牌仓库实现
+type refreshTokenRepository struct {
+	db database.DB
+}
+
+// NewRefreshTokenRepository 创建刷新令牌仓库
+func NewRefreshTokenRepository(db database.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+// Create 保存刷新令牌
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error) {
+	token.CreatedAt = time.Now()
+
+	id, err := database.Conn(ctx, r.db).InsertContext(ctx,
+		"INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, user_agent, ip, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt, token.UserAgent, token.IP, token.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("保存刷新令牌失败: %w", err)
+	}
+	token.ID = id
+
+	return token, nil
+}
+
+// refreshTokenColumns 刷新令牌查询列
+const refreshTokenColumns = "id, user_id, family_id, token_hash, expires_at, revoked_at, replaced_by, user_agent, ip, created_at"
+
+// FindByHash 根据令牌哈希查找刷新令牌
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	query := "SELECT " + refreshTokenColumns + " FROM refresh_tokens WHERE token_hash = ?"
+
+	token, err := scanRefreshToken(database.Conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("刷新令牌不存在: %w", err)
+		}
+		return nil, fmt.Errorf("查询刷新令牌失败: %w", err)
+	}
+
+	return token, nil
+}
+
+// FindActiveByUserID 查找用户所有有效的刷新令牌
+func (r *refreshTokenRepository) FindActiveByUserID(ctx context.Context, userID int64) ([]*models.RefreshToken, error) {
+	query := "SELECT " + refreshTokenColumns + `
+		FROM refresh_tokens
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := database.Conn(ctx, r.db).QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("查询刷新令牌列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*models.RefreshToken
+	for rows.Next() {
+		token, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描刷新令牌数据失败: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历刷新令牌数据失败: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// rowScanner *sql.Row 与 *sql.Rows 共同的扫描接口
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRefreshToken 扫描一行刷新令牌数据
+func scanRefreshToken(row rowScanner) (*models.RefreshToken, error) {
+	var revokedAt sql.NullTime
+	var replacedBy sql.NullInt64
+	token := &models.RefreshToken{}
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&revokedAt,
+		&replacedBy,
+		&token.UserAgent,
+		&token.IP,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	if replacedBy.Valid {
+		token.ReplacedBy = &replacedBy.Int64
+	}
+
+	return token, nil
+}
+
+// MarkReplaced 标记令牌已被轮换
+// 通过 revoked_at IS NULL 条件保证并发刷新时只有一个请求能轮换成功
+func (r *refreshTokenRepository) MarkReplaced(ctx context.Context, id, replacedBy int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ?, replaced_by = ? WHERE id = ? AND revoked_at IS NULL",
+		time.Now(), replacedBy, id,
+	)
+	if err != nil {
+		return fmt.Errorf("轮换刷新令牌失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrRefreshTokenRevoked
+	}
+
+	return nil
+}
+
+// RevokeFamily 撤销整个令牌族
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL",
+		time.Now(), familyID,
+	)
+	if err != nil {
+		return fmt.Errorf("撤销令牌族失败: %w", err)
+	}
+	return nil
+}
+
+// RevokeByUserID 撤销用户的所有刷新令牌
+func (r *refreshTokenRepository) RevokeByUserID(ctx context.Context, userID int64) error {
+	_, err := database.Conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		time.Now(), userID,
+	)
+	if err != nil {
+		return fmt.Errorf("撤销用户刷新令牌失败: %w", err)
+	}
+	return nil
+}
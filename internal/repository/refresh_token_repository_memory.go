@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gin/internal/models"
+)
+
+// memoryRefreshTokenRepository 基于内存的刷新令牌仓库（用于测试和无数据库场景，重启后数据丢失）
+type memoryRefreshTokenRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	tokens map[int64]*models.RefreshToken
+}
+
+// NewMemoryRefreshTokenRepository 创建内存刷新令牌仓库
+func NewMemoryRefreshTokenRepository() RefreshTokenRepository {
+	return &memoryRefreshTokenRepository{
+		tokens: make(map[int64]*models.RefreshToken),
+	}
+}
+
+// Create 保存刷新令牌
+func (r *memoryRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return nil, fmt.Errorf("保存刷新令牌失败: 令牌已存在")
+		}
+	}
+
+	r.nextID++
+	token.ID = r.nextID
+	token.CreatedAt = time.Now()
+	stored := *token
+	r.tokens[token.ID] = &stored
+
+	return token, nil
+}
+
+// FindByHash 根据令牌哈希查找刷新令牌
+func (r *memoryRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("刷新令牌不存在")
+}
+
+// FindActiveByUserID 查找用户所有有效的刷新令牌
+func (r *memoryRefreshTokenRepository) FindActiveByUserID(ctx context.Context, userID int64) ([]*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var tokens []*models.RefreshToken
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.RevokedAt == nil && !token.IsExpired(now) {
+			found := *token
+			tokens = append(tokens, &found)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+
+	return tokens, nil
+}
+
+// MarkReplaced 标记令牌已被轮换
+func (r *memoryRefreshTokenRepository) MarkReplaced(ctx context.Context, id, replacedBy int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.RevokedAt != nil {
+		return ErrRefreshTokenRevoked
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	token.ReplacedBy = &replacedBy
+	return nil
+}
+
+// RevokeFamily 撤销整个令牌族
+func (r *memoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	r.revokeWhere(func(token *models.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+// RevokeByUserID 撤销用户的所有刷新令牌
+func (r *memoryRefreshTokenRepository) RevokeByUserID(ctx context.Context, userID int64) error {
+	r.revokeWhere(func(token *models.RefreshToken) bool { return token.UserID == userID })
+	return nil
+}
+
+// revokeWhere 撤销所有满足条件且未撤销的令牌
+func (r *memoryRefreshTokenRepository) revokeWhere(match func(token *models.RefreshToken) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range r.tokens {
+		if token.RevokedAt == nil && match(token) {
+			revokedAt := now
+			token.RevokedAt = &revokedAt
+		}
+	}
+}
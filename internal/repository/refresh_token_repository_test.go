@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRefreshTokenRepository 测试刷新令牌仓库（SQL 与内存实现行为一致）
+func TestRefreshTokenRepository(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		ctx := context.Background()
+		user, err := NewUserRepository(db).Create(ctx, &models.User{
+			Name:     "令牌用户",
+			Email:    "token@example.com",
+			Password: "hashed_password",
+		})
+		require.NoError(t, err)
+
+		repos := map[string]RefreshTokenRepository{
+			"sql":    NewRefreshTokenRepository(db),
+			"memory": NewMemoryRefreshTokenRepository(),
+		}
+
+		for name, repo := range repos {
+			t.Run(name, func(t *testing.T) {
+				first, err := repo.Create(ctx, &models.RefreshToken{
+					UserID:    user.ID,
+					FamilyID:  "family-" + name,
+					TokenHash: "hash-1-" + name,
+					ExpiresAt: time.Now().Add(time.Hour),
+				})
+				require.NoError(t, err)
+				assert.NotZero(t, first.ID)
+
+				found, err := repo.FindByHash(ctx, "hash-1-"+name)
+				require.NoError(t, err)
+				assert.Equal(t, first.ID, found.ID)
+				assert.False(t, found.IsRevoked())
+
+				second, err := repo.Create(ctx, &models.RefreshToken{
+					UserID:    user.ID,
+					FamilyID:  "family-" + name,
+					TokenHash: "hash-2-" + name,
+					ExpiresAt: time.Now().Add(time.Hour),
+				})
+				require.NoError(t, err)
+
+				// 第一次轮换成功，第二次视为复用
+				require.NoError(t, repo.MarkReplaced(ctx, first.ID, second.ID))
+				assert.ErrorIs(t, repo.MarkReplaced(ctx, first.ID, second.ID), ErrRefreshTokenRevoked)
+
+				replaced, err := repo.FindByHash(ctx, "hash-1-"+name)
+				require.NoError(t, err)
+				assert.True(t, replaced.IsRevoked())
+				require.NotNil(t, replaced.ReplacedBy)
+				assert.Equal(t, second.ID, *replaced.ReplacedBy)
+
+				// 撤销令牌族
+				require.NoError(t, repo.RevokeFamily(ctx, "family-"+name))
+				revoked, err := repo.FindByHash(ctx, "hash-2-"+name)
+				require.NoError(t, err)
+				assert.True(t, revoked.IsRevoked())
+
+				_, err = repo.FindByHash(ctx, "not-exist")
+				assert.Error(t, err)
+			})
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+var (
+	// ErrUnknownPermission 授权时引用了不存在的权限
+	ErrUnknownPermission = errors.New("权限不存在")
+	// ErrRoleInUse 角色仍被用户使用，不能删除
+	ErrRoleInUse = errors.New("角色仍被用户使用")
+)
+
+// RoleRepository 角色与权限仓库接口
+type RoleRepository interface {
+	FindAll(ctx context.Context) ([]*models.Role, error)
+	FindByID(ctx context.Context, id int64) (*models.Role, error)
+	FindByName(ctx context.Context, name string) (*models.Role, error)
+	// Create 创建角色并授予 role.Permissions 中的权限
+	Create(ctx context.Context, role *models.Role) (*models.Role, error)
+	// Update 更新角色名称、描述，并将授权整体替换为 role.Permissions
+	Update(ctx context.Context, id int64, role *models.Role) (*models.Role, error)
+	// Delete 删除角色及其授权；仍有用户使用该角色时返回 ErrRoleInUse
+	Delete(ctx context.Context, id int64) error
+	FindAllPermissions(ctx context.Context) ([]*models.Permission, error)
+	// LoadGrants 加载所有角色的授权关系，用于刷新权限缓存
+	LoadGrants(ctx context.Context) (map[auth.Role][]string, error)
+}
+
+// roleRepository 角色仓库实现
+type roleRepository struct {
+	db        database.DB
+	txManager database.TxManager
+}
+
+// NewRoleRepository 创建角色仓库
+func NewRoleRepository(db database.DB) RoleRepository {
+	return &roleRepository{db: db, txManager: database.NewTxManager(db)}
+}
+
+// roleColumns 角色查询列
+const roleColumns = "id, name, description, built_in, created_at, updated_at"
+
+// FindAll 查找所有角色
+func (r *roleRepository) FindAll(ctx context.Context) ([]*models.Role, error) {
+	rows, err := database.ReadConn(ctx, r.db).QueryContext(ctx, "SELECT "+roleColumns+" FROM roles ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("查询角色列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []*models.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描角色数据失败: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历角色数据失败: %w", err)
+	}
+
+	grants, err := r.LoadGrants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		role.Permissions = grants[auth.Role(role.Name)]
+	}
+
+	return roles, nil
+}
+
+// FindByID 根据ID查找角色
+func (r *roleRepository) FindByID(ctx context.Context, id int64) (*models.Role, error) {
+	return r.findOne(ctx, "id", id)
+}
+
+// FindByName 根据名称查找角色
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	return r.findOne(ctx, "name", name)
+}
+
+// findOne 按指定列查找单个角色及其权限
+func (r *roleRepository) findOne(ctx context.Context, column string, value interface{}) (*models.Role, error) {
+	role, err := scanRole(database.ReadConn(ctx, r.db).QueryRowContext(ctx, "SELECT "+roleColumns+" FROM roles WHERE "+column+" = ?", value))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("角色不存在: %w", err)
+		}
+		return nil, fmt.Errorf("查询角色失败: %w", err)
+	}
+
+	rows, err := database.ReadConn(ctx, r.db).QueryContext(ctx, `
+		SELECT p.name
+		FROM role_permissions rp
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE rp.role_id = ?
+		ORDER BY p.name
+	`, role.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询角色权限失败: %w", err)
+	}
+	defer rows.Close()
+
+	role.Permissions = []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("扫描角色权限失败: %w", err)
+		}
+		role.Permissions = append(role.Permissions, name)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历角色权限失败: %w", err)
+	}
+
+	return role, nil
+}
+
+// Create 创建角色
+func (r *roleRepository) Create(ctx context.Context, role *models.Role) (*models.Role, error) {
+	now := time.Now()
+
+	var id int64
+	err := r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		conn := database.Conn(ctx, r.db)
+		var err error
+		id, err = conn.InsertContext(ctx,
+			"INSERT INTO roles (name, description, built_in, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+			role.Name, role.Description, role.BuiltIn, now, now,
+		)
+		if err != nil {
+			return fmt.Errorf("创建角色失败: %w", err)
+		}
+
+		return setRolePermissions(ctx, conn, id, role.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(database.WithPrimary(ctx), id)
+}
+
+// Update 更新角色
+func (r *roleRepository) Update(ctx context.Context, id int64, role *models.Role) (*models.Role, error) {
+	err := r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		conn := database.Conn(ctx, r.db)
+		result, err := conn.ExecContext(ctx,
+			"UPDATE roles SET name = ?, description = ?, updated_at = ? WHERE id = ?",
+			role.Name, role.Description, time.Now(), id,
+		)
+		if err != nil {
+			return fmt.Errorf("更新角色失败: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("获取影响行数失败: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("角色不存在")
+		}
+
+		return setRolePermissions(ctx, conn, id, role.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(database.WithPrimary(ctx), id)
+}
+
+// Delete 删除角色
+func (r *roleRepository) Delete(ctx context.Context, id int64) error {
+	return r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		conn := database.Conn(ctx, r.db)
+
+		var users int
+		if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE role_id = ?", id).Scan(&users); err != nil {
+			return fmt.Errorf("查询角色用户数失败: %w", err)
+		}
+		if users > 0 {
+			return ErrRoleInUse
+		}
+
+		if _, err := conn.ExecContext(ctx, "DELETE FROM role_permissions WHERE role_id = ?", id); err != nil {
+			return fmt.Errorf("删除角色权限失败: %w", err)
+		}
+
+		result, err := conn.ExecContext(ctx, "DELETE FROM roles WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("删除角色失败: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("获取影响行数失败: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("角色不存在")
+		}
+		return nil
+	})
+}
+
+// FindAllPermissions 查找所有权限
+func (r *roleRepository) FindAllPermissions(ctx context.Context) ([]*models.Permission, error) {
+	rows, err := database.ReadConn(ctx, r.db).QueryContext(ctx, "SELECT id, name, description FROM permissions ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("查询权限列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var permissions []*models.Permission
+	for rows.Next() {
+		permission := &models.Permission{}
+		if err := rows.Scan(&permission.ID, &permission.Name, &permission.Description); err != nil {
+			return nil, fmt.Errorf("扫描权限数据失败: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历权限数据失败: %w", err)
+	}
+
+	return permissions, nil
+}
+
+// LoadGrants 加载所有角色的授权关系
+func (r *roleRepository) LoadGrants(ctx context.Context) (map[auth.Role][]string, error) {
+	rows, err := database.Conn(ctx, r.db).QueryContext(ctx, `
+		SELECT r.name, p.name
+		FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		ORDER BY r.name, p.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询授权关系失败: %w", err)
+	}
+	defer rows.Close()
+
+	grants := make(map[auth.Role][]string)
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, fmt.Errorf("扫描授权关系失败: %w", err)
+		}
+		grants[auth.Role(role)] = append(grants[auth.Role(role)], permission)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历授权关系失败: %w", err)
+	}
+
+	return grants, nil
+}
+
+// setRolePermissions 将角色的授权整体替换为指定权限
+func setRolePermissions(ctx context.Context, conn database.Executor, roleID int64, permissions []string) error {
+	if _, err := conn.ExecContext(ctx, "DELETE FROM role_permissions WHERE role_id = ?", roleID); err != nil {
+		return fmt.Errorf("清除角色权限失败: %w", err)
+	}
+
+	// 占位符只出现在比较条件中（不放在 SELECT 列表里），PostgreSQL 才能推断出参数类型
+	for _, permission := range permissions {
+		result, err := conn.ExecContext(ctx,
+			"INSERT INTO role_permissions (role_id, permission_id) SELECT r.id, p.id FROM roles r, permissions p WHERE r.id = ? AND p.name = ?",
+			roleID, permission,
+		)
+		if err != nil {
+			return fmt.Errorf("授予角色权限失败: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("获取影响行数失败: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, permission)
+		}
+	}
+	return nil
+}
+
+// scanRole 扫描角色数据
+func scanRole(row rowScanner) (*models.Role, error) {
+	role := &models.Role{}
+	err := row.Scan(&role.ID, &role.Name, &role.Description, &role.BuiltIn, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
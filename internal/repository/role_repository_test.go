@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gin/internal/auth"
+	"gin/internal/database"
+	"gin/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoleRepository 测试角色仓库
+func TestRoleRepository(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		ctx := context.Background()
+		repo := NewRoleRepository(db)
+
+		t.Run("内置角色与权限已初始化", func(t *testing.T) {
+			roles, err := repo.FindAll(ctx)
+			require.NoError(t, err)
+			require.Len(t, roles, 2)
+			assert.Equal(t, "user", roles[0].Name)
+			assert.True(t, roles[1].BuiltIn)
+
+			admin, err := repo.FindByName(ctx, "admin")
+			require.NoError(t, err)
+			assert.Contains(t, admin.Permissions, auth.PermissionUsersDelete)
+
+			permissions, err := repo.FindAllPermissions(ctx)
+			require.NoError(t, err)
+			assert.Len(t, permissions, len(admin.Permissions), "管理员拥有全部权限")
+
+			grants, err := repo.LoadGrants(ctx)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{auth.PermissionUsersRead, auth.PermissionUsersUpdate, auth.PermissionFilesUpload}, grants[auth.RoleUser])
+		})
+
+		var editor *models.Role
+		t.Run("创建角色并授权", func(t *testing.T) {
+			var err error
+			editor, err = repo.Create(ctx, &models.Role{
+				Name:        "editor",
+				Description: "编辑",
+				Permissions: []string{auth.PermissionUsersRead, auth.PermissionUsersUpdate},
+			})
+			require.NoError(t, err)
+			assert.False(t, editor.BuiltIn)
+			assert.ElementsMatch(t, []string{auth.PermissionUsersRead, auth.PermissionUsersUpdate}, editor.Permissions)
+
+			_, err = repo.Create(ctx, &models.Role{Name: "broken", Permissions: []string{"unknown:perm"}})
+			assert.ErrorIs(t, err, ErrUnknownPermission)
+			_, err = repo.FindByName(ctx, "broken")
+			assert.Error(t, err, "授权失败时角色不应被创建")
+		})
+
+		t.Run("更新角色替换授权", func(t *testing.T) {
+			updated, err := repo.Update(ctx, editor.ID, &models.Role{
+				Name:        "editor",
+				Description: "内容编辑",
+				Permissions: []string{auth.PermissionUsersDelete},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "内容编辑", updated.Description)
+			assert.Equal(t, []string{auth.PermissionUsersDelete}, updated.Permissions)
+		})
+
+		t.Run("仍被用户使用的角色不能删除", func(t *testing.T) {
+			userRepo := NewUserRepository(db)
+			user, err := userRepo.Create(ctx, &models.User{
+				Name:     "编辑",
+				Email:    "editor@example.com",
+				Password: "hashed_password",
+				Role:     auth.Role("editor"),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, auth.Role("editor"), user.Role)
+
+			assert.ErrorIs(t, repo.Delete(ctx, editor.ID), ErrRoleInUse)
+
+			user.Role = auth.RoleUser
+			_, err = userRepo.Update(ctx, user.ID, user)
+			require.NoError(t, err)
+
+			require.NoError(t, repo.Delete(ctx, editor.ID))
+			_, err = repo.FindByID(ctx, editor.ID)
+			assert.Error(t, err)
+		})
+
+		t.Run("不存在的角色不能分配给用户", func(t *testing.T) {
+			_, err := NewUserRepository(db).Create(ctx, &models.User{
+				Name:     "无角色",
+				Email:    "norole@example.com",
+				Password: "hashed_password",
+				Role:     auth.Role("missing"),
+			})
+			assert.Error(t, err)
+		})
+	})
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/database"
+)
+
+// 撤销记录类型
+const (
+	RevocationKindToken   = "token"   // 按 jti 撤销单个访问令牌
+	RevocationKindSession = "session" // 按 sid 撤销整个会话的访问令牌
+	RevocationKindUser    = "user"    // 撤销用户在撤销时间之前签发的所有访问令牌
+)
+
+// TokenRevocationStore 访问令牌撤销存储（AuthMiddleware 在校验签名后查询）
+// expiresAt 为撤销记录的保留期限，应不早于受影响令牌的最晚过期时间
+type TokenRevocationStore interface {
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error
+	RevokeUser(ctx context.Context, userID int64, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, claims *auth.UserClaims) (bool, error)
+	// PurgeExpired 清理已过保留期限的撤销记录，返回清理数量
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// isRevokedBy 判断令牌是否被某条撤销记录命中
+// 用户级撤销只影响撤销时间之前签发的令牌（iat 精度为秒，同一秒内签发的令牌也视为已撤销）
+func isRevokedBy(claims *auth.UserClaims, kind, value string, revokedAt time.Time) bool {
+	switch kind {
+	case RevocationKindToken:
+		return claims.ID != "" && value == claims.ID
+	case RevocationKindSession:
+		return claims.SessionID != "" && value == claims.SessionID
+	case RevocationKindUser:
+		if value != strconv.FormatInt(claims.UserID, 10) {
+			return false
+		}
+		if claims.IssuedAt == nil {
+			return true
+		}
+		return !claims.IssuedAt.Time.After(revokedAt)
+	default:
+		return false
+	}
+}
+
+// tokenRevocationStore 基于数据库的访问令牌撤销存储
+type tokenRevocationStore struct {
+	db database.DB
+}
+
+// NewTokenRevocationStore 创建基于数据库的访问令牌撤销存储
+func NewTokenRevocationStore(db database.DB) TokenRevocationStore {
+	return &tokenRevocationStore{db: db}
+}
+
+// RevokeToken 撤销单个访问令牌
+func (s *tokenRevocationStore) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	return s.revoke(ctx, RevocationKindToken, tokenID, expiresAt)
+}
+
+// RevokeSession 撤销会话的所有访问令牌
+func (s *tokenRevocationStore) RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	return s.revoke(ctx, RevocationKindSession, sessionID, expiresAt)
+}
+
+// RevokeUser 撤销用户当前所有访问令牌
+func (s *tokenRevocationStore) RevokeUser(ctx context.Context, userID int64, expiresAt time.Time) error {
+	return s.revoke(ctx, RevocationKindUser, strconv.FormatInt(userID, 10), expiresAt)
+}
+
+// revoke 写入撤销记录
+func (s *tokenRevocationStore) revoke(ctx context.Context, kind, value string, expiresAt time.Time) error {
+	_, err := database.Conn(ctx, s.db).ExecContext(ctx,
+		"INSERT INTO token_revocations (kind, value, revoked_at, expires_at) VALUES (?, ?, ?, ?)",
+		kind, value, time.Now(), expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("写入令牌撤销记录失败: %w", err)
+	}
+	return nil
+}
+
+// IsRevoked 检查访问令牌是否已被撤销
+func (s *tokenRevocationStore) IsRevoked(ctx context.Context, claims *auth.UserClaims) (bool, error) {
+	query := `
+		SELECT kind, value, revoked_at
+		FROM token_revocations
+		WHERE expires_at > ?
+		  AND ((kind = ? AND value = ?) OR (kind = ? AND value = ?) OR (kind = ? AND value = ?))
+	`
+
+	rows, err := database.Conn(ctx, s.db).QueryContext(ctx, query, time.Now(),
+		RevocationKindToken, claims.ID,
+		RevocationKindSession, claims.SessionID,
+		RevocationKindUser, strconv.FormatInt(claims.UserID, 10),
+	)
+	if err != nil {
+		return false, fmt.Errorf("查询令牌撤销记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind, value string
+		var revokedAt time.Time
+		if err := rows.Scan(&kind, &value, &revokedAt); err != nil {
+			return false, fmt.Errorf("扫描令牌撤销记录失败: %w", err)
+		}
+		if isRevokedBy(claims, kind, value, revokedAt) {
+			return true, nil
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("遍历令牌撤销记录失败: %w", err)
+	}
+
+	return false, nil
+}
+
+// PurgeExpired 清理过期的撤销记录
+func (s *tokenRevocationStore) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := database.Conn(ctx, s.db).ExecContext(ctx, "DELETE FROM token_revocations WHERE expires_at <= ?", time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("清理令牌撤销记录失败: %w", err)
+	}
+	return result.RowsAffected()
+}
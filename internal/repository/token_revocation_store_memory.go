@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"gin/internal/auth"
+)
+
+// revocationEntry 内存撤销记录
+type revocationEntry struct {
+	kind      string
+	value     string
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+// memoryTokenRevocationStore 基于内存的访问令牌撤销存储（单实例部署或测试使用）
+type memoryTokenRevocationStore struct {
+	mu      sync.RWMutex
+	entries []revocationEntry
+}
+
+// NewMemoryTokenRevocationStore 创建内存访问令牌撤销存储
+func NewMemoryTokenRevocationStore() TokenRevocationStore {
+	return &memoryTokenRevocationStore{}
+}
+
+// RevokeToken 撤销单个访问令牌
+func (s *memoryTokenRevocationStore) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	s.add(RevocationKindToken, tokenID, expiresAt)
+	return nil
+}
+
+// RevokeSession 撤销会话的所有访问令牌
+func (s *memoryTokenRevocationStore) RevokeSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	s.add(RevocationKindSession, sessionID, expiresAt)
+	return nil
+}
+
+// RevokeUser 撤销用户当前所有访问令牌
+func (s *memoryTokenRevocationStore) RevokeUser(ctx context.Context, userID int64, expiresAt time.Time) error {
+	s.add(RevocationKindUser, strconv.FormatInt(userID, 10), expiresAt)
+	return nil
+}
+
+// add 添加撤销记录
+func (s *memoryTokenRevocationStore) add(kind, value string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, revocationEntry{
+		kind:      kind,
+		value:     value,
+		revokedAt: time.Now(),
+		expiresAt: expiresAt,
+	})
+}
+
+// IsRevoked 检查访问令牌是否已被撤销
+func (s *memoryTokenRevocationStore) IsRevoked(ctx context.Context, claims *auth.UserClaims) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, entry := range s.entries {
+		if entry.expiresAt.After(now) && isRevokedBy(claims, entry.kind, entry.value, entry.revokedAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PurgeExpired 清理过期的撤销记录
+func (s *memoryTokenRevocationStore) PurgeExpired(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	kept := s.entries[:0]
+	for _, entry := range s.entries {
+		if entry.expiresAt.After(now) {
+			kept = append(kept, entry)
+		}
+	}
+	purged := int64(len(s.entries) - len(kept))
+	s.entries = kept
+
+	return purged, nil
+}
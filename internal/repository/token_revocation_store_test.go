@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/database"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenRevocationStore 测试访问令牌撤销存储（SQL 与内存实现行为一致）
+func TestTokenRevocationStore(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		ctx := context.Background()
+		stores := map[string]TokenRevocationStore{
+			"sql":    NewTokenRevocationStore(db),
+			"memory": NewMemoryTokenRevocationStore(),
+		}
+
+		claimsFor := func(userID int64, jti, sid string, issuedAt time.Time) *auth.UserClaims {
+			return &auth.UserClaims{
+				UserID:    userID,
+				SessionID: sid,
+				RegisteredClaims: jwt.RegisteredClaims{
+					ID:       jti,
+					IssuedAt: jwt.NewNumericDate(issuedAt),
+				},
+			}
+		}
+
+		for name, store := range stores {
+			t.Run(name, func(t *testing.T) {
+				expiresAt := time.Now().Add(time.Hour)
+				issuedAt := time.Now().Add(-time.Minute)
+
+				require.NoError(t, store.RevokeToken(ctx, "jti-1", expiresAt))
+				require.NoError(t, store.RevokeSession(ctx, "sid-1", expiresAt))
+				require.NoError(t, store.RevokeUser(ctx, 42, expiresAt))
+
+				cases := []struct {
+					name    string
+					claims  *auth.UserClaims
+					revoked bool
+				}{
+					{"按 jti 撤销", claimsFor(1, "jti-1", "sid-x", issuedAt), true},
+					{"按会话撤销", claimsFor(1, "jti-2", "sid-1", issuedAt), true},
+					{"按用户撤销", claimsFor(42, "jti-3", "sid-y", issuedAt), true},
+					{"撤销之后签发的令牌不受用户撤销影响", claimsFor(42, "jti-4", "sid-z", time.Now().Add(time.Minute)), false},
+					{"未撤销的令牌", claimsFor(2, "jti-5", "sid-2", issuedAt), false},
+				}
+				for _, tc := range cases {
+					revoked, err := store.IsRevoked(ctx, tc.claims)
+					require.NoError(t, err, tc.name)
+					assert.Equal(t, tc.revoked, revoked, tc.name)
+				}
+
+				// 过期的撤销记录会被清理且不再生效
+				require.NoError(t, store.RevokeToken(ctx, "jti-expired", time.Now().Add(-time.Second)))
+				revoked, err := store.IsRevoked(ctx, claimsFor(3, "jti-expired", "", issuedAt))
+				require.NoError(t, err)
+				assert.False(t, revoked)
+
+				purged, err := store.PurgeExpired(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), purged)
+			})
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gin/internal/database"
+	"gin/internal/models"
+)
+
+// ErrProfileNotFound 用户还没有保存过扩展资料
+var ErrProfileNotFound = errors.New("用户资料不存在")
+
+// UserProfileRepository 用户扩展资料仓库接口
+type UserProfileRepository interface {
+	// FindByUserID 查询用户的扩展资料；没有保存过时返回 ErrProfileNotFound
+	FindByUserID(ctx context.Context, userID int64) (*models.UserProfile, error)
+	// Save 插入或覆盖用户的扩展资料
+	Save(ctx context.Context, profile *models.UserProfile) (*models.UserProfile, error)
+}
+
+// userProfileRepository 用户扩展资料仓库实现
+type userProfileRepository struct {
+	db database.DB
+}
+
+// NewUserProfileRepository 创建用户扩展资料仓库
+func NewUserProfileRepository(db database.DB) UserProfileRepository {
+	return &userProfileRepository{db: db}
+}
+
+// FindByUserID 查询用户的扩展资料
+func (r *userProfileRepository) FindByUserID(ctx context.Context, userID int64) (*models.UserProfile, error) {
+	profile := &models.UserProfile{}
+	var avatarFileID sql.NullInt64
+	var preferences string
+	err := database.ReadConn(ctx, r.db).QueryRowContext(ctx,
+		"SELECT user_id, avatar_file_id, bio, locale, timezone, preferences, updated_at FROM user_profiles WHERE user_id = ?",
+		userID,
+	).Scan(&profile.UserID, &avatarFileID, &profile.Bio, &profile.Locale, &profile.Timezone, &preferences, &profile.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("查询用户资料失败: %w", err)
+	}
+	if avatarFileID.Valid {
+		profile.AvatarFileID = &avatarFileID.Int64
+	}
+	profile.Preferences = json.RawMessage(preferences)
+	return profile, nil
+}
+
+// Save 插入或覆盖用户的扩展资料
+// 通过 upsert 在一条语句中完成，首次保存与并发保存不会因主键冲突失败
+func (r *userProfileRepository) Save(ctx context.Context, profile *models.UserProfile) (*models.UserProfile, error) {
+	var avatarFileID sql.NullInt64
+	if profile.AvatarFileID != nil {
+		avatarFileID = sql.NullInt64{Int64: *profile.AvatarFileID, Valid: true}
+	}
+	preferences := string(profile.Preferences)
+	if preferences == "" {
+		preferences = "{}"
+	}
+
+	dialect := database.DialectOf(r.db)
+	query := "INSERT INTO user_profiles (user_id, avatar_file_id, bio, locale, timezone, preferences, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)" + dialect.Upsert(
+		[]string{"user_id"},
+		"avatar_file_id = "+dialect.Excluded("avatar_file_id"),
+		"bio = "+dialect.Excluded("bio"),
+		"locale = "+dialect.Excluded("locale"),
+		"timezone = "+dialect.Excluded("timezone"),
+		"preferences = "+dialect.Excluded("preferences"),
+		"updated_at = "+dialect.Excluded("updated_at"),
+	)
+	now := time.Now()
+	_, err := database.Conn(ctx, r.db).ExecContext(ctx, query,
+		profile.UserID, avatarFileID, profile.Bio, profile.Locale, profile.Timezone, preferences, now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("保存用户资料失败: %w", err)
+	}
+
+	saved := *profile
+	saved.Preferences = json.RawMessage(preferences)
+	saved.UpdatedAt = &now
+	return &saved, nil
+}
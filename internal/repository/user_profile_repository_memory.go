@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"gin/internal/models"
+)
+
+// memoryUserProfileRepository 基于内存的用户扩展资料仓库（用于测试和无数据库场景，重启后数据丢失）
+type memoryUserProfileRepository struct {
+	mu       sync.Mutex
+	profiles map[int64]*models.UserProfile
+}
+
+// NewMemoryUserProfileRepository 创建内存用户扩展资料仓库
+func NewMemoryUserProfileRepository() UserProfileRepository {
+	return &memoryUserProfileRepository{profiles: make(map[int64]*models.UserProfile)}
+}
+
+// FindByUserID 查询用户的扩展资料
+func (r *memoryUserProfileRepository) FindByUserID(ctx context.Context, userID int64) (*models.UserProfile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	profile, ok := r.profiles[userID]
+	if !ok {
+		return nil, ErrProfileNotFound
+	}
+	return copyProfile(profile), nil
+}
+
+// Save 插入或覆盖用户的扩展资料
+func (r *memoryUserProfileRepository) Save(ctx context.Context, profile *models.UserProfile) (*models.UserProfile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := copyProfile(profile)
+	if len(saved.Preferences) == 0 {
+		saved.Preferences = json.RawMessage("{}")
+	}
+	now := time.Now()
+	saved.UpdatedAt = &now
+	r.profiles[profile.UserID] = saved
+	return copyProfile(saved), nil
+}
+
+// copyProfile 复制资料，避免调用方修改仓库中保存的数据
+func copyProfile(profile *models.UserProfile) *models.UserProfile {
+	found := *profile
+	if profile.AvatarFileID != nil {
+		avatarFileID := *profile.AvatarFileID
+		found.AvatarFileID = &avatarFileID
+	}
+	found.Preferences = append(json.RawMessage(nil), profile.Preferences...)
+	return &found
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gin/internal/database"
+	"gin/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUserProfileRepository 测试用户扩展资料仓库（SQL 与内存实现行为一致）
+func TestUserProfileRepository(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		ctx := context.Background()
+		userRepo := NewUserRepository(db)
+		fileRepo := NewFileRepository(db)
+
+		repos := map[string]UserProfileRepository{
+			"sql":    NewUserProfileRepository(db),
+			"memory": NewMemoryUserProfileRepository(),
+		}
+
+		for name, repo := range repos {
+			t.Run(name, func(t *testing.T) {
+				user, err := userRepo.Create(ctx, &models.User{
+					Name:     "资料用户",
+					Email:    "profile-" + name + "@example.com",
+					Password: "hashed_password",
+				})
+				require.NoError(t, err)
+				avatar, err := fileRepo.Create(ctx, &models.File{
+					StorageKey:  "avatar-" + name,
+					Name:        "avatar.png",
+					ContentType: "image/png",
+					Checksum:    "checksum",
+					OwnerID:     user.ID,
+				})
+				require.NoError(t, err)
+
+				_, err = repo.FindByUserID(ctx, user.ID)
+				assert.ErrorIs(t, err, ErrProfileNotFound)
+
+				// 首次保存时插入，未提供偏好设置时保存为空对象
+				saved, err := repo.Save(ctx, &models.UserProfile{UserID: user.ID, Bio: "你好", Locale: "zh-CN"})
+				require.NoError(t, err)
+				assert.JSONEq(t, `{}`, string(saved.Preferences))
+				assert.NotNil(t, saved.UpdatedAt)
+
+				found, err := repo.FindByUserID(ctx, user.ID)
+				require.NoError(t, err)
+				assert.Equal(t, user.ID, found.UserID)
+				assert.Nil(t, found.AvatarFileID)
+				assert.Equal(t, "你好", found.Bio)
+				assert.Equal(t, "zh-CN", found.Locale)
+				assert.JSONEq(t, `{}`, string(found.Preferences))
+
+				// 再次保存时覆盖全部字段
+				_, err = repo.Save(ctx, &models.UserProfile{
+					UserID:       user.ID,
+					AvatarFileID: &avatar.ID,
+					Timezone:     "Asia/Shanghai",
+					Preferences:  json.RawMessage(`{"theme":"dark"}`),
+				})
+				require.NoError(t, err)
+
+				found, err = repo.FindByUserID(ctx, user.ID)
+				require.NoError(t, err)
+				require.NotNil(t, found.AvatarFileID)
+				assert.Equal(t, avatar.ID, *found.AvatarFileID)
+				assert.Empty(t, found.Bio)
+				assert.Empty(t, found.Locale)
+				assert.Equal(t, "Asia/Shanghai", found.Timezone)
+				assert.JSONEq(t, `{"theme":"dark"}`, string(found.Preferences))
+			})
+		}
+	})
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,14 +12,53 @@ import (
 	"gin/internal/models"
 )
 
+var (
+	// ErrUserNotFound 用户不存在（查询时返回）
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrUserNotDeleted 用户不存在或未被软删除（恢复、彻底删除时返回）
+	ErrUserNotDeleted = errors.New("用户不存在或未被删除")
+	// ErrEmailExists 邮箱已被其他用户（包括已软删除的用户）使用
+	ErrEmailExists = errors.New("邮箱已被使用")
+	// ErrVersionConflict 用户在读取之后已被其他请求修改（乐观锁版本不一致）
+	ErrVersionConflict = errors.New("用户已被其他请求修改")
+)
+
 // UserRepository 用户仓库接口
+// 除特别说明外，查询和更新都会忽略已软删除（deleted_at 不为空）的用户
 type UserRepository interface {
+	// Create 创建用户；邮箱已被使用时返回 ErrEmailExists
 	Create(ctx context.Context, user *models.User) (*models.User, error)
+	// FindByID、FindByEmail、FindDeletedByEmail 在用户不存在时返回 ErrUserNotFound
 	FindByID(ctx context.Context, id int64) (*models.User, error)
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
-	FindAll(ctx context.Context) ([]*models.User, error)
+	// FindDeletedByEmail 查找使用该邮箱的已软删除用户（邮箱在彻底删除前仍被占用）
+	FindDeletedByEmail(ctx context.Context, email string) (*models.User, error)
+	// List 按过滤条件分页查询用户，支持 offset 与游标两种分页方式；filter.Deleted 为 true 时只查询已软删除的用户
+	List(ctx context.Context, filter models.UserFilter, page models.PageQuery) (*models.Page[*models.User], error)
+	// Update 更新用户并将版本号加一；user.Version 必须是读取时的版本，读取之后用户已被修改时返回 ErrVersionConflict，
+	// 新邮箱已被使用时返回 ErrEmailExists
 	Update(ctx context.Context, id int64, user *models.User) (*models.User, error)
+	// UpdatePassword 更新用户的密码哈希（Update 不会修改密码）
+	UpdatePassword(ctx context.Context, id int64, passwordHash string) error
+	// Delete 软删除用户
 	Delete(ctx context.Context, id int64) error
+	// Restore 恢复已软删除的用户；用户不存在或未被删除时返回 ErrUserNotDeleted
+	Restore(ctx context.Context, id int64) error
+	// Purge 彻底删除已软删除的用户（关联数据按外键级联删除）；用户不存在或未被删除时返回 ErrUserNotDeleted
+	Purge(ctx context.Context, id int64) error
+	// ListDeletedBefore 查询在 before 之前软删除的用户ID（超过保留期后逐个彻底删除）
+	ListDeletedBefore(ctx context.Context, before time.Time) ([]int64, error)
+}
+
+// roleIDSubquery 按角色名查询角色ID的子查询，角色不存在时为 NULL（违反 NOT NULL 约束而写入失败）
+const roleIDSubquery = "(SELECT id FROM roles WHERE name = ?)"
+
+// roleName 未指定角色时默认为普通用户
+func roleName(role auth.Role) string {
+	if role == "" {
+		return auth.RoleUser.String()
+	}
+	return role.String()
 }
 
 // userRepository 用户仓库实现
@@ -37,51 +77,48 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) (*models
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
-	// SQLite 不支持 RETURNING，使用 Exec + LastInsertId
-	result, err := r.db.Exec(
-		"INSERT INTO users (name, email, password, age, role, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		user.Name, user.Email, user.Password, user.Age, int(user.Role), user.CreatedAt, user.UpdatedAt,
+	id, err := database.Conn(ctx, r.db).InsertContext(ctx,
+		"INSERT INTO users (name, email, password, age, role_id, created_at, updated_at) VALUES (?, ?, ?, ?, "+roleIDSubquery+", ?, ?)",
+		user.Name, user.Email, user.Password, user.Age, roleName(user.Role), user.CreatedAt, user.UpdatedAt,
 	)
 	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrEmailExists
+		}
 		return nil, fmt.Errorf("创建用户失败: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, fmt.Errorf("获取用户ID失败: %w", err)
-	}
-
 	// 查询刚创建的用户
-	return r.FindByID(ctx, id)
+	return r.FindByID(database.WithPrimary(ctx), id)
 }
 
 // FindByID 根据ID查找用户
 func (r *userRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
 	query := `
-		SELECT id, name, email, password, age, role, created_at, updated_at
-		FROM users
-		WHERE id = ?
+		SELECT u.id, u.name, u.email, u.password, u.age, r.name, u.version, u.created_at, u.updated_at
+		FROM users u
+		JOIN roles r ON r.id = u.role_id
+		WHERE u.id = ? AND u.deleted_at IS NULL
 	`
 
-	var roleInt int
 	user := &models.User{}
-	err := r.db.QueryRow(query, id).Scan(
+	err := database.ReadConn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Email,
 		&user.Password,
 		&user.Age,
-		&roleInt,
+		&user.Role,
+		&user.Version,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("用户不存在: %w", err)
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("查询用户失败: %w", err)
 	}
-	user.Role = auth.Role(roleInt)
 
 	return user, nil
 }
@@ -89,43 +126,130 @@ func (r *userRepository) FindByID(ctx context.Context, id int64) (*models.User,
 // FindByEmail 根据邮箱查找用户
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, name, email, password, age, role, created_at, updated_at
-		FROM users
-		WHERE email = ?
+		SELECT u.id, u.name, u.email, u.password, u.age, r.name, u.version, u.created_at, u.updated_at
+		FROM users u
+		JOIN roles r ON r.id = u.role_id
+		WHERE u.email = ? AND u.deleted_at IS NULL
 	`
 
-	var roleInt int
 	user := &models.User{}
-	err := r.db.QueryRow(query, email).Scan(
+	err := database.Conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Email,
 		&user.Password,
 		&user.Age,
-		&roleInt,
+		&user.Role,
+		&user.Version,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("用户不存在: %w", err)
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("查询用户失败: %w", err)
 	}
-	user.Role = auth.Role(roleInt)
 
 	return user, nil
 }
 
-// FindAll 查找所有用户
-func (r *userRepository) FindAll(ctx context.Context) ([]*models.User, error) {
+// FindDeletedByEmail 查找使用该邮箱的已软删除用户
+func (r *userRepository) FindDeletedByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, name, email, age, role, created_at, updated_at
-		FROM users
-		ORDER BY created_at DESC
+		SELECT u.id, u.name, u.email, u.age, r.name, u.version, u.created_at, u.updated_at, u.deleted_at
+		FROM users u
+		JOIN roles r ON r.id = u.role_id
+		WHERE u.email = ? AND u.deleted_at IS NOT NULL
 	`
 
-	rows, err := r.db.Query(query)
+	var deletedAt sql.NullTime
+	user := &models.User{}
+	err := database.Conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Age,
+		&user.Role,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&deletedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+
+	return user, nil
+}
+
+// userSortFields 用户列表允许排序的字段
+var userSortFields = map[string]SortField{
+	"id":         {Column: "u.id"},
+	"name":       {Column: "u.name"},
+	"email":      {Column: "u.email"},
+	"age":        {Column: "u.age"},
+	"created_at": {Column: "u.created_at", Time: true},
+	"updated_at": {Column: "u.updated_at", Time: true},
+}
+
+// defaultUserSort 默认按创建时间倒序
+const defaultUserSort = "-created_at"
+
+// List 分页查询用户
+func (r *userRepository) List(ctx context.Context, filter models.UserFilter, page models.PageQuery) (*models.Page[*models.User], error) {
+	sort, err := ParseSort(page.Sort, userSortFields, defaultUserSort)
+	if err != nil {
+		return nil, err
+	}
+
+	qb := NewQueryBuilder("FROM users u JOIN roles r ON r.id = u.role_id")
+	if filter.Deleted {
+		qb.Where("u.deleted_at IS NOT NULL")
+	} else {
+		qb.Where("u.deleted_at IS NULL")
+	}
+	if filter.Name != "" {
+		qb.WhereContains("u.name", filter.Name)
+	}
+	if filter.Email != "" {
+		qb.WhereContains("u.email", filter.Email)
+	}
+	if filter.Role != "" {
+		qb.Where("r.name = ?", filter.Role)
+	}
+	if filter.MinAge != nil {
+		qb.Where("u.age >= ?", *filter.MinAge)
+	}
+	if filter.MaxAge != nil {
+		qb.Where("u.age <= ?", *filter.MaxAge)
+	}
+	// 创建时间以服务器本地时区写入，转换为本地时间后比较（SQLite 按字符串比较时间）
+	if filter.CreatedAfter != nil {
+		qb.Where("u.created_at >= ?", filter.CreatedAfter.Local())
+	}
+	if filter.CreatedBefore != nil {
+		qb.Where("u.created_at < ?", filter.CreatedBefore.Local())
+	}
+
+	var total int64
+	countQuery, countArgs := qb.CountQuery()
+	if err := database.ReadConn(ctx, r.db).QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("统计用户数量失败: %w", err)
+	}
+
+	query, args, err := qb.SelectPage("u.id, u.name, u.email, u.age, r.name, u.version, u.created_at, u.updated_at, u.deleted_at", sort, "u.id", page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.ReadConn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("查询用户列表失败: %w", err)
 	}
@@ -133,21 +257,25 @@ func (r *userRepository) FindAll(ctx context.Context) ([]*models.User, error) {
 
 	var users []*models.User
 	for rows.Next() {
-		var roleInt int
+		var deletedAt sql.NullTime
 		user := &models.User{}
 		err := rows.Scan(
 			&user.ID,
 			&user.Name,
 			&user.Email,
 			&user.Age,
-			&roleInt,
+			&user.Role,
+			&user.Version,
 			&user.CreatedAt,
 			&user.UpdatedAt,
+			&deletedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("扫描用户数据失败: %w", err)
 		}
-		user.Role = auth.Role(roleInt)
+		if deletedAt.Valid {
+			user.DeletedAt = &deletedAt.Time
+		}
 		users = append(users, user)
 	}
 
@@ -155,7 +283,27 @@ func (r *userRepository) FindAll(ctx context.Context) ([]*models.User, error) {
 		return nil, fmt.Errorf("遍历用户数据失败: %w", err)
 	}
 
-	return users, nil
+	return buildPage(users, total, sort, page, func(user *models.User) (interface{}, int64) {
+		return userSortValue(user, sort.Name), user.ID
+	}), nil
+}
+
+// userSortValue 返回用户在排序字段上的值（用于生成游标）
+func userSortValue(user *models.User, field string) interface{} {
+	switch field {
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "age":
+		return user.Age
+	case "created_at":
+		return user.CreatedAt
+	case "updated_at":
+		return user.UpdatedAt
+	default:
+		return user.ID
+	}
 }
 
 // Update 更新用户
@@ -164,12 +312,15 @@ func (r *userRepository) Update(ctx context.Context, id int64, user *models.User
 
 	query := `
 		UPDATE users
-		SET name = ?, email = ?, age = ?, role = ?, updated_at = ?
-		WHERE id = ?
+		SET name = ?, email = ?, age = ?, role_id = ` + roleIDSubquery + `, updated_at = ?, version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL
 	`
 
-	result, err := r.db.Exec(query, user.Name, user.Email, user.Age, int(user.Role), user.UpdatedAt, id)
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, query, user.Name, user.Email, user.Age, roleName(user.Role), user.UpdatedAt, id, user.Version)
 	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrEmailExists
+		}
 		return nil, fmt.Errorf("更新用户失败: %w", err)
 	}
 
@@ -179,17 +330,37 @@ func (r *userRepository) Update(ctx context.Context, id int64, user *models.User
 	}
 
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("用户不存在")
+		// 区分用户不存在和版本冲突
+		if _, err := r.FindByID(database.WithPrimary(ctx), id); err != nil {
+			return nil, err
+		}
+		return nil, ErrVersionConflict
 	}
 
-	return r.FindByID(ctx, id)
+	return r.FindByID(database.WithPrimary(ctx), id)
 }
 
-// Delete 删除用户
-func (r *userRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM users WHERE id = ?`
+// UpdatePassword 更新用户密码
+func (r *userRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET password = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL", passwordHash, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("更新用户密码失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("用户不存在")
+	}
+
+	return nil
+}
 
-	result, err := r.db.Exec(query, id)
+// Delete 软删除用户
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("删除用户失败: %w", err)
 	}
@@ -205,3 +376,61 @@ func (r *userRepository) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// Restore 恢复已软删除的用户
+func (r *userRepository) Restore(ctx context.Context, id int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL", time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("恢复用户失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotDeleted
+	}
+
+	return nil
+}
+
+// Purge 彻底删除已软删除的用户
+func (r *userRepository) Purge(ctx context.Context, id int64) error {
+	result, err := database.Conn(ctx, r.db).ExecContext(ctx, "DELETE FROM users WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return fmt.Errorf("彻底删除用户失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotDeleted
+	}
+
+	return nil
+}
+
+// ListDeletedBefore 查询保留期已过的软删除用户
+func (r *userRepository) ListDeletedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	rows, err := database.Conn(ctx, r.db).QueryContext(ctx, "SELECT id FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY id", before)
+	if err != nil {
+		return nil, fmt.Errorf("查询已删除用户失败: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("扫描用户ID失败: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历已删除用户失败: %w", err)
+	}
+	return ids, nil
+}
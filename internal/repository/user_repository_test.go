@@ -2,330 +2,610 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"gin/internal/database"
 	"gin/internal/models"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// setupTestDB 创建测试数据库
-func setupTestDB(t *testing.T) database.DB {
-	// 使用内存数据库进行测试
-	db, err := database.InitDB("sqlite3", ":memory:")
-	require.NoError(t, err, "应该能创建测试数据库")
-
-	// 初始化表结构
-	createTable := `
-		CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL,
-			email TEXT NOT NULL UNIQUE,
-			password TEXT NOT NULL,
-			age INTEGER NOT NULL DEFAULT 0,
-			role INTEGER NOT NULL DEFAULT 0,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-		)
-	`
-	_, err = db.Exec(createTable)
-	require.NoError(t, err, "应该能创建表")
-
-	// 创建索引
-	createIndex := `CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)`
-	_, err = db.Exec(createIndex)
-	require.NoError(t, err, "应该能创建索引")
-
-	return db
-}
-
-// teardownTestDB 清理测试数据库
-func teardownTestDB(t *testing.T, db database.DB) {
-	if db != nil {
-		err := db.Close()
-		assert.NoError(t, err, "应该能关闭数据库")
-	}
-}
-
 // TestUserRepository_Create 测试创建用户
 func TestUserRepository_Create(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
-
-	repo := NewUserRepository(db)
-	ctx := context.Background()
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		ctx := context.Background()
+
+		t.Run("成功创建用户", func(t *testing.T) {
+			user := &models.User{
+				Name:     "张三",
+				Email:    "zhangsan@example.com",
+				Password: "hashed_password_for_test",
+				Age:      25,
+			}
+
+			created, err := repo.Create(ctx, user)
+			require.NoError(t, err, "应该能创建用户")
+			assert.NotZero(t, created.ID, "用户ID应该不为0")
+			assert.Equal(t, "张三", created.Name)
+			assert.Equal(t, "zhangsan@example.com", created.Email)
+			assert.Equal(t, 25, created.Age)
+			assert.False(t, created.CreatedAt.IsZero())
+			assert.False(t, created.UpdatedAt.IsZero())
+		})
+
+		t.Run("创建重复邮箱应该失败", func(t *testing.T) {
+			user1 := &models.User{
+				Name:     "用户1",
+				Email:    "duplicate@example.com",
+				Password: "hashed_password1",
+				Age:      20,
+			}
+			_, err := repo.Create(ctx, user1)
+			require.NoError(t, err, "第一次创建应该成功")
+
+			user2 := &models.User{
+				Name:     "用户2",
+				Email:    "duplicate@example.com", // 重复邮箱
+				Password: "hashed_password2",
+				Age:      30,
+			}
+			_, err = repo.Create(ctx, user2)
+			assert.ErrorIs(t, err, ErrEmailExists, "重复邮箱应该失败")
+		})
+	})
+}
 
-	t.Run("成功创建用户", func(t *testing.T) {
-		user := &models.User{
-			Name:     "张三",
-			Email:    "zhangsan@example.com",
-			Password: "hashed_password_for_test",
-			Age:      25,
-		}
+// TestUserRepository_FindByID 测试根据ID查找用户
+func TestUserRepository_FindByID(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		ctx := context.Background()
+
+		t.Run("成功查找存在的用户", func(t *testing.T) {
+			// 先创建用户
+			user := &models.User{
+				Name:     "李四",
+				Email:    "lisi@example.com",
+				Password: "hashed_password",
+				Age:      30,
+			}
+			created, err := repo.Create(ctx, user)
+			require.NoError(t, err)
 
-		created, err := repo.Create(ctx, user)
-		require.NoError(t, err, "应该能创建用户")
-		assert.NotZero(t, created.ID, "用户ID应该不为0")
-		assert.Equal(t, "张三", created.Name)
-		assert.Equal(t, "zhangsan@example.com", created.Email)
-		assert.Equal(t, 25, created.Age)
-		assert.False(t, created.CreatedAt.IsZero())
-		assert.False(t, created.UpdatedAt.IsZero())
+			// 查找用户
+			found, err := repo.FindByID(ctx, created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, created.ID, found.ID)
+			assert.Equal(t, "李四", found.Name)
+			assert.Equal(t, "lisi@example.com", found.Email)
+			assert.Equal(t, 30, found.Age)
+		})
+
+		t.Run("查找不存在的用户应该失败", func(t *testing.T) {
+			_, err := repo.FindByID(ctx, 99999)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "用户不存在")
+		})
 	})
+}
 
-	t.Run("创建重复邮箱应该失败", func(t *testing.T) {
-		user1 := &models.User{
-			Name:     "用户1",
-			Email:    "duplicate@example.com",
-			Password: "hashed_password1",
-			Age:      20,
-		}
-		_, err := repo.Create(ctx, user1)
-		require.NoError(t, err, "第一次创建应该成功")
+// TestUserRepository_FindByEmail 测试根据邮箱查找用户
+func TestUserRepository_FindByEmail(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		ctx := context.Background()
+
+		t.Run("成功查找存在的用户", func(t *testing.T) {
+			// 先创建用户
+			user := &models.User{
+				Name:     "王五",
+				Email:    "wangwu@example.com",
+				Password: "hashed_password",
+				Age:      28,
+			}
+			created, err := repo.Create(ctx, user)
+			require.NoError(t, err)
 
-		user2 := &models.User{
-			Name:     "用户2",
-			Email:    "duplicate@example.com", // 重复邮箱
-			Password: "hashed_password2",
-			Age:      30,
-		}
-		_, err = repo.Create(ctx, user2)
-		assert.Error(t, err, "重复邮箱应该失败")
+			// 根据邮箱查找
+			found, err := repo.FindByEmail(ctx, "wangwu@example.com")
+			require.NoError(t, err)
+			assert.Equal(t, created.ID, found.ID)
+			assert.Equal(t, "王五", found.Name)
+			assert.Equal(t, "wangwu@example.com", found.Email)
+		})
+
+		t.Run("查找不存在的邮箱应该失败", func(t *testing.T) {
+			_, err := repo.FindByEmail(ctx, "notexist@example.com")
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "用户不存在")
+		})
 	})
 }
 
-// TestUserRepository_FindByID 测试根据ID查找用户
-func TestUserRepository_FindByID(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+// TestUserRepository_List 测试分页查询用户
+func TestUserRepository_List(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		ctx := context.Background()
 
-	repo := NewUserRepository(db)
-	ctx := context.Background()
+		t.Run("空表应该返回空列表", func(t *testing.T) {
+			page, err := repo.List(ctx, models.UserFilter{}, models.PageQuery{})
+			require.NoError(t, err)
+			assert.Empty(t, page.Items)
+			assert.Zero(t, page.Total)
+			assert.False(t, page.HasMore)
+		})
 
-	t.Run("成功查找存在的用户", func(t *testing.T) {
-		// 先创建用户
-		user := &models.User{
-			Name:     "李四",
-			Email:    "lisi@example.com",
-			Password: "hashed_password",
-			Age:      30,
+		// 创建多个用户
+		for i := 1; i <= 5; i++ {
+			_, err := repo.Create(ctx, &models.User{
+				Name:     fmt.Sprintf("用户%d", i),
+				Email:    fmt.Sprintf("user%d@example.com", i),
+				Password: "hashed_password",
+				Age:      18 + i,
+			})
+			require.NoError(t, err)
 		}
-		created, err := repo.Create(ctx, user)
+		_, err := repo.Create(ctx, &models.User{Name: "100%_用户", Email: "percent@example.com", Password: "hashed_password", Age: 40})
 		require.NoError(t, err)
 
-		// 查找用户
-		found, err := repo.FindByID(ctx, created.ID)
-		require.NoError(t, err)
-		assert.Equal(t, created.ID, found.ID)
-		assert.Equal(t, "李四", found.Name)
-		assert.Equal(t, "lisi@example.com", found.Email)
-		assert.Equal(t, 30, found.Age)
-	})
+		t.Run("默认按创建时间倒序", func(t *testing.T) {
+			page, err := repo.List(ctx, models.UserFilter{}, models.PageQuery{})
+			require.NoError(t, err)
+			assert.Len(t, page.Items, 6)
+			assert.Equal(t, int64(6), page.Total)
+			assert.Equal(t, "percent@example.com", page.Items[0].Email)
+			assert.Equal(t, models.DefaultPageLimit, page.Limit)
+		})
+
+		t.Run("offset 分页", func(t *testing.T) {
+			page, err := repo.List(ctx, models.UserFilter{}, models.PageQuery{Limit: 2, Offset: 2, Sort: "age"})
+			require.NoError(t, err)
+			require.Len(t, page.Items, 2)
+			assert.Equal(t, 21, page.Items[0].Age)
+			assert.Equal(t, 22, page.Items[1].Age)
+			assert.Equal(t, int64(6), page.Total)
+			assert.True(t, page.HasMore)
+		})
+
+		t.Run("游标分页遍历所有数据", func(t *testing.T) {
+			for _, sort := range []string{"name", "-age", "created_at", "-id"} {
+				var seen []int64
+				query := models.PageQuery{Limit: 4, Sort: sort}
+				for {
+					page, err := repo.List(ctx, models.UserFilter{}, query)
+					require.NoError(t, err)
+					assert.Equal(t, int64(6), page.Total)
+					for _, user := range page.Items {
+						seen = append(seen, user.ID)
+					}
+					if !page.HasMore {
+						assert.Empty(t, page.NextCursor)
+						break
+					}
+					query.Cursor = page.NextCursor
+				}
+				assert.Len(t, seen, 6, "排序 %s 应该不重不漏", sort)
+			}
+		})
+
+		t.Run("过滤条件", func(t *testing.T) {
+			minAge, maxAge := 20, 22
+			page, err := repo.List(ctx, models.UserFilter{MinAge: &minAge, MaxAge: &maxAge}, models.PageQuery{Sort: "age"})
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), page.Total)
 
-	t.Run("查找不存在的用户应该失败", func(t *testing.T) {
-		_, err := repo.FindByID(ctx, 99999)
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "用户不存在")
+			page, err = repo.List(ctx, models.UserFilter{Email: "user3"}, models.PageQuery{})
+			require.NoError(t, err)
+			require.Len(t, page.Items, 1)
+			assert.Equal(t, "user3@example.com", page.Items[0].Email)
+
+			// LIKE 通配符按普通字符匹配
+			page, err = repo.List(ctx, models.UserFilter{Name: "%_"}, models.PageQuery{})
+			require.NoError(t, err)
+			require.Len(t, page.Items, 1)
+			assert.Equal(t, "percent@example.com", page.Items[0].Email)
+
+			page, err = repo.List(ctx, models.UserFilter{Role: "admin"}, models.PageQuery{})
+			require.NoError(t, err)
+			assert.Zero(t, page.Total)
+
+			future := time.Now().Add(time.Hour)
+			page, err = repo.List(ctx, models.UserFilter{CreatedAfter: &future}, models.PageQuery{})
+			require.NoError(t, err)
+			assert.Zero(t, page.Total)
+			page, err = repo.List(ctx, models.UserFilter{CreatedBefore: &future}, models.PageQuery{})
+			require.NoError(t, err)
+			assert.Equal(t, int64(6), page.Total)
+		})
+
+		t.Run("无效的排序字段和游标", func(t *testing.T) {
+			_, err := repo.List(ctx, models.UserFilter{}, models.PageQuery{Sort: "password"})
+			assert.ErrorIs(t, err, ErrInvalidSort)
+
+			_, err = repo.List(ctx, models.UserFilter{}, models.PageQuery{Cursor: "not-a-cursor"})
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+
+			// 游标与排序方式绑定
+			page, err := repo.List(ctx, models.UserFilter{}, models.PageQuery{Limit: 1, Sort: "name"})
+			require.NoError(t, err)
+			_, err = repo.List(ctx, models.UserFilter{}, models.PageQuery{Limit: 1, Sort: "age", Cursor: page.NextCursor})
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
 	})
 }
 
-// TestUserRepository_FindByEmail 测试根据邮箱查找用户
-func TestUserRepository_FindByEmail(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+// TestUserRepository_Update 测试更新用户
+func TestUserRepository_Update(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		ctx := context.Background()
+
+		t.Run("成功更新用户", func(t *testing.T) {
+			// 先创建用户
+			user := &models.User{
+				Name:     "原始名称",
+				Email:    "original@example.com",
+				Password: "hashed_password",
+				Age:      20,
+			}
+			created, err := repo.Create(ctx, user)
+			require.NoError(t, err)
 
-	repo := NewUserRepository(db)
-	ctx := context.Background()
+			// 更新用户
+			updatedUser := &models.User{
+				Name:     "更新后的名称",
+				Email:    "updated@example.com",
+				Password: "hashed_password",
+				Age:      25,
+				Version:  created.Version,
+			}
+			updated, err := repo.Update(ctx, created.ID, updatedUser)
+			require.NoError(t, err)
+			assert.Equal(t, created.ID, updated.ID)
+			assert.Equal(t, "更新后的名称", updated.Name)
+			assert.Equal(t, "updated@example.com", updated.Email)
+			assert.Equal(t, 25, updated.Age)
+			assert.Equal(t, created.Version+1, updated.Version, "每次更新版本号加一")
+			// SQLite 的时间精度可能不够，只验证 UpdatedAt 不为零
+			assert.False(t, updated.UpdatedAt.IsZero(), "更新时间应该设置")
+		})
+
+		t.Run("版本号已变化时返回冲突", func(t *testing.T) {
+			created, err := repo.Create(ctx, &models.User{
+				Name:     "并发用户",
+				Email:    "concurrent@example.com",
+				Password: "hashed_password",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), created.Version)
 
-	t.Run("成功查找存在的用户", func(t *testing.T) {
-		// 先创建用户
-		user := &models.User{
-			Name:     "王五",
-			Email:    "wangwu@example.com",
-			Password: "hashed_password",
-			Age:      28,
-		}
-		created, err := repo.Create(ctx, user)
-		require.NoError(t, err)
+			// 两个请求读取到同一个版本，先提交的成功，后提交的冲突
+			first := *created
+			first.Name = "第一个请求"
+			_, err = repo.Update(ctx, created.ID, &first)
+			require.NoError(t, err)
 
-		// 根据邮箱查找
-		found, err := repo.FindByEmail(ctx, "wangwu@example.com")
-		require.NoError(t, err)
-		assert.Equal(t, created.ID, found.ID)
-		assert.Equal(t, "王五", found.Name)
-		assert.Equal(t, "wangwu@example.com", found.Email)
+			second := *created
+			second.Name = "第二个请求"
+			_, err = repo.Update(ctx, created.ID, &second)
+			assert.ErrorIs(t, err, ErrVersionConflict)
+
+			found, err := repo.FindByID(ctx, created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "第一个请求", found.Name)
+			assert.Equal(t, int64(2), found.Version)
+		})
+
+		t.Run("更新不存在的用户应该失败", func(t *testing.T) {
+			user := &models.User{
+				Name:     "测试",
+				Email:    "test@example.com",
+				Password: "hashed_password",
+				Age:      20,
+			}
+			_, err := repo.Update(ctx, 99999, user)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "用户不存在")
+		})
 	})
+}
 
-	t.Run("查找不存在的邮箱应该失败", func(t *testing.T) {
-		_, err := repo.FindByEmail(ctx, "notexist@example.com")
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "用户不存在")
+// TestUserRepository_UpdatePassword 测试更新用户密码
+func TestUserRepository_UpdatePassword(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		ctx := context.Background()
+
+		t.Run("成功更新密码", func(t *testing.T) {
+			created, err := repo.Create(ctx, &models.User{
+				Name:     "改密用户",
+				Email:    "password@example.com",
+				Password: "old_hash",
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, repo.UpdatePassword(ctx, created.ID, "new_hash"))
+
+			found, err := repo.FindByID(ctx, created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "new_hash", found.Password)
+		})
+
+		t.Run("更新不存在的用户应该失败", func(t *testing.T) {
+			err := repo.UpdatePassword(ctx, 99999, "new_hash")
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "用户不存在")
+		})
 	})
 }
 
-// TestUserRepository_FindAll 测试查找所有用户
-func TestUserRepository_FindAll(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+// TestUserRepository_Delete 测试删除用户
+func TestUserRepository_Delete(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		ctx := context.Background()
+
+		t.Run("成功删除用户", func(t *testing.T) {
+			// 先创建用户
+			user := &models.User{
+				Name:     "待删除用户",
+				Email:    "todelete@example.com",
+				Password: "hashed_password",
+				Age:      20,
+			}
+			created, err := repo.Create(ctx, user)
+			require.NoError(t, err)
 
-	repo := NewUserRepository(db)
-	ctx := context.Background()
+			// 删除用户
+			err = repo.Delete(ctx, created.ID)
+			require.NoError(t, err)
 
-	t.Run("空表应该返回空列表", func(t *testing.T) {
-		users, err := repo.FindAll(ctx)
-		require.NoError(t, err)
-		assert.Empty(t, users)
+			// 验证用户已删除
+			_, err = repo.FindByID(ctx, created.ID)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "用户不存在")
+		})
+
+		t.Run("删除不存在的用户应该失败", func(t *testing.T) {
+			err := repo.Delete(ctx, 99999)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "用户不存在")
+		})
 	})
+}
 
-	t.Run("应该返回所有用户", func(t *testing.T) {
-		// 创建多个用户
-		users := []*models.User{
-			{Name: "用户1", Email: "user1@example.com", Password: "hashed_password1", Age: 20},
-			{Name: "用户2", Email: "user2@example.com", Password: "hashed_password2", Age: 25},
-			{Name: "用户3", Email: "user3@example.com", Password: "hashed_password3", Age: 30},
+// TestUserRepository_SoftDelete 测试软删除、恢复与彻底删除
+func TestUserRepository_SoftDelete(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		ctx := context.Background()
+
+		create := func(t *testing.T, email string) *models.User {
+			created, err := repo.Create(ctx, &models.User{
+				Name:     "软删除用户",
+				Email:    email,
+				Password: "hashed_password",
+				Age:      20,
+			})
+			require.NoError(t, err)
+			return created
 		}
 
-		for _, user := range users {
-			_, err := repo.Create(ctx, user)
+		t.Run("软删除后查询不到用户", func(t *testing.T) {
+			user := create(t, "soft@example.com")
+			require.NoError(t, repo.Delete(ctx, user.ID))
+
+			_, err := repo.FindByID(ctx, user.ID)
+			assert.Error(t, err)
+			_, err = repo.FindByEmail(ctx, user.Email)
+			assert.Error(t, err)
+			_, err = repo.Update(ctx, user.ID, &models.User{Name: "新名字", Email: user.Email, Role: user.Role})
+			assert.Error(t, err)
+			assert.Error(t, repo.UpdatePassword(ctx, user.ID, "new_hash"))
+			assert.Error(t, repo.Delete(ctx, user.ID), "重复删除应该失败")
+
+			// 已删除的用户不出现在默认列表中，只出现在已删除列表中
+			page, err := repo.List(ctx, models.UserFilter{}, models.PageQuery{})
 			require.NoError(t, err)
-		}
+			for _, u := range page.Items {
+				assert.NotEqual(t, user.ID, u.ID)
+			}
+			deleted, err := repo.List(ctx, models.UserFilter{Deleted: true}, models.PageQuery{})
+			require.NoError(t, err)
+			require.Len(t, deleted.Items, 1)
+			assert.Equal(t, user.ID, deleted.Items[0].ID)
+			assert.NotNil(t, deleted.Items[0].DeletedAt)
 
-		// 查找所有用户
-		all, err := repo.FindAll(ctx)
-		require.NoError(t, err)
-		assert.Len(t, all, 3, "应该返回3个用户")
+			found, err := repo.FindDeletedByEmail(ctx, user.Email)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, found.ID)
+			assert.NotNil(t, found.DeletedAt)
+		})
+
+		t.Run("恢复已删除的用户", func(t *testing.T) {
+			user := create(t, "restore@example.com")
+			assert.ErrorIs(t, repo.Restore(ctx, user.ID), ErrUserNotDeleted, "未删除的用户不能恢复")
+
+			require.NoError(t, repo.Delete(ctx, user.ID))
+			require.NoError(t, repo.Restore(ctx, user.ID))
+
+			found, err := repo.FindByID(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, user.Email, found.Email)
+			_, err = repo.FindDeletedByEmail(ctx, user.Email)
+			assert.Error(t, err)
+
+			assert.ErrorIs(t, repo.Restore(ctx, 99999), ErrUserNotDeleted)
+		})
+
+		t.Run("只能彻底删除已删除的用户", func(t *testing.T) {
+			user := create(t, "purge@example.com")
+			assert.ErrorIs(t, repo.Purge(ctx, user.ID), ErrUserNotDeleted)
+
+			require.NoError(t, repo.Delete(ctx, user.ID))
+			require.NoError(t, repo.Purge(ctx, user.ID))
+			assert.ErrorIs(t, repo.Restore(ctx, user.ID), ErrUserNotDeleted)
+
+			// 彻底删除后邮箱可以重新注册
+			create(t, "purge@example.com")
+		})
+
+		t.Run("清理超过保留期的已删除用户", func(t *testing.T) {
+			user := create(t, "retention@example.com")
+			require.NoError(t, repo.Delete(ctx, user.ID))
+
+			ids, err := repo.ListDeletedBefore(ctx, time.Now().Add(-time.Hour))
+			require.NoError(t, err)
+			assert.Empty(t, ids, "未超过保留期的用户不应该被清理")
+
+			ids, err = repo.ListDeletedBefore(ctx, time.Now().Add(time.Hour))
+			require.NoError(t, err)
+			require.Len(t, ids, 2) // 包括第一个子测试删除的用户
+			assert.Contains(t, ids, user.ID)
+			for _, id := range ids {
+				require.NoError(t, repo.Purge(ctx, id))
+			}
+			_, err = repo.FindDeletedByEmail(ctx, user.Email)
+			assert.Error(t, err)
+		})
+
+		t.Run("彻底删除用户时级联删除其关联数据", func(t *testing.T) {
+			// 依赖外键的 ON DELETE CASCADE，SQLite 需要在连接上开启外键约束
+			dependents := []string{"refresh_tokens", "password_reset_tokens", "user_mfa", "mfa_recovery_codes", "mfa_challenges", "api_keys", "user_profiles"}
+			seed := func(t *testing.T, userID int64) {
+				expiresAt := time.Now().Add(time.Hour)
+				_, err := NewRefreshTokenRepository(db).Create(ctx, &models.RefreshToken{UserID: userID, FamilyID: fmt.Sprintf("family-%d", userID), TokenHash: fmt.Sprintf("refresh-%d", userID), ExpiresAt: expiresAt})
+				require.NoError(t, err)
+				_, err = NewPasswordResetTokenRepository(db).Create(ctx, &models.PasswordResetToken{UserID: userID, TokenHash: fmt.Sprintf("reset-%d", userID), ExpiresAt: expiresAt})
+				require.NoError(t, err)
+				mfaRepo := NewMFARepository(db)
+				require.NoError(t, mfaRepo.SaveSecret(ctx, userID, "secret"))
+				require.NoError(t, mfaRepo.ReplaceRecoveryCodes(ctx, userID, []string{fmt.Sprintf("code-%d", userID)}))
+				_, err = NewMFAChallengeRepository(db).Create(ctx, &models.MFAChallenge{UserID: userID, TokenHash: fmt.Sprintf("challenge-%d", userID), ExpiresAt: expiresAt})
+				require.NoError(t, err)
+				_, err = NewAPIKeyRepository(db).Create(ctx, &models.APIKey{UserID: userID, Name: "key", Prefix: "gsk_", KeyHash: fmt.Sprintf("key-%d", userID), Scopes: []string{"users:read"}, ExpiresAt: expiresAt, CreatedBy: userID})
+				require.NoError(t, err)
+				_, err = NewUserProfileRepository(db).Save(ctx, &models.UserProfile{UserID: userID, Preferences: json.RawMessage(`{}`)})
+				require.NoError(t, err)
+			}
+			count := func(t *testing.T, table string, userID int64) int {
+				var n int
+				err := database.Conn(ctx, db).QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+" WHERE user_id = ?", userID).Scan(&n)
+				require.NoError(t, err)
+				return n
+			}
+
+			purged := create(t, "cascade-purge@example.com")
+			expired := create(t, "cascade-retention@example.com")
+			for _, user := range []*models.User{purged, expired} {
+				seed(t, user.ID)
+				for _, table := range dependents {
+					require.Equal(t, 1, count(t, table, user.ID), table)
+				}
+				require.NoError(t, repo.Delete(ctx, user.ID))
+			}
+
+			require.NoError(t, repo.Purge(ctx, purged.ID))
+			ids, err := repo.ListDeletedBefore(ctx, time.Now().Add(time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, []int64{expired.ID}, ids)
+			require.NoError(t, repo.Purge(ctx, expired.ID))
+
+			for _, user := range []*models.User{purged, expired} {
+				for _, table := range dependents {
+					assert.Zero(t, count(t, table, user.ID), "%s 中应该没有已彻底删除用户的数据", table)
+				}
+			}
+		})
 	})
 }
 
-// TestUserRepository_Update 测试更新用户
-func TestUserRepository_Update(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+// TestUserRepository_Transaction 测试仓库加入 context 中的事务
+func TestUserRepository_Transaction(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		txManager := database.NewTxManager(db)
+		ctx := context.Background()
+
+		t.Run("事务回滚后写入全部撤销", func(t *testing.T) {
+			errRollback := errors.New("回滚")
+			err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+				created, err := repo.Create(ctx, &models.User{Name: "事务用户", Email: "tx@example.com", Password: "hashed_password"})
+				require.NoError(t, err)
+
+				// 事务内可以读到未提交的数据
+				_, err = repo.FindByEmail(ctx, "tx@example.com")
+				require.NoError(t, err)
+				require.NoError(t, repo.UpdatePassword(ctx, created.ID, "new_hash"))
+				return errRollback
+			})
+			assert.ErrorIs(t, err, errRollback)
+
+			_, err = repo.FindByEmail(ctx, "tx@example.com")
+			assert.Error(t, err)
+		})
+
+		t.Run("事务提交后写入生效", func(t *testing.T) {
+			err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+				_, err := repo.Create(ctx, &models.User{Name: "事务用户", Email: "tx@example.com", Password: "hashed_password"})
+				return err
+			})
+			require.NoError(t, err)
+
+			_, err = repo.FindByEmail(ctx, "tx@example.com")
+			assert.NoError(t, err)
+		})
+	})
+}
 
-	repo := NewUserRepository(db)
-	ctx := context.Background()
+// TestUserRepository_Integration 集成测试：完整的CRUD流程
+func TestUserRepository_Integration(t *testing.T) {
+	forEachTestDB(t, func(t *testing.T, db database.DB) {
+		repo := NewUserRepository(db)
+		ctx := context.Background()
 
-	t.Run("成功更新用户", func(t *testing.T) {
-		// 先创建用户
+		// 1. 创建用户
 		user := &models.User{
-			Name:     "原始名称",
-			Email:    "original@example.com",
+			Name:     "集成测试用户",
+			Email:    "integration@example.com",
 			Password: "hashed_password",
-			Age:      20,
+			Age:      25,
 		}
 		created, err := repo.Create(ctx, user)
 		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
 
-		// 更新用户
-		updatedUser := &models.User{
-			Name:     "更新后的名称",
-			Email:    "updated@example.com",
-			Password: "hashed_password",
-			Age:      25,
-		}
-		updated, err := repo.Update(ctx, created.ID, updatedUser)
+		// 2. 查找用户
+		found, err := repo.FindByID(ctx, created.ID)
 		require.NoError(t, err)
-		assert.Equal(t, created.ID, updated.ID)
-		assert.Equal(t, "更新后的名称", updated.Name)
-		assert.Equal(t, "updated@example.com", updated.Email)
-		assert.Equal(t, 25, updated.Age)
-		// SQLite 的时间精度可能不够，只验证 UpdatedAt 不为零
-		assert.False(t, updated.UpdatedAt.IsZero(), "更新时间应该设置")
-	})
-
-	t.Run("更新不存在的用户应该失败", func(t *testing.T) {
-		user := &models.User{
-			Name:     "测试",
-			Email:    "test@example.com",
-			Password: "hashed_password",
-			Age:      20,
-		}
-		_, err := repo.Update(ctx, 99999, user)
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "用户不存在")
-	})
-}
-
-// TestUserRepository_Delete 测试删除用户
-func TestUserRepository_Delete(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
-
-	repo := NewUserRepository(db)
-	ctx := context.Background()
+		assert.Equal(t, created.ID, found.ID)
 
-	t.Run("成功删除用户", func(t *testing.T) {
-		// 先创建用户
-		user := &models.User{
-			Name:     "待删除用户",
-			Email:    "todelete@example.com",
+		// 3. 更新用户
+		updatedUser := &models.User{
+			Name:     "更新后的集成测试用户",
+			Email:    "integration@example.com",
 			Password: "hashed_password",
-			Age:      20,
+			Age:      30,
+			Version:  found.Version,
 		}
-		created, err := repo.Create(ctx, user)
+		updated, err := repo.Update(ctx, created.ID, updatedUser)
 		require.NoError(t, err)
+		assert.Equal(t, "更新后的集成测试用户", updated.Name)
+		assert.Equal(t, 30, updated.Age)
 
-		// 删除用户
+		// 4. 删除用户
 		err = repo.Delete(ctx, created.ID)
 		require.NoError(t, err)
 
-		// 验证用户已删除
+		// 5. 验证用户已删除
 		_, err = repo.FindByID(ctx, created.ID)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "用户不存在")
-	})
-
-	t.Run("删除不存在的用户应该失败", func(t *testing.T) {
-		err := repo.Delete(ctx, 99999)
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "用户不存在")
 	})
 }
-
-// TestUserRepository_Integration 集成测试：完整的CRUD流程
-func TestUserRepository_Integration(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
-
-	repo := NewUserRepository(db)
-	ctx := context.Background()
-
-	// 1. 创建用户
-	user := &models.User{
-		Name:     "集成测试用户",
-		Email:    "integration@example.com",
-		Password: "hashed_password",
-		Age:      25,
-	}
-	created, err := repo.Create(ctx, user)
-	require.NoError(t, err)
-	assert.NotZero(t, created.ID)
-
-	// 2. 查找用户
-	found, err := repo.FindByID(ctx, created.ID)
-	require.NoError(t, err)
-	assert.Equal(t, created.ID, found.ID)
-
-	// 3. 更新用户
-	updatedUser := &models.User{
-		Name:     "更新后的集成测试用户",
-		Email:    "integration@example.com",
-		Password: "hashed_password",
-		Age:      30,
-	}
-	updated, err := repo.Update(ctx, created.ID, updatedUser)
-	require.NoError(t, err)
-	assert.Equal(t, "更新后的集成测试用户", updated.Name)
-	assert.Equal(t, 30, updated.Age)
-
-	// 4. 删除用户
-	err = repo.Delete(ctx, created.ID)
-	require.NoError(t, err)
-
-	// 5. 验证用户已删除
-	_, err = repo.FindByID(ctx, created.ID)
-	assert.Error(t, err)
-}
@@ -0,0 +1,27 @@
+package requestctx
+
+import "context"
+
+// Metadata 请求元数据（由中间件写入 request context，供 service 等非 HTTP 层读取）
+type Metadata struct {
+	RequestID string
+	ClientIP  string
+	UserAgent string
+}
+
+// metadataKey context 键类型（避免与其他包冲突）
+type metadataKey struct{}
+
+// WithMetadata 将请求元数据写入 context
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// FromContext 从 context 中读取请求元数据，不存在时返回零值
+func FromContext(ctx context.Context) Metadata {
+	if ctx == nil {
+		return Metadata{}
+	}
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
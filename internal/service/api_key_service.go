@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/database"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/logger"
+	"gin/internal/models"
+	"gin/internal/repository"
+	"gin/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// apiKeyTouchInterval 更新API密钥最近使用时间的最小间隔
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyService API密钥服务接口
+type APIKeyService interface {
+	// CreateAPIKey 为指定用户创建API密钥，密钥明文只在返回值中出现一次
+	CreateAPIKey(ctx context.Context, createdBy int64, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error)
+	// ListAPIKeys 列出API密钥，userID 为 0 时列出所有用户的密钥
+	ListAPIKeys(ctx context.Context, userID int64) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	// AuthenticateAPIKey 校验密钥明文，返回密钥和所属用户；密钥无效、已过期或已吊销时返回401错误
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, *models.User, error)
+}
+
+// apiKeyService API密钥服务实现
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	userRepo   repository.UserRepository
+	config     config.APIKeyConfig
+	audit      AuditRecorder
+}
+
+// APIKeyServiceOption API密钥服务可选配置
+type APIKeyServiceOption func(*apiKeyService)
+
+// WithAPIKeyAuditRecorder 指定审计日志记录器（默认不记录）
+func WithAPIKeyAuditRecorder(audit AuditRecorder) APIKeyServiceOption {
+	return func(s *apiKeyService) {
+		s.audit = audit
+	}
+}
+
+// NewAPIKeyService 创建API密钥服务
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository, cfg config.APIKeyConfig, opts ...APIKeyServiceOption) APIKeyService {
+	s := &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		config:     cfg,
+		audit:      noopAuditRecorder{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateAPIKey 创建API密钥
+// 密钥的权限范围必须是所属用户当前角色已被授予的权限
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, createdBy int64, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	owner, err := s.userRepo.FindByID(ctx, req.UserID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrUserNotFound) {
+			return nil, errors.NewNotFoundError("用户不存在", err)
+		}
+		return nil, errors.NewInternalServerError("创建API密钥失败", err)
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if !owner.Role.HasPermission(scope) {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("用户角色未被授予权限: %s", scope), fmt.Errorf("role %s has no permission %q", owner.Role, scope))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	ttl := req.ExpiresIn
+	if ttl == 0 {
+		ttl = s.config.DefaultTTL
+	}
+	if s.config.MaxTTL > 0 && ttl > s.config.MaxTTL {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("有效期不能超过 %d 天", s.config.MaxTTL), fmt.Errorf("api key ttl %d exceeds %d", ttl, s.config.MaxTTL))
+	}
+
+	plain, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, errors.NewInternalServerError("生成API密钥失败", err)
+	}
+
+	key, err := s.apiKeyRepo.Create(ctx, &models.APIKey{
+		UserID:    owner.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   auth.HashToken(plain),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().AddDate(0, 0, ttl),
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return nil, errors.NewInternalServerError("保存API密钥失败", err)
+	}
+
+	logger.Log.Info(i18n.LogMessage(i18n.LogAPIKeyCreated),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("api_key_id", key.ID),
+		zap.Int64("user_id", key.UserID),
+		zap.Int64("created_by", createdBy),
+		zap.Strings("scopes", key.Scopes),
+	)
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditAPIKeyCreate,
+		TargetType: models.AuditTargetAPIKey,
+		TargetID:   auditTargetID(key.ID),
+		Detail:     fmt.Sprintf("user_id=%d, scopes=%s", key.UserID, strings.Join(key.Scopes, ",")),
+	})
+
+	return &models.CreateAPIKeyResponse{APIKey: key, Key: plain}, nil
+}
+
+// ListAPIKeys 列出API密钥
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, userID int64) ([]*models.APIKey, error) {
+	keys, err := s.apiKeyRepo.List(ctx, userID)
+	if err != nil {
+		return nil, errors.NewInternalServerError("获取API密钥列表失败", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey 吊销API密钥，吊销后立即失效
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id int64) error {
+	if err := s.apiKeyRepo.Revoke(ctx, id); err != nil {
+		if stderrors.Is(err, repository.ErrAPIKeyNotFound) {
+			return errors.NewNotFoundError("API密钥不存在或已吊销", err)
+		}
+		return errors.NewInternalServerError("吊销API密钥失败", err)
+	}
+
+	logger.Log.Info(i18n.LogMessage(i18n.LogAPIKeyRevoked),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("api_key_id", id),
+	)
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditAPIKeyRevoke,
+		TargetType: models.AuditTargetAPIKey,
+		TargetID:   auditTargetID(id),
+	})
+	return nil
+}
+
+// AuthenticateAPIKey 校验API密钥
+// 请求以密钥所属用户的当前角色执行，用户被删除后密钥随之失效
+func (s *apiKeyService) AuthenticateAPIKey(ctx context.Context, plain string) (*models.APIKey, *models.User, error) {
+	if !strings.HasPrefix(plain, auth.APIKeyPrefix) {
+		return nil, nil, errors.NewUnauthorizedError("API密钥无效", fmt.Errorf("api key without %q prefix", auth.APIKeyPrefix))
+	}
+
+	key, err := s.apiKeyRepo.FindByHash(ctx, auth.HashToken(plain))
+	if err != nil {
+		if stderrors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, nil, errors.NewUnauthorizedError("API密钥无效", err)
+		}
+		return nil, nil, errors.NewInternalServerError("校验API密钥失败", err)
+	}
+
+	now := time.Now()
+	if key.IsRevoked() {
+		return nil, nil, errors.NewUnauthorizedError("API密钥已吊销", fmt.Errorf("api key %d revoked", key.ID))
+	}
+	if key.IsExpired(now) {
+		return nil, nil, errors.NewUnauthorizedError("API密钥已过期", fmt.Errorf("api key %d expired", key.ID))
+	}
+
+	// 从主库读取所属用户，从库延迟时可能读到旧角色或已删除的用户
+	owner, err := s.userRepo.FindByID(database.WithPrimary(ctx), key.UserID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrUserNotFound) {
+			return nil, nil, errors.NewUnauthorizedError("API密钥所属用户不存在", err)
+		}
+		return nil, nil, errors.NewInternalServerError("校验API密钥失败", err)
+	}
+
+	// 最近使用时间只用于展示，更新失败不影响本次请求
+	if err := s.apiKeyRepo.TouchLastUsed(ctx, key.ID, now, apiKeyTouchInterval); err != nil {
+		logger.Log.Warn(i18n.LogMessage(i18n.LogInternalError),
+			zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+			zap.Int64("api_key_id", key.ID),
+			zap.Error(err),
+		)
+	}
+
+	return key, owner, nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIKeyService 测试API密钥的创建、认证与吊销
+func TestAPIKeyService(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.APIKeyConfig{DefaultTTL: 90, MaxTTL: 365}
+
+	auth.Permissions.Load(map[auth.Role][]string{
+		auth.RoleUser: {auth.PermissionUsersRead, auth.PermissionUsersCreate},
+	})
+	defer auth.Permissions.Load(nil)
+
+	setup := func(t *testing.T) (APIKeyService, repository.APIKeyRepository, *models.User) {
+		owner := newLoginTestUser(t)
+		userRepo := new(MockUserRepository)
+		userRepo.On("FindByID", mock.Anything, owner.ID).Return(owner, nil)
+		userRepo.On("FindByID", mock.Anything, mock.Anything).Return(nil, repository.ErrUserNotFound)
+		apiKeyRepo := repository.NewMemoryAPIKeyRepository()
+		return NewAPIKeyService(apiKeyRepo, userRepo, cfg), apiKeyRepo, owner
+	}
+
+	t.Run("创建后可以认证，只保存哈希", func(t *testing.T) {
+		svc, apiKeyRepo, owner := setup(t)
+
+		created, err := svc.CreateAPIKey(ctx, 99, &models.CreateAPIKeyRequest{
+			UserID: owner.ID,
+			Name:   "CI",
+			Scopes: []string{auth.PermissionUsersRead, auth.PermissionUsersRead},
+		})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(created.Key, auth.APIKeyPrefix))
+		assert.True(t, strings.HasPrefix(created.Key, created.APIKey.Prefix))
+		assert.Equal(t, []string{auth.PermissionUsersRead}, created.APIKey.Scopes)
+		assert.Equal(t, int64(99), created.APIKey.CreatedBy)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 90), created.APIKey.ExpiresAt, time.Minute)
+
+		stored, err := apiKeyRepo.FindByID(ctx, created.APIKey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, auth.HashToken(created.Key), stored.KeyHash)
+		assert.NotContains(t, stored.KeyHash, created.Key)
+
+		key, user, err := svc.AuthenticateAPIKey(ctx, created.Key)
+		require.NoError(t, err)
+		assert.Equal(t, created.APIKey.ID, key.ID)
+		assert.Equal(t, owner.ID, user.ID)
+
+		// 认证成功后记录最近使用时间
+		stored, err = apiKeyRepo.FindByID(ctx, created.APIKey.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, stored.LastUsedAt)
+	})
+
+	t.Run("权限范围必须是所属用户角色已有的权限", func(t *testing.T) {
+		svc, _, owner := setup(t)
+
+		_, err := svc.CreateAPIKey(ctx, 99, &models.CreateAPIKeyRequest{
+			UserID: owner.ID,
+			Name:   "越权",
+			Scopes: []string{auth.PermissionUsersDelete},
+		})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("有效期不能超过上限", func(t *testing.T) {
+		svc, _, owner := setup(t)
+
+		_, err := svc.CreateAPIKey(ctx, 99, &models.CreateAPIKeyRequest{
+			UserID:    owner.ID,
+			Name:      "长期",
+			Scopes:    []string{auth.PermissionUsersRead},
+			ExpiresIn: 366,
+		})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("用户不存在", func(t *testing.T) {
+		svc, _, _ := setup(t)
+
+		_, err := svc.CreateAPIKey(ctx, 99, &models.CreateAPIKeyRequest{
+			UserID: 404,
+			Name:   "CI",
+			Scopes: []string{auth.PermissionUsersRead},
+		})
+		assertAppErrorCode(t, err, http.StatusNotFound)
+	})
+
+	t.Run("查询用户出错时返回服务器错误", func(t *testing.T) {
+		owner := newLoginTestUser(t)
+		userRepo := new(MockUserRepository)
+		userRepo.On("FindByID", mock.Anything, owner.ID).Return(nil, errors.New("数据库连接失败"))
+		apiKeyRepo := repository.NewMemoryAPIKeyRepository()
+		svc := NewAPIKeyService(apiKeyRepo, userRepo, cfg)
+
+		_, err := svc.CreateAPIKey(ctx, 99, &models.CreateAPIKeyRequest{
+			UserID: owner.ID,
+			Name:   "CI",
+			Scopes: []string{auth.PermissionUsersRead},
+		})
+		assertAppErrorCode(t, err, http.StatusInternalServerError)
+
+		// 数据库故障时不能当作密钥无效返回401
+		plain := auth.APIKeyPrefix + "stored"
+		_, err = apiKeyRepo.Create(ctx, &models.APIKey{
+			UserID:    owner.ID,
+			Name:      "CI",
+			Prefix:    plain[:8],
+			KeyHash:   auth.HashToken(plain),
+			Scopes:    []string{auth.PermissionUsersRead},
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		_, _, err = svc.AuthenticateAPIKey(ctx, plain)
+		assertAppErrorCode(t, err, http.StatusInternalServerError)
+	})
+
+	t.Run("无效、过期和已吊销的密钥不能认证", func(t *testing.T) {
+		svc, apiKeyRepo, owner := setup(t)
+
+		_, _, err := svc.AuthenticateAPIKey(ctx, "not-an-api-key")
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+		_, _, err = svc.AuthenticateAPIKey(ctx, auth.APIKeyPrefix+"unknown")
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+
+		expiredKey := auth.APIKeyPrefix + "expired"
+		_, err = apiKeyRepo.Create(ctx, &models.APIKey{
+			UserID:    owner.ID,
+			Name:      "过期",
+			Prefix:    expiredKey[:8],
+			KeyHash:   auth.HashToken(expiredKey),
+			Scopes:    []string{auth.PermissionUsersRead},
+			ExpiresAt: time.Now().Add(-time.Minute),
+		})
+		require.NoError(t, err)
+		_, _, err = svc.AuthenticateAPIKey(ctx, expiredKey)
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+
+		created, err := svc.CreateAPIKey(ctx, 99, &models.CreateAPIKeyRequest{
+			UserID: owner.ID,
+			Name:   "CI",
+			Scopes: []string{auth.PermissionUsersRead},
+		})
+		require.NoError(t, err)
+		require.NoError(t, svc.RevokeAPIKey(ctx, created.APIKey.ID))
+		_, _, err = svc.AuthenticateAPIKey(ctx, created.Key)
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+
+		// 重复吊销
+		assertAppErrorCode(t, svc.RevokeAPIKey(ctx, created.APIKey.ID), http.StatusNotFound)
+	})
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"strconv"
+	"unicode/utf8"
+
+	"gin/internal/auth"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/logger"
+	"gin/internal/models"
+	"gin/internal/repository"
+	"gin/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// auditVerifyBatchSize 校验哈希链时每次读取的日志数
+const auditVerifyBatchSize = 500
+
+// redactedValue 审计日志中代替敏感字段值的占位符
+const redactedValue = "******"
+
+// auditIgnoredFields 比较修改内容时忽略的字段（自动维护或敏感的字段）
+var auditIgnoredFields = map[string]bool{
+	"id":         true,
+	"password":   true,
+	"version":    true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+}
+
+// AuditRecorder 记录审计事件
+type AuditRecorder interface {
+	// Record 记录一条审计事件：操作者、请求ID、IP 和 User-Agent 未设置时从 context 中读取；
+	// 写入失败只记录错误日志，不影响已完成的业务操作
+	Record(ctx context.Context, entry *models.AuditLog)
+}
+
+// AuditService 审计日志服务接口
+type AuditService interface {
+	AuditRecorder
+	ListAuditLogs(ctx context.Context, req *models.ListAuditLogsRequest) (*models.Page[*models.AuditLog], error)
+	// VerifyChain 按写入顺序重新计算哈希，校验日志是否被修改、删除或插入
+	VerifyChain(ctx context.Context) (*models.AuditVerifyResult, error)
+}
+
+// auditService 审计日志服务实现
+type auditService struct {
+	auditRepo repository.AuditLogRepository
+}
+
+// NewAuditService 创建审计日志服务
+func NewAuditService(auditRepo repository.AuditLogRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// Record 记录审计事件
+func (s *auditService) Record(ctx context.Context, entry *models.AuditLog) {
+	if entry.ActorID == 0 {
+		if caller, ok := auth.PrincipalFromContext(ctx); ok {
+			entry.ActorID = caller.UserID
+			entry.ActorRole = caller.Role.String()
+		}
+	}
+	md := requestctx.FromContext(ctx)
+	if entry.RequestID == "" {
+		entry.RequestID = md.RequestID
+	}
+	if entry.IP == "" {
+		entry.IP = md.ClientIP
+	}
+	if entry.UserAgent == "" {
+		entry.UserAgent = md.UserAgent
+	}
+	entry.UserAgent = truncate(entry.UserAgent, 500)
+	entry.Detail = truncate(entry.Detail, 500)
+	if entry.Outcome == "" {
+		entry.Outcome = models.AuditOutcomeSuccess
+	}
+
+	// 请求已结束或被取消时仍需写入（例如客户端断开后的权限拒绝）
+	if _, err := s.auditRepo.Append(context.WithoutCancel(ctx), entry); err != nil {
+		logger.Log.Error(i18n.LogMessage(i18n.LogAuditWriteFailed),
+			zap.String("request_id", entry.RequestID),
+			zap.String("action", entry.Action),
+			zap.String("target_id", entry.TargetID),
+			zap.Int64("actor_id", entry.ActorID),
+			zap.Error(err),
+		)
+	}
+}
+
+// ListAuditLogs 分页查询审计日志
+func (s *auditService) ListAuditLogs(ctx context.Context, req *models.ListAuditLogsRequest) (*models.Page[*models.AuditLog], error) {
+	page, err := s.auditRepo.List(ctx, req.AuditFilter, req.PageQuery)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrInvalidSort) || stderrors.Is(err, repository.ErrInvalidCursor) {
+			return nil, errors.NewBadRequestError(err.Error(), err)
+		}
+		return nil, errors.NewInternalServerError("获取审计日志失败", err)
+	}
+	return page, nil
+}
+
+// VerifyChain 校验哈希链
+// 每条日志的 PrevHash 必须等于上一条的 Hash（第一条为空），且 Hash 与按内容重新计算的结果一致
+func (s *auditService) VerifyChain(ctx context.Context) (*models.AuditVerifyResult, error) {
+	result := &models.AuditVerifyResult{Valid: true}
+	prevHash := ""
+	var afterID int64
+	for {
+		logs, err := s.auditRepo.ListAfter(ctx, afterID, auditVerifyBatchSize)
+		if err != nil {
+			return nil, errors.NewInternalServerError("校验审计日志失败", err)
+		}
+		for _, log := range logs {
+			switch {
+			case log.PrevHash != prevHash:
+				result.Valid, result.BrokenID = false, log.ID
+				result.Reason = "与上一条日志的哈希不连续，之前的日志可能被删除或插入"
+			case log.ComputeHash() != log.Hash:
+				result.Valid, result.BrokenID = false, log.ID
+				result.Reason = "日志内容与哈希不一致，日志可能被修改"
+			}
+			if !result.Valid {
+				logger.Log.Error(i18n.LogMessage(i18n.LogAuditChainBroken),
+					zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+					zap.Int64("audit_log_id", log.ID),
+					zap.String("reason", result.Reason),
+				)
+				return result, nil
+			}
+			prevHash = log.Hash
+			afterID = log.ID
+			result.Checked++
+		}
+		if len(logs) < auditVerifyBatchSize {
+			return result, nil
+		}
+	}
+}
+
+// noopAuditRecorder 不记录审计事件（未配置审计日志时使用）
+type noopAuditRecorder struct{}
+
+// Record 忽略审计事件
+func (noopAuditRecorder) Record(ctx context.Context, entry *models.AuditLog) {}
+
+// auditFieldChanges 比较两个对象的 JSON 表示，返回修改过的字段（键为 JSON 字段名），
+// before 为 nil 时返回 after 的全部字段；自动维护和敏感的字段不参与比较
+func auditFieldChanges(before, after interface{}) map[string]models.AuditChange {
+	beforeFields, afterFields := auditFields(before), auditFields(after)
+	changes := make(map[string]models.AuditChange)
+	for name, value := range afterFields {
+		old, ok := beforeFields[name]
+		switch {
+		case !ok:
+			changes[name] = models.AuditChange{After: value}
+		case string(old) != string(value):
+			changes[name] = models.AuditChange{Before: old, After: value}
+		}
+	}
+	for name, old := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			changes[name] = models.AuditChange{Before: old}
+		}
+	}
+	return changes
+}
+
+// auditFields 返回对象需要比较的 JSON 字段
+func auditFields(v interface{}) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if v == nil {
+		return fields
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	for name := range auditIgnoredFields {
+		delete(fields, name)
+	}
+	return fields
+}
+
+// encodeAuditChanges 编码修改内容，没有修改时返回 nil
+func encodeAuditChanges(changes map[string]models.AuditChange) json.RawMessage {
+	if len(changes) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(changes) // map 按键排序编码，结果稳定
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// redactedChange 敏感字段的修改记录，只表示字段被修改，不记录值
+func redactedChange() models.AuditChange {
+	return models.AuditChange{Before: redactedValue, After: redactedValue}
+}
+
+// auditTargetID 返回审计对象的ID
+func auditTargetID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// truncate 截断过长的字符串（按字节，保证 UTF-8 完整）
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/models"
+	"gin/internal/requestctx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAuditLogRepository 是 AuditLogRepository 的 mock 实现
+type MockAuditLogRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditLogRepository) Append(ctx context.Context, entry *models.AuditLog) (*models.AuditLog, error) {
+	args := m.Called(ctx, entry)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuditLog), args.Error(1)
+}
+
+func (m *MockAuditLogRepository) List(ctx context.Context, filter models.AuditFilter, page models.PageQuery) (*models.Page[*models.AuditLog], error) {
+	args := m.Called(ctx, filter, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Page[*models.AuditLog]), args.Error(1)
+}
+
+func (m *MockAuditLogRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]*models.AuditLog, error) {
+	args := m.Called(ctx, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AuditLog), args.Error(1)
+}
+
+// recordingAuditRecorder 保存记录的审计事件，供测试断言
+type recordingAuditRecorder struct {
+	entries []*models.AuditLog
+}
+
+func (r *recordingAuditRecorder) Record(ctx context.Context, entry *models.AuditLog) {
+	r.entries = append(r.entries, entry)
+}
+
+// chainedAuditLogs 生成哈希链完整的审计日志
+func chainedAuditLogs(actions ...string) []*models.AuditLog {
+	logs := make([]*models.AuditLog, 0, len(actions))
+	prevHash := ""
+	for i, action := range actions {
+		log := &models.AuditLog{
+			ID:        int64(i + 1),
+			ActorID:   1,
+			Action:    action,
+			Outcome:   models.AuditOutcomeSuccess,
+			PrevHash:  prevHash,
+			CreatedAt: time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+		}
+		log.Hash = log.ComputeHash()
+		prevHash = log.Hash
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+// TestAuditService_Record 测试记录审计事件
+func TestAuditService_Record(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 9, Role: auth.RoleAdmin})
+	ctx = requestctx.WithMetadata(ctx, requestctx.Metadata{RequestID: "req-1", ClientIP: "10.0.0.1", UserAgent: "curl/8.0"})
+
+	repo := new(MockAuditLogRepository)
+	repo.On("Append", mock.Anything, mock.MatchedBy(func(entry *models.AuditLog) bool {
+		return entry.ActorID == 9 && entry.ActorRole == "admin" && entry.RequestID == "req-1" &&
+			entry.IP == "10.0.0.1" && entry.UserAgent == "curl/8.0" && entry.Outcome == models.AuditOutcomeSuccess
+	})).Return(&models.AuditLog{ID: 1}, nil)
+
+	NewAuditService(repo).Record(ctx, &models.AuditLog{Action: models.AuditUserDelete})
+	repo.AssertExpectations(t)
+}
+
+// TestAuditService_VerifyChain 测试校验哈希链
+func TestAuditService_VerifyChain(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("哈希链完整", func(t *testing.T) {
+		repo := new(MockAuditLogRepository)
+		repo.On("ListAfter", ctx, int64(0), auditVerifyBatchSize).Return(chainedAuditLogs(models.AuditUserCreate, models.AuditUserUpdate), nil)
+
+		result, err := NewAuditService(repo).VerifyChain(ctx)
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Equal(t, int64(2), result.Checked)
+	})
+
+	t.Run("日志内容被修改", func(t *testing.T) {
+		logs := chainedAuditLogs(models.AuditUserCreate, models.AuditUserUpdate, models.AuditUserDelete)
+		logs[1].Detail = "已篡改"
+		repo := new(MockAuditLogRepository)
+		repo.On("ListAfter", ctx, int64(0), auditVerifyBatchSize).Return(logs, nil)
+
+		result, err := NewAuditService(repo).VerifyChain(ctx)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(2), result.BrokenID)
+		assert.Equal(t, int64(1), result.Checked)
+	})
+
+	t.Run("日志被删除", func(t *testing.T) {
+		logs := chainedAuditLogs(models.AuditUserCreate, models.AuditUserUpdate, models.AuditUserDelete)
+		repo := new(MockAuditLogRepository)
+		repo.On("ListAfter", ctx, int64(0), auditVerifyBatchSize).Return([]*models.AuditLog{logs[0], logs[2]}, nil)
+
+		result, err := NewAuditService(repo).VerifyChain(ctx)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.BrokenID)
+		assert.Contains(t, result.Reason, "不连续")
+	})
+}
+
+// TestAuditFieldChanges 测试比较修改的字段
+func TestAuditFieldChanges(t *testing.T) {
+	before := &models.User{ID: 1, Name: "张三", Email: "zhangsan@example.com", Password: "old", Version: 1}
+	after := &models.User{ID: 1, Name: "张三丰", Email: "zhangsan@example.com", Password: "new", Version: 2}
+
+	changes := auditFieldChanges(before, after)
+	require.Len(t, changes, 1, "只记录修改的字段，忽略密码和版本号")
+
+	var decoded map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(encodeAuditChanges(changes), &decoded))
+	assert.Equal(t, "张三", decoded["name"]["before"])
+	assert.Equal(t, "张三丰", decoded["name"]["after"])
+
+	assert.Nil(t, encodeAuditChanges(auditFieldChanges(before, before)))
+}
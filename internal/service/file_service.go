@@ -0,0 +1,492 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/database"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/imaging"
+	"gin/internal/logger"
+	"gin/internal/models"
+	"gin/internal/policy"
+	"gin/internal/repository"
+	"gin/internal/requestctx"
+	"gin/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// sniffLength 识别文件类型时读取的字节数（与 http.DetectContentType 一致）
+const sniffLength = 512
+
+// maxFileNameLength 保存的原始文件名的最大字节数
+const maxFileNameLength = 255
+
+// fileExtensions 按识别出的文件类型生成存储键的扩展名
+var fileExtensions = map[string]string{
+	"image/jpeg":      "jpg",
+	"image/png":       "png",
+	"image/gif":       "gif",
+	"image/webp":      "webp",
+	"image/bmp":       "bmp",
+	"application/pdf": "pdf",
+	"application/zip": "zip",
+	"text/plain":      "txt",
+}
+
+// FileUpload 待保存的上传文件
+type FileUpload struct {
+	Name    string    // 客户端提供的文件名，只用于展示
+	Size    int64     // 内容长度
+	Content io.Reader // 文件内容
+}
+
+// FileService 文件服务接口
+type FileService interface {
+	// UploadFiles 保存上传的文件：按内容识别类型并检查上传限制，全部保存成功后写入文件信息；
+	// 任一文件失败时不保存任何文件
+	UploadFiles(ctx context.Context, uploads []*FileUpload, policy models.UploadPolicy) ([]*models.File, error)
+	// GetFile 获取当前用户可以访问的文件信息（所有者本人、管理员或拥有 files:read 权限）
+	GetFile(ctx context.Context, id int64) (*models.File, error)
+	// OpenFile 打开当前用户可以访问的文件，调用方负责关闭；transform 不为空时返回变换后的图片
+	OpenFile(ctx context.Context, id int64, transform models.ImageTransform) (*models.File, io.ReadSeekCloser, error)
+	// SignFile 为当前用户可以访问的文件生成签名，ttl 为 0 时使用默认有效期；transform 参与签名
+	SignFile(ctx context.Context, id int64, ttl time.Duration, transform models.ImageTransform) (signature string, expiresAt time.Time, err error)
+	// OpenSignedFile 校验签名后打开文件，不需要调用者身份；transform 必须与签名时一致
+	OpenSignedFile(ctx context.Context, id, expires int64, signature string, transform models.ImageTransform) (*models.File, io.ReadSeekCloser, error)
+}
+
+// fileService 文件服务实现
+type fileService struct {
+	fileRepo  repository.FileRepository
+	storage   storage.Storage
+	txManager database.TxManager
+	audit     AuditRecorder
+	signer    *auth.HMACSigner
+	signedURL config.SignedURLConfig
+	images    *imaging.Processor
+}
+
+// FileServiceOption 文件服务可选配置
+type FileServiceOption func(*fileService)
+
+// WithFileTxManager 指定事务管理器，批量上传的文件信息在一个事务中写入（默认不开启事务）
+func WithFileTxManager(txManager database.TxManager) FileServiceOption {
+	return func(s *fileService) {
+		s.txManager = txManager
+	}
+}
+
+// WithFileAuditRecorder 指定审计日志记录器（默认不记录）
+func WithFileAuditRecorder(audit AuditRecorder) FileServiceOption {
+	return func(s *fileService) {
+		s.audit = audit
+	}
+}
+
+// WithFileURLSigner 指定签名链接的签名器和有效期配置（默认不支持签名链接）
+func WithFileURLSigner(signer *auth.HMACSigner, cfg config.SignedURLConfig) FileServiceOption {
+	return func(s *fileService) {
+		s.signer = signer
+		s.signedURL = cfg
+	}
+}
+
+// WithFileImageProcessor 指定图片处理器：上传的图片去除元数据并生成缩略图，下载时支持缩放（默认不处理图片）
+func WithFileImageProcessor(images *imaging.Processor) FileServiceOption {
+	return func(s *fileService) {
+		s.images = images
+	}
+}
+
+// NewFileService 创建文件服务
+func NewFileService(fileRepo repository.FileRepository, store storage.Storage, opts ...FileServiceOption) FileService {
+	s := &fileService{
+		fileRepo:  fileRepo,
+		storage:   store,
+		txManager: database.NewNoopTxManager(),
+		audit:     noopAuditRecorder{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// UploadFiles 保存上传的文件
+// 存储键由服务端生成，客户端提供的文件名只保存在文件信息中，不会出现在存储路径里
+func (s *fileService) UploadFiles(ctx context.Context, uploads []*FileUpload, policy models.UploadPolicy) ([]*models.File, error) {
+	if len(uploads) == 0 {
+		return nil, errors.NewBadRequestError("没有上传文件", fmt.Errorf("no files"))
+	}
+	if policy.MaxFiles > 0 && len(uploads) > policy.MaxFiles {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("一次最多上传 %d 个文件", policy.MaxFiles), fmt.Errorf("%d files exceeds %d", len(uploads), policy.MaxFiles))
+	}
+	for _, upload := range uploads {
+		if upload.Size <= 0 {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("文件为空: %s", sanitizeFileName(upload.Name)), fmt.Errorf("empty file"))
+		}
+		if upload.Size > policy.MaxSize {
+			return nil, errors.NewRequestEntityTooLargeError(fmt.Sprintf("文件不能超过 %d MB: %s", policy.MaxSize>>20, sanitizeFileName(upload.Name)),
+				fmt.Errorf("file size %d exceeds %d", upload.Size, policy.MaxSize))
+		}
+	}
+
+	caller, _ := auth.PrincipalFromContext(ctx)
+	files := make([]*models.File, 0, len(uploads))
+	var err error
+	defer func() {
+		// 失败时删除已写入存储的文件（文件信息随事务回滚）
+		if err != nil {
+			s.removeStored(ctx, files)
+		}
+	}()
+
+	for _, upload := range uploads {
+		var file *models.File
+		if file, err = s.store(ctx, upload, policy); err != nil {
+			return nil, err
+		}
+		file.OwnerID = caller.UserID
+		files = append(files, file)
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		for _, file := range files {
+			if _, err := s.fileRepo.Create(ctx, file); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.NewInternalServerError("保存文件信息失败", err)
+	}
+
+	for _, file := range files {
+		logger.Log.Info(i18n.LogMessage(i18n.LogFileUploaded),
+			zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+			zap.Int64("file_id", file.ID),
+			zap.Int64("owner_id", file.OwnerID),
+			zap.String("content_type", file.ContentType),
+			zap.Int64("size", file.Size),
+		)
+		s.audit.Record(ctx, &models.AuditLog{
+			Action:     models.AuditFileUpload,
+			TargetType: models.AuditTargetFile,
+			TargetID:   auditTargetID(file.ID),
+			Detail:     fmt.Sprintf("name=%s, content_type=%s, size=%d", file.Name, file.ContentType, file.Size),
+		})
+		s.generateThumbnails(ctx, file)
+	}
+	return files, nil
+}
+
+// store 识别文件类型并写入存储，返回尚未保存的文件信息
+func (s *fileService) store(ctx context.Context, upload *FileUpload, policy models.UploadPolicy) (*models.File, error) {
+	name := sanitizeFileName(upload.Name)
+
+	// 读取文件开头识别类型，不信任客户端提供的 Content-Type 和扩展名
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(upload.Content, head)
+	if err != nil && !stderrors.Is(err, io.ErrUnexpectedEOF) && !stderrors.Is(err, io.EOF) {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("读取文件失败: %s", name), err)
+	}
+	head = head[:n]
+	contentType := sniffContentType(head)
+	if !policy.Allows(contentType) {
+		return nil, errors.NewUnsupportedMediaTypeError(fmt.Sprintf("不支持的文件类型: %s（%s）", contentType, name),
+			fmt.Errorf("content type %s not allowed", contentType))
+	}
+
+	content := io.LimitReader(io.MultiReader(bytes.NewReader(head), upload.Content), upload.Size)
+	size := upload.Size
+	if s.images != nil && s.images.Supported(contentType) {
+		// 图片去除元数据后保存，大小和校验和按处理后的内容计算
+		data, imageType, err := s.sanitizeImage(content, size, contentType, name)
+		if err != nil {
+			return nil, err
+		}
+		if !policy.Allows(imageType) {
+			return nil, errors.NewUnsupportedMediaTypeError(fmt.Sprintf("不支持的文件类型: %s（%s）", imageType, name),
+				fmt.Errorf("content type %s not allowed", imageType))
+		}
+		content, size, contentType = bytes.NewReader(data), int64(len(data)), imageType
+	}
+
+	key, err := storage.NewKey(time.Now(), fileExtensions[contentType])
+	if err != nil {
+		return nil, errors.NewInternalServerError("保存文件失败", err)
+	}
+
+	hash := sha256.New()
+	if err := s.storage.Put(ctx, key, io.TeeReader(content, hash), size, contentType); err != nil {
+		return nil, errors.NewInternalServerError("保存文件失败", err)
+	}
+
+	return &models.File{
+		StorageKey:  key,
+		Name:        name,
+		ContentType: contentType,
+		Size:        size,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// sanitizeImage 读取图片并去除元数据、按拍摄方向旋转，返回处理后的内容和类型
+func (s *fileService) sanitizeImage(r io.Reader, size int64, contentType, name string) ([]byte, string, error) {
+	if size > imaging.MaxInputSize {
+		return nil, "", errors.NewRequestEntityTooLargeError(fmt.Sprintf("图片不能超过 %d MB: %s", imaging.MaxInputSize>>20, name),
+			fmt.Errorf("image size %d exceeds %d", size, imaging.MaxInputSize))
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", errors.NewBadRequestError(fmt.Sprintf("读取文件失败: %s", name), err)
+	}
+
+	data, contentType, err = s.images.Sanitize(data, contentType)
+	switch {
+	case err == nil:
+		return data, contentType, nil
+	case stderrors.Is(err, imaging.ErrTooLarge):
+		return nil, "", errors.NewRequestEntityTooLargeError(fmt.Sprintf("图片尺寸过大: %s", name), err)
+	case stderrors.Is(err, imaging.ErrInvalidImage):
+		return nil, "", errors.NewBadRequestError(fmt.Sprintf("图片无法解析: %s", name), err)
+	default:
+		return nil, "", errors.NewInternalServerError("处理图片失败", err)
+	}
+}
+
+// generateThumbnails 生成配置的缩略图，失败时只记录日志，下载缩略图时会重新生成
+func (s *fileService) generateThumbnails(ctx context.Context, file *models.File) {
+	if s.images == nil || !s.images.Supported(file.ContentType) {
+		return
+	}
+	if err := s.images.GenerateThumbnails(ctx, s.imageSource(file)); err != nil {
+		logger.Log.Warn(i18n.LogMessage(i18n.LogImageThumbnailFailed),
+			zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+			zap.Int64("file_id", file.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// imageSource 从文件存储中读取原图
+func (s *fileService) imageSource(file *models.File) imaging.Source {
+	return imaging.Source{
+		Checksum:    file.Checksum,
+		ContentType: file.ContentType,
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			return s.storage.Get(ctx, file.StorageKey)
+		},
+	}
+}
+
+// removeStored 删除已写入存储的文件
+func (s *fileService) removeStored(ctx context.Context, files []*models.File) {
+	ctx = context.WithoutCancel(ctx)
+	for _, file := range files {
+		if err := s.storage.Delete(ctx, file.StorageKey); err != nil {
+			logger.Log.Error(i18n.LogMessage(i18n.LogFileCleanupFailed),
+				zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+				zap.String("storage_key", file.StorageKey),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// GetFile 获取当前用户可以访问的文件信息
+// 无权访问时按不存在处理，不暴露其他用户的文件是否存在
+func (s *fileService) GetFile(ctx context.Context, id int64) (*models.File, error) {
+	file, err := s.findFile(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.Authorize(ctx, file.OwnerID, policy.Self, policy.Admin, policy.Permission(auth.PermissionFilesRead)); err != nil {
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) && appErr.Code == http.StatusForbidden {
+			return nil, errors.NewNotFoundError("文件不存在", err)
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// OpenFile 打开当前用户可以访问的文件
+func (s *fileService) OpenFile(ctx context.Context, id int64, transform models.ImageTransform) (*models.File, io.ReadSeekCloser, error) {
+	file, err := s.GetFile(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.open(ctx, file, transform)
+}
+
+// SignFile 为文件生成签名
+func (s *fileService) SignFile(ctx context.Context, id int64, ttl time.Duration, transform models.ImageTransform) (string, time.Time, error) {
+	if s.signer == nil {
+		return "", time.Time{}, errors.NewInternalServerError("未配置签名链接", fmt.Errorf("file url signer not configured"))
+	}
+	if ttl == 0 {
+		ttl = time.Duration(s.signedURL.DefaultTTL) * time.Second
+	}
+	if maxTTL := time.Duration(s.signedURL.MaxTTL) * time.Second; ttl <= 0 || (maxTTL > 0 && ttl > maxTTL) {
+		return "", time.Time{}, errors.NewBadRequestError(fmt.Sprintf("有效期必须在 1 到 %d 秒之间", s.signedURL.MaxTTL),
+			fmt.Errorf("invalid ttl %s", ttl))
+	}
+
+	file, err := s.GetFile(ctx, id)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	signature := s.signer.Sign(fileSignatureMessage(file.ID, expiresAt.Unix(), transform))
+	detail := fmt.Sprintf("expires_at=%s", expiresAt.UTC().Format(time.RFC3339))
+	if !transform.IsZero() {
+		detail += ", " + transform.Values().Encode()
+	}
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditFileShare,
+		TargetType: models.AuditTargetFile,
+		TargetID:   auditTargetID(file.ID),
+		Detail:     detail,
+	})
+	return signature, expiresAt, nil
+}
+
+// OpenSignedFile 校验签名后打开文件
+// 签名包含文件ID、过期时间和图片变换参数，修改、增加或去掉任一参数都会导致签名无效
+func (s *fileService) OpenSignedFile(ctx context.Context, id, expires int64, signature string, transform models.ImageTransform) (*models.File, io.ReadSeekCloser, error) {
+	if s.signer == nil || !s.signer.Verify(fileSignatureMessage(id, expires, transform), signature) {
+		return nil, nil, errors.NewForbiddenError("签名无效", fmt.Errorf("invalid signature for file %d", id))
+	}
+	if time.Now().Unix() > expires {
+		return nil, nil, errors.NewForbiddenError("链接已过期", fmt.Errorf("signature for file %d expired at %d", id, expires))
+	}
+
+	file, err := s.findFile(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.open(ctx, file, transform)
+}
+
+// findFile 查询文件信息（不检查访问权限）
+func (s *fileService) findFile(ctx context.Context, id int64) (*models.File, error) {
+	if id <= 0 {
+		return nil, errors.NewBadRequestError("文件ID无效", fmt.Errorf("invalid file id: %d", id))
+	}
+
+	file, err := s.fileRepo.FindByID(ctx, id)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrFileNotFound) {
+			return nil, errors.NewNotFoundError("文件不存在", err)
+		}
+		return nil, errors.NewInternalServerError("获取文件信息失败", err)
+	}
+	return file, nil
+}
+
+// open 从文件存储中打开文件内容
+func (s *fileService) open(ctx context.Context, file *models.File, transform models.ImageTransform) (*models.File, io.ReadSeekCloser, error) {
+	if !transform.IsZero() {
+		return s.openVariant(ctx, file, transform)
+	}
+	content, err := s.storage.Open(ctx, file.StorageKey, file.Size)
+	if err != nil {
+		if stderrors.Is(err, storage.ErrNotFound) {
+			return nil, nil, errors.NewNotFoundError("文件不存在", err)
+		}
+		return nil, nil, errors.NewInternalServerError("读取文件失败", err)
+	}
+	return file, content, nil
+}
+
+// openVariant 打开变换后的图片，返回的文件信息中类型、大小、名称和校验和按变换后的图片填写
+func (s *fileService) openVariant(ctx context.Context, file *models.File, transform models.ImageTransform) (*models.File, io.ReadSeekCloser, error) {
+	if s.images == nil || !s.images.Supported(file.ContentType) {
+		return nil, nil, errors.NewBadRequestError("该文件不支持图片变换", fmt.Errorf("cannot transform %s", file.ContentType))
+	}
+
+	opts := imaging.Options{Width: transform.Width, Height: transform.Height, Fit: imaging.Fit(transform.Fit)}
+	if transform.Thumbnail != "" {
+		if transform.Width > 0 || transform.Height > 0 || transform.Fit != "" {
+			return nil, nil, errors.NewBadRequestError("缩略图不能与宽高参数同时使用", fmt.Errorf("thumbnail with size options"))
+		}
+		var err error
+		if opts, err = s.images.Thumbnail(transform.Thumbnail); err != nil {
+			return nil, nil, errors.NewBadRequestError(fmt.Sprintf("缩略图不存在: %s", transform.Thumbnail), err)
+		}
+	}
+
+	variant, err := s.images.Variant(ctx, s.imageSource(file), opts)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, imaging.ErrInvalidOptions):
+			return nil, nil, errors.NewBadRequestError(err.Error(), err)
+		case stderrors.Is(err, storage.ErrNotFound):
+			return nil, nil, errors.NewNotFoundError("文件不存在", err)
+		case stderrors.Is(err, imaging.ErrTooLarge), stderrors.Is(err, imaging.ErrInvalidImage):
+			return nil, nil, errors.NewBadRequestError("该图片不支持变换", err)
+		default:
+			return nil, nil, errors.NewInternalServerError("处理图片失败", err)
+		}
+	}
+
+	derived := *file
+	derived.ContentType = variant.ContentType
+	derived.Size = variant.Size
+	derived.Checksum = variant.Key
+	derived.Name = strings.TrimSuffix(file.Name, path.Ext(file.Name)) + "." + fileExtensions[variant.ContentType]
+	return &derived, variant.Content, nil
+}
+
+// fileSignatureMessage 文件签名链接的签名内容
+// 图片变换参数按参数名排序后编码追加在后面；不带变换参数时与之前签发的链接保持一致
+func fileSignatureMessage(id, expires int64, transform models.ImageTransform) string {
+	message := fmt.Sprintf("files/%d/%d", id, expires)
+	if !transform.IsZero() {
+		message += "?" + transform.Values().Encode()
+	}
+	return message
+}
+
+// sniffContentType 按文件内容识别类型（不带 charset 等参数）
+func sniffContentType(head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// sanitizeFileName 只保留文件名中最后一段，去掉控制字符，过长时截断
+func sanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return truncate(name, maxFileNameLength)
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/imaging"
+	"gin/internal/models"
+	"gin/internal/repository"
+	"gin/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockFileRepository 是 FileRepository 的 mock 实现
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) Create(ctx context.Context, file *models.File) (*models.File, error) {
+	args := m.Called(ctx, file)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) FindByID(ctx context.Context, id int64) (*models.File, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) ListByOwner(ctx context.Context, ownerID int64) ([]*models.File, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.File), args.Error(1)
+}
+
+func (m *MockFileRepository) DeleteByOwner(ctx context.Context, ownerID int64) (int64, error) {
+	args := m.Called(ctx, ownerID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// pngContent 以 PNG 文件头开始的内容
+const pngContent = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR-test-image"
+
+// storedFiles 返回存储目录中的所有文件（不含目录）
+func storedFiles(t *testing.T, dir string) []string {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return files
+}
+
+// TestFileService_UploadFiles 测试上传文件
+func TestFileService_UploadFiles(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Role: auth.RoleUser})
+	policy := models.UploadPolicy{MaxSize: 1 << 20, MaxFiles: 2, AllowedTypes: []string{"image/*", "text/plain"}}
+
+	upload := func(name, content string) *FileUpload {
+		return &FileUpload{Name: name, Size: int64(len(content)), Content: strings.NewReader(content)}
+	}
+
+	t.Run("按内容识别类型并生成存储键", func(t *testing.T) {
+		dir := t.TempDir()
+		fileRepo := new(MockFileRepository)
+		service := NewFileService(fileRepo, storage.NewLocalStorage(dir))
+		fileRepo.On("Create", ctx, mock.AnythingOfType("*models.File")).Return(&models.File{ID: 1}, nil)
+
+		files, err := service.UploadFiles(ctx, []*FileUpload{upload(`..\..\C:\tmp\头像.txt`, pngContent)}, policy)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		file := files[0]
+		assert.Equal(t, "头像.txt", file.Name, "只保留文件名，不影响存储路径")
+		assert.Equal(t, "image/png", file.ContentType, "不信任扩展名")
+		assert.Regexp(t, `^\d{4}/\d{2}/\d{2}/[0-9a-f]{32}\.png$`, file.StorageKey)
+		assert.Equal(t, int64(7), file.OwnerID)
+		sum := sha256.Sum256([]byte(pngContent))
+		assert.Equal(t, hex.EncodeToString(sum[:]), file.Checksum)
+
+		r, err := storage.NewLocalStorage(dir).Get(ctx, file.StorageKey)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(r)
+		r.Close()
+		assert.Equal(t, pngContent, string(data))
+	})
+
+	t.Run("不允许的类型返回415", func(t *testing.T) {
+		dir := t.TempDir()
+		fileRepo := new(MockFileRepository)
+		service := NewFileService(fileRepo, storage.NewLocalStorage(dir))
+
+		_, err := service.UploadFiles(ctx, []*FileUpload{upload("a.png", "<html><script>alert(1)</script></html>")}, policy)
+		assertAppErrorCode(t, err, http.StatusUnsupportedMediaType)
+		assert.Empty(t, storedFiles(t, dir))
+		fileRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("超过大小和数量限制", func(t *testing.T) {
+		service := NewFileService(new(MockFileRepository), storage.NewLocalStorage(t.TempDir()))
+
+		_, err := service.UploadFiles(ctx, []*FileUpload{{Name: "big.png", Size: 2 << 20, Content: strings.NewReader(pngContent)}}, policy)
+		assertAppErrorCode(t, err, http.StatusRequestEntityTooLarge)
+
+		_, err = service.UploadFiles(ctx, []*FileUpload{upload("1.txt", "a"), upload("2.txt", "b"), upload("3.txt", "c")}, policy)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		_, err = service.UploadFiles(ctx, []*FileUpload{upload("empty.txt", "")}, policy)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("批量上传中有文件失败时不保存任何文件", func(t *testing.T) {
+		dir := t.TempDir()
+		fileRepo := new(MockFileRepository)
+		service := NewFileService(fileRepo, storage.NewLocalStorage(dir))
+
+		_, err := service.UploadFiles(ctx, []*FileUpload{upload("a.txt", "hello"), upload("b.pdf", "%PDF-1.4")}, policy)
+		assertAppErrorCode(t, err, http.StatusUnsupportedMediaType)
+		assert.Empty(t, storedFiles(t, dir))
+		fileRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("保存文件信息失败时删除已保存的文件", func(t *testing.T) {
+		dir := t.TempDir()
+		fileRepo := new(MockFileRepository)
+		service := NewFileService(fileRepo, storage.NewLocalStorage(dir))
+		fileRepo.On("Create", ctx, mock.Anything).Return(nil, errors.New("数据库错误"))
+
+		_, err := service.UploadFiles(ctx, []*FileUpload{upload("a.txt", "hello")}, policy)
+		assertAppErrorCode(t, err, http.StatusInternalServerError)
+		assert.Empty(t, storedFiles(t, dir))
+	})
+}
+
+// TestUploadPolicy_Allows 测试上传类型白名单
+func TestUploadPolicy_Allows(t *testing.T) {
+	policy := models.UploadPolicy{AllowedTypes: []string{"image/*", "application/pdf"}}
+	assert.True(t, policy.Allows("image/png"))
+	assert.True(t, policy.Allows("application/pdf"))
+	assert.False(t, policy.Allows("imagex/png"))
+	assert.False(t, policy.Allows("text/html"))
+}
+
+// TestFileService_OpenFile 测试下载文件的访问控制和签名链接
+func TestFileService_OpenFile(t *testing.T) {
+	owner := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Role: auth.RoleUser})
+	other := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 8, Role: auth.RoleUser})
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1, Role: auth.RoleAdmin})
+	auditor := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 9, Role: auth.Role("auditor")})
+	auth.Permissions.Load(map[auth.Role][]string{"auditor": {auth.PermissionFilesRead}})
+	defer auth.Permissions.Load(nil)
+
+	fileStorage := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, fileStorage.Put(context.Background(), "2024/01/02/a.txt", strings.NewReader("hello"), 5, "text/plain"))
+	file := &models.File{ID: 1, StorageKey: "2024/01/02/a.txt", Name: "a.txt", ContentType: "text/plain", Size: 5, OwnerID: 7}
+
+	fileRepo := new(MockFileRepository)
+	fileRepo.On("FindByID", mock.Anything, int64(1)).Return(file, nil)
+	fileRepo.On("FindByID", mock.Anything, int64(2)).Return(nil, repository.ErrFileNotFound)
+	signer, err := auth.NewHMACSigner("secret")
+	require.NoError(t, err)
+	service := NewFileService(fileRepo, fileStorage, WithFileURLSigner(signer, config.SignedURLConfig{DefaultTTL: 60, MaxTTL: 3600}))
+
+	t.Run("所有者、管理员和有files:read权限的用户可以下载", func(t *testing.T) {
+		for _, ctx := range []context.Context{owner, admin, auditor} {
+			got, content, err := service.OpenFile(ctx, 1, models.ImageTransform{})
+			require.NoError(t, err)
+			_, err = content.Seek(1, io.SeekStart)
+			require.NoError(t, err)
+			data, _ := io.ReadAll(content)
+			content.Close()
+			assert.Equal(t, "ello", string(data))
+			assert.Equal(t, file, got)
+		}
+	})
+
+	t.Run("其他用户按不存在处理", func(t *testing.T) {
+		_, _, err := service.OpenFile(other, 1, models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusNotFound)
+		_, _, err = service.OpenFile(owner, 2, models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusNotFound)
+		_, _, err = service.OpenFile(context.Background(), 1, models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("签名链接", func(t *testing.T) {
+		signature, expiresAt, err := service.SignFile(owner, 1, 0, models.ImageTransform{})
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, 2*time.Second, "使用默认有效期")
+
+		_, content, err := service.OpenSignedFile(context.Background(), 1, expiresAt.Unix(), signature, models.ImageTransform{})
+		require.NoError(t, err)
+		content.Close()
+
+		_, _, err = service.OpenSignedFile(context.Background(), 1, expiresAt.Unix()+3600, signature, models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusForbidden)
+		_, _, err = service.OpenSignedFile(context.Background(), 2, expiresAt.Unix(), signature, models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusForbidden)
+
+		// 图片变换参数参与签名：不能在原图链接上增加参数，也不能修改或去掉签名时的参数
+		_, _, err = service.OpenSignedFile(context.Background(), 1, expiresAt.Unix(), signature, models.ImageTransform{Width: 100})
+		assertAppErrorCode(t, err, http.StatusForbidden)
+		thumbSignature, thumbExpiresAt, err := service.SignFile(owner, 1, 0, models.ImageTransform{Thumbnail: "small"})
+		require.NoError(t, err)
+		_, _, err = service.OpenSignedFile(context.Background(), 1, thumbExpiresAt.Unix(), thumbSignature, models.ImageTransform{Thumbnail: "large"})
+		assertAppErrorCode(t, err, http.StatusForbidden)
+		_, _, err = service.OpenSignedFile(context.Background(), 1, thumbExpiresAt.Unix(), thumbSignature, models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusForbidden)
+
+		expired := time.Now().Add(-time.Second).Unix()
+		_, _, err = service.OpenSignedFile(context.Background(), 1, expired, signer.Sign(fileSignatureMessage(1, expired, models.ImageTransform{})), models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusForbidden)
+	})
+
+	t.Run("签名链接的有效期和访问控制", func(t *testing.T) {
+		_, _, err := service.SignFile(owner, 1, 2*time.Hour, models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		_, _, err = service.SignFile(other, 1, time.Minute, models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusNotFound)
+
+		_, _, err = NewFileService(fileRepo, fileStorage).SignFile(owner, 1, time.Minute, models.ImageTransform{})
+		assertAppErrorCode(t, err, http.StatusInternalServerError)
+	})
+}
+
+// jpegWithOrientation 生成带有 EXIF 拍摄方向的 JPEG
+func jpegWithOrientation(t *testing.T, w, h, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h)), nil))
+
+	// 大端序 TIFF，IFD0 只有拍摄方向一个条目
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01")
+	tiff = append(tiff, 0, byte(orientation), 0, 0, 0, 0, 0, 0)
+	exif := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, 0xE1}, binary.BigEndian.AppendUint16(nil, uint16(len(exif)+2))...)
+	segment = append(segment, exif...)
+	return append(append(append([]byte{}, buf.Bytes()[:2]...), segment...), buf.Bytes()[2:]...)
+}
+
+// TestFileService_Images 测试上传图片的处理和下载时的图片变换
+func TestFileService_Images(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Role: auth.RoleUser})
+	policy := models.UploadPolicy{MaxSize: 1 << 20, MaxFiles: 1, AllowedTypes: []string{"image/*", "text/plain"}}
+
+	dir, cacheDir := t.TempDir(), t.TempDir()
+	images, err := imaging.New(config.ImagesConfig{
+		CacheDir:     cacheDir,
+		MaxDimension: 1024,
+		Thumbnails:   []config.ThumbnailConfig{{Name: "small", Width: 16, Height: 16, Fit: "cover"}},
+	})
+	require.NoError(t, err)
+	fileStorage := storage.NewLocalStorage(dir)
+	fileRepo := new(MockFileRepository)
+	fileRepo.On("Create", ctx, mock.AnythingOfType("*models.File")).Return(&models.File{ID: 1}, nil)
+	service := NewFileService(fileRepo, fileStorage, WithFileImageProcessor(images))
+
+	source := jpegWithOrientation(t, 40, 20, 6)
+	files, err := service.UploadFiles(ctx, []*FileUpload{{Name: "头像.jpeg", Size: int64(len(source)), Content: bytes.NewReader(source)}}, policy)
+	require.NoError(t, err)
+	file := files[0]
+	file.ID = 1
+	fileRepo.On("FindByID", mock.Anything, int64(1)).Return(file, nil)
+
+	t.Run("上传时去除EXIF并按拍摄方向旋转", func(t *testing.T) {
+		r, err := fileStorage.Get(ctx, file.StorageKey)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(r)
+		r.Close()
+
+		assert.NotContains(t, string(data), "Exif")
+		assert.Equal(t, int64(len(data)), file.Size, "大小按处理后的内容计算")
+		sum := sha256.Sum256(data)
+		assert.Equal(t, hex.EncodeToString(sum[:]), file.Checksum)
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, [2]int{20, 40}, [2]int{cfg.Width, cfg.Height})
+	})
+
+	t.Run("上传后生成缩略图", func(t *testing.T) {
+		assert.Len(t, storedFiles(t, cacheDir), 1)
+	})
+
+	t.Run("无法解析的图片返回400", func(t *testing.T) {
+		before := len(storedFiles(t, dir))
+		_, err := service.UploadFiles(ctx, []*FileUpload{{Name: "a.png", Size: int64(len(pngContent)), Content: strings.NewReader(pngContent)}}, policy)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		assert.Len(t, storedFiles(t, dir), before)
+	})
+
+	t.Run("按参数缩放", func(t *testing.T) {
+		got, content, err := service.OpenFile(ctx, 1, models.ImageTransform{Width: 10})
+		require.NoError(t, err)
+		defer content.Close()
+
+		assert.Equal(t, "image/jpeg", got.ContentType)
+		assert.Equal(t, "头像.jpg", got.Name)
+		assert.NotEqual(t, file.Checksum, got.Checksum, "ETag 按变换后的图片计算")
+		cfg, err := jpeg.DecodeConfig(content)
+		require.NoError(t, err)
+		assert.Equal(t, [2]int{10, 20}, [2]int{cfg.Width, cfg.Height})
+	})
+
+	t.Run("缩略图", func(t *testing.T) {
+		_, content, err := service.OpenFile(ctx, 1, models.ImageTransform{Thumbnail: "small"})
+		require.NoError(t, err)
+		defer content.Close()
+
+		cfg, err := jpeg.DecodeConfig(content)
+		require.NoError(t, err)
+		assert.Equal(t, [2]int{16, 16}, [2]int{cfg.Width, cfg.Height})
+		assert.Len(t, storedFiles(t, cacheDir), 2)
+	})
+
+	t.Run("参数无效返回400", func(t *testing.T) {
+		for _, transform := range []models.ImageTransform{
+			{Width: 2000},
+			{Thumbnail: "large"},
+			{Thumbnail: "small", Width: 10},
+		} {
+			_, _, err := service.OpenFile(ctx, 1, transform)
+			assertAppErrorCode(t, err, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("非图片文件不支持变换", func(t *testing.T) {
+		fileRepo.On("FindByID", mock.Anything, int64(2)).Return(&models.File{ID: 2, ContentType: "text/plain", OwnerID: 7}, nil)
+		_, _, err := service.OpenFile(ctx, 2, models.ImageTransform{Width: 10})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		_, _, err = NewFileService(fileRepo, fileStorage).OpenFile(ctx, 1, models.ImageTransform{Width: 10})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/logger"
+	"gin/internal/models"
+	"gin/internal/repository"
+	"gin/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// loginGuard 登录防暴力破解
+// 按账户（邮箱）和客户端IP分别统计连续失败次数：每次失败后需要等待指数增长的退避时间，
+// 达到上限后临时锁定。未注册的邮箱同样计数，避免通过锁定行为探测邮箱是否注册
+type loginGuard struct {
+	store repository.LoginAttemptStore
+	cfg   config.LoginConfig
+	now   func() time.Time
+}
+
+// newLoginGuard 创建登录防暴力破解检查
+func newLoginGuard(store repository.LoginAttemptStore, cfg config.LoginConfig) *loginGuard {
+	return &loginGuard{store: store, cfg: cfg, now: time.Now}
+}
+
+// accountAttemptKey 账户计数键（邮箱不区分大小写）
+func accountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// ipAttemptKey 客户端IP计数键
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// keys 本次登录需要检查的计数键（无法获取客户端IP时只按账户统计）
+func (g *loginGuard) keys(email, ip string) []string {
+	keys := []string{accountAttemptKey(email)}
+	if ip != "" {
+		keys = append(keys, ipAttemptKey(ip))
+	}
+	return keys
+}
+
+// check 登录前检查账户和IP是否处于锁定或退避期，处于其中时不再校验密码
+func (g *loginGuard) check(ctx context.Context, email, ip string) error {
+	return g.checkAttempts(ctx, email, ip, true)
+}
+
+// checkLocked 两步验证前检查账户和IP是否被锁定，被锁定时不再校验验证码
+// 不检查退避期：同一个两步验证令牌的尝试次数另有上限，获取新令牌需要重新登录并经过退避检查
+func (g *loginGuard) checkLocked(ctx context.Context, email, ip string) error {
+	return g.checkAttempts(ctx, email, ip, false)
+}
+
+// checkAttempts 检查账户和IP是否被锁定，withBackoff 为 true 时同时检查退避期
+func (g *loginGuard) checkAttempts(ctx context.Context, email, ip string, withBackoff bool) error {
+	now := g.now()
+	for _, key := range g.keys(email, ip) {
+		attempt, err := g.store.Get(ctx, key)
+		if err != nil {
+			return errors.NewInternalServerError("登录失败", err)
+		}
+
+		var wait time.Duration
+		if attempt.IsLocked(now) {
+			wait = attempt.LockedUntil.Sub(now)
+		} else if withBackoff && attempt.Failures > 0 {
+			wait = attempt.LastFailedAt.Add(g.backoff(attempt.Failures)).Sub(now)
+		}
+		if wait <= 0 {
+			continue
+		}
+
+		logger.Log.Warn(i18n.LogMessage(i18n.LogLoginThrottled),
+			zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+			zap.String("key", key),
+			zap.Int("failures", attempt.Failures),
+			zap.Bool("locked", attempt.IsLocked(now)),
+			zap.Duration("retry_after", wait),
+		)
+		seconds := int(math.Ceil(wait.Seconds()))
+		if attempt.IsLocked(now) {
+			return errors.NewTooManyRequestsError(fmt.Sprintf("登录失败次数过多，请在%d秒后重试", seconds), fmt.Errorf("%s locked", key))
+		}
+		return errors.NewTooManyRequestsError(fmt.Sprintf("登录尝试过于频繁，请在%d秒后重试", seconds), fmt.Errorf("%s in backoff", key))
+	}
+	return nil
+}
+
+// recordFailure 记录一次登录失败（密码错误或两步验证码错误），达到上限时锁定
+// 计数存储出错只记录日志，不影响本次登录返回的错误
+func (g *loginGuard) recordFailure(ctx context.Context, email, ip string) {
+	now := g.now()
+	requestID := requestctx.FromContext(ctx).RequestID
+	window := time.Duration(g.cfg.FailureWindow) * time.Minute
+
+	failures := make(map[string]int, 2)
+	for _, key := range g.keys(email, ip) {
+		attempt, err := g.store.RecordFailure(ctx, key, now, window)
+		if err != nil {
+			logger.Log.Error(i18n.LogMessage(i18n.LogInternalError), zap.String("request_id", requestID), zap.Error(err))
+			continue
+		}
+		failures[key] = attempt.Failures
+
+		limit, lockedKey := g.cfg.MaxAccountFailures, i18n.LogAccountLocked
+		if strings.HasPrefix(key, "ip:") {
+			limit, lockedKey = g.cfg.MaxIPFailures, i18n.LogIPLocked
+		}
+		if limit <= 0 || attempt.Failures < limit {
+			continue
+		}
+
+		until := now.Add(time.Duration(g.cfg.LockoutDuration) * time.Minute)
+		if err := g.store.Lock(ctx, key, until); err != nil {
+			logger.Log.Error(i18n.LogMessage(i18n.LogInternalError), zap.String("request_id", requestID), zap.Error(err))
+			continue
+		}
+		logger.Log.Warn(i18n.LogMessage(lockedKey),
+			zap.String("request_id", requestID),
+			zap.String("email", email),
+			zap.String("ip", ip),
+			zap.Int("failures", attempt.Failures),
+			zap.Time("locked_until", until),
+		)
+	}
+
+	logger.Log.Warn(i18n.LogMessage(i18n.LogLoginFailed),
+		zap.String("request_id", requestID),
+		zap.String("email", email),
+		zap.String("ip", ip),
+		zap.Int("account_failures", failures[accountAttemptKey(email)]),
+		zap.Int("ip_failures", failures[ipAttemptKey(ip)]),
+	)
+}
+
+// recordSuccess 完成登录（包括两步验证）后清除账户的失败计数
+// 只校验通过密码时不能清除，否则知道密码的人可以不断获取新的两步验证令牌暴力破解验证码
+// IP 计数不清除，避免攻击者用自己的账户登录来重置IP计数
+func (g *loginGuard) recordSuccess(ctx context.Context, email string) {
+	if err := g.store.Reset(ctx, accountAttemptKey(email)); err != nil {
+		logger.Log.Error(i18n.LogMessage(i18n.LogInternalError),
+			zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+			zap.Error(err),
+		)
+	}
+}
+
+// backoff 第 failures 次失败后的退避时间：backoff_base * 2^(failures-1)，不超过 backoff_max
+func (g *loginGuard) backoff(failures int) time.Duration {
+	if g.cfg.BackoffBase <= 0 || failures <= 0 {
+		return 0
+	}
+	base := time.Duration(g.cfg.BackoffBase) * time.Second
+	maxDelay := time.Duration(g.cfg.BackoffMax) * time.Second
+	if failures > 31 {
+		failures = 31
+	}
+	delay := base << (failures - 1)
+	if maxDelay > 0 && (delay > maxDelay || delay <= 0) {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// UnlockUser 解锁被锁定的账户（清除该账户的失败计数）
+func (s *userService) UnlockUser(ctx context.Context, userID int64) error {
+	if userID <= 0 {
+		return errors.NewBadRequestError("用户ID无效", fmt.Errorf("invalid user id: %d", userID))
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return errors.NewNotFoundError("用户不存在", err)
+	}
+
+	if err := s.loginGuard.store.Reset(ctx, accountAttemptKey(user.Email)); err != nil {
+		return errors.NewInternalServerError("解锁账户失败", err)
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserUnlock,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(userID),
+	})
+
+	caller, _ := auth.PrincipalFromContext(ctx)
+	logger.Log.Info(i18n.LogMessage(i18n.LogAccountUnlocked),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("user_id", user.ID),
+		zap.String("email", user.Email),
+		zap.Int64("operator_id", caller.UserID),
+	)
+	return nil
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/repository"
+	"gin/internal/requestctx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoginGuard_Backoff 测试退避时间计算
+func TestLoginGuard_Backoff(t *testing.T) {
+	guard := newLoginGuard(repository.NewMemoryLoginAttemptStore(), config.LoginConfig{BackoffBase: 1, BackoffMax: 30})
+
+	assert.Equal(t, time.Duration(0), guard.backoff(0))
+	assert.Equal(t, 1*time.Second, guard.backoff(1))
+	assert.Equal(t, 2*time.Second, guard.backoff(2))
+	assert.Equal(t, 16*time.Second, guard.backoff(5))
+	assert.Equal(t, 30*time.Second, guard.backoff(6), "超过上限时取 backoff_max")
+	assert.Equal(t, 30*time.Second, guard.backoff(100), "失败次数很大时不应溢出")
+
+	guard.cfg.BackoffBase = 0
+	assert.Equal(t, time.Duration(0), guard.backoff(5), "backoff_base 为 0 时不退避")
+}
+
+// TestUserService_LoginProtection 测试登录防暴力破解
+func TestUserService_LoginProtection(t *testing.T) {
+	ctx := requestctx.WithMetadata(context.Background(), requestctx.Metadata{ClientIP: "203.0.113.7"})
+	cfg := config.LoginConfig{
+		MaxAccountFailures: 3,
+		MaxIPFailures:      5,
+		LockoutDuration:    15,
+		FailureWindow:      15,
+		BackoffBase:        1,
+		BackoffMax:         30,
+	}
+
+	// setup 创建使用可控时钟的用户服务，返回推进时钟的函数
+	setup := func(t *testing.T) (*userService, *models.User, func(time.Duration)) {
+		mockRepo := new(MockUserRepository)
+		user := newLoginTestUser(t)
+		mockRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+		mockRepo.On("FindByEmail", ctx, "nobody@example.com").Return(nil, fmt.Errorf("user not found"))
+		mockRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+
+		svc := NewUserService(mockRepo,
+			WithLoginAttemptStore(repository.NewMemoryLoginAttemptStore()),
+			WithLoginConfig(cfg),
+		).(*userService)
+
+		now := time.Now()
+		svc.loginGuard.now = func() time.Time { return now }
+		advance := func(d time.Duration) { now = now.Add(d) }
+		return svc, user, advance
+	}
+
+	login := func(svc *userService, email, password string) error {
+		_, _, err := svc.Login(ctx, &models.LoginRequest{Email: email, Password: password})
+		return err
+	}
+
+	t.Run("失败后在退避期内拒绝登录", func(t *testing.T) {
+		svc, user, advance := setup(t)
+
+		assertAppErrorCode(t, login(svc, user.Email, "wrong"), http.StatusUnauthorized)
+
+		// 退避期内即使密码正确也拒绝
+		err := login(svc, user.Email, "123456")
+		assertAppErrorCode(t, err, http.StatusTooManyRequests)
+		assert.Contains(t, err.Error(), "登录尝试过于频繁")
+
+		advance(time.Second)
+		require.NoError(t, login(svc, user.Email, "123456"))
+	})
+
+	t.Run("连续失败达到上限后锁定账户", func(t *testing.T) {
+		svc, user, advance := setup(t)
+
+		for i := 0; i < cfg.MaxAccountFailures; i++ {
+			assertAppErrorCode(t, login(svc, user.Email, "wrong"), http.StatusUnauthorized)
+			advance(time.Minute) // 跳过退避期
+		}
+
+		err := login(svc, user.Email, "123456")
+		assertAppErrorCode(t, err, http.StatusTooManyRequests)
+		assert.Contains(t, err.Error(), "登录失败次数过多")
+
+		advance(time.Duration(cfg.LockoutDuration) * time.Minute)
+		require.NoError(t, login(svc, user.Email, "123456"), "锁定期过后应允许登录")
+	})
+
+	t.Run("邮箱大小写不同视为同一账户", func(t *testing.T) {
+		svc, user, advance := setup(t)
+
+		assertAppErrorCode(t, login(svc, user.Email, "wrong"), http.StatusUnauthorized)
+		assertAppErrorCode(t, login(svc, "ZhangSan@Example.com", "wrong"), http.StatusTooManyRequests)
+
+		advance(time.Second)
+		require.NoError(t, login(svc, user.Email, "123456"))
+	})
+
+	t.Run("登录成功后清除账户失败计数", func(t *testing.T) {
+		svc, user, advance := setup(t)
+
+		for i := 0; i < cfg.MaxAccountFailures-1; i++ {
+			assertAppErrorCode(t, login(svc, user.Email, "wrong"), http.StatusUnauthorized)
+			advance(time.Minute)
+		}
+		require.NoError(t, login(svc, user.Email, "123456"))
+
+		attempt, err := svc.loginGuard.store.Get(ctx, accountAttemptKey(user.Email))
+		require.NoError(t, err)
+		assert.Zero(t, attempt.Failures)
+
+		// IP 计数不随登录成功清除
+		attempt, err = svc.loginGuard.store.Get(ctx, ipAttemptKey("203.0.113.7"))
+		require.NoError(t, err)
+		assert.Equal(t, cfg.MaxAccountFailures-1, attempt.Failures)
+	})
+
+	t.Run("两步验证码错误计入账户失败计数，锁定对所有两步验证令牌有效", func(t *testing.T) {
+		svc, user, advance := setup(t)
+		setupResp, err := svc.SetupMFA(ctx, user.ID)
+		require.NoError(t, err)
+		_, err = svc.EnableMFA(ctx, user.ID, &models.MFACodeRequest{Code: totpCodeAt(t, setupResp.Secret, 0)})
+		require.NoError(t, err)
+
+		mfaLogin := func() *models.MFAChallengeResponse {
+			_, challenge, err := svc.Login(ctx, &models.LoginRequest{Email: user.Email, Password: "123456"})
+			require.NoError(t, err)
+			require.NotNil(t, challenge)
+			return challenge
+		}
+		spare := mfaLogin()
+
+		// 每次都用新的令牌尝试，单个令牌的次数限制不会生效，但账户失败计数累加
+		for i := 0; i < cfg.MaxAccountFailures; i++ {
+			_, err := svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: mfaLogin().MFAToken, Code: "000000"})
+			assertAppErrorCode(t, err, http.StatusUnauthorized)
+			advance(time.Minute) // 跳过退避期
+		}
+
+		// 密码正确也不能获取新的令牌，之前获取的令牌即使验证码正确也被拒绝
+		err = login(svc, user.Email, "123456")
+		assertAppErrorCode(t, err, http.StatusTooManyRequests)
+		_, err = svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: spare.MFAToken, Code: totpCodeAt(t, setupResp.Secret, 1)})
+		assertAppErrorCode(t, err, http.StatusTooManyRequests)
+
+		// 锁定期过后完成两步验证才清除失败计数
+		advance(time.Duration(cfg.LockoutDuration) * time.Minute)
+		challenge := mfaLogin()
+		attempt, err := svc.loginGuard.store.Get(ctx, accountAttemptKey(user.Email))
+		require.NoError(t, err)
+		assert.Equal(t, cfg.MaxAccountFailures, attempt.Failures, "只校验通过密码时不应清除失败计数")
+
+		_, err = svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCodeAt(t, setupResp.Secret, 1)})
+		require.NoError(t, err)
+		attempt, err = svc.loginGuard.store.Get(ctx, accountAttemptKey(user.Email))
+		require.NoError(t, err)
+		assert.Zero(t, attempt.Failures)
+	})
+
+	t.Run("管理两步验证时验证码错误同样计数", func(t *testing.T) {
+		svc, user, advance := setup(t)
+		svc.userRepo.(*MockUserRepository).On("FindByID", mock.Anything, user.ID).Return(user, nil)
+		setupResp, err := svc.SetupMFA(ctx, user.ID)
+		require.NoError(t, err)
+
+		for i := 0; i < cfg.MaxAccountFailures; i++ {
+			_, err := svc.EnableMFA(ctx, user.ID, &models.MFACodeRequest{Code: "000000"})
+			assertAppErrorCode(t, err, http.StatusBadRequest)
+			advance(time.Minute)
+		}
+
+		// 账户被锁定后即使验证码正确也拒绝
+		_, err = svc.EnableMFA(ctx, user.ID, &models.MFACodeRequest{Code: totpCodeAt(t, setupResp.Secret, 0)})
+		assertAppErrorCode(t, err, http.StatusTooManyRequests)
+
+		// 锁定期过后验证码正确才能启用，并清除失败计数
+		advance(time.Duration(cfg.LockoutDuration) * time.Minute)
+		_, err = svc.EnableMFA(ctx, user.ID, &models.MFACodeRequest{Code: totpCodeAt(t, setupResp.Secret, 0)})
+		require.NoError(t, err)
+		attempt, err := svc.loginGuard.store.Get(ctx, accountAttemptKey(user.Email))
+		require.NoError(t, err)
+		assert.Zero(t, attempt.Failures)
+
+		// 关闭两步验证时密码和恢复码错误同样计数
+		for i := 0; i < cfg.MaxAccountFailures-1; i++ {
+			err := svc.DisableMFA(ctx, user.ID, &models.MFADisableRequest{Password: "123456", Code: "AAAA-BBBB"})
+			assertAppErrorCode(t, err, http.StatusBadRequest)
+		}
+		err = svc.DisableMFA(ctx, user.ID, &models.MFADisableRequest{Password: "wrong", Code: totpCodeAt(t, setupResp.Secret, 1)})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		_, err = svc.RegenerateRecoveryCodes(ctx, user.ID, &models.MFACodeRequest{Code: totpCodeAt(t, setupResp.Secret, 1)})
+		assertAppErrorCode(t, err, http.StatusTooManyRequests)
+	})
+
+	t.Run("未注册的邮箱同样计数", func(t *testing.T) {
+		svc, _, advance := setup(t)
+
+		for i := 0; i < cfg.MaxAccountFailures; i++ {
+			assertAppErrorCode(t, login(svc, "nobody@example.com", "wrong"), http.StatusUnauthorized)
+			advance(time.Minute)
+		}
+
+		err := login(svc, "nobody@example.com", "wrong")
+		assertAppErrorCode(t, err, http.StatusTooManyRequests)
+		assert.Contains(t, err.Error(), "登录失败次数过多")
+	})
+
+	t.Run("同一IP失败过多时锁定该IP", func(t *testing.T) {
+		svc, user, advance := setup(t)
+
+		// 每个邮箱的失败次数都未达到账户上限，但同一IP累计达到上限
+		for i := 0; i < cfg.MaxIPFailures; i++ {
+			email := fmt.Sprintf("user%d@example.com", i)
+			svc.userRepo.(*MockUserRepository).On("FindByEmail", ctx, email).Return(nil, fmt.Errorf("user not found"))
+			assertAppErrorCode(t, login(svc, email, "wrong"), http.StatusUnauthorized)
+			advance(time.Minute)
+		}
+
+		err := login(svc, user.Email, "123456")
+		assertAppErrorCode(t, err, http.StatusTooManyRequests)
+
+		// 其他IP不受影响
+		otherCtx := requestctx.WithMetadata(context.Background(), requestctx.Metadata{ClientIP: "198.51.100.1"})
+		svc.userRepo.(*MockUserRepository).On("FindByEmail", otherCtx, user.Email).Return(user, nil)
+		_, _, err = svc.Login(otherCtx, &models.LoginRequest{Email: user.Email, Password: "123456"})
+		require.NoError(t, err)
+	})
+
+	t.Run("管理员解锁账户", func(t *testing.T) {
+		svc, user, advance := setup(t)
+
+		for i := 0; i < cfg.MaxAccountFailures; i++ {
+			assertAppErrorCode(t, login(svc, user.Email, "wrong"), http.StatusUnauthorized)
+			advance(time.Minute)
+		}
+		assertAppErrorCode(t, login(svc, user.Email, "123456"), http.StatusTooManyRequests)
+
+		adminCtx := auth.WithPrincipal(ctx, auth.Principal{UserID: 2, Role: auth.RoleAdmin})
+		svc.userRepo.(*MockUserRepository).On("FindByID", adminCtx, user.ID).Return(user, nil)
+		require.NoError(t, svc.UnlockUser(adminCtx, user.ID))
+
+		require.NoError(t, login(svc, user.Email, "123456"))
+	})
+
+	t.Run("解锁不存在的用户", func(t *testing.T) {
+		svc, _, _ := setup(t)
+		svc.userRepo.(*MockUserRepository).On("FindByID", ctx, int64(999)).Return(nil, fmt.Errorf("user not found"))
+
+		assertAppErrorCode(t, svc.UnlockUser(ctx, 999), http.StatusNotFound)
+	})
+}
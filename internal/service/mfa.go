@@ -0,0 +1,470 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/database"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/logger"
+	"gin/internal/models"
+	"gin/internal/repository"
+	"gin/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// mfaTokenInvalidMessage 两步验证令牌无效、过期或已使用时的提示
+const mfaTokenInvalidMessage = "两步验证已失效，请重新登录"
+
+// GetMFAStatus 查询两步验证状态
+func (s *userService) GetMFAStatus(ctx context.Context, userID int64) (*models.MFAStatusResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("用户不存在", err)
+	}
+
+	status := &models.MFAStatusResponse{Required: s.mfaRequired(user)}
+	mfa, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return status, nil
+	}
+
+	status.Enabled = true
+	status.RecoveryCodesRemaining, err = s.mfaRepo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, errors.NewInternalServerError("查询两步验证状态失败", err)
+	}
+	return status, nil
+}
+
+// SetupMFA 生成新的 TOTP 密钥，输入验证码确认（EnableMFA）后才会启用
+func (s *userService) SetupMFA(ctx context.Context, userID int64) (*models.MFASetupResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("用户不存在", err)
+	}
+
+	mfa, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.Enabled {
+		return nil, errors.NewBadRequestError("两步验证已启用，如需更换身份验证器请先关闭", fmt.Errorf("mfa already enabled for user %d", userID))
+	}
+
+	secret, err := s.newMFASecret(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.mfaSetupResponse(user, secret), nil
+}
+
+// EnableMFA 使用身份验证器生成的验证码确认密钥并启用两步验证，返回恢复码
+func (s *userService) EnableMFA(ctx context.Context, userID int64, req *models.MFACodeRequest) (*models.MFARecoveryCodesResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("用户不存在", err)
+	}
+
+	mfa, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, errors.NewBadRequestError("请先获取两步验证密钥", fmt.Errorf("mfa not set up for user %d", userID))
+	}
+	if mfa.Enabled {
+		return nil, errors.NewBadRequestError("两步验证已启用", fmt.Errorf("mfa already enabled for user %d", userID))
+	}
+
+	if err := s.verifyAccountMFACode(ctx, user, mfa, req.Code, false, "启用两步验证失败"); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.enableMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditAuthMFAEnable,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(userID),
+	})
+	return &models.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableMFA 关闭两步验证（需要验证当前密码和验证码或恢复码）
+func (s *userService) DisableMFA(ctx context.Context, userID int64, req *models.MFADisableRequest) error {
+	// 校验密码必须读取主库，避免从库延迟导致旧密码仍然有效
+	user, err := s.userRepo.FindByID(database.WithPrimary(ctx), userID)
+	if err != nil {
+		return errors.NewNotFoundError("用户不存在", err)
+	}
+	// 密码和验证码错误与登录失败计入同一个账户和IP失败计数
+	clientIP := requestctx.FromContext(ctx).ClientIP
+	if err := s.loginGuard.checkLocked(ctx, user.Email, clientIP); err != nil {
+		return err
+	}
+	if !auth.CheckPassword(user.Password, req.Password) {
+		s.loginGuard.recordFailure(ctx, user.Email, clientIP)
+		return errors.NewBadRequestError("当前密码错误", fmt.Errorf("current password mismatch"))
+	}
+	if s.mfaRequired(user) {
+		return errors.NewForbiddenError("管理员必须启用两步验证", fmt.Errorf("mfa is required for admin user %d", userID))
+	}
+
+	mfa, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return errors.NewBadRequestError("未启用两步验证", fmt.Errorf("mfa not enabled for user %d", userID))
+	}
+
+	if err := s.verifyAccountMFACode(ctx, user, mfa, req.Code, true, "关闭两步验证失败"); err != nil {
+		return err
+	}
+
+	if err := s.mfaRepo.Delete(ctx, userID); err != nil {
+		return errors.NewInternalServerError("关闭两步验证失败", err)
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditAuthMFADisable,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(userID),
+	})
+
+	logger.Log.Info(i18n.LogMessage(i18n.LogMFADisabled),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("user_id", userID),
+	)
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（需要验证码），之前的恢复码全部失效
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, userID int64, req *models.MFACodeRequest) (*models.MFARecoveryCodesResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("用户不存在", err)
+	}
+
+	mfa, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return nil, errors.NewBadRequestError("未启用两步验证", fmt.Errorf("mfa not enabled for user %d", userID))
+	}
+
+	if err := s.verifyAccountMFACode(ctx, user, mfa, req.Code, false, "生成恢复码失败"); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.newRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info(i18n.LogMessage(i18n.LogMFARecoveryCodesRegenerated),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("user_id", userID),
+	)
+	return &models.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyMFA 使用登录返回的两步验证令牌和验证码（或恢复码）换取访问令牌
+// 必须启用但尚未设置两步验证的账户在这里完成设置，响应中会附带恢复码
+func (s *userService) VerifyMFA(ctx context.Context, req *models.MFAVerifyRequest) (*models.LoginResponse, error) {
+	challenge, err := s.mfaChallengeRepo.FindByHash(ctx, auth.HashToken(req.MFAToken))
+	if err != nil {
+		return nil, errors.NewUnauthorizedError(mfaTokenInvalidMessage, err)
+	}
+	if challenge.IsUsed() || challenge.IsExpired(time.Now()) {
+		return nil, errors.NewUnauthorizedError(mfaTokenInvalidMessage, fmt.Errorf("mfa challenge used or expired"))
+	}
+
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, errors.NewUnauthorizedError(mfaTokenInvalidMessage, err)
+	}
+
+	// 验证码错误与密码错误计入同一个账户和IP失败计数，账户被锁定后已获取的令牌也不能继续尝试
+	clientIP := requestctx.FromContext(ctx).ClientIP
+	if err := s.loginGuard.checkLocked(ctx, user.Email, clientIP); err != nil {
+		return nil, err
+	}
+
+	// 校验验证码之前先占用一次尝试次数，避免并发请求绕过次数限制
+	if err := s.mfaChallengeRepo.IncrementAttempts(ctx, challenge.ID, s.mfaMaxAttempts()); err != nil {
+		if stderrors.Is(err, repository.ErrMFAChallengeUsed) {
+			return nil, errors.NewUnauthorizedError("验证码错误次数过多，请重新登录", err)
+		}
+		return nil, errors.NewInternalServerError("两步验证失败", err)
+	}
+	mfa, err := s.findMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, errors.NewUnauthorizedError(mfaTokenInvalidMessage, fmt.Errorf("mfa not set up for user %d", user.ID))
+	}
+
+	// 尚未启用时还没有恢复码，只接受验证码
+	ok, err := s.verifyMFACode(ctx, mfa, req.Code, mfa.Enabled)
+	if err != nil {
+		return nil, errors.NewInternalServerError("两步验证失败", err)
+	}
+	if !ok {
+		logger.Log.Warn(i18n.LogMessage(i18n.LogMFAVerifyFailed),
+			zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+			zap.Int64("user_id", user.ID),
+			zap.Int("attempts", challenge.Attempts+1),
+		)
+		s.loginGuard.recordFailure(ctx, user.Email, clientIP)
+		return nil, errors.NewUnauthorizedError("验证码错误", fmt.Errorf("invalid mfa code"))
+	}
+
+	// 并发请求中只有一个能使用令牌
+	if err := s.mfaChallengeRepo.MarkUsed(ctx, challenge.ID); err != nil {
+		if stderrors.Is(err, repository.ErrMFAChallengeUsed) {
+			return nil, errors.NewUnauthorizedError(mfaTokenInvalidMessage, err)
+		}
+		return nil, errors.NewInternalServerError("两步验证失败", err)
+	}
+
+	var recoveryCodes []string
+	if !mfa.Enabled {
+		recoveryCodes, err = s.enableMFA(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := s.completeLogin(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	s.loginGuard.recordSuccess(ctx, user.Email)
+	resp.RecoveryCodes = recoveryCodes
+	return resp, nil
+}
+
+// mfaChallenge 密码校验通过后判断是否需要两步验证，需要时创建两步验证令牌
+// 返回 nil 表示不需要两步验证，可以直接签发令牌
+func (s *userService) mfaChallenge(ctx context.Context, user *models.User) (*models.MFAChallengeResponse, error) {
+	mfa, err := s.findMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := mfa != nil && mfa.Enabled
+	if !enabled && !s.mfaRequired(user) {
+		return nil, nil
+	}
+
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, errors.NewInternalServerError("生成两步验证令牌失败", err)
+	}
+	ttl := s.mfaChallengeTTL()
+	if _, err := s.mfaChallengeRepo.Create(ctx, &models.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return nil, errors.NewInternalServerError("保存两步验证令牌失败", err)
+	}
+
+	resp := &models.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(ttl.Seconds()),
+	}
+	if enabled {
+		return resp, nil
+	}
+
+	// 必须启用但尚未设置：沿用之前未确认的密钥，避免每次登录都要重新扫码
+	secret := ""
+	if mfa != nil {
+		secret = mfa.Secret
+	} else if secret, err = s.newMFASecret(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	resp.SetupRequired = true
+	resp.Setup = s.mfaSetupResponse(user, secret)
+	return resp, nil
+}
+
+// verifyMFACode 校验验证码，allowRecovery 为 true 时也接受恢复码
+// 验证码或恢复码错误时返回 false，error 只表示存储出错
+func (s *userService) verifyMFACode(ctx context.Context, mfa *models.UserMFA, code string, allowRecovery bool) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := auth.ValidateTOTP(mfa.Secret, code, time.Now(), s.mfaSkew()); ok {
+		// 同一个验证码只能使用一次
+		if err := s.mfaRepo.MarkStepUsed(ctx, mfa.UserID, step); err != nil {
+			if stderrors.Is(err, repository.ErrMFACodeReplayed) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	if !allowRecovery {
+		return false, nil
+	}
+
+	if err := s.mfaRepo.UseRecoveryCode(ctx, mfa.UserID, auth.HashToken(auth.NormalizeRecoveryCode(code))); err != nil {
+		if stderrors.Is(err, repository.ErrRecoveryCodeInvalid) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	logger.Log.Warn(i18n.LogMessage(i18n.LogMFARecoveryCodeUsed),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("user_id", mfa.UserID),
+	)
+	return true, nil
+}
+
+// verifyAccountMFACode 校验已登录用户提交的验证码（allowRecovery 为 true 时也接受恢复码）
+// 与 VerifyMFA 一样，错误的验证码计入账户和IP失败计数，账户或IP被锁定后不再校验，校验通过后清除账户计数
+// failMessage 为存储出错时返回的提示
+func (s *userService) verifyAccountMFACode(ctx context.Context, user *models.User, mfa *models.UserMFA, code string, allowRecovery bool, failMessage string) error {
+	clientIP := requestctx.FromContext(ctx).ClientIP
+	if err := s.loginGuard.checkLocked(ctx, user.Email, clientIP); err != nil {
+		return err
+	}
+
+	ok, err := s.verifyMFACode(ctx, mfa, code, allowRecovery)
+	if err != nil {
+		return errors.NewInternalServerError(failMessage, err)
+	}
+	if !ok {
+		logger.Log.Warn(i18n.LogMessage(i18n.LogMFAVerifyFailed),
+			zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+			zap.Int64("user_id", user.ID),
+		)
+		s.loginGuard.recordFailure(ctx, user.Email, clientIP)
+		return errors.NewBadRequestError("验证码错误", fmt.Errorf("invalid mfa code"))
+	}
+	s.loginGuard.recordSuccess(ctx, user.Email)
+	return nil
+}
+
+// enableMFA 启用两步验证并生成恢复码
+func (s *userService) enableMFA(ctx context.Context, userID int64) ([]string, error) {
+	if err := s.mfaRepo.Enable(ctx, userID); err != nil {
+		return nil, errors.NewInternalServerError("启用两步验证失败", err)
+	}
+	codes, err := s.newRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info(i18n.LogMessage(i18n.LogMFAEnabled),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("user_id", userID),
+	)
+	return codes, nil
+}
+
+// newMFASecret 生成并保存待确认的密钥
+func (s *userService) newMFASecret(ctx context.Context, userID int64) (string, error) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return "", errors.NewInternalServerError("生成两步验证密钥失败", err)
+	}
+	if err := s.mfaRepo.SaveSecret(ctx, userID, secret); err != nil {
+		return "", errors.NewInternalServerError("保存两步验证密钥失败", err)
+	}
+	return secret, nil
+}
+
+// newRecoveryCodes 生成新的恢复码替换原有恢复码，服务端只保存哈希
+func (s *userService) newRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(s.mfaRecoveryCodeCount())
+	if err != nil {
+		return nil, errors.NewInternalServerError("生成恢复码失败", err)
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, errors.NewInternalServerError("保存恢复码失败", err)
+	}
+	return codes, nil
+}
+
+// findMFA 查询用户的两步验证设置，未设置时返回 nil
+func (s *userService) findMFA(ctx context.Context, userID int64) (*models.UserMFA, error) {
+	mfa, err := s.mfaRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrMFANotFound) {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError("查询两步验证设置失败", err)
+	}
+	return mfa, nil
+}
+
+// mfaSetupResponse 构造密钥和二维码内容
+func (s *userService) mfaSetupResponse(user *models.User, secret string) *models.MFASetupResponse {
+	uri := auth.TOTPProvisioningURI(s.mfaConfig.Issuer, user.Email, secret)
+	return &models.MFASetupResponse{
+		Secret:          secret,
+		ProvisioningURI: uri,
+		QRPayload:       uri,
+	}
+}
+
+// mfaRequired 账户是否必须启用两步验证
+func (s *userService) mfaRequired(user *models.User) bool {
+	return s.mfaConfig.RequireForAdmin && user.Role.IsAdmin()
+}
+
+// mfaChallengeTTL 两步验证令牌有效期
+func (s *userService) mfaChallengeTTL() time.Duration {
+	ttl := time.Duration(s.mfaConfig.ChallengeTTL) * time.Minute
+	if ttl <= 0 {
+		ttl = 5 * time.Minute // 默认5分钟
+	}
+	return ttl
+}
+
+// mfaMaxAttempts 每个两步验证令牌允许的尝试次数
+func (s *userService) mfaMaxAttempts() int {
+	if s.mfaConfig.MaxAttempts <= 0 {
+		return 5 // 默认5次
+	}
+	return s.mfaConfig.MaxAttempts
+}
+
+// mfaRecoveryCodeCount 恢复码数量
+func (s *userService) mfaRecoveryCodeCount() int {
+	if s.mfaConfig.RecoveryCodes <= 0 {
+		return 10 // 默认10个
+	}
+	return s.mfaConfig.RecoveryCodes
+}
+
+// mfaSkew 允许前后偏差的时间步数
+func (s *userService) mfaSkew() int {
+	if s.mfaConfig.Skew < 0 {
+		return 0
+	}
+	return s.mfaConfig.Skew
+}
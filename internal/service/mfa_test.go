@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// totpCodeAt 计算相对当前时间偏移 steps 个时间步的验证码
+// 同一时间步的验证码只能使用一次，测试中需要多个验证码时使用不同的偏移
+func totpCodeAt(t *testing.T, secret string, steps int) string {
+	code, err := auth.TOTPCode(secret, time.Now().Add(time.Duration(steps*auth.TOTPPeriod)*time.Second))
+	require.NoError(t, err)
+	return code
+}
+
+// TestUserService_MFA 测试两步验证
+func TestUserService_MFA(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.MFAConfig{
+		Issuer:        "Go Startup",
+		ChallengeTTL:  5,
+		MaxAttempts:   3,
+		RecoveryCodes: 4,
+		Skew:          2,
+	}
+
+	setup := func(t *testing.T, user *models.User, cfg config.MFAConfig) *userService {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+		mockRepo.On("FindByID", mock.Anything, user.ID).Return(user, nil)
+
+		return NewUserService(mockRepo,
+			WithMFARepository(repository.NewMemoryMFARepository()),
+			WithMFAChallengeRepository(repository.NewMemoryMFAChallengeRepository()),
+			WithMFAConfig(cfg),
+		).(*userService)
+	}
+
+	// enable 为用户启用两步验证，返回密钥和恢复码
+	enable := func(t *testing.T, svc *userService, userID int64) (string, []string) {
+		setupResp, err := svc.SetupMFA(ctx, userID)
+		require.NoError(t, err)
+
+		codes, err := svc.EnableMFA(ctx, userID, &models.MFACodeRequest{Code: totpCodeAt(t, setupResp.Secret, 0)})
+		require.NoError(t, err)
+		return setupResp.Secret, codes.RecoveryCodes
+	}
+
+	login := func(t *testing.T, svc *userService, user *models.User) *models.MFAChallengeResponse {
+		resp, challenge, err := svc.Login(ctx, &models.LoginRequest{Email: user.Email, Password: "123456"})
+		require.NoError(t, err)
+		require.Nil(t, resp, "启用两步验证后登录不应直接返回令牌")
+		require.NotNil(t, challenge)
+		return challenge
+	}
+
+	t.Run("设置并启用两步验证", func(t *testing.T) {
+		user := newLoginTestUser(t)
+		svc := setup(t, user, cfg)
+
+		setupResp, err := svc.SetupMFA(ctx, user.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, setupResp.Secret)
+		assert.Contains(t, setupResp.ProvisioningURI, "otpauth://totp/Go%20Startup:zhangsan@example.com?")
+		assert.Equal(t, setupResp.ProvisioningURI, setupResp.QRPayload)
+
+		// 未确认前登录不需要两步验证
+		resp, challenge, err := svc.Login(ctx, &models.LoginRequest{Email: user.Email, Password: "123456"})
+		require.NoError(t, err)
+		assert.NotNil(t, resp)
+		assert.Nil(t, challenge)
+
+		_, err = svc.EnableMFA(ctx, user.ID, &models.MFACodeRequest{Code: "000000"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		codes, err := svc.EnableMFA(ctx, user.ID, &models.MFACodeRequest{Code: totpCodeAt(t, setupResp.Secret, 0)})
+		require.NoError(t, err)
+		assert.Len(t, codes.RecoveryCodes, cfg.RecoveryCodes)
+
+		status, err := svc.GetMFAStatus(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.False(t, status.Required)
+		assert.Equal(t, cfg.RecoveryCodes, status.RecoveryCodesRemaining)
+
+		// 已启用时不能重新获取密钥
+		_, err = svc.SetupMFA(ctx, user.ID)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("登录需要两步验证", func(t *testing.T) {
+		user := newLoginTestUser(t)
+		svc := setup(t, user, cfg)
+		secret, _ := enable(t, svc, user.ID)
+
+		challenge := login(t, svc, user)
+		assert.True(t, challenge.MFARequired)
+		assert.False(t, challenge.SetupRequired)
+		assert.Equal(t, 300, challenge.ExpiresIn)
+
+		_, err := svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: "000000"})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+
+		resp, err := svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCodeAt(t, secret, 1)})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEmpty(t, resp.RefreshToken)
+		assert.Empty(t, resp.User.Password)
+		assert.Empty(t, resp.RecoveryCodes)
+
+		// 两步验证令牌只能使用一次
+		_, err = svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCodeAt(t, secret, 2)})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("同一个验证码不能重复使用", func(t *testing.T) {
+		user := newLoginTestUser(t)
+		svc := setup(t, user, cfg)
+		secret, _ := enable(t, svc, user.ID)
+
+		// 启用时使用过的验证码不能再用于登录
+		challenge := login(t, svc, user)
+		_, err := svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCodeAt(t, secret, 0)})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("使用恢复码登录", func(t *testing.T) {
+		user := newLoginTestUser(t)
+		svc := setup(t, user, cfg)
+		_, recoveryCodes := enable(t, svc, user.ID)
+
+		challenge := login(t, svc, user)
+		resp, err := svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: "  " + recoveryCodes[0] + " "})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+
+		// 恢复码只能使用一次
+		challenge = login(t, svc, user)
+		_, err = svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: recoveryCodes[0]})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+
+		status, err := svc.GetMFAStatus(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, cfg.RecoveryCodes-1, status.RecoveryCodesRemaining)
+	})
+
+	t.Run("验证码错误次数过多后令牌失效", func(t *testing.T) {
+		user := newLoginTestUser(t)
+		svc := setup(t, user, cfg)
+		secret, _ := enable(t, svc, user.ID)
+
+		challenge := login(t, svc, user)
+		for i := 0; i < cfg.MaxAttempts; i++ {
+			_, err := svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: "000000"})
+			assertAppErrorCode(t, err, http.StatusUnauthorized)
+		}
+
+		_, err := svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCodeAt(t, secret, 1)})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+		assert.Contains(t, err.Error(), "次数过多")
+	})
+
+	t.Run("两步验证令牌过期", func(t *testing.T) {
+		user := newLoginTestUser(t)
+		svc := setup(t, user, cfg)
+		secret, _ := enable(t, svc, user.ID)
+
+		_, err := svc.mfaChallengeRepo.Create(ctx, &models.MFAChallenge{
+			UserID:    user.ID,
+			TokenHash: auth.HashToken("expired-token"),
+			ExpiresAt: time.Now().Add(-time.Second),
+		})
+		require.NoError(t, err)
+
+		_, err = svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: "expired-token", Code: totpCodeAt(t, secret, 1)})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+
+		_, err = svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: "not-exist", Code: totpCodeAt(t, secret, 1)})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("重新生成恢复码", func(t *testing.T) {
+		user := newLoginTestUser(t)
+		svc := setup(t, user, cfg)
+		secret, oldCodes := enable(t, svc, user.ID)
+
+		// 只接受验证码，不接受恢复码
+		_, err := svc.RegenerateRecoveryCodes(ctx, user.ID, &models.MFACodeRequest{Code: oldCodes[0]})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		codes, err := svc.RegenerateRecoveryCodes(ctx, user.ID, &models.MFACodeRequest{Code: totpCodeAt(t, secret, 1)})
+		require.NoError(t, err)
+		assert.Len(t, codes.RecoveryCodes, cfg.RecoveryCodes)
+
+		// 旧恢复码失效
+		challenge := login(t, svc, user)
+		_, err = svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: oldCodes[1]})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("关闭两步验证", func(t *testing.T) {
+		user := newLoginTestUser(t)
+		svc := setup(t, user, cfg)
+		secret, _ := enable(t, svc, user.ID)
+
+		err := svc.DisableMFA(ctx, user.ID, &models.MFADisableRequest{Password: "wrong", Code: totpCodeAt(t, secret, 1)})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		err = svc.DisableMFA(ctx, user.ID, &models.MFADisableRequest{Password: "123456", Code: "000000"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		require.NoError(t, svc.DisableMFA(ctx, user.ID, &models.MFADisableRequest{Password: "123456", Code: totpCodeAt(t, secret, 1)}))
+
+		resp, challenge, err := svc.Login(ctx, &models.LoginRequest{Email: user.Email, Password: "123456"})
+		require.NoError(t, err)
+		assert.NotNil(t, resp)
+		assert.Nil(t, challenge)
+
+		err = svc.DisableMFA(ctx, user.ID, &models.MFADisableRequest{Password: "123456", Code: totpCodeAt(t, secret, 2)})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("管理员必须启用两步验证", func(t *testing.T) {
+		admin := newLoginTestUser(t)
+		admin.Role = auth.RoleAdmin
+		requiredCfg := cfg
+		requiredCfg.RequireForAdmin = true
+		svc := setup(t, admin, requiredCfg)
+
+		// 未设置两步验证的管理员登录时需要先完成设置
+		challenge := login(t, svc, admin)
+		assert.True(t, challenge.SetupRequired)
+		require.NotNil(t, challenge.Setup)
+		secret := challenge.Setup.Secret
+
+		// 再次登录沿用同一个未确认的密钥
+		challenge = login(t, svc, admin)
+		require.NotNil(t, challenge.Setup)
+		assert.Equal(t, secret, challenge.Setup.Secret)
+
+		// 错误的验证码不能完成设置
+		_, err := svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: "000000"})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+
+		resp, err := svc.VerifyMFA(ctx, &models.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCodeAt(t, secret, 0)})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.Len(t, resp.RecoveryCodes, requiredCfg.RecoveryCodes, "首次设置时返回恢复码")
+
+		// 之后登录只需要输入验证码
+		challenge = login(t, svc, admin)
+		assert.False(t, challenge.SetupRequired)
+		assert.Nil(t, challenge.Setup)
+
+		status, err := svc.GetMFAStatus(ctx, admin.ID)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.True(t, status.Required)
+
+		// 管理员不能关闭两步验证
+		err = svc.DisableMFA(ctx, admin.ID, &models.MFADisableRequest{Password: "123456", Code: totpCodeAt(t, secret, 1)})
+		assertAppErrorCode(t, err, http.StatusForbidden)
+	})
+
+	t.Run("强制管理员启用时普通用户不受影响", func(t *testing.T) {
+		user := newLoginTestUser(t)
+		requiredCfg := cfg
+		requiredCfg.RequireForAdmin = true
+		svc := setup(t, user, requiredCfg)
+
+		resp, challenge, err := svc.Login(ctx, &models.LoginRequest{Email: user.Email, Password: "123456"})
+		require.NoError(t, err)
+		assert.NotNil(t, resp)
+		assert.Nil(t, challenge)
+	})
+}
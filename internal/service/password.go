@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/database"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/logger"
+	"gin/internal/models"
+	"gin/internal/notify"
+	"gin/internal/repository"
+	"gin/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// ChangePassword 修改自己的密码（需要验证当前密码）
+// 修改成功后撤销该用户的所有会话，客户端需要重新登录
+func (s *userService) ChangePassword(ctx context.Context, userID int64, req *models.ChangePasswordRequest) error {
+	// 校验密码必须读取主库，避免从库延迟导致旧密码仍然有效
+	user, err := s.userRepo.FindByID(database.WithPrimary(ctx), userID)
+	if err != nil {
+		return errors.NewNotFoundError("用户不存在", err)
+	}
+
+	if !auth.CheckPassword(user.Password, req.CurrentPassword) {
+		s.audit.Record(ctx, &models.AuditLog{
+			Action:     models.AuditAuthPasswordChange,
+			Outcome:    models.AuditOutcomeFailure,
+			TargetType: models.AuditTargetUser,
+			TargetID:   auditTargetID(userID),
+			Detail:     "当前密码错误",
+		})
+		return errors.NewBadRequestError("当前密码错误", fmt.Errorf("current password mismatch"))
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return errors.NewBadRequestError("新密码不能与当前密码相同", fmt.Errorf("new password equals current password"))
+	}
+
+	hashedPassword, err := s.hashNewPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.setPassword(ctx, userID, hashedPassword); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditAuthPasswordChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(userID),
+	})
+	return nil
+}
+
+// ForgotPassword 发送重置密码链接
+// 无论邮箱是否注册都返回成功，避免被用来探测已注册的邮箱
+func (s *userService) ForgotPassword(ctx context.Context, req *models.ForgotPasswordRequest) error {
+	user, err := s.userRepo.FindByEmail(ctx, req.Email)
+	if err != nil {
+		return nil
+	}
+
+	// 同一时间只保留最新的一个重置令牌
+	if err := s.resetTokenRepo.InvalidateByUserID(ctx, user.ID); err != nil {
+		return errors.NewInternalServerError("生成重置密码令牌失败", err)
+	}
+
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return errors.NewInternalServerError("生成重置密码令牌失败", err)
+	}
+
+	ttl := passwordResetTTL()
+	if _, err := s.resetTokenRepo.Create(ctx, &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return errors.NewInternalServerError("保存重置密码令牌失败", err)
+	}
+
+	link := strings.ReplaceAll(config.GetConfig().Password.ResetURL, "{token}", token)
+	msg := notify.Message{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body: fmt.Sprintf("%s，您好：\n\n请在 %d 分钟内访问以下链接重置密码：\n%s\n\n如果不是您本人操作，请忽略此消息。",
+			user.Name, int(ttl.Minutes()), link),
+	}
+	// 发送失败只记录日志，不向调用方暴露该邮箱已注册
+	if err := s.notifier.Send(ctx, msg); err != nil {
+		logger.Log.Error(i18n.LogMessage(i18n.LogInternalError),
+			zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+			zap.Int64("user_id", user.ID),
+			zap.Error(err),
+		)
+	}
+
+	return nil
+}
+
+// ResetPassword 使用重置令牌设置新密码
+// 令牌只能使用一次，重置成功后撤销该用户的所有会话
+func (s *userService) ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error {
+	stored, err := s.resetTokenRepo.FindByHash(ctx, auth.HashToken(req.Token))
+	if err != nil {
+		return errors.NewBadRequestError("重置密码链接无效或已过期", err)
+	}
+	if stored.IsUsed() || stored.IsExpired(time.Now()) {
+		return errors.NewBadRequestError("重置密码链接无效或已过期", fmt.Errorf("reset token used or expired"))
+	}
+
+	// 先校验新密码，避免不合格的密码浪费掉令牌
+	hashedPassword, err := s.hashNewPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	// 使用令牌与保存新密码在同一个事务中执行，保存失败时令牌不会被消耗
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// 并发请求中只有一个能成功使用令牌
+		if err := s.resetTokenRepo.MarkUsed(ctx, stored.ID); err != nil {
+			if stderrors.Is(err, repository.ErrPasswordResetTokenUsed) {
+				return errors.NewBadRequestError("重置密码链接无效或已过期", err)
+			}
+			return errors.NewInternalServerError("重置密码失败", err)
+		}
+
+		return s.setPassword(ctx, stored.UserID, hashedPassword)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 重置密码时未登录，操作者即令牌所属的用户
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    stored.UserID,
+		Action:     models.AuditAuthPasswordReset,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(stored.UserID),
+	})
+	return nil
+}
+
+// hashNewPassword 校验新密码是否符合密码策略并计算哈希
+// 所有写入新密码的地方都必须经过这里，而不是直接调用 auth.HashPassword
+func (s *userService) hashNewPassword(password string) (string, error) {
+	if err := s.passwordPolicy.Validate(password); err != nil {
+		return "", errors.NewBadRequestError(err.Error(), err)
+	}
+
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return "", errors.NewInternalServerError("密码加密失败", err)
+	}
+	return hashedPassword, nil
+}
+
+// setPassword 保存新密码，并作废未使用的重置令牌、撤销用户的所有会话（在同一个事务中执行）
+func (s *userService) setPassword(ctx context.Context, userID int64, hashedPassword string) error {
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+			return errors.NewInternalServerError("更新密码失败", err)
+		}
+		if err := s.resetTokenRepo.InvalidateByUserID(ctx, userID); err != nil {
+			return errors.NewInternalServerError("作废重置密码令牌失败", err)
+		}
+		return s.revokeUserSessions(ctx, userID)
+	})
+}
+
+// passwordResetTTL 重置密码令牌有效期
+func passwordResetTTL() time.Duration {
+	ttl := time.Duration(config.GetConfig().Password.ResetTokenTTL) * time.Minute
+	if ttl <= 0 {
+		ttl = 30 * time.Minute // 默认30分钟
+	}
+	return ttl
+}
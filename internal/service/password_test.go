@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"gin/internal/auth"
+	"gin/internal/models"
+	"gin/internal/notify"
+	"gin/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// captureNotifier 记录发送的通知，便于测试中取出重置令牌
+type captureNotifier struct {
+	messages []notify.Message
+}
+
+func (n *captureNotifier) Send(ctx context.Context, msg notify.Message) error {
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+// resetTokenPattern 从重置链接中提取令牌
+var resetTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// TestUserService_ChangePassword 测试修改密码
+func TestUserService_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("成功修改密码并撤销所有会话", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		user := newLoginTestUser(t)
+		mockRepo.On("FindByID", mock.Anything, user.ID).Return(user, nil)
+		mockRepo.On("UpdatePassword", ctx, user.ID, mock.MatchedBy(func(hash string) bool {
+			return auth.CheckPassword(hash, "new-secret-2024")
+		})).Return(nil)
+
+		store := repository.NewMemoryTokenRevocationStore()
+		service := NewUserService(mockRepo, WithTokenRevocationStore(store))
+
+		err := service.ChangePassword(ctx, user.ID, &models.ChangePasswordRequest{
+			CurrentPassword: "123456",
+			NewPassword:     "new-secret-2024",
+		})
+		require.NoError(t, err)
+
+		revoked, err := store.IsRevoked(ctx, &auth.UserClaims{UserID: user.ID})
+		require.NoError(t, err)
+		assert.True(t, revoked, "修改密码后已签发的访问令牌应失效")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("当前密码错误", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		user := newLoginTestUser(t)
+		mockRepo.On("FindByID", mock.Anything, user.ID).Return(user, nil)
+
+		service := NewUserService(mockRepo)
+		err := service.ChangePassword(ctx, user.ID, &models.ChangePasswordRequest{
+			CurrentPassword: "wrong",
+			NewPassword:     "new-secret-2024",
+		})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		assert.Contains(t, err.Error(), "当前密码错误")
+
+		mockRepo.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("新密码不符合密码策略", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		user := newLoginTestUser(t)
+		mockRepo.On("FindByID", mock.Anything, user.ID).Return(user, nil)
+
+		service := NewUserService(mockRepo)
+		err := service.ChangePassword(ctx, user.ID, &models.ChangePasswordRequest{
+			CurrentPassword: "123456",
+			NewPassword:     "password123",
+		})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		assert.Contains(t, err.Error(), "过于常见")
+
+		mockRepo.AssertNotCalled(t, "UpdatePassword")
+	})
+}
+
+// TestUserService_PasswordReset 测试忘记密码与重置密码
+func TestUserService_PasswordReset(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (UserService, *MockUserRepository, *captureNotifier, *models.User) {
+		mockRepo := new(MockUserRepository)
+		user := newLoginTestUser(t)
+		mockRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+		mockRepo.On("FindByEmail", ctx, mock.Anything).Return(nil, assert.AnError)
+
+		notifier := &captureNotifier{}
+		service := NewUserService(mockRepo, WithNotifier(notifier))
+		return service, mockRepo, notifier, user
+	}
+
+	requestToken := func(t *testing.T, service UserService, notifier *captureNotifier, email string) string {
+		require.NoError(t, service.ForgotPassword(ctx, &models.ForgotPasswordRequest{Email: email}))
+		require.NotEmpty(t, notifier.messages)
+		msg := notifier.messages[len(notifier.messages)-1]
+		assert.Equal(t, email, msg.To)
+		match := resetTokenPattern.FindStringSubmatch(msg.Body)
+		require.Len(t, match, 2, "通知中应包含重置链接")
+		return match[1]
+	}
+
+	t.Run("令牌只能使用一次", func(t *testing.T) {
+		service, mockRepo, notifier, user := setup(t)
+		mockRepo.On("UpdatePassword", ctx, user.ID, mock.AnythingOfType("string")).Return(nil).Once()
+
+		token := requestToken(t, service, notifier, user.Email)
+
+		require.NoError(t, service.ResetPassword(ctx, &models.ResetPasswordRequest{Token: token, NewPassword: "reset-secret-2024"}))
+
+		err := service.ResetPassword(ctx, &models.ResetPasswordRequest{Token: token, NewPassword: "reset-secret-2025"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		assert.Contains(t, err.Error(), "无效或已过期")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("重新申请后旧令牌失效", func(t *testing.T) {
+		service, mockRepo, notifier, user := setup(t)
+
+		oldToken := requestToken(t, service, notifier, user.Email)
+		_ = requestToken(t, service, notifier, user.Email)
+
+		err := service.ResetPassword(ctx, &models.ResetPasswordRequest{Token: oldToken, NewPassword: "reset-secret-2024"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		mockRepo.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("新密码不符合策略时不消耗令牌", func(t *testing.T) {
+		service, mockRepo, notifier, user := setup(t)
+		mockRepo.On("UpdatePassword", ctx, user.ID, mock.AnythingOfType("string")).Return(nil).Once()
+
+		token := requestToken(t, service, notifier, user.Email)
+
+		err := service.ResetPassword(ctx, &models.ResetPasswordRequest{Token: token, NewPassword: "short"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		require.NoError(t, service.ResetPassword(ctx, &models.ResetPasswordRequest{Token: token, NewPassword: "reset-secret-2024"}))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("未注册的邮箱同样返回成功且不发送通知", func(t *testing.T) {
+		service, _, notifier, _ := setup(t)
+
+		require.NoError(t, service.ForgotPassword(ctx, &models.ForgotPasswordRequest{Email: "nobody@example.com"}))
+		assert.Empty(t, notifier.messages)
+	})
+
+	t.Run("无效令牌", func(t *testing.T) {
+		service, _, _, _ := setup(t)
+
+		err := service.ResetPassword(ctx, &models.ResetPasswordRequest{Token: "invalid", NewPassword: "reset-secret-2024"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+}
+
+// TestUserService_UpdateUserPassword 测试通过更新用户接口设置密码
+func TestUserService_UpdateUserPassword(t *testing.T) {
+	existing := &models.User{ID: 2, Name: "李四", Email: "lisi@example.com", Role: auth.RoleUser}
+
+	t.Run("管理员可以为其他用户设置密码", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 100, Role: auth.RoleAdmin})
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", mock.Anything, int64(2)).Return(existing, nil)
+		mockRepo.On("Update", ctx, int64(2), mock.AnythingOfType("*models.User")).Return(existing, nil)
+		mockRepo.On("UpdatePassword", ctx, int64(2), mock.MatchedBy(func(hash string) bool {
+			return auth.CheckPassword(hash, "admin-set-2024")
+		})).Return(nil)
+
+		service := NewUserService(mockRepo)
+		_, err := service.UpdateUser(ctx, 2, &models.UpdateUserRequest{Password: "admin-set-2024"})
+		require.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("修改自己的密码必须验证当前密码", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 2, Role: auth.RoleUser})
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", mock.Anything, int64(2)).Return(existing, nil)
+
+		service := NewUserService(mockRepo)
+		_, err := service.UpdateUser(ctx, 2, &models.UpdateUserRequest{Password: "self-set-2024"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		mockRepo.AssertNotCalled(t, "Update")
+		mockRepo.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("管理员设置的密码也必须符合密码策略", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 100, Role: auth.RoleAdmin})
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", mock.Anything, int64(2)).Return(existing, nil)
+
+		service := NewUserService(mockRepo)
+		_, err := service.UpdateUser(ctx, 2, &models.UpdateUserRequest{Password: "123456"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+}
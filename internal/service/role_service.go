@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/database"
+	"gin/internal/errors"
+	"gin/internal/models"
+	"gin/internal/repository"
+)
+
+// roleNamePattern 角色名格式：小写字母开头，只包含小写字母、数字、下划线和连字符
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,63}$`)
+
+// RoleService 角色服务接口
+type RoleService interface {
+	ListRoles(ctx context.Context) ([]*models.Role, error)
+	GetRole(ctx context.Context, id int64) (*models.Role, error)
+	CreateRole(ctx context.Context, req *models.CreateRoleRequest) (*models.Role, error)
+	UpdateRole(ctx context.Context, id int64, req *models.UpdateRoleRequest) (*models.Role, error)
+	DeleteRole(ctx context.Context, id int64) error
+	ListPermissions(ctx context.Context) ([]*models.Permission, error)
+	AssignRole(ctx context.Context, userID int64, req *models.AssignRoleRequest) (*models.User, error)
+	// ReloadPermissions 从数据库重新加载授权关系到权限缓存
+	ReloadPermissions(ctx context.Context) error
+}
+
+// roleService 角色服务实现
+type roleService struct {
+	roleRepo        repository.RoleRepository
+	userRepo        repository.UserRepository
+	revocationStore repository.TokenRevocationStore
+	permissions     *auth.PermissionCache
+	audit           AuditRecorder
+}
+
+// RoleServiceOption 角色服务可选配置
+type RoleServiceOption func(*roleService)
+
+// WithRoleAuditRecorder 指定审计日志记录器（默认不记录）
+func WithRoleAuditRecorder(audit AuditRecorder) RoleServiceOption {
+	return func(s *roleService) {
+		s.audit = audit
+	}
+}
+
+// NewRoleService 创建角色服务
+// 角色与授权变更后会刷新全局权限缓存 auth.Permissions；
+// revocationStore 用于在用户角色变更后使其已签发的访问令牌失效（可为 nil）
+func NewRoleService(roleRepo repository.RoleRepository, userRepo repository.UserRepository, revocationStore repository.TokenRevocationStore, opts ...RoleServiceOption) RoleService {
+	s := &roleService{
+		roleRepo:        roleRepo,
+		userRepo:        userRepo,
+		revocationStore: revocationStore,
+		permissions:     auth.Permissions,
+		audit:           noopAuditRecorder{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ListRoles 获取所有角色
+func (s *roleService) ListRoles(ctx context.Context) ([]*models.Role, error) {
+	roles, err := s.roleRepo.FindAll(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError("获取角色列表失败", err)
+	}
+	return roles, nil
+}
+
+// GetRole 根据ID获取角色
+func (s *roleService) GetRole(ctx context.Context, id int64) (*models.Role, error) {
+	if id <= 0 {
+		return nil, errors.NewBadRequestError("角色ID无效", fmt.Errorf("invalid role id: %d", id))
+	}
+
+	role, err := s.roleRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("角色不存在", err)
+	}
+	return role, nil
+}
+
+// CreateRole 创建角色
+func (s *roleService) CreateRole(ctx context.Context, req *models.CreateRoleRequest) (*models.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, errors.NewBadRequestError("角色名格式无效", fmt.Errorf("invalid role name: %s", req.Name))
+	}
+
+	if _, err := s.roleRepo.FindByName(ctx, req.Name); err == nil {
+		return nil, errors.NewBadRequestError("角色名已存在", fmt.Errorf("role already exists: %s", req.Name))
+	}
+
+	role, err := s.roleRepo.Create(ctx, &models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: uniquePermissions(req.Permissions),
+	})
+	if err != nil {
+		return nil, roleWriteError("创建角色失败", err)
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditRoleCreate,
+		TargetType: models.AuditTargetRole,
+		TargetID:   auditTargetID(role.ID),
+		Changes:    encodeAuditChanges(auditFieldChanges(nil, role)),
+	})
+	return role, s.ReloadPermissions(ctx)
+}
+
+// UpdateRole 更新角色
+// 内置角色不能改名；超级管理员始终拥有全部权限，不能修改其授权
+func (s *roleService) UpdateRole(ctx context.Context, id int64, req *models.UpdateRoleRequest) (*models.Role, error) {
+	existing, err := s.GetRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        existing.Name,
+		Description: existing.Description,
+		Permissions: existing.Permissions,
+	}
+
+	if req.Name != "" && req.Name != existing.Name {
+		if existing.BuiltIn {
+			return nil, errors.NewBadRequestError("内置角色不能改名", fmt.Errorf("built-in role: %s", existing.Name))
+		}
+		if !roleNamePattern.MatchString(req.Name) {
+			return nil, errors.NewBadRequestError("角色名格式无效", fmt.Errorf("invalid role name: %s", req.Name))
+		}
+		if _, err := s.roleRepo.FindByName(ctx, req.Name); err == nil {
+			return nil, errors.NewBadRequestError("角色名已存在", fmt.Errorf("role already exists: %s", req.Name))
+		}
+		role.Name = req.Name
+	}
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+	if req.Permissions != nil {
+		if auth.Role(existing.Name).IsAdmin() {
+			return nil, errors.NewBadRequestError("不能修改超级管理员的权限", fmt.Errorf("admin role permissions are fixed"))
+		}
+		role.Permissions = uniquePermissions(req.Permissions)
+	}
+
+	updated, err := s.roleRepo.Update(ctx, id, role)
+	if err != nil {
+		return nil, roleWriteError("更新角色失败", err)
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditRoleUpdate,
+		TargetType: models.AuditTargetRole,
+		TargetID:   auditTargetID(id),
+		Changes:    encodeAuditChanges(auditFieldChanges(existing, updated)),
+	})
+	return updated, s.ReloadPermissions(ctx)
+}
+
+// DeleteRole 删除角色（内置角色和仍被用户使用的角色不能删除）
+func (s *roleService) DeleteRole(ctx context.Context, id int64) error {
+	existing, err := s.GetRole(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing.BuiltIn {
+		return errors.NewBadRequestError("内置角色不能删除", fmt.Errorf("built-in role: %s", existing.Name))
+	}
+
+	if err := s.roleRepo.Delete(ctx, id); err != nil {
+		if stderrors.Is(err, repository.ErrRoleInUse) {
+			return errors.NewBadRequestError("角色仍被用户使用，不能删除", err)
+		}
+		return errors.NewInternalServerError("删除角色失败", err)
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditRoleDelete,
+		TargetType: models.AuditTargetRole,
+		TargetID:   auditTargetID(id),
+		Detail:     "name=" + existing.Name,
+	})
+	return s.ReloadPermissions(ctx)
+}
+
+// ListPermissions 获取所有权限
+func (s *roleService) ListPermissions(ctx context.Context) ([]*models.Permission, error) {
+	permissions, err := s.roleRepo.FindAllPermissions(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError("获取权限列表失败", err)
+	}
+	return permissions, nil
+}
+
+// AssignRole 为用户分配角色
+// 角色写在访问令牌中，分配后撤销该用户已签发的访问令牌，客户端刷新令牌后即获得新角色
+func (s *roleService) AssignRole(ctx context.Context, userID int64, req *models.AssignRoleRequest) (*models.User, error) {
+	if userID <= 0 {
+		return nil, errors.NewBadRequestError("用户ID无效", fmt.Errorf("invalid user id: %d", userID))
+	}
+
+	if _, err := s.roleRepo.FindByName(ctx, req.Role); err != nil {
+		return nil, errors.NewBadRequestError("角色不存在", err)
+	}
+
+	user, err := s.userRepo.FindByID(database.WithPrimary(ctx), userID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("用户不存在", err)
+	}
+	if user.Role == auth.Role(req.Role) {
+		user.Password = ""
+		return user, nil
+	}
+
+	previousRole := user.Role
+	user.Role = auth.Role(req.Role)
+	updated, err := s.userRepo.Update(ctx, userID, user)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrVersionConflict) {
+			return nil, errors.NewConflictError("用户已被其他请求修改，请重试", err)
+		}
+		return nil, errors.NewInternalServerError("分配角色失败", err)
+	}
+
+	if s.revocationStore != nil {
+		if err := s.revocationStore.RevokeUser(ctx, userID, time.Now().Add(accessTokenTTL())); err != nil {
+			return nil, errors.NewInternalServerError("撤销用户访问令牌失败", err)
+		}
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserRoleAssign,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(userID),
+		Changes: encodeAuditChanges(map[string]models.AuditChange{
+			"role": {Before: previousRole, After: updated.Role},
+		}),
+	})
+
+	// 注意：不要返回密码字段
+	updated.Password = ""
+	return updated, nil
+}
+
+// ReloadPermissions 从数据库重新加载授权关系
+func (s *roleService) ReloadPermissions(ctx context.Context) error {
+	grants, err := s.roleRepo.LoadGrants(ctx)
+	if err != nil {
+		return errors.NewInternalServerError("加载权限失败", err)
+	}
+	s.permissions.Load(grants)
+	return nil
+}
+
+// uniquePermissions 去除重复的权限名
+func uniquePermissions(permissions []string) []string {
+	seen := make(map[string]struct{}, len(permissions))
+	result := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if _, ok := seen[permission]; ok {
+			continue
+		}
+		seen[permission] = struct{}{}
+		result = append(result, permission)
+	}
+	return result
+}
+
+// roleWriteError 将角色写入错误转换为应用错误
+func roleWriteError(msg string, err error) error {
+	if stderrors.Is(err, repository.ErrUnknownPermission) {
+		return errors.NewBadRequestError("权限不存在", err)
+	}
+	return errors.NewInternalServerError(msg, err)
+}
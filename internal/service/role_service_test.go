@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/models"
+	"gin/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockRoleRepository 是 RoleRepository 的 mock 实现
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) FindAll(ctx context.Context) ([]*models.Role, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) FindByID(ctx context.Context, id int64) (*models.Role, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) Create(ctx context.Context, role *models.Role) (*models.Role, error) {
+	args := m.Called(ctx, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) Update(ctx context.Context, id int64, role *models.Role) (*models.Role, error) {
+	args := m.Called(ctx, id, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) FindAllPermissions(ctx context.Context) ([]*models.Permission, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Permission), args.Error(1)
+}
+
+func (m *MockRoleRepository) LoadGrants(ctx context.Context) (map[auth.Role][]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[auth.Role][]string), args.Error(1)
+}
+
+// TestRoleService 测试角色服务
+func TestRoleService(t *testing.T) {
+	ctx := context.Background()
+	builtInAdmin := &models.Role{ID: 2, Name: "admin", BuiltIn: true, Permissions: []string{auth.PermissionUsersDelete}}
+	builtInUser := &models.Role{ID: 1, Name: "user", BuiltIn: true, Permissions: []string{auth.PermissionUsersRead}}
+
+	t.Run("创建角色后刷新权限缓存", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		service := NewRoleService(roleRepo, new(MockUserRepository), nil)
+
+		editor := &models.Role{ID: 3, Name: "editor", Permissions: []string{auth.PermissionUsersRead}}
+		roleRepo.On("FindByName", ctx, "editor").Return(nil, errors.New("角色不存在"))
+		roleRepo.On("Create", ctx, mock.MatchedBy(func(role *models.Role) bool {
+			return role.Name == "editor" && len(role.Permissions) == 1
+		})).Return(editor, nil)
+		roleRepo.On("LoadGrants", ctx).Return(map[auth.Role][]string{"editor": {auth.PermissionUsersRead}}, nil)
+
+		role, err := service.CreateRole(ctx, &models.CreateRoleRequest{
+			Name:        "editor",
+			Permissions: []string{auth.PermissionUsersRead, auth.PermissionUsersRead},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, editor, role)
+		assert.True(t, auth.Role("editor").HasPermission(auth.PermissionUsersRead))
+		assert.False(t, auth.Role("editor").HasPermission(auth.PermissionUsersDelete))
+		roleRepo.AssertExpectations(t)
+	})
+
+	t.Run("角色名格式无效", func(t *testing.T) {
+		service := NewRoleService(new(MockRoleRepository), new(MockUserRepository), nil)
+		_, err := service.CreateRole(ctx, &models.CreateRoleRequest{Name: "Bad Name"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "角色名格式无效")
+	})
+
+	t.Run("引用不存在的权限", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		service := NewRoleService(roleRepo, new(MockUserRepository), nil)
+		roleRepo.On("FindByName", ctx, "editor").Return(nil, errors.New("角色不存在"))
+		roleRepo.On("Create", ctx, mock.Anything).Return(nil, repository.ErrUnknownPermission)
+
+		_, err := service.CreateRole(ctx, &models.CreateRoleRequest{Name: "editor", Permissions: []string{"x:y"}})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "权限不存在")
+	})
+
+	t.Run("内置角色不能改名和删除", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		service := NewRoleService(roleRepo, new(MockUserRepository), nil)
+		roleRepo.On("FindByID", ctx, int64(1)).Return(builtInUser, nil)
+
+		_, err := service.UpdateRole(ctx, 1, &models.UpdateRoleRequest{Name: "member"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "内置角色不能改名")
+
+		err = service.DeleteRole(ctx, 1)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "内置角色不能删除")
+		roleRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("不能修改超级管理员的权限", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		service := NewRoleService(roleRepo, new(MockUserRepository), nil)
+		roleRepo.On("FindByID", ctx, int64(2)).Return(builtInAdmin, nil)
+
+		_, err := service.UpdateRole(ctx, 2, &models.UpdateRoleRequest{Permissions: []string{}})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "不能修改超级管理员的权限")
+	})
+
+	t.Run("仍被使用的角色不能删除", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		service := NewRoleService(roleRepo, new(MockUserRepository), nil)
+		roleRepo.On("FindByID", ctx, int64(3)).Return(&models.Role{ID: 3, Name: "editor"}, nil)
+		roleRepo.On("Delete", ctx, int64(3)).Return(repository.ErrRoleInUse)
+
+		err := service.DeleteRole(ctx, 3)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "角色仍被用户使用")
+	})
+
+	t.Run("分配角色后撤销已签发的访问令牌", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		userRepo := new(MockUserRepository)
+		store := repository.NewMemoryTokenRevocationStore()
+		recorder := &recordingAuditRecorder{}
+		service := NewRoleService(roleRepo, userRepo, store, WithRoleAuditRecorder(recorder))
+
+		user := newLoginTestUser(t)
+		roleRepo.On("FindByName", ctx, "admin").Return(builtInAdmin, nil)
+		userRepo.On("FindByID", mock.Anything, user.ID).Return(user, nil)
+		userRepo.On("Update", ctx, user.ID, mock.MatchedBy(func(u *models.User) bool {
+			return u.Role == auth.RoleAdmin
+		})).Return(&models.User{ID: user.ID, Role: auth.RoleAdmin, Password: "hash"}, nil)
+
+		claims := &auth.UserClaims{UserID: user.ID}
+		claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+		updated, err := service.AssignRole(ctx, user.ID, &models.AssignRoleRequest{Role: "admin"})
+		require.NoError(t, err)
+		assert.Equal(t, auth.RoleAdmin, updated.Role)
+		assert.Empty(t, updated.Password)
+
+		revoked, err := store.IsRevoked(ctx, claims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		require.Len(t, recorder.entries, 1)
+		assert.Equal(t, models.AuditUserRoleAssign, recorder.entries[0].Action)
+		assert.JSONEq(t, `{"role":{"before":"user","after":"admin"}}`, string(recorder.entries[0].Changes))
+	})
+
+	t.Run("分配不存在的角色", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		service := NewRoleService(roleRepo, new(MockUserRepository), nil)
+		roleRepo.On("FindByName", ctx, "missing").Return(nil, errors.New("角色不存在"))
+
+		_, err := service.AssignRole(ctx, 1, &models.AssignRoleRequest{Role: "missing"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "角色不存在")
+	})
+}
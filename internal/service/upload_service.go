@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/logger"
+	"gin/internal/models"
+	"gin/internal/requestctx"
+	"gin/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// UploadService 断点续传上传服务接口（tus 协议）
+// 上传内容先保存在分片上传存储中，全部接收后通过 FileService 保存到文件存储
+type UploadService interface {
+	// CreateUpload 创建上传，length 为文件总长度
+	CreateUpload(ctx context.Context, length int64, metadata map[string]string, policy models.UploadPolicy) (*storage.PartialUpload, error)
+	// GetUpload 获取当前用户的上传
+	GetUpload(ctx context.Context, id string) (*storage.PartialUpload, error)
+	// WriteChunk 从 offset 处写入一个分片，size 为分片长度（未知时为 -1）；全部接收后保存文件
+	WriteChunk(ctx context.Context, id string, offset, size int64, r io.Reader, policy models.UploadPolicy) (*storage.PartialUpload, error)
+	// TerminateUpload 取消上传并删除已接收的内容
+	TerminateUpload(ctx context.Context, id string) error
+	// PurgeExpired 清理过期的上传
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// uploadService 断点续传上传服务实现
+type uploadService struct {
+	store       storage.PartialStore
+	fileService FileService
+	expiration  time.Duration
+	finishing   sync.Map // 正在保存到文件存储的上传ID，同一上传同时只有一个请求保存
+}
+
+// NewUploadService 创建断点续传上传服务
+func NewUploadService(store storage.PartialStore, fileService FileService, cfg config.TusConfig) UploadService {
+	return &uploadService{
+		store:       store,
+		fileService: fileService,
+		expiration:  time.Duration(cfg.Expiration) * time.Hour,
+	}
+}
+
+// CreateUpload 创建上传
+func (s *uploadService) CreateUpload(ctx context.Context, length int64, metadata map[string]string, policy models.UploadPolicy) (*storage.PartialUpload, error) {
+	if length <= 0 {
+		return nil, errors.NewBadRequestError("文件为空", fmt.Errorf("upload length %d", length))
+	}
+	if length > policy.MaxSize {
+		return nil, errors.NewRequestEntityTooLargeError(fmt.Sprintf("文件不能超过 %d MB", policy.MaxSize>>20),
+			fmt.Errorf("upload length %d exceeds %d", length, policy.MaxSize))
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, errors.NewInternalServerError("创建上传失败", err)
+	}
+	caller, _ := auth.PrincipalFromContext(ctx)
+	now := time.Now()
+	upload := &storage.PartialUpload{
+		ID:        hex.EncodeToString(buf),
+		OwnerID:   caller.UserID,
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.expiration),
+	}
+	if err := s.store.Create(ctx, upload); err != nil {
+		return nil, errors.NewInternalServerError("创建上传失败", err)
+	}
+
+	logger.Log.Info(i18n.LogMessage(i18n.LogUploadCreated),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.String("upload_id", upload.ID),
+		zap.Int64("owner_id", upload.OwnerID),
+		zap.Int64("length", upload.Length),
+	)
+	return upload, nil
+}
+
+// GetUpload 获取当前用户的上传
+// 其他用户的上传按不存在处理，不暴露上传ID是否存在
+func (s *uploadService) GetUpload(ctx context.Context, id string) (*storage.PartialUpload, error) {
+	upload, err := s.store.Get(ctx, id)
+	if err != nil {
+		if stderrors.Is(err, storage.ErrNotFound) || stderrors.Is(err, storage.ErrInvalidKey) {
+			return nil, errors.NewNotFoundError("上传不存在", err)
+		}
+		return nil, errors.NewInternalServerError("获取上传失败", err)
+	}
+
+	caller, _ := auth.PrincipalFromContext(ctx)
+	if upload.OwnerID != caller.UserID {
+		return nil, errors.NewNotFoundError("上传不存在", fmt.Errorf("upload %s owned by %d", id, upload.OwnerID))
+	}
+	if !upload.ExpiresAt.After(time.Now()) {
+		return nil, errors.NewGoneError("上传已过期", fmt.Errorf("upload %s expired at %s", id, upload.ExpiresAt))
+	}
+	return upload, nil
+}
+
+// WriteChunk 写入一个分片
+func (s *uploadService) WriteChunk(ctx context.Context, id string, offset, size int64, r io.Reader, policy models.UploadPolicy) (*storage.PartialUpload, error) {
+	upload, err := s.GetUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return nil, errors.NewConflictError(fmt.Sprintf("上传位置不一致，已接收 %d 字节", upload.Offset),
+			fmt.Errorf("offset %d, received %d", offset, upload.Offset))
+	}
+	if upload.Completed() {
+		// 上传已完成，重复发送最后一个分片时直接返回
+		return upload, nil
+	}
+	remaining := upload.Length - upload.Offset
+	if size > remaining {
+		return nil, errors.NewRequestEntityTooLargeError(fmt.Sprintf("分片超过剩余长度 %d 字节", remaining),
+			fmt.Errorf("chunk size %d exceeds remaining %d", size, remaining))
+	}
+
+	// 不信任 Content-Length，最多读取剩余长度
+	previous := upload.Offset
+	upload.Offset, err = s.store.Append(ctx, id, offset, io.LimitReader(r, remaining))
+	if err != nil {
+		if stderrors.Is(err, storage.ErrOffsetMismatch) {
+			return nil, errors.NewConflictError(fmt.Sprintf("上传位置不一致，已接收 %d 字节", upload.Offset), err)
+		}
+		// 已写入的内容保留，客户端可以查询位置后继续上传
+		return nil, errors.NewInternalServerError("保存上传内容失败", err)
+	}
+
+	// 收到文件开头后立即识别类型，不允许的文件不必等到上传完成
+	if previous < sniffLength && (upload.Offset >= sniffLength || upload.Offset == upload.Length) {
+		if err := s.checkContentType(ctx, upload, policy); err != nil {
+			return nil, err
+		}
+	}
+
+	if upload.Offset == upload.Length {
+		if err := s.finishOnce(ctx, upload, policy); err != nil {
+			return nil, err
+		}
+	}
+	return upload, nil
+}
+
+// finishOnce 保存已接收完的上传，同一上传同时只有一个请求执行保存
+// 保存期间的其他请求（如重复发送最后一个空分片）返回 409，客户端稍后查询上传状态即可得到保存结果
+func (s *uploadService) finishOnce(ctx context.Context, upload *storage.PartialUpload, policy models.UploadPolicy) error {
+	if _, busy := s.finishing.LoadOrStore(upload.ID, struct{}{}); busy {
+		return errors.NewConflictError("上传正在保存，请稍后查询上传状态", fmt.Errorf("upload %s is being finished", upload.ID))
+	}
+	defer s.finishing.Delete(upload.ID)
+
+	// 读取上传状态之后，其他请求可能已经保存完成或因文件不符合限制删除了上传
+	current, err := s.store.Get(ctx, upload.ID)
+	if err != nil {
+		if stderrors.Is(err, storage.ErrNotFound) {
+			return errors.NewNotFoundError("上传不存在", err)
+		}
+		return errors.NewInternalServerError("获取上传失败", err)
+	}
+	if current.Completed() {
+		upload.FileID = current.FileID
+		return nil
+	}
+	return s.finish(ctx, upload, policy)
+}
+
+// checkContentType 按已接收的文件开头识别类型，不允许时删除上传
+func (s *uploadService) checkContentType(ctx context.Context, upload *storage.PartialUpload, policy models.UploadPolicy) error {
+	r, err := s.store.Open(ctx, upload.ID)
+	if err != nil {
+		return errors.NewInternalServerError("读取上传内容失败", err)
+	}
+	defer r.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !stderrors.Is(err, io.ErrUnexpectedEOF) && !stderrors.Is(err, io.EOF) {
+		return errors.NewInternalServerError("读取上传内容失败", err)
+	}
+	contentType := sniffContentType(head[:n])
+	if policy.Allows(contentType) {
+		return nil
+	}
+
+	s.discard(ctx, upload.ID)
+	return errors.NewUnsupportedMediaTypeError(fmt.Sprintf("不支持的文件类型: %s", contentType),
+		fmt.Errorf("content type %s not allowed", contentType))
+}
+
+// finish 把已接收的内容保存到文件存储
+// 文件不符合上传限制时删除上传；保存失败时保留上传，客户端可以重新发送最后一个分片重试
+func (s *uploadService) finish(ctx context.Context, upload *storage.PartialUpload, policy models.UploadPolicy) error {
+	r, err := s.store.Open(ctx, upload.ID)
+	if err != nil {
+		return errors.NewInternalServerError("读取上传内容失败", err)
+	}
+	defer r.Close()
+
+	policy.MaxFiles = 1
+	files, err := s.fileService.UploadFiles(ctx, []*FileUpload{{
+		Name:    upload.Metadata["filename"],
+		Size:    upload.Length,
+		Content: r,
+	}}, policy)
+	if err != nil {
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) && appErr.Code < http.StatusInternalServerError {
+			s.discard(ctx, upload.ID)
+		}
+		return err
+	}
+
+	upload.FileID = files[0].ID
+	if err := s.store.Complete(ctx, upload.ID, upload.FileID); err != nil {
+		return errors.NewInternalServerError("保存上传状态失败", err)
+	}
+	return nil
+}
+
+// discard 删除被拒绝的上传
+func (s *uploadService) discard(ctx context.Context, id string) {
+	if err := s.store.Delete(context.WithoutCancel(ctx), id); err != nil {
+		logger.Log.Error(i18n.LogMessage(i18n.LogFileCleanupFailed),
+			zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+			zap.String("upload_id", id),
+			zap.Error(err),
+		)
+	}
+}
+
+// TerminateUpload 取消上传
+func (s *uploadService) TerminateUpload(ctx context.Context, id string) error {
+	upload, err := s.GetUpload(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, upload.ID); err != nil {
+		return errors.NewInternalServerError("取消上传失败", err)
+	}
+
+	logger.Log.Info(i18n.LogMessage(i18n.LogUploadTerminated),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.String("upload_id", upload.ID),
+		zap.Int64("offset", upload.Offset),
+	)
+	return nil
+}
+
+// PurgeExpired 清理过期的上传（包括已完成的上传状态）
+func (s *uploadService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.store.PurgeExpired(ctx, time.Now())
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/models"
+	"gin/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUploadService 测试断点续传上传
+func TestUploadService(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Role: auth.RoleUser})
+	policy := models.UploadPolicy{MaxSize: 1 << 20, MaxFiles: 1, AllowedTypes: []string{"image/png"}}
+	content := pngContent + strings.Repeat("x", 1000-len(pngContent))
+
+	// newService 创建使用临时目录的上传服务，文件信息由 mock 保存
+	newService := func(t *testing.T, expiration int) (UploadService, *MockFileRepository, storage.Storage) {
+		fileRepo := new(MockFileRepository)
+		fileStorage := storage.NewLocalStorage(t.TempDir())
+		fileService := NewFileService(fileRepo, fileStorage)
+		return NewUploadService(storage.NewPartialStore(t.TempDir()), fileService, config.TusConfig{Expiration: expiration}), fileRepo, fileStorage
+	}
+	createFile := func(fileRepo *MockFileRepository) *mock.Call {
+		return fileRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.File")).Run(func(args mock.Arguments) {
+			args.Get(1).(*models.File).ID = 99
+		}).Return(&models.File{ID: 99}, nil)
+	}
+
+	t.Run("分片上传完成后保存文件", func(t *testing.T) {
+		service, fileRepo, fileStorage := newService(t, 24)
+		createFile(fileRepo).Once()
+
+		upload, err := service.CreateUpload(ctx, int64(len(content)), map[string]string{"filename": "photo.png"}, policy)
+		require.NoError(t, err)
+		assert.Regexp(t, `^[0-9a-f]{32}$`, upload.ID)
+		assert.Equal(t, int64(7), upload.OwnerID)
+
+		upload, err = service.WriteChunk(ctx, upload.ID, 0, 600, strings.NewReader(content[:600]), policy)
+		require.NoError(t, err)
+		assert.Equal(t, int64(600), upload.Offset)
+		assert.False(t, upload.Completed())
+
+		_, err = service.WriteChunk(ctx, upload.ID, 0, 600, strings.NewReader(content[:600]), policy)
+		assertAppErrorCode(t, err, http.StatusConflict)
+
+		// 多出的内容不会写入
+		upload, err = service.WriteChunk(ctx, upload.ID, 600, -1, strings.NewReader(content[600:]+"extra"), policy)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), upload.Offset)
+		assert.Equal(t, int64(99), upload.FileID)
+
+		file := fileRepo.Calls[0].Arguments.Get(1).(*models.File)
+		assert.Equal(t, "photo.png", file.Name)
+		assert.Equal(t, "image/png", file.ContentType)
+		assert.Equal(t, int64(len(content)), file.Size)
+		r, err := fileStorage.Get(ctx, file.StorageKey)
+		require.NoError(t, err)
+		r.Close()
+
+		// 重复发送最后一个分片不会再次保存
+		upload, err = service.WriteChunk(ctx, upload.ID, int64(len(content)), 0, strings.NewReader(""), policy)
+		require.NoError(t, err)
+		assert.Equal(t, int64(99), upload.FileID)
+		fileRepo.AssertNumberOfCalls(t, "Create", 1)
+	})
+
+	t.Run("只能访问自己的上传", func(t *testing.T) {
+		service, _, _ := newService(t, 24)
+		upload, err := service.CreateUpload(ctx, 100, nil, policy)
+		require.NoError(t, err)
+
+		other := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 8, Role: auth.RoleUser})
+		_, err = service.GetUpload(other, upload.ID)
+		assertAppErrorCode(t, err, http.StatusNotFound)
+		assertAppErrorCode(t, service.TerminateUpload(other, upload.ID), http.StatusNotFound)
+
+		require.NoError(t, service.TerminateUpload(ctx, upload.ID))
+		_, err = service.GetUpload(ctx, upload.ID)
+		assertAppErrorCode(t, err, http.StatusNotFound)
+	})
+
+	t.Run("不允许的类型在收到文件开头后拒绝", func(t *testing.T) {
+		service, fileRepo, _ := newService(t, 24)
+		upload, err := service.CreateUpload(ctx, 2000, nil, policy)
+		require.NoError(t, err)
+
+		_, err = service.WriteChunk(ctx, upload.ID, 0, 600, strings.NewReader("<html>"+strings.Repeat(" ", 594)), policy)
+		assertAppErrorCode(t, err, http.StatusUnsupportedMediaType)
+		_, err = service.GetUpload(ctx, upload.ID)
+		assertAppErrorCode(t, err, http.StatusNotFound)
+		fileRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("保存文件失败时可以重试", func(t *testing.T) {
+		service, fileRepo, _ := newService(t, 24)
+		fileRepo.On("Create", mock.Anything, mock.Anything).Return(nil, errors.New("数据库错误")).Once()
+		createFile(fileRepo).Once()
+
+		upload, err := service.CreateUpload(ctx, int64(len(content)), nil, policy)
+		require.NoError(t, err)
+		_, err = service.WriteChunk(ctx, upload.ID, 0, int64(len(content)), strings.NewReader(content), policy)
+		assertAppErrorCode(t, err, http.StatusInternalServerError)
+
+		upload, err = service.GetUpload(ctx, upload.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), upload.Offset)
+
+		upload, err = service.WriteChunk(ctx, upload.ID, int64(len(content)), 0, strings.NewReader(""), policy)
+		require.NoError(t, err)
+		assert.Equal(t, int64(99), upload.FileID)
+	})
+
+	t.Run("保存文件期间重复发送最后一个分片返回409且不会重复保存", func(t *testing.T) {
+		service, fileRepo, _ := newService(t, 24)
+		saving, release := make(chan struct{}), make(chan struct{})
+		createFile(fileRepo).Run(func(args mock.Arguments) {
+			args.Get(1).(*models.File).ID = 99
+			close(saving)
+			<-release
+		}).Once()
+
+		upload, err := service.CreateUpload(ctx, int64(len(content)), nil, policy)
+		require.NoError(t, err)
+		done := make(chan error, 1)
+		go func() {
+			_, err := service.WriteChunk(ctx, upload.ID, 0, int64(len(content)), strings.NewReader(content), policy)
+			done <- err
+		}()
+
+		<-saving
+		_, err = service.WriteChunk(ctx, upload.ID, int64(len(content)), 0, strings.NewReader(""), policy)
+		assertAppErrorCode(t, err, http.StatusConflict)
+
+		close(release)
+		require.NoError(t, <-done)
+		upload, err = service.WriteChunk(ctx, upload.ID, int64(len(content)), 0, strings.NewReader(""), policy)
+		require.NoError(t, err)
+		assert.Equal(t, int64(99), upload.FileID)
+		fileRepo.AssertNumberOfCalls(t, "Create", 1)
+	})
+
+	t.Run("上传限制与过期", func(t *testing.T) {
+		service, _, _ := newService(t, 0)
+		_, err := service.CreateUpload(ctx, 2<<20, nil, policy)
+		assertAppErrorCode(t, err, http.StatusRequestEntityTooLarge)
+		_, err = service.CreateUpload(ctx, 0, nil, policy)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		upload, err := service.CreateUpload(ctx, 100, nil, policy)
+		require.NoError(t, err)
+		_, err = service.WriteChunk(ctx, upload.ID, 0, 200, strings.NewReader("x"), policy)
+		assertAppErrorCode(t, err, http.StatusGone)
+
+		purged, err := service.PurgeExpired(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+	})
+}
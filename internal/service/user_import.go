@@ -0,0 +1,434 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/logger"
+	"gin/internal/models"
+	"gin/internal/repository"
+	"gin/internal/requestctx"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// maxImportRows 单次导入的最大行数（不含 CSV 表头）
+const maxImportRows = 10000
+
+// maxNDJSONLineSize NDJSON 单行的最大字节数
+const maxNDJSONLineSize = 64 * 1024
+
+// userExportColumns 导出 CSV 的列
+var userExportColumns = []string{"id", "name", "email", "age", "role", "version", "created_at", "updated_at"}
+
+// importRecord 从导入文件解析出的一行，解析失败时 err 不为空
+type importRecord struct {
+	line   int
+	record models.ImportUserRecord
+	err    error
+}
+
+// ImportUsers 从 CSV 或 NDJSON 批量导入用户，返回每一行的校验与导入结果
+// 每行按 models.ImportUserRecord 的 binding 规则、密码策略和邮箱占用情况校验，文件内重复的邮箱同样视为失败；
+// atomic 模式在一个事务中写入，任一行失败时不导入任何用户；best_effort 模式逐行写入并跳过失败的行；
+// 试运行只校验不写入。文件本身无法解析（格式错误、缺少必需的列、行数超限）时返回 400
+func (s *userService) ImportUsers(ctx context.Context, r io.Reader, opts models.ImportUsersOptions) (*models.ImportUsersResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = models.ImportModeAtomic
+	}
+	if opts.Mode != models.ImportModeAtomic && opts.Mode != models.ImportModeBestEffort {
+		return nil, errors.NewBadRequestError("导入模式无效，应为 atomic 或 best_effort", fmt.Errorf("invalid import mode: %q", opts.Mode))
+	}
+
+	var records []importRecord
+	var err error
+	switch opts.Format {
+	case models.UserFileFormatCSV:
+		records, err = readCSVImport(r)
+	case models.UserFileFormatNDJSON:
+		records, err = readNDJSONImport(r)
+	default:
+		return nil, errors.NewBadRequestError("导入格式无效，应为 csv 或 ndjson", fmt.Errorf("invalid import format: %q", opts.Format))
+	}
+	if err != nil {
+		return nil, errors.NewBadRequestError("导入文件无效: "+err.Error(), err)
+	}
+
+	result := &models.ImportUsersResult{
+		Mode:   opts.Mode,
+		DryRun: opts.DryRun,
+		Total:  len(records),
+		Rows:   make([]models.ImportRowResult, len(records)),
+	}
+
+	// 逐行校验，校验通过的行保存在 valid 中（下标与 result.Rows 对应）
+	valid := make(map[int]*models.User, len(records))
+	emailLines := make(map[string]int, len(records))
+	assignableRoles := make(map[auth.Role]error)
+	for i, rec := range records {
+		row := &result.Rows[i]
+		row.Line = rec.line
+		row.Email = rec.record.Email
+
+		user, err := s.validateImportRecord(ctx, rec, emailLines, assignableRoles)
+		if err != nil {
+			row.Status = models.ImportRowFailed
+			row.Error = importErrorMessage(err)
+			result.Failed++
+			continue
+		}
+		row.Status = models.ImportRowValid
+		valid[i] = user
+	}
+
+	if opts.DryRun || len(valid) == 0 || (opts.Mode == models.ImportModeAtomic && result.Failed > 0) {
+		return result, nil
+	}
+
+	if opts.Mode == models.ImportModeAtomic {
+		err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+			for i := range records {
+				user, ok := valid[i]
+				if !ok {
+					continue
+				}
+				created, err := s.createImportedUser(ctx, user)
+				if err != nil {
+					return fmt.Errorf("第 %d 行: %w", records[i].line, err)
+				}
+				result.Rows[i].UserID = created.ID
+			}
+			return nil
+		})
+		if err != nil {
+			var appErr *errors.AppError
+			if stderrors.As(err, &appErr) {
+				return nil, errors.NewBadRequestError("导入失败，未导入任何用户: "+appErr.Message, err)
+			}
+			return nil, errors.NewInternalServerError("导入失败，未导入任何用户", err)
+		}
+		for i := range valid {
+			result.Rows[i].Status = models.ImportRowCreated
+		}
+		result.Created = len(valid)
+	} else {
+		for i := range records {
+			user, ok := valid[i]
+			if !ok {
+				continue
+			}
+			row := &result.Rows[i]
+			created, err := s.createImportedUser(ctx, user)
+			if err != nil {
+				row.Status = models.ImportRowFailed
+				row.Error = importErrorMessage(err)
+				result.Failed++
+				continue
+			}
+			row.Status = models.ImportRowCreated
+			row.UserID = created.ID
+			result.Created++
+		}
+	}
+	result.Committed = result.Created > 0
+
+	// 逐个用户记录会拖慢大批量导入，导入整体记为一条审计日志
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserImport,
+		TargetType: models.AuditTargetUser,
+		Detail:     fmt.Sprintf("mode=%s, total=%d, created=%d, failed=%d", opts.Mode, result.Total, result.Created, result.Failed),
+	})
+
+	caller, _ := auth.PrincipalFromContext(ctx)
+	logger.Log.Info(i18n.LogMessage(i18n.LogUsersImported),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.String("mode", opts.Mode),
+		zap.Int("total", result.Total),
+		zap.Int("created", result.Created),
+		zap.Int("failed", result.Failed),
+		zap.Int64("operator_id", caller.UserID),
+	)
+	return result, nil
+}
+
+// validateImportRecord 校验导入的一行，返回待创建的用户（密码为明文，写入时再加密，试运行不必计算哈希）
+// emailLines 记录已出现的邮箱所在行，assignableRoles 缓存角色能否分配的检查结果
+func (s *userService) validateImportRecord(ctx context.Context, rec importRecord, emailLines map[string]int, assignableRoles map[auth.Role]error) (*models.User, error) {
+	if rec.err != nil {
+		return nil, rec.err
+	}
+	record := rec.record
+
+	if err := userValidator.Struct(&record); err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(record.Email)
+	if line, ok := emailLines[email]; ok {
+		return nil, fmt.Errorf("邮箱与第 %d 行重复", line)
+	}
+	emailLines[email] = rec.line
+
+	role := auth.RoleUser
+	if record.Role != "" && auth.Role(record.Role) != auth.RoleUser {
+		role = auth.Role(record.Role)
+		err, checked := assignableRoles[role]
+		if !checked {
+			err = s.checkRoleAssignable(ctx, 0, role)
+			assignableRoles[role] = err
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.passwordPolicy.Validate(record.Password); err != nil {
+		return nil, err
+	}
+	if err := s.ensureEmailAvailable(ctx, record.Email); err != nil {
+		return nil, err
+	}
+
+	return &models.User{
+		Name:     record.Name,
+		Email:    record.Email,
+		Password: record.Password,
+		Age:      record.Age,
+		Role:     role,
+	}, nil
+}
+
+// createImportedUser 加密密码并创建导入的用户
+func (s *userService) createImportedUser(ctx context.Context, user *models.User) (*models.User, error) {
+	hashedPassword, err := auth.HashPassword(user.Password)
+	if err != nil {
+		return nil, errors.NewInternalServerError("密码加密失败", err)
+	}
+
+	created, err := s.userRepo.Create(ctx, &models.User{
+		Name:     user.Name,
+		Email:    user.Email,
+		Password: hashedPassword,
+		Age:      user.Age,
+		Role:     user.Role,
+	})
+	if err != nil {
+		// 校验之后其他请求注册了同一邮箱时由唯一约束兜底
+		if stderrors.Is(err, repository.ErrEmailExists) {
+			return nil, errors.NewBadRequestError("邮箱已被使用", err)
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+// importErrorMessage 返回导入结果中展示的错误信息
+func importErrorMessage(err error) string {
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		return appErr.Message
+	}
+	var validationErrors validator.ValidationErrors
+	if stderrors.As(err, &validationErrors) {
+		fields := make([]string, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, fmt.Sprintf("%s(%s)", strings.ToLower(fe.Field()), fe.Tag()))
+		}
+		return "字段校验失败: " + strings.Join(fields, ", ")
+	}
+	return err.Error()
+}
+
+// readCSVImport 解析 CSV 导入文件，第一行为表头
+func readCSVImport(r io.Reader) ([]importRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取表头失败: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Excel 导出的 UTF-8 BOM
+		}
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "email", "password"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("缺少 %s 列", required)
+		}
+	}
+	field := func(values []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+
+	var records []importRecord
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		// 列数与表头不一致时只有这一行失败，其他解析错误无法定位后续的行
+		if err != nil && !stderrors.Is(err, csv.ErrFieldCount) {
+			return nil, err
+		}
+		if len(records) >= maxImportRows {
+			return nil, fmt.Errorf("超过单次导入的最大行数 %d", maxImportRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rec := importRecord{line: line}
+		if err != nil {
+			rec.err = fmt.Errorf("列数与表头不一致")
+		} else {
+			rec.record = models.ImportUserRecord{
+				Name:     field(values, "name"),
+				Email:    field(values, "email"),
+				Password: field(values, "password"),
+				Role:     field(values, "role"),
+			}
+			if age := field(values, "age"); age != "" {
+				if rec.record.Age, err = strconv.Atoi(age); err != nil {
+					rec.err = fmt.Errorf("年龄无效: %s", age)
+				}
+			}
+		}
+		records = append(records, rec)
+	}
+}
+
+// readNDJSONImport 解析 NDJSON 导入文件，忽略空行和未知字段
+func readNDJSONImport(r io.Reader) ([]importRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxNDJSONLineSize)
+
+	var records []importRecord
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(records) >= maxImportRows {
+			return nil, fmt.Errorf("超过单次导入的最大行数 %d", maxImportRows)
+		}
+
+		rec := importRecord{line: line}
+		if err := json.Unmarshal(data, &rec.record); err != nil {
+			rec.err = fmt.Errorf("JSON 无效: %v", err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ExportUsers 按过滤条件导出用户（不含密码），按 ID 顺序分页读取并逐页写入 w
+// 导出已删除的用户需要 users:restore 权限（与列表查询相同）
+func (s *userService) ExportUsers(ctx context.Context, w io.Writer, format string, filter models.UserFilter) error {
+	var write func(user *models.User) error
+	var flush func() error
+	switch format {
+	case models.UserFileFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(userExportColumns); err != nil {
+			return err
+		}
+		write = func(user *models.User) error {
+			return writer.Write([]string{
+				strconv.FormatInt(user.ID, 10),
+				csvSafe(user.Name),
+				csvSafe(user.Email),
+				strconv.Itoa(user.Age),
+				csvSafe(user.Role.String()),
+				strconv.FormatInt(user.Version, 10),
+				user.CreatedAt.Format(time.RFC3339),
+				user.UpdatedAt.Format(time.RFC3339),
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case models.UserFileFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		write = func(user *models.User) error {
+			user.Password = ""
+			return encoder.Encode(user)
+		}
+		flush = func() error { return nil }
+	default:
+		return errors.NewBadRequestError("导出格式无效，应为 csv 或 ndjson", fmt.Errorf("invalid export format: %q", format))
+	}
+
+	req := &models.ListUsersRequest{
+		UserFilter: filter,
+		PageQuery:  models.PageQuery{Limit: models.MaxPageLimit, Sort: "id"},
+	}
+	exported := 0
+	for {
+		page, err := s.GetAllUsers(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, user := range page.Items {
+			if err := write(user); err != nil {
+				return err
+			}
+		}
+		exported += len(page.Items)
+		if err := flush(); err != nil {
+			return err
+		}
+		if !page.HasMore {
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserExport,
+		TargetType: models.AuditTargetUser,
+		Detail:     fmt.Sprintf("format=%s, exported=%d, role=%q, deleted=%t", format, exported, filter.Role, filter.Deleted),
+	})
+
+	caller, _ := auth.PrincipalFromContext(ctx)
+	logger.Log.Info(i18n.LogMessage(i18n.LogUsersExported),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.String("format", format),
+		zap.Int("exported", exported),
+		zap.Int64("operator_id", caller.UserID),
+	)
+	return nil
+}
+
+// csvSafe 防止 CSV 注入：以 = + - @ 等开头的单元格在表格软件中会被当作公式执行，加上 ' 前缀
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gin/internal/auth"
+	"gin/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUserService_ImportUsers 测试批量导入用户
+func TestUserService_ImportUsers(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 99, Role: auth.RoleAdmin})
+
+	// emailAvailable 模拟邮箱未被占用
+	emailAvailable := func(mockRepo *MockUserRepository, emails ...string) {
+		for _, email := range emails {
+			mockRepo.On("FindByEmail", ctx, email).Return(nil, errors.New("用户不存在"))
+			mockRepo.On("FindDeletedByEmail", ctx, email).Return(nil, errors.New("用户不存在"))
+		}
+	}
+
+	csvFile := "name,email,password,age\n" +
+		"张三,zhangsan@example.com,zhangsan2024,25\n" +
+		"李四,not-an-email,lisi2024abc,30\n"
+
+	t.Run("试运行只校验不写入", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		emailAvailable(mockRepo, "zhangsan@example.com")
+
+		result, err := service.ImportUsers(ctx, strings.NewReader(csvFile), models.ImportUsersOptions{
+			Format: models.UserFileFormatCSV, Mode: models.ImportModeBestEffort, DryRun: true,
+		})
+		require.NoError(t, err)
+		assert.False(t, result.Committed)
+		assert.Equal(t, 2, result.Total)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, models.ImportRowValid, result.Rows[0].Status)
+		assert.Equal(t, 2, result.Rows[0].Line)
+		assert.Equal(t, models.ImportRowFailed, result.Rows[1].Status)
+		assert.Equal(t, 3, result.Rows[1].Line)
+		assert.Equal(t, "字段校验失败: email(email)", result.Rows[1].Error)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("atomic模式有一行失败时不导入任何用户", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		emailAvailable(mockRepo, "zhangsan@example.com")
+
+		result, err := service.ImportUsers(ctx, strings.NewReader(csvFile), models.ImportUsersOptions{Format: models.UserFileFormatCSV})
+		require.NoError(t, err)
+		assert.Equal(t, models.ImportModeAtomic, result.Mode)
+		assert.False(t, result.Committed)
+		assert.Equal(t, 0, result.Created)
+		assert.Equal(t, 1, result.Failed)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("best_effort模式跳过失败的行", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		emailAvailable(mockRepo, "zhangsan@example.com")
+		mockRepo.On("Create", ctx, mock.MatchedBy(func(u *models.User) bool {
+			return u.Email == "zhangsan@example.com" && u.Age == 25 && u.Role == auth.RoleUser && u.Password != "zhangsan2024"
+		})).Return(&models.User{ID: 7, Email: "zhangsan@example.com"}, nil)
+
+		result, err := service.ImportUsers(ctx, strings.NewReader(csvFile), models.ImportUsersOptions{
+			Format: models.UserFileFormatCSV, Mode: models.ImportModeBestEffort,
+		})
+		require.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, models.ImportRowCreated, result.Rows[0].Status)
+		assert.Equal(t, int64(7), result.Rows[0].UserID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("NDJSON中重复的邮箱和无效的行只影响所在行", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		emailAvailable(mockRepo, "zhangsan@example.com")
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(&models.User{ID: 7}, nil).Once()
+
+		file := `{"name":"张三","email":"zhangsan@example.com","password":"zhangsan2024"}` + "\n" +
+			"\n" +
+			`{"name":"张三","email":"ZhangSan@example.com","password":"zhangsan2024"}` + "\n" +
+			`{"name":` + "\n"
+
+		result, err := service.ImportUsers(ctx, strings.NewReader(file), models.ImportUsersOptions{
+			Format: models.UserFileFormatNDJSON, Mode: models.ImportModeBestEffort,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Total)
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, 2, result.Failed)
+		assert.Equal(t, 3, result.Rows[1].Line)
+		assert.Equal(t, "邮箱与第 1 行重复", result.Rows[1].Error)
+		assert.Equal(t, 4, result.Rows[2].Line)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("没有roles:assign权限不能导入其他角色的用户", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		userCtx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1, Role: "operator"})
+
+		file := `{"name":"张三","email":"zhangsan@example.com","password":"zhangsan2024","role":"admin"}`
+		result, err := service.ImportUsers(userCtx, strings.NewReader(file), models.ImportUsersOptions{Format: models.UserFileFormatNDJSON})
+		require.NoError(t, err)
+		assert.Equal(t, models.ImportRowFailed, result.Rows[0].Status)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CSV缺少必需的列返回400", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		_, err := service.ImportUsers(ctx, strings.NewReader("name,email\n张三,zhangsan@example.com\n"), models.ImportUsersOptions{Format: models.UserFileFormatCSV})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+}
+
+// TestUserService_ExportUsers 测试批量导出用户
+func TestUserService_ExportUsers(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 99, Role: auth.RoleAdmin})
+
+	t.Run("按游标分页导出CSV并转义公式", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("List", ctx, models.UserFilter{}, models.PageQuery{Limit: models.MaxPageLimit, Sort: "id"}).
+			Return(&models.Page[*models.User]{Items: []*models.User{{ID: 1, Name: "=1+1", Email: "a@example.com", Role: auth.RoleUser, Version: 1}}, HasMore: true, NextCursor: "c1"}, nil)
+		mockRepo.On("List", ctx, models.UserFilter{}, models.PageQuery{Limit: models.MaxPageLimit, Sort: "id", Cursor: "c1"}).
+			Return(&models.Page[*models.User]{Items: []*models.User{{ID: 2, Name: "李四", Email: "b@example.com", Role: auth.RoleUser, Version: 2}}}, nil)
+
+		var buf bytes.Buffer
+		require.NoError(t, service.ExportUsers(ctx, &buf, models.UserFileFormatCSV, models.UserFilter{}))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, "id,name,email,age,role,version,created_at,updated_at", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "1,'=1+1,a@example.com,0,user,1,"))
+		assert.True(t, strings.HasPrefix(lines[2], "2,李四,b@example.com,0,user,2,"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("NDJSON不包含密码", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("List", ctx, models.UserFilter{}, mock.Anything).
+			Return(&models.Page[*models.User]{Items: []*models.User{{ID: 1, Name: "张三", Password: "hash"}}}, nil)
+
+		var buf bytes.Buffer
+		require.NoError(t, service.ExportUsers(ctx, &buf, models.UserFileFormatNDJSON, models.UserFilter{}))
+
+		var user map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &user))
+		assert.Equal(t, "张三", user["name"])
+		assert.NotContains(t, buf.String(), "hash")
+	})
+
+	t.Run("没有users:restore权限不能导出已删除的用户", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		userCtx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1, Role: auth.RoleUser})
+
+		var buf bytes.Buffer
+		err := service.ExportUsers(userCtx, &buf, models.UserFileFormatCSV, models.UserFilter{Deleted: true})
+		assertAppErrorCode(t, err, http.StatusForbidden)
+		mockRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/errors"
+	"gin/internal/jsonpatch"
+	"gin/internal/models"
+	"gin/internal/policy"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// userValidator 按 models.User 上的 binding 规则校验应用补丁后的用户（与 gin 绑定请求时使用同一套规则）
+var userValidator = func() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	return v
+}()
+
+// patchableUserFields 可以通过 PATCH 修改的字段
+// 用户 JSON 表示中的其他字段（id、version、created_at 等）只读，补丁修改它们时返回 400
+var patchableUserFields = map[string]struct{}{
+	"name":     {},
+	"email":    {},
+	"age":      {},
+	"role":     {}, // 需要 roles:assign 权限
+	"password": {}, // 只有管理员可以为其他用户设置
+}
+
+// PatchUser 按 JSON Merge Patch（RFC 7396）或 JSON Patch（RFC 6902）部分更新用户（只能修改自己，管理员可以修改任何用户）
+// 补丁作用于用户的 JSON 表示，未提供的字段保持不变，null 清空字段，0 和空字符串按提供的值保存；
+// 应用后的结果按 models.User 的 binding 规则校验，不满足时不做任何修改
+func (s *userService) PatchUser(ctx context.Context, id int64, req *models.PatchUserRequest) (*models.User, error) {
+	if id <= 0 {
+		return nil, errors.NewBadRequestError("用户ID无效", fmt.Errorf("invalid user id: %d", id))
+	}
+
+	if err := policy.SelfOrAdmin(ctx, id); err != nil {
+		return nil, err
+	}
+
+	existingUser, err := s.findUserForUpdate(ctx, id, req.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// 补丁作用于接口返回的用户 JSON（不含密码）
+	current := *existingUser
+	current.Password = ""
+	doc, err := json.Marshal(&current)
+	if err != nil {
+		return nil, errors.NewInternalServerError("序列化用户失败", err)
+	}
+
+	patched, err := applyPatch(req.ContentType, doc, req.Patch)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := patchedUser(doc, patched)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = existingUser.ID
+	user.Version = existingUser.Version
+	user.CreatedAt = existingUser.CreatedAt
+
+	// 校验补丁应用后的结果；没有设置新密码时不校验密码字段
+	if user.Password != "" {
+		err = userValidator.Struct(user)
+	} else {
+		err = userValidator.StructExcept(user, "Password")
+	}
+	if err != nil {
+		return nil, errors.NewBadRequestError("补丁应用后的用户信息无效", err)
+	}
+
+	roleChanged := user.Role != existingUser.Role
+	if roleChanged {
+		if err := s.checkRoleAssignable(ctx, id, user.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	hashedPassword, err := s.hashPasswordForUser(ctx, id, user.Password)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+
+	updated, err := s.saveUser(ctx, existingUser, user, hashedPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// 角色变更后使该用户已签发的访问令牌失效，重新登录后按新角色授权
+	if roleChanged && s.revocationStore != nil {
+		if err := s.revocationStore.RevokeUser(ctx, id, time.Now().Add(accessTokenTTL())); err != nil {
+			return nil, errors.NewInternalServerError("撤销用户访问令牌失败", err)
+		}
+	}
+
+	return updated, nil
+}
+
+// applyPatch 按补丁格式将补丁应用到 JSON 文档
+func applyPatch(contentType string, doc, patch []byte) ([]byte, error) {
+	var patched []byte
+	var err error
+	switch contentType {
+	case jsonpatch.MergePatchType:
+		patched, err = jsonpatch.MergePatch(doc, patch)
+	case jsonpatch.JSONPatchType:
+		patched, err = jsonpatch.Apply(doc, patch)
+	default:
+		return nil, errors.NewUnsupportedMediaTypeError(
+			fmt.Sprintf("不支持的补丁格式，请使用 %s 或 %s", jsonpatch.MergePatchType, jsonpatch.JSONPatchType),
+			fmt.Errorf("unsupported content type: %q", contentType))
+	}
+	if err != nil {
+		if stderrors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, errors.NewConflictError("补丁的 test 操作未通过，用户可能已被修改", err)
+		}
+		return nil, errors.NewBadRequestError("补丁无效: "+err.Error(), err)
+	}
+	return patched, nil
+}
+
+// patchedUser 检查补丁只修改了允许修改的字段，并解析应用补丁后的用户
+func patchedUser(doc, patched []byte) (*models.User, error) {
+	var before, after map[string]interface{}
+	if err := decodeJSONObject(doc, &before); err != nil {
+		return nil, errors.NewInternalServerError("解析用户失败", err)
+	}
+	if err := decodeJSONObject(patched, &after); err != nil {
+		return nil, errors.NewBadRequestError("补丁应用后的结果必须是JSON对象", err)
+	}
+	if err := checkPatchedFields(before, after, patchableUserFields, ""); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := json.Unmarshal(patched, &user); err != nil {
+		return nil, errors.NewBadRequestError("补丁应用后的字段类型无效", err)
+	}
+	return &user, nil
+}
+
+// checkPatchedFields 检查补丁前后的 JSON 对象，只有 patchable 中的字段可以修改、新增或删除
+// prefix 为嵌套对象的路径前缀，用于错误信息
+func checkPatchedFields(before, after map[string]interface{}, patchable map[string]struct{}, prefix string) error {
+	for name, value := range after {
+		if _, ok := patchable[name]; ok {
+			continue
+		}
+		original, ok := before[name]
+		if !ok {
+			return errors.NewBadRequestError(fmt.Sprintf("未知字段: %s", prefix+name), fmt.Errorf("unknown field %q", prefix+name))
+		}
+		if !reflect.DeepEqual(original, value) {
+			return errors.NewBadRequestError(fmt.Sprintf("字段 %s 不能修改", prefix+name), fmt.Errorf("read-only field %q modified", prefix+name))
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			if _, ok := patchable[name]; !ok {
+				return errors.NewBadRequestError(fmt.Sprintf("字段 %s 不能修改", prefix+name), fmt.Errorf("read-only field %q removed", prefix+name))
+			}
+		}
+	}
+	return nil
+}
+
+// decodeJSONObject 将 JSON 对象解析为 map，数字保留为 json.Number 以便精确比较
+func decodeJSONObject(data []byte, v *map[string]interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if *v == nil {
+		return fmt.Errorf("not a JSON object")
+	}
+	return nil
+}
+
+// checkRoleAssignable 检查调用者能否为用户分配角色，以及角色是否存在
+func (s *userService) checkRoleAssignable(ctx context.Context, id int64, role auth.Role) error {
+	if err := policy.Authorize(ctx, id, policy.Admin, policy.Permission(auth.PermissionRolesAssign)); err != nil {
+		return err
+	}
+	if s.roleRepo == nil {
+		return errors.NewForbiddenError("不支持通过 PATCH 修改角色", fmt.Errorf("role repository not configured"))
+	}
+	if _, err := s.roleRepo.FindByName(ctx, role.String()); err != nil {
+		return errors.NewBadRequestError("角色不存在", err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"gin/internal/auth"
+	"gin/internal/jsonpatch"
+	"gin/internal/models"
+	"gin/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUserService_PatchUser 测试按 JSON Merge Patch / JSON Patch 部分更新用户
+func TestUserService_PatchUser(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1, Role: auth.RoleUser})
+	adminCtx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 99, Role: auth.RoleAdmin})
+
+	existingUser := func() *models.User {
+		return &models.User{ID: 1, Name: "张三", Email: "zhangsan@example.com", Age: 20, Role: auth.RoleUser, Version: 3}
+	}
+	mergePatch := func(patch string) *models.PatchUserRequest {
+		return &models.PatchUserRequest{ContentType: jsonpatch.MergePatchType, Patch: []byte(patch)}
+	}
+	jsonPatch := func(patch string) *models.PatchUserRequest {
+		return &models.PatchUserRequest{ContentType: jsonpatch.JSONPatchType, Patch: []byte(patch)}
+	}
+
+	t.Run("Merge Patch可以把年龄设置为0且不改变未提供的字段", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+		mockRepo.On("Update", ctx, int64(1), mock.MatchedBy(func(u *models.User) bool {
+			return u.Age == 0 && u.Name == "张三" && u.Email == "zhangsan@example.com" && u.Role == auth.RoleUser && u.Version == 3
+		})).Return(&models.User{ID: 1, Name: "张三", Age: 0, Version: 4}, nil)
+
+		user, err := service.PatchUser(ctx, 1, mergePatch(`{"age":0}`))
+		require.NoError(t, err)
+		assert.Equal(t, 0, user.Age)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("JSON Patch按顺序执行test和replace", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+		mockRepo.On("Update", ctx, int64(1), mock.MatchedBy(func(u *models.User) bool {
+			return u.Name == "李四" && u.Age == 20
+		})).Return(&models.User{ID: 1, Name: "李四", Version: 4}, nil)
+
+		_, err := service.PatchUser(ctx, 1, jsonPatch(`[{"op":"test","path":"/name","value":"张三"},{"op":"replace","path":"/name","value":"李四"}]`))
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("test操作未通过返回409", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+		_, err := service.PatchUser(ctx, 1, jsonPatch(`[{"op":"test","path":"/name","value":"王五"},{"op":"replace","path":"/name","value":"李四"}]`))
+		assertAppErrorCode(t, err, http.StatusConflict)
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("应用补丁后不满足校验规则返回400", func(t *testing.T) {
+		patches := []*models.PatchUserRequest{
+			mergePatch(`{"name":null}`), // null 清空必填字段
+			mergePatch(`{"email":"not-an-email"}`),
+			mergePatch(`{"age":200}`),
+			mergePatch(`{"age":"二十"}`),
+			jsonPatch(`[{"op":"remove","path":"/email"}]`),
+		}
+		for _, patch := range patches {
+			mockRepo := new(MockUserRepository)
+			service := NewUserService(mockRepo)
+			mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+			_, err := service.PatchUser(ctx, 1, patch)
+			assertAppErrorCode(t, err, http.StatusBadRequest)
+			mockRepo.AssertNotCalled(t, "Update")
+		}
+	})
+
+	t.Run("修改只读字段或未知字段返回400", func(t *testing.T) {
+		patches := []*models.PatchUserRequest{
+			mergePatch(`{"id":2}`),
+			mergePatch(`{"version":10}`),
+			mergePatch(`{"nickname":"小张"}`),
+			jsonPatch(`[{"op":"remove","path":"/created_at"}]`),
+			mergePatch(`"张三"`),
+			jsonPatch(`{"op":"replace"}`),
+		}
+		for _, patch := range patches {
+			mockRepo := new(MockUserRepository)
+			service := NewUserService(mockRepo)
+			mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+			_, err := service.PatchUser(ctx, 1, patch)
+			assertAppErrorCode(t, err, http.StatusBadRequest)
+			mockRepo.AssertNotCalled(t, "Update")
+		}
+	})
+
+	t.Run("不支持的补丁格式返回415", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+		_, err := service.PatchUser(ctx, 1, &models.PatchUserRequest{ContentType: "application/json", Patch: []byte(`{"age":0}`)})
+		assertAppErrorCode(t, err, http.StatusUnsupportedMediaType)
+	})
+
+	t.Run("If-Match版本与当前版本不一致返回412", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+		req := mergePatch(`{"age":0}`)
+		req.ExpectedVersion = 2
+		_, err := service.PatchUser(ctx, 1, req)
+		assertAppErrorCode(t, err, http.StatusPreconditionFailed)
+	})
+
+	t.Run("没有roles:assign权限不能修改角色", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, WithRoleRepository(new(MockRoleRepository)))
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+		_, err := service.PatchUser(ctx, 1, mergePatch(`{"role":"admin"}`))
+		assertAppErrorCode(t, err, http.StatusForbidden)
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("管理员可以修改角色并撤销访问令牌", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRoleRepo := new(MockRoleRepository)
+		revocationStore := repository.NewMemoryTokenRevocationStore()
+		service := NewUserService(mockRepo, WithRoleRepository(mockRoleRepo), WithTokenRevocationStore(revocationStore))
+
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+		mockRoleRepo.On("FindByName", adminCtx, "editor").Return(&models.Role{ID: 3, Name: "editor"}, nil)
+		mockRepo.On("Update", adminCtx, int64(1), mock.MatchedBy(func(u *models.User) bool {
+			return u.Role == "editor"
+		})).Return(&models.User{ID: 1, Role: "editor", Version: 4}, nil)
+
+		claims := &auth.UserClaims{UserID: 1}
+		claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+		user, err := service.PatchUser(adminCtx, 1, jsonPatch(`[{"op":"replace","path":"/role","value":"editor"}]`))
+		require.NoError(t, err)
+		assert.Equal(t, auth.Role("editor"), user.Role)
+
+		revoked, err := revocationStore.IsRevoked(adminCtx, claims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+		mockRepo.AssertExpectations(t)
+		mockRoleRepo.AssertExpectations(t)
+	})
+
+	t.Run("修改自己的密码返回400", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+		_, err := service.PatchUser(ctx, 1, mergePatch(`{"password":"NewPassw0rd!"}`))
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("修改自己的邮箱返回400", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+		_, err := service.PatchUser(ctx, 1, mergePatch(`{"email":"attacker@example.com"}`))
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		mockRepo.AssertNotCalled(t, "FindByEmail")
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("管理员可以修改他人的邮箱", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+		mockRepo.On("FindByEmail", adminCtx, "new@example.com").Return(nil, repository.ErrUserNotFound)
+		mockRepo.On("FindDeletedByEmail", adminCtx, "new@example.com").Return(nil, repository.ErrUserNotFound)
+		mockRepo.On("Update", adminCtx, int64(1), mock.MatchedBy(func(u *models.User) bool {
+			return u.Email == "new@example.com"
+		})).Return(&models.User{ID: 1, Email: "new@example.com", Version: 4}, nil)
+
+		user, err := service.PatchUser(adminCtx, 1, mergePatch(`{"email":"new@example.com"}`))
+		require.NoError(t, err)
+		assert.Equal(t, "new@example.com", user.Email)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"gin/internal/auth"
+	"gin/internal/database"
+	"gin/internal/errors"
+	"gin/internal/models"
+	"gin/internal/repository"
+)
+
+// patchableMeFields 可以通过 PATCH /api/v1/users/me 修改的字段
+// 角色需要由有权限的用户分配，密码需要通过修改密码接口验证当前密码后修改
+var patchableMeFields = map[string]struct{}{
+	"name":    {},
+	"email":   {},
+	"age":     {},
+	"profile": {}, // 只能修改 patchableProfileFields 中的字段
+}
+
+// patchableProfileFields 扩展资料中可以修改的字段，updated_at 只读
+var patchableProfileFields = map[string]struct{}{
+	"avatar_file_id": {},
+	"bio":            {},
+	"locale":         {},
+	"timezone":       {},
+	"preferences":    {},
+}
+
+// GetMe 获取当前用户及其扩展资料（没有保存过扩展资料时返回默认值）
+func (s *userService) GetMe(ctx context.Context, userID int64) (*models.Me, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("用户不存在", err)
+	}
+	profile, err := s.findProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	me := &models.Me{User: *user, Profile: profile}
+	me.Password = ""
+	return me, nil
+}
+
+// PatchMe 按 JSON Merge Patch（RFC 7396）或 JSON Patch（RFC 6902）部分更新当前用户及其扩展资料
+// 补丁作用于 GetMe 返回的 JSON 表示，可以修改 name、email、age 和 profile 中除 updated_at 以外的字段；
+// 邮箱可以用于重置密码，修改邮箱时需要验证当前密码，避免令牌泄露后被用来接管账户；
+// 用户和扩展资料在同一个事务中保存，只修改扩展资料时用户的版本号同样加一，ETag 随之变化
+func (s *userService) PatchMe(ctx context.Context, userID int64, req *models.PatchMeRequest) (*models.Me, error) {
+	existingUser, err := s.findUserForUpdate(ctx, userID, req.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	existingProfile, err := s.findProfile(database.WithPrimary(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := models.Me{User: *existingUser, Profile: existingProfile}
+	current.Password = ""
+	doc, err := json.Marshal(&current)
+	if err != nil {
+		return nil, errors.NewInternalServerError("序列化用户失败", err)
+	}
+
+	patched, err := applyPatch(req.ContentType, doc, req.Patch)
+	if err != nil {
+		return nil, err
+	}
+	me, err := patchedMe(doc, patched)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &me.User
+	user.ID = existingUser.ID
+	user.Version = existingUser.Version
+	user.CreatedAt = existingUser.CreatedAt
+	if err := userValidator.StructExcept(user, "Password"); err != nil {
+		return nil, errors.NewBadRequestError("补丁应用后的用户信息无效", err)
+	}
+	if user.Email != existingUser.Email && !auth.CheckPassword(existingUser.Password, req.CurrentPassword) {
+		return nil, errors.NewBadRequestError("修改邮箱需要验证当前密码，当前密码错误", fmt.Errorf("current password mismatch"))
+	}
+
+	profile := me.Profile
+	profile.UserID = userID
+	if err := userValidator.Struct(profile); err != nil {
+		return nil, errors.NewBadRequestError("补丁应用后的用户资料无效", err)
+	}
+	if profile.Preferences, err = normalizePreferences(profile.Preferences); err != nil {
+		return nil, err
+	}
+	if profile.AvatarFileID != nil && !equalInt64Ptr(profile.AvatarFileID, existingProfile.AvatarFileID) {
+		if err := s.checkAvatar(ctx, userID, *profile.AvatarFileID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 用户和扩展资料在同一个事务中保存
+	var updated *models.User
+	var savedProfile *models.UserProfile
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if updated, err = s.updateUser(ctx, existingUser, user, ""); err != nil {
+			return err
+		}
+		if savedProfile, err = s.profileRepo.Save(ctx, profile); err != nil {
+			return errors.NewInternalServerError("保存用户资料失败", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := auditFieldChanges(existingUser, updated)
+	for name, change := range auditFieldChanges(existingProfile, savedProfile) {
+		changes["profile."+name] = change
+	}
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserUpdate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(userID),
+		Changes:    encodeAuditChanges(changes),
+	})
+
+	result := &models.Me{User: *updated, Profile: savedProfile}
+	result.Password = ""
+	return result, nil
+}
+
+// DeleteMe 验证当前密码后注销当前用户（软删除，保留期内管理员可以恢复）
+func (s *userService) DeleteMe(ctx context.Context, userID int64, req *models.DeleteMeRequest) error {
+	// 校验密码必须读取主库，避免从库延迟导致旧密码仍然有效
+	user, err := s.userRepo.FindByID(database.WithPrimary(ctx), userID)
+	if err != nil {
+		return errors.NewNotFoundError("用户不存在", err)
+	}
+	if !auth.CheckPassword(user.Password, req.Password) {
+		return errors.NewBadRequestError("当前密码错误", fmt.Errorf("current password mismatch"))
+	}
+
+	return s.deleteUser(ctx, user)
+}
+
+// findProfile 查询用户的扩展资料，没有保存过时返回默认值
+func (s *userService) findProfile(ctx context.Context, userID int64) (*models.UserProfile, error) {
+	profile, err := s.profileRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrProfileNotFound) {
+			return &models.UserProfile{UserID: userID, Preferences: json.RawMessage("{}")}, nil
+		}
+		return nil, errors.NewInternalServerError("查询用户资料失败", err)
+	}
+	return profile, nil
+}
+
+// checkAvatar 检查头像文件存在、属于该用户且是图片
+func (s *userService) checkAvatar(ctx context.Context, userID, fileID int64) error {
+	if s.fileRepo == nil {
+		return errors.NewBadRequestError("不支持设置头像", fmt.Errorf("file repository not configured"))
+	}
+	file, err := s.fileRepo.FindByID(ctx, fileID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrFileNotFound) {
+			return errors.NewBadRequestError("头像文件不存在", err)
+		}
+		return errors.NewInternalServerError("查询头像文件失败", err)
+	}
+	// 不区分不存在和不属于自己，避免探测其他用户的文件
+	if file.OwnerID != userID {
+		return errors.NewBadRequestError("头像文件不存在", fmt.Errorf("file %d is owned by user %d", fileID, file.OwnerID))
+	}
+	if !strings.HasPrefix(file.ContentType, "image/") {
+		return errors.NewBadRequestError("头像必须是图片", fmt.Errorf("avatar content type %q", file.ContentType))
+	}
+	return nil
+}
+
+// patchedMe 检查补丁只修改了允许修改的字段，并解析应用补丁后的用户和扩展资料
+func patchedMe(doc, patched []byte) (*models.Me, error) {
+	var before, after map[string]interface{}
+	if err := decodeJSONObject(doc, &before); err != nil {
+		return nil, errors.NewInternalServerError("解析用户失败", err)
+	}
+	if err := decodeJSONObject(patched, &after); err != nil {
+		return nil, errors.NewBadRequestError("补丁应用后的结果必须是JSON对象", err)
+	}
+	if err := checkPatchedFields(before, after, patchableMeFields, ""); err != nil {
+		return nil, err
+	}
+
+	beforeProfile, _ := before["profile"].(map[string]interface{})
+	afterProfile, ok := after["profile"].(map[string]interface{})
+	if !ok {
+		return nil, errors.NewBadRequestError("profile 必须是JSON对象", fmt.Errorf("profile is not an object"))
+	}
+	if err := checkPatchedFields(beforeProfile, afterProfile, patchableProfileFields, "profile."); err != nil {
+		return nil, err
+	}
+
+	var me models.Me
+	if err := json.Unmarshal(patched, &me); err != nil {
+		return nil, errors.NewBadRequestError("补丁应用后的字段类型无效", err)
+	}
+	return &me, nil
+}
+
+// normalizePreferences 检查偏好设置是 JSON 对象且不超过大小限制，null 或未提供时重置为空对象
+func normalizePreferences(preferences json.RawMessage) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(preferences)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return json.RawMessage("{}"), nil
+	}
+	if trimmed[0] != '{' {
+		return nil, errors.NewBadRequestError("preferences 必须是JSON对象", fmt.Errorf("preferences is not an object"))
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, trimmed); err != nil {
+		return nil, errors.NewBadRequestError("preferences 不是有效的JSON", err)
+	}
+	if compacted.Len() > models.MaxPreferencesSize {
+		return nil, errors.NewBadRequestError(
+			fmt.Sprintf("preferences 不能超过 %d 字节", models.MaxPreferencesSize),
+			fmt.Errorf("preferences size %d exceeds %d", compacted.Len(), models.MaxPreferencesSize))
+	}
+	return compacted.Bytes(), nil
+}
+
+// equalInt64Ptr 比较两个可能为空的 int64
+func equalInt64Ptr(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
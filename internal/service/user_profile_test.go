@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gin/internal/auth"
+	"gin/internal/jsonpatch"
+	"gin/internal/models"
+	"gin/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUserService_Me 测试当前用户的查询、部分更新和注销
+func TestUserService_Me(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1, Role: auth.RoleUser})
+
+	existingUser := func() *models.User {
+		return &models.User{ID: 1, Name: "张三", Email: "zhangsan@example.com", Password: "hashed", Age: 20, Role: auth.RoleUser, Version: 3}
+	}
+	mergePatch := func(patch string) *models.PatchMeRequest {
+		return &models.PatchMeRequest{ContentType: jsonpatch.MergePatchType, Patch: []byte(patch)}
+	}
+	jsonPatch := func(patch string) *models.PatchMeRequest {
+		return &models.PatchMeRequest{ContentType: jsonpatch.JSONPatchType, Patch: []byte(patch)}
+	}
+
+	t.Run("没有保存过资料时返回默认值且不返回密码", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+		me, err := service.GetMe(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "张三", me.Name)
+		assert.Empty(t, me.Password)
+		require.NotNil(t, me.Profile)
+		assert.Nil(t, me.Profile.AvatarFileID)
+		assert.JSONEq(t, `{}`, string(me.Profile.Preferences))
+	})
+
+	t.Run("只修改资料时同样保存用户使版本号加一", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		profileRepo := repository.NewMemoryUserProfileRepository()
+		service := NewUserService(mockRepo, WithUserProfileRepository(profileRepo))
+
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+		mockRepo.On("Update", ctx, int64(1), mock.MatchedBy(func(u *models.User) bool {
+			return u.Name == "张三" && u.Role == auth.RoleUser && u.Version == 3
+		})).Return(&models.User{ID: 1, Name: "张三", Password: "hashed", Version: 4}, nil)
+
+		me, err := service.PatchMe(ctx, 1, mergePatch(`{"profile":{"bio":"你好","locale":"zh-CN","timezone":"Asia/Shanghai","preferences":{"theme":"dark","ui":{"compact":true}}}}`))
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), me.Version)
+		assert.Empty(t, me.Password)
+		assert.Equal(t, "你好", me.Profile.Bio)
+		assert.Equal(t, "zh-CN", me.Profile.Locale)
+		assert.Equal(t, "Asia/Shanghai", me.Profile.Timezone)
+		assert.NotNil(t, me.Profile.UpdatedAt)
+
+		saved, err := profileRepo.FindByUserID(ctx, 1)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"theme":"dark","ui":{"compact":true}}`, string(saved.Preferences))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Merge Patch递归合并偏好设置，null删除其中的键", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		profileRepo := repository.NewMemoryUserProfileRepository()
+		_, err := profileRepo.Save(ctx, &models.UserProfile{UserID: 1, Bio: "旧简介", Preferences: json.RawMessage(`{"theme":"dark","lang":"zh"}`)})
+		require.NoError(t, err)
+		service := NewUserService(mockRepo, WithUserProfileRepository(profileRepo))
+
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+		mockRepo.On("Update", ctx, int64(1), mock.Anything).Return(&models.User{ID: 1, Version: 4}, nil)
+
+		me, err := service.PatchMe(ctx, 1, mergePatch(`{"profile":{"preferences":{"lang":null,"font":14}}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "旧简介", me.Profile.Bio)
+		assert.JSONEq(t, `{"theme":"dark","font":14}`, string(me.Profile.Preferences))
+	})
+
+	t.Run("JSON Patch同时修改用户和资料", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+		mockRepo.On("Update", ctx, int64(1), mock.MatchedBy(func(u *models.User) bool {
+			return u.Name == "李四" && u.Age == 20
+		})).Return(&models.User{ID: 1, Name: "李四", Version: 4}, nil)
+
+		me, err := service.PatchMe(ctx, 1, jsonPatch(`[{"op":"replace","path":"/name","value":"李四"},{"op":"replace","path":"/profile/bio","value":"简介"}]`))
+		require.NoError(t, err)
+		assert.Equal(t, "李四", me.Name)
+		assert.Equal(t, "简介", me.Profile.Bio)
+	})
+
+	t.Run("修改邮箱需要验证当前密码", func(t *testing.T) {
+		for _, password := range []string{"", "wrong"} {
+			mockRepo := new(MockUserRepository)
+			service := NewUserService(mockRepo)
+			mockRepo.On("FindByID", mock.Anything, int64(1)).Return(newLoginTestUser(t), nil)
+
+			req := mergePatch(`{"email":"attacker@example.com"}`)
+			req.CurrentPassword = password
+			_, err := service.PatchMe(ctx, 1, req)
+			assertAppErrorCode(t, err, http.StatusBadRequest)
+			mockRepo.AssertNotCalled(t, "Update")
+		}
+
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(newLoginTestUser(t), nil)
+		mockRepo.On("FindByEmail", ctx, "new@example.com").Return(nil, fmt.Errorf("user not found"))
+		mockRepo.On("FindDeletedByEmail", ctx, "new@example.com").Return(nil, fmt.Errorf("user not found"))
+		mockRepo.On("Update", ctx, int64(1), mock.MatchedBy(func(u *models.User) bool {
+			return u.Email == "new@example.com"
+		})).Return(&models.User{ID: 1, Email: "new@example.com", Version: 2}, nil)
+
+		req := mergePatch(`{"email":"new@example.com"}`)
+		req.CurrentPassword = "123456"
+		me, err := service.PatchMe(ctx, 1, req)
+		require.NoError(t, err)
+		assert.Equal(t, "new@example.com", me.Email)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("If-Match与当前版本不一致返回412", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+		req := mergePatch(`{"profile":{"bio":"你好"}}`)
+		req.ExpectedVersion = 2
+		_, err := service.PatchMe(ctx, 1, req)
+		assertAppErrorCode(t, err, http.StatusPreconditionFailed)
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("只读字段和无效资料返回400", func(t *testing.T) {
+		patches := []*models.PatchMeRequest{
+			mergePatch(`{"role":"admin"}`),           // 角色只能由有权限的用户分配
+			mergePatch(`{"password":"newpassword"}`), // 密码需要通过修改密码接口修改
+			mergePatch(`{"profile":null}`),           // 不能删除资料
+			mergePatch(`{"profile":{"updated_at":"2020-01-01T00:00:00Z"}}`),
+			mergePatch(`{"profile":{"nickname":"小张"}}`),
+			mergePatch(`{"profile":{"locale":"not a locale"}}`),
+			mergePatch(`{"profile":{"timezone":"Mars/Olympus"}}`),
+			mergePatch(`{"profile":{"bio":"` + strings.Repeat("长", 501) + `"}}`),
+			mergePatch(`{"profile":{"bio":1}}`),
+			mergePatch(`{"profile":{"preferences":["dark"]}}`),
+			mergePatch(`{"profile":{"preferences":{"blob":"` + strings.Repeat("x", models.MaxPreferencesSize) + `"}}}`),
+			jsonPatch(`[{"op":"remove","path":"/profile/updated_at"}]`),
+		}
+		for _, patch := range patches {
+			mockRepo := new(MockUserRepository)
+			service := NewUserService(mockRepo)
+			mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+			_, err := service.PatchMe(ctx, 1, patch)
+			assertAppErrorCode(t, err, http.StatusBadRequest)
+			mockRepo.AssertNotCalled(t, "Update")
+		}
+	})
+
+	t.Run("头像必须是本人上传的图片", func(t *testing.T) {
+		fileRepo := new(MockFileRepository)
+		fileRepo.On("FindByID", mock.Anything, int64(10)).Return(&models.File{ID: 10, ContentType: "image/png", OwnerID: 1}, nil)
+		fileRepo.On("FindByID", mock.Anything, int64(11)).Return(&models.File{ID: 11, ContentType: "image/png", OwnerID: 2}, nil)
+		fileRepo.On("FindByID", mock.Anything, int64(12)).Return(&models.File{ID: 12, ContentType: "application/pdf", OwnerID: 1}, nil)
+		fileRepo.On("FindByID", mock.Anything, int64(13)).Return(nil, repository.ErrFileNotFound)
+
+		for _, id := range []string{"11", "12", "13"} {
+			mockRepo := new(MockUserRepository)
+			service := NewUserService(mockRepo, WithFileRepository(fileRepo))
+			mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+			_, err := service.PatchMe(ctx, 1, mergePatch(`{"profile":{"avatar_file_id":`+id+`}}`))
+			assertAppErrorCode(t, err, http.StatusBadRequest)
+			mockRepo.AssertNotCalled(t, "Update")
+		}
+
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, WithFileRepository(fileRepo))
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+		mockRepo.On("Update", ctx, int64(1), mock.Anything).Return(&models.User{ID: 1, Version: 4}, nil)
+
+		me, err := service.PatchMe(ctx, 1, mergePatch(`{"profile":{"avatar_file_id":10}}`))
+		require.NoError(t, err)
+		require.NotNil(t, me.Profile.AvatarFileID)
+		assert.Equal(t, int64(10), *me.Profile.AvatarFileID)
+	})
+
+	t.Run("未配置文件仓库时不能设置头像", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser(), nil)
+
+		_, err := service.PatchMe(ctx, 1, mergePatch(`{"profile":{"avatar_file_id":10}}`))
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("注销需要验证当前密码", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(newLoginTestUser(t), nil)
+		mockRepo.On("Delete", ctx, int64(1)).Return(nil)
+
+		err := service.DeleteMe(ctx, 1, &models.DeleteMeRequest{Password: "wrong"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		mockRepo.AssertNotCalled(t, "Delete")
+
+		require.NoError(t, service.DeleteMe(ctx, 1, &models.DeleteMeRequest{Password: "123456"}))
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -2,14 +2,26 @@ package service
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"io"
 
 	"gin/internal/auth"
 	"gin/internal/config"
+	"gin/internal/database"
 	"gin/internal/errors"
+	"gin/internal/i18n"
+	"gin/internal/logger"
 	"gin/internal/models"
+	"gin/internal/notify"
+	"gin/internal/policy"
 	"gin/internal/repository"
+	"gin/internal/requestctx"
+	"gin/internal/storage"
 	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 // UserService 用户服务接口
@@ -17,58 +29,276 @@ type UserService interface {
 	CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error)
 	GetUserByID(ctx context.Context, id int64) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
-	GetAllUsers(ctx context.Context) ([]*models.User, error)
+	// GetAllUsers 按过滤条件分页查询用户
+	GetAllUsers(ctx context.Context, req *models.ListUsersRequest) (*models.Page[*models.User], error)
 	UpdateUser(ctx context.Context, id int64, req *models.UpdateUserRequest) (*models.User, error)
+	// PatchUser 按 JSON Merge Patch 或 JSON Patch 部分更新用户
+	PatchUser(ctx context.Context, id int64, req *models.PatchUserRequest) (*models.User, error)
+	// DeleteUser 软删除用户，保留期内管理员可以恢复
 	DeleteUser(ctx context.Context, id int64) error
-	Login(ctx context.Context, req *models.LoginRequest) (*models.LoginResponse, error)
+	RestoreUser(ctx context.Context, id int64) (*models.User, error)
+	// PurgeUser 彻底删除已软删除的用户
+	PurgeUser(ctx context.Context, id int64) error
+	// PurgeDeletedUsers 彻底删除在 before 之前软删除的用户，返回删除数量
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
+	// ImportUsers 从 CSV 或 NDJSON 批量导入用户，返回每一行的结果
+	ImportUsers(ctx context.Context, r io.Reader, opts models.ImportUsersOptions) (*models.ImportUsersResult, error)
+	// ExportUsers 按过滤条件以 CSV 或 NDJSON 格式导出用户，逐页写入 w
+	ExportUsers(ctx context.Context, w io.Writer, format string, filter models.UserFilter) error
+	// Login 校验邮箱和密码；账户启用了两步验证时返回两步验证令牌而不是访问令牌
+	Login(ctx context.Context, req *models.LoginRequest) (*models.LoginResponse, *models.MFAChallengeResponse, error)
+	VerifyMFA(ctx context.Context, req *models.MFAVerifyRequest) (*models.LoginResponse, error)
 	RefreshToken(ctx context.Context, req *models.RefreshTokenRequest) (*models.RefreshTokenResponse, error)
+	Logout(ctx context.Context, req *models.LogoutRequest) error
+	ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, userID int64, sessionID string) error
+	ForceLogout(ctx context.Context, userID int64) error
+	ChangePassword(ctx context.Context, userID int64, req *models.ChangePasswordRequest) error
+	ForgotPassword(ctx context.Context, req *models.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error
+	UnlockUser(ctx context.Context, userID int64) error
+	GetMFAStatus(ctx context.Context, userID int64) (*models.MFAStatusResponse, error)
+	SetupMFA(ctx context.Context, userID int64) (*models.MFASetupResponse, error)
+	EnableMFA(ctx context.Context, userID int64, req *models.MFACodeRequest) (*models.MFARecoveryCodesResponse, error)
+	DisableMFA(ctx context.Context, userID int64, req *models.MFADisableRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, req *models.MFACodeRequest) (*models.MFARecoveryCodesResponse, error)
+	// GetMe 获取当前用户及其扩展资料
+	GetMe(ctx context.Context, userID int64) (*models.Me, error)
+	// PatchMe 按 JSON Merge Patch 或 JSON Patch 部分更新当前用户及其扩展资料
+	PatchMe(ctx context.Context, userID int64, req *models.PatchMeRequest) (*models.Me, error)
+	// DeleteMe 验证当前密码后注销当前用户
+	DeleteMe(ctx context.Context, userID int64, req *models.DeleteMeRequest) error
 }
 
 // userService 用户服务实现
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo         repository.UserRepository
+	txManager        database.TxManager
+	refreshTokenRepo repository.RefreshTokenRepository
+	revocationStore  repository.TokenRevocationStore
+	jwtConfig        *auth.JWTConfig
+	passwordPolicy   *auth.PasswordPolicy
+	resetTokenRepo   repository.PasswordResetTokenRepository
+	notifier         notify.Notifier
+	loginGuard       *loginGuard
+	mfaRepo          repository.MFARepository
+	mfaChallengeRepo repository.MFAChallengeRepository
+	mfaConfig        config.MFAConfig
+	roleRepo         repository.RoleRepository
+	profileRepo      repository.UserProfileRepository
+	fileRepo         repository.FileRepository
+	fileStorage      storage.Storage
+	audit            AuditRecorder
+}
+
+// UserServiceOption 用户服务可选配置
+type UserServiceOption func(*userService)
+
+// WithTxManager 指定事务管理器（默认不开启事务）
+// 需要与仓库使用同一个数据库，多步操作才能在同一个事务中执行
+func WithTxManager(txManager database.TxManager) UserServiceOption {
+	return func(s *userService) {
+		s.txManager = txManager
+	}
+}
+
+// WithRefreshTokenRepository 指定刷新令牌仓库（默认使用内存仓库）
+func WithRefreshTokenRepository(repo repository.RefreshTokenRepository) UserServiceOption {
+	return func(s *userService) {
+		s.refreshTokenRepo = repo
+	}
+}
+
+// WithTokenRevocationStore 指定访问令牌撤销存储（默认使用内存存储）
+// 需要与 AuthMiddleware 使用同一个存储，撤销才能生效
+func WithTokenRevocationStore(store repository.TokenRevocationStore) UserServiceOption {
+	return func(s *userService) {
+		s.revocationStore = store
+	}
+}
+
+// WithJWTConfig 指定签发访问令牌使用的JWT配置（默认使用配置文件中的 secret_key 进行 HS256 签名）
+// 需要与 AuthMiddleware 使用同一个配置，签发的令牌才能通过验签
+func WithJWTConfig(jwtConfig *auth.JWTConfig) UserServiceOption {
+	return func(s *userService) {
+		s.jwtConfig = jwtConfig
+	}
+}
+
+// WithPasswordPolicy 指定密码策略（默认使用 auth.DefaultPasswordPolicy）
+func WithPasswordPolicy(passwordPolicy *auth.PasswordPolicy) UserServiceOption {
+	return func(s *userService) {
+		s.passwordPolicy = passwordPolicy
+	}
+}
+
+// WithPasswordResetTokenRepository 指定重置密码令牌仓库（默认使用内存仓库）
+func WithPasswordResetTokenRepository(repo repository.PasswordResetTokenRepository) UserServiceOption {
+	return func(s *userService) {
+		s.resetTokenRepo = repo
+	}
+}
+
+// WithNotifier 指定发送重置密码链接等通知的方式（默认写入应用日志）
+func WithNotifier(notifier notify.Notifier) UserServiceOption {
+	return func(s *userService) {
+		s.notifier = notifier
+	}
+}
+
+// WithLoginAttemptStore 指定登录失败计数存储（默认使用内存存储，多实例部署时应使用数据库存储）
+func WithLoginAttemptStore(store repository.LoginAttemptStore) UserServiceOption {
+	return func(s *userService) {
+		s.loginGuard.store = store
+	}
+}
+
+// WithLoginConfig 指定登录防暴力破解配置（默认使用配置文件中的 login）
+func WithLoginConfig(cfg config.LoginConfig) UserServiceOption {
+	return func(s *userService) {
+		s.loginGuard.cfg = cfg
+	}
+}
+
+// WithMFARepository 指定两步验证仓库（默认使用内存仓库）
+func WithMFARepository(repo repository.MFARepository) UserServiceOption {
+	return func(s *userService) {
+		s.mfaRepo = repo
+	}
+}
+
+// WithMFAChallengeRepository 指定登录两步验证令牌仓库（默认使用内存仓库）
+func WithMFAChallengeRepository(repo repository.MFAChallengeRepository) UserServiceOption {
+	return func(s *userService) {
+		s.mfaChallengeRepo = repo
+	}
+}
+
+// WithMFAConfig 指定两步验证配置（默认使用配置文件中的 mfa）
+func WithMFAConfig(cfg config.MFAConfig) UserServiceOption {
+	return func(s *userService) {
+		s.mfaConfig = cfg
+	}
+}
+
+// WithRoleRepository 指定角色仓库，PATCH 修改用户角色时用于检查角色是否存在（未指定时不能通过 PATCH 修改角色）
+func WithRoleRepository(repo repository.RoleRepository) UserServiceOption {
+	return func(s *userService) {
+		s.roleRepo = repo
+	}
+}
+
+// WithUserProfileRepository 指定用户扩展资料仓库（默认使用内存仓库）
+func WithUserProfileRepository(repo repository.UserProfileRepository) UserServiceOption {
+	return func(s *userService) {
+		s.profileRepo = repo
+	}
+}
+
+// WithFileRepository 指定文件元数据仓库，设置头像时用于检查文件（未指定时不能设置头像），
+// 彻底删除用户时同时删除其上传的文件信息
+func WithFileRepository(repo repository.FileRepository) UserServiceOption {
+	return func(s *userService) {
+		s.fileRepo = repo
+	}
+}
+
+// WithFileStorage 指定文件存储，彻底删除用户时删除其上传的文件内容（未指定时只删除文件信息）
+func WithFileStorage(store storage.Storage) UserServiceOption {
+	return func(s *userService) {
+		s.fileStorage = store
+	}
+}
+
+// WithAuditRecorder 指定审计日志记录器（默认不记录）
+func WithAuditRecorder(audit AuditRecorder) UserServiceOption {
+	return func(s *userService) {
+		s.audit = audit
+	}
 }
 
 // NewUserService 创建用户服务
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{
-		userRepo: userRepo,
+func NewUserService(userRepo repository.UserRepository, opts ...UserServiceOption) UserService {
+	s := &userService{
+		userRepo:         userRepo,
+		txManager:        database.NewNoopTxManager(),
+		refreshTokenRepo: repository.NewMemoryRefreshTokenRepository(),
+		revocationStore:  repository.NewMemoryTokenRevocationStore(),
+		passwordPolicy:   auth.DefaultPasswordPolicy(),
+		resetTokenRepo:   repository.NewMemoryPasswordResetTokenRepository(),
+		notifier:         notify.NewLogNotifier(),
+		loginGuard:       newLoginGuard(repository.NewMemoryLoginAttemptStore(), config.GetConfig().Login),
+		mfaRepo:          repository.NewMemoryMFARepository(),
+		mfaChallengeRepo: repository.NewMemoryMFAChallengeRepository(),
+		mfaConfig:        config.GetConfig().MFA,
+		profileRepo:      repository.NewMemoryUserProfileRepository(),
+		audit:            noopAuditRecorder{},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // CreateUser 创建用户
 func (s *userService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error) {
-	// 业务逻辑：检查邮箱是否已存在
-	_, err := s.userRepo.FindByEmail(ctx, req.Email)
-	if err == nil {
-		// 邮箱已存在
-		return nil, errors.NewBadRequestError("邮箱已被使用", fmt.Errorf("email already exists: %s", req.Email))
-	}
+	// 检查邮箱与创建用户在同一个事务中执行
+	var created *models.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// 业务逻辑：检查邮箱是否已存在
+		if err := s.ensureEmailAvailable(ctx, req.Email); err != nil {
+			return err
+		}
 
-	// 加密密码
-	hashedPassword, err := auth.HashPassword(req.Password)
-	if err != nil {
-		return nil, errors.NewInternalServerError("密码加密失败", err)
-	}
+		// 校验密码策略并加密密码
+		hashedPassword, err := s.hashNewPassword(req.Password)
+		if err != nil {
+			return err
+		}
 
-	// 创建用户（默认为普通用户）
-	user := &models.User{
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: hashedPassword,
-		Age:      req.Age,
-		Role:     auth.RoleUser, // 默认角色为普通用户
+		// 创建用户（默认为普通用户）
+		user := &models.User{
+			Name:     req.Name,
+			Email:    req.Email,
+			Password: hashedPassword,
+			Age:      req.Age,
+			Role:     auth.RoleUser, // 默认角色为普通用户
+		}
+
+		created, err = s.userRepo.Create(ctx, user)
+		if err != nil {
+			// 并发注册同一邮箱时由唯一约束兜底
+			if stderrors.Is(err, repository.ErrEmailExists) {
+				return errors.NewBadRequestError("邮箱已被使用", err)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return s.userRepo.Create(ctx, user)
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserCreate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(created.ID),
+		Changes:    encodeAuditChanges(auditFieldChanges(nil, created)),
+	})
+	return created, nil
 }
 
-// GetUserByID 根据ID获取用户
+// GetUserByID 根据ID获取用户（只能查看自己，管理员可以查看任何用户）
 func (s *userService) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
 	if id <= 0 {
 		return nil, errors.NewBadRequestError("用户ID无效", fmt.Errorf("invalid user id: %d", id))
 	}
 
+	// 先鉴权再查询，避免通过 403/404 的差异探测用户是否存在
+	if err := policy.SelfOrAdmin(ctx, id); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, errors.NewNotFoundError("用户不存在", err)
@@ -91,26 +321,43 @@ func (s *userService) GetUserByEmail(ctx context.Context, email string) (*models
 	return user, nil
 }
 
-// GetAllUsers 获取所有用户
-func (s *userService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
-	users, err := s.userRepo.FindAll(ctx)
+// GetAllUsers 分页查询用户（查询已删除的用户需要 users:restore 权限）
+func (s *userService) GetAllUsers(ctx context.Context, req *models.ListUsersRequest) (*models.Page[*models.User], error) {
+	if req.Deleted {
+		if err := policy.Authorize(ctx, 0, policy.Permission(auth.PermissionUsersRestore)); err != nil {
+			return nil, err
+		}
+	}
+
+	page, err := s.userRepo.List(ctx, req.UserFilter, req.PageQuery)
 	if err != nil {
+		if stderrors.Is(err, repository.ErrInvalidSort) || stderrors.Is(err, repository.ErrInvalidCursor) {
+			return nil, errors.NewBadRequestError(err.Error(), err)
+		}
 		return nil, errors.NewInternalServerError("获取用户列表失败", err)
 	}
 
-	return users, nil
+	return page, nil
 }
 
-// UpdateUser 更新用户
+// UpdateUser 更新用户（只能修改自己，管理员可以修改任何用户）
 func (s *userService) UpdateUser(ctx context.Context, id int64, req *models.UpdateUserRequest) (*models.User, error) {
 	if id <= 0 {
 		return nil, errors.NewBadRequestError("用户ID无效", fmt.Errorf("invalid user id: %d", id))
 	}
 
-	// 先获取现有用户
-	existingUser, err := s.userRepo.FindByID(ctx, id)
+	if err := policy.SelfOrAdmin(ctx, id); err != nil {
+		return nil, err
+	}
+
+	existingUser, err := s.findUserForUpdate(ctx, id, req.ExpectedVersion)
 	if err != nil {
-		return nil, errors.NewNotFoundError("用户不存在", err)
+		return nil, err
+	}
+
+	hashedPassword, err := s.hashPasswordForUser(ctx, id, req.Password)
+	if err != nil {
+		return nil, err
 	}
 
 	// 更新字段（只更新提供的字段）
@@ -119,6 +366,8 @@ func (s *userService) UpdateUser(ctx context.Context, id int64, req *models.Upda
 		Name:      req.Name,
 		Email:     req.Email,
 		Age:       req.Age,
+		Role:      existingUser.Role, // 角色只能通过 AssignRole 修改
+		Version:   existingUser.Version,
 		CreatedAt: existingUser.CreatedAt,
 	}
 
@@ -133,101 +382,609 @@ func (s *userService) UpdateUser(ctx context.Context, id int64, req *models.Upda
 		user.Age = existingUser.Age
 	}
 
+	return s.saveUser(ctx, existingUser, user, hashedPassword)
+}
+
+// findUserForUpdate 读取待更新的用户并校验客户端读取时的版本号（expectedVersion 为 0 时不校验）
+func (s *userService) findUserForUpdate(ctx context.Context, id int64, expectedVersion int64) (*models.User, error) {
+	// 读主库，只读副本上的版本号可能已过期
+	existingUser, err := s.userRepo.FindByID(database.WithPrimary(ctx), id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("用户不存在", err)
+	}
+	if expectedVersion != 0 && expectedVersion != existingUser.Version {
+		return nil, errors.NewPreconditionFailedError("用户已被修改，请重新获取后再更新",
+			fmt.Errorf("version mismatch: expected %d, current %d", expectedVersion, existingUser.Version))
+	}
+	return existingUser, nil
+}
+
+// hashPasswordForUser 校验并加密为用户设置的新密码，password 为空时返回空字符串
+// 修改自己的密码必须验证当前密码（/api/v1/auth/password/change），这里只允许管理员为其他用户设置新密码
+func (s *userService) hashPasswordForUser(ctx context.Context, id int64, password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if caller, _ := auth.PrincipalFromContext(ctx); caller.UserID == id {
+		return "", errors.NewBadRequestError("修改自己的密码请使用修改密码接口", fmt.Errorf("password change requires current password"))
+	}
+	return s.hashNewPassword(password)
+}
+
+// checkEmailChangeForUser 检查是否可以通过 /api/v1/users/:id 修改邮箱
+// 修改自己的邮箱必须验证当前密码（PATCH /api/v1/users/me），这里只允许管理员修改其他用户的邮箱
+func checkEmailChangeForUser(ctx context.Context, existingUser, user *models.User) error {
+	if user.Email == existingUser.Email {
+		return nil
+	}
+	if caller, _ := auth.PrincipalFromContext(ctx); caller.UserID == existingUser.ID {
+		return errors.NewBadRequestError("修改自己的邮箱请使用 /api/v1/users/me 接口", fmt.Errorf("email change requires current password"))
+	}
+	return nil
+}
+
+// saveUser 保存更新后的用户资料，hashedPassword 不为空时同时重置密码并撤销该用户的所有会话
+func (s *userService) saveUser(ctx context.Context, existingUser, user *models.User, hashedPassword string) (*models.User, error) {
+	id := existingUser.ID
+	if err := checkEmailChangeForUser(ctx, existingUser, user); err != nil {
+		return nil, err
+	}
+
+	// 检查邮箱、更新资料和密码在同一个事务中执行
+	var updated *models.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		updated, err = s.updateUser(ctx, existingUser, user, hashedPassword)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := auditFieldChanges(existingUser, updated)
+	if hashedPassword != "" {
+		changes["password"] = redactedChange()
+	}
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserUpdate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(id),
+		Changes:    encodeAuditChanges(changes),
+	})
+	return updated, nil
+}
+
+// updateUser 检查邮箱并更新用户资料，需要在事务中调用
+func (s *userService) updateUser(ctx context.Context, existingUser, user *models.User, hashedPassword string) (*models.User, error) {
+	id := existingUser.ID
+
 	// 如果邮箱有变化，检查新邮箱是否已被使用
 	if user.Email != existingUser.Email {
-		_, err := s.userRepo.FindByEmail(ctx, user.Email)
-		if err == nil {
-			return nil, errors.NewBadRequestError("邮箱已被使用", fmt.Errorf("email already exists: %s", user.Email))
+		if err := s.ensureEmailAvailable(ctx, user.Email); err != nil {
+			return nil, err
 		}
 	}
 
-	return s.userRepo.Update(ctx, id, user)
+	updated, err := s.userRepo.Update(ctx, id, user)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrEmailExists) {
+			return nil, errors.NewBadRequestError("邮箱已被使用", err)
+		}
+		if stderrors.Is(err, repository.ErrVersionConflict) {
+			return nil, errors.NewConflictError("用户已被其他请求修改，请重新获取后再更新", err)
+		}
+		return nil, err
+	}
+
+	// 密码被管理员重置后撤销该用户的所有会话
+	if hashedPassword != "" {
+		if err := s.setPassword(ctx, id, hashedPassword); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
 }
 
-// DeleteUser 删除用户
+// DeleteUser 删除用户（软删除，超过保留期后由清理任务彻底删除）
 func (s *userService) DeleteUser(ctx context.Context, id int64) error {
 	if id <= 0 {
 		return errors.NewBadRequestError("用户ID无效", fmt.Errorf("invalid user id: %d", id))
 	}
 
 	// 检查用户是否存在
-	_, err := s.userRepo.FindByID(ctx, id)
+	user, err := s.userRepo.FindByID(ctx, id)
 	if err != nil {
 		return errors.NewNotFoundError("用户不存在", err)
 	}
 
-	return s.userRepo.Delete(ctx, id)
+	return s.deleteUser(ctx, user)
+}
+
+// deleteUser 软删除用户并撤销其所有会话，记录审计事件和日志
+func (s *userService) deleteUser(ctx context.Context, user *models.User) error {
+	id := user.ID
+
+	// 删除用户与撤销会话在同一个事务中执行
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		// 用户删除后撤销其所有会话和访问令牌
+		return s.revokeUserSessions(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserDelete,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(id),
+		Detail:     "email=" + user.Email,
+	})
+
+	caller, _ := auth.PrincipalFromContext(ctx)
+	logger.Log.Info(i18n.LogMessage(i18n.LogUserDeleted),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("user_id", user.ID),
+		zap.String("email", user.Email),
+		zap.Int64("operator_id", caller.UserID),
+	)
+	return nil
+}
+
+// RestoreUser 恢复已软删除的用户
+// 删除时已撤销该用户的所有会话，恢复后需要重新登录
+func (s *userService) RestoreUser(ctx context.Context, id int64) (*models.User, error) {
+	if id <= 0 {
+		return nil, errors.NewBadRequestError("用户ID无效", fmt.Errorf("invalid user id: %d", id))
+	}
+
+	if err := s.userRepo.Restore(ctx, id); err != nil {
+		if stderrors.Is(err, repository.ErrUserNotDeleted) {
+			return nil, errors.NewNotFoundError(err.Error(), err)
+		}
+		return nil, errors.NewInternalServerError("恢复用户失败", err)
+	}
+
+	user, err := s.userRepo.FindByID(database.WithPrimary(ctx), id)
+	if err != nil {
+		return nil, errors.NewInternalServerError("恢复用户失败", err)
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserRestore,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(id),
+		Detail:     "email=" + user.Email,
+	})
+
+	caller, _ := auth.PrincipalFromContext(ctx)
+	logger.Log.Info(i18n.LogMessage(i18n.LogUserRestored),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("user_id", user.ID),
+		zap.String("email", user.Email),
+		zap.Int64("operator_id", caller.UserID),
+	)
+	return user, nil
+}
+
+// PurgeUser 彻底删除已软删除的用户，删除后不可恢复，邮箱可以重新注册
+// 只能彻底删除已软删除的用户，避免误操作跳过恢复窗口
+func (s *userService) PurgeUser(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return errors.NewBadRequestError("用户ID无效", fmt.Errorf("invalid user id: %d", id))
+	}
+
+	if err := s.purgeUser(ctx, id); err != nil {
+		if stderrors.Is(err, repository.ErrUserNotDeleted) {
+			return errors.NewNotFoundError(err.Error(), err)
+		}
+		return errors.NewInternalServerError("彻底删除用户失败", err)
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserPurge,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(id),
+	})
+
+	caller, _ := auth.PrincipalFromContext(ctx)
+	logger.Log.Info(i18n.LogMessage(i18n.LogUserPurged),
+		zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+		zap.Int64("user_id", id),
+		zap.Int64("operator_id", caller.UserID),
+	)
+	return nil
+}
+
+// PurgeDeletedUsers 彻底删除保留期已过的软删除用户（由定时任务调用）
+// 逐个删除，单个用户失败时跳过并继续，返回成功删除的数量和遇到的第一个错误
+func (s *userService) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	ids, err := s.userRepo.ListDeletedBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	var firstErr error
+	for _, id := range ids {
+		if err := s.purgeUser(ctx, id); err != nil {
+			// 查询之后被恢复的用户不再删除
+			if !stderrors.Is(err, repository.ErrUserNotDeleted) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		purged++
+	}
+	return purged, firstErr
+}
+
+// purgeUser 在一个事务中彻底删除用户及其上传的文件信息，提交后删除文件内容
+// 其他关联数据由外键级联删除，文件表没有外键约束，需要单独删除
+func (s *userService) purgeUser(ctx context.Context, id int64) error {
+	var files []*models.File
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Purge(ctx, id); err != nil {
+			return err
+		}
+		if s.fileRepo == nil {
+			return nil
+		}
+
+		var err error
+		if files, err = s.fileRepo.ListByOwner(ctx, id); err != nil {
+			return err
+		}
+		_, err = s.fileRepo.DeleteByOwner(ctx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// 文件内容删除失败只记录日志，文件信息已删除，不会再被访问
+	if s.fileStorage != nil {
+		ctx := context.WithoutCancel(ctx)
+		for _, file := range files {
+			if err := s.fileStorage.Delete(ctx, file.StorageKey); err != nil {
+				logger.Log.Error(i18n.LogMessage(i18n.LogUserFileCleanupFailed),
+					zap.String("request_id", requestctx.FromContext(ctx).RequestID),
+					zap.Int64("user_id", id),
+					zap.String("storage_key", file.StorageKey),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// ensureEmailAvailable 检查邮箱是否可以用于注册或修改
+// 已软删除的用户在彻底删除前仍占用邮箱：重新注册会被拒绝并提示联系管理员恢复账户，
+// 而不是静默复活旧账户或创建重复账户
+func (s *userService) ensureEmailAvailable(ctx context.Context, email string) error {
+	if _, err := s.userRepo.FindByEmail(ctx, email); err == nil {
+		return errors.NewBadRequestError("邮箱已被使用", fmt.Errorf("email already exists: %s", email))
+	}
+	if _, err := s.userRepo.FindDeletedByEmail(ctx, email); err == nil {
+		return errors.NewBadRequestError("该邮箱属于已删除的账户，请联系管理员恢复", fmt.Errorf("email belongs to deleted user: %s", email))
+	}
+	return nil
 }
 
 // Login 用户登录
-func (s *userService) Login(ctx context.Context, req *models.LoginRequest) (*models.LoginResponse, error) {
+func (s *userService) Login(ctx context.Context, req *models.LoginRequest) (*models.LoginResponse, *models.MFAChallengeResponse, error) {
+	// 账户或IP处于锁定、退避期时直接拒绝，不校验密码
+	clientIP := requestctx.FromContext(ctx).ClientIP
+	if err := s.loginGuard.check(ctx, req.Email, clientIP); err != nil {
+		s.recordLoginFailure(ctx, req.Email, 0, "账户或IP已被锁定")
+		return nil, nil, err
+	}
+
 	// 检查邮箱是否存在
 	user, err := s.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil {
-		return nil, errors.NewUnauthorizedError("邮箱或密码错误", fmt.Errorf("invalid email or password"))
+		s.loginGuard.recordFailure(ctx, req.Email, clientIP)
+		s.recordLoginFailure(ctx, req.Email, 0, "邮箱不存在")
+		return nil, nil, errors.NewUnauthorizedError("邮箱或密码错误", fmt.Errorf("invalid email or password"))
 	}
 
 	// 验证密码
 	if !auth.CheckPassword(user.Password, req.Password) {
-		return nil, errors.NewUnauthorizedError("邮箱或密码错误", fmt.Errorf("invalid email or password"))
+		s.loginGuard.recordFailure(ctx, req.Email, clientIP)
+		s.recordLoginFailure(ctx, req.Email, user.ID, "密码错误")
+		return nil, nil, errors.NewUnauthorizedError("邮箱或密码错误", fmt.Errorf("invalid email or password"))
 	}
 
-	// 获取JWT配置
-	cfg := config.GetConfig()
-	jwtConfig := auth.NewJWTConfig(
-		cfg.JWT.SecretKey,
-		time.Duration(cfg.JWT.ExpiresIn)*time.Hour,
-	)
-
-	// 生成访问令牌（Access Token）
-	accessToken, err := jwtConfig.GenerateToken(user.ID, user.Email, user.Name, user.Role)
+	// 启用了两步验证（或必须启用）的账户还需要提交验证码才能获取令牌
+	challenge, err := s.mfaChallenge(ctx, user)
 	if err != nil {
-		return nil, errors.NewInternalServerError("生成访问令牌失败", err)
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return nil, challenge, nil
 	}
 
-	// 生成刷新令牌（Refresh Token）
-	refreshExpiresIn := time.Duration(cfg.JWT.RefreshExpiresIn) * time.Hour
-	if refreshExpiresIn == 0 {
-		refreshExpiresIn = 7 * 24 * time.Hour // 默认7天
+	resp, err := s.completeLogin(ctx, user)
+	if err != nil {
+		return nil, nil, err
 	}
-	refreshToken, err := jwtConfig.GenerateRefreshToken(user.ID, user.Email, user.Name, user.Role, refreshExpiresIn)
+	s.loginGuard.recordSuccess(ctx, req.Email)
+	return resp, nil, nil
+}
+
+// completeLogin 通过所有登录验证后签发令牌
+func (s *userService) completeLogin(ctx context.Context, user *models.User) (*models.LoginResponse, error) {
+	// 每次登录开启一个新的令牌族（会话）
+	accessToken, refreshToken, _, err := s.issueTokens(ctx, user, uuid.New().String())
 	if err != nil {
-		return nil, errors.NewInternalServerError("生成刷新令牌失败", err)
+		return nil, err
 	}
 
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    user.ID,
+		ActorRole:  user.Role.String(),
+		Action:     models.AuditAuthLogin,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(user.ID),
+	})
+
 	// 返回用户信息和令牌
-	// 注意：不要返回密码字段
-	user.Password = ""
+	// 注意：不要返回密码字段（复制一份，不修改仓库返回的对象）
+	respUser := *user
+	respUser.Password = ""
 
 	return &models.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		User:         *user,
+		User:         respUser,
 	}, nil
 }
 
+// recordLoginFailure 记录登录失败（登录前没有调用者身份，操作者为匿名）
+// userID 为邮箱对应的用户，邮箱不存在时为 0
+func (s *userService) recordLoginFailure(ctx context.Context, email string, userID int64, reason string) {
+	entry := &models.AuditLog{
+		Action:  models.AuditAuthLogin,
+		Outcome: models.AuditOutcomeFailure,
+		Detail:  reason + ", email=" + email,
+	}
+	if userID > 0 {
+		entry.TargetType = models.AuditTargetUser
+		entry.TargetID = auditTargetID(userID)
+	}
+	s.audit.Record(ctx, entry)
+}
+
 // RefreshToken 刷新访问令牌
+// 刷新令牌只能使用一次：每次刷新都会轮换出新的刷新令牌，
+// 已轮换或已撤销的令牌再次出现时视为令牌泄露，撤销整个令牌族
 func (s *userService) RefreshToken(ctx context.Context, req *models.RefreshTokenRequest) (*models.RefreshTokenResponse, error) {
-	// 获取JWT配置
-	cfg := config.GetConfig()
-	jwtConfig := auth.NewJWTConfig(
-		cfg.JWT.SecretKey,
-		time.Duration(cfg.JWT.ExpiresIn)*time.Hour,
-	)
+	stored, err := s.refreshTokenRepo.FindByHash(ctx, auth.HashToken(req.RefreshToken))
+	if err != nil {
+		return nil, errors.NewUnauthorizedError("无效的刷新令牌", err)
+	}
+
+	if stored.IsRevoked() {
+		s.revokeReusedFamily(ctx, stored)
+		return nil, errors.NewUnauthorizedError("无效的刷新令牌", repository.ErrRefreshTokenRevoked)
+	}
+
+	if stored.IsExpired(time.Now()) {
+		return nil, errors.NewUnauthorizedError("刷新令牌已过期", fmt.Errorf("refresh token expired"))
+	}
 
-	// 解析刷新令牌
-	claims, err := jwtConfig.ParseToken(req.RefreshToken)
+	// 从主库重新读取用户，保证新令牌中的角色等信息是最新的（从库延迟时可能读到旧角色或已删除的用户）
+	user, err := s.userRepo.FindByID(database.WithPrimary(ctx), stored.UserID)
 	if err != nil {
+		_ = s.revokeSession(ctx, stored.FamilyID)
 		return nil, errors.NewUnauthorizedError("无效的刷新令牌", err)
 	}
 
-	// 生成新的访问令牌
-	accessToken, err := jwtConfig.GenerateToken(claims.UserID, claims.Email, claims.Name, claims.Role)
+	// 签发新令牌与标记旧令牌在同一个事务中执行，轮换失败时不会留下未关联的新令牌
+	var accessToken, refreshToken string
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var newToken *models.RefreshToken
+		var err error
+		accessToken, refreshToken, newToken, err = s.issueTokens(ctx, user, stored.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		// 将旧令牌标记为已轮换；并发请求中只有一个能成功，失败的一方视为令牌复用
+		if err := s.refreshTokenRepo.MarkReplaced(ctx, stored.ID, newToken.ID); err != nil {
+			if stderrors.Is(err, repository.ErrRefreshTokenRevoked) {
+				return errors.NewUnauthorizedError("无效的刷新令牌", err)
+			}
+			return errors.NewInternalServerError("轮换刷新令牌失败", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, errors.NewInternalServerError("生成访问令牌失败", err)
+		// 令牌族在事务回滚后撤销，避免撤销操作随事务一起回滚
+		if stderrors.Is(err, repository.ErrRefreshTokenRevoked) {
+			s.revokeReusedFamily(ctx, stored)
+		}
+		return nil, err
 	}
 
 	return &models.RefreshTokenResponse{
-		AccessToken: accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}, nil
 }
+
+// Logout 退出登录，撤销刷新令牌所属的令牌族（All 为 true 时撤销该用户的所有会话）
+func (s *userService) Logout(ctx context.Context, req *models.LogoutRequest) error {
+	stored, err := s.refreshTokenRepo.FindByHash(ctx, auth.HashToken(req.RefreshToken))
+	if err != nil {
+		return errors.NewUnauthorizedError("无效的刷新令牌", err)
+	}
+
+	if req.All {
+		err = s.revokeUserSessions(ctx, stored.UserID)
+	} else {
+		err = s.revokeSession(ctx, stored.FamilyID)
+	}
+	if err != nil {
+		return err
+	}
+
+	detail := "session=" + stored.FamilyID
+	if req.All {
+		detail = "all sessions"
+	}
+	s.audit.Record(ctx, &models.AuditLog{
+		ActorID:    stored.UserID,
+		Action:     models.AuditAuthLogout,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(stored.UserID),
+		Detail:     detail,
+	})
+	return nil
+}
+
+// ListSessions 列出用户当前有效的会话
+func (s *userService) ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]*models.Session, error) {
+	tokens, err := s.refreshTokenRepo.FindActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.NewInternalServerError("获取会话列表失败", err)
+	}
+
+	sessions := make([]*models.Session, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, &models.Session{
+			ID:         token.FamilyID,
+			UserAgent:  token.UserAgent,
+			IP:         token.IP,
+			LastUsedAt: token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    token.FamilyID == currentSessionID,
+		})
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession 撤销用户自己的某个会话
+func (s *userService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	tokens, err := s.refreshTokenRepo.FindActiveByUserID(ctx, userID)
+	if err != nil {
+		return errors.NewInternalServerError("获取会话列表失败", err)
+	}
+
+	// 只能撤销属于自己的有效会话
+	for _, token := range tokens {
+		if token.FamilyID == sessionID {
+			return s.revokeSession(ctx, sessionID)
+		}
+	}
+
+	return errors.NewNotFoundError("会话不存在", fmt.Errorf("session not found: %s", sessionID))
+}
+
+// ForceLogout 强制用户下线（撤销其所有会话和访问令牌）
+func (s *userService) ForceLogout(ctx context.Context, userID int64) error {
+	if userID <= 0 {
+		return errors.NewBadRequestError("用户ID无效", fmt.Errorf("invalid user id: %d", userID))
+	}
+
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return errors.NewNotFoundError("用户不存在", err)
+	}
+
+	if err := s.revokeUserSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditLog{
+		Action:     models.AuditUserForceLogout,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditTargetID(userID),
+	})
+	return nil
+}
+
+// revokeSession 撤销单个会话：刷新令牌族 + 该会话签发的访问令牌
+func (s *userService) revokeSession(ctx context.Context, sessionID string) error {
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, sessionID); err != nil {
+		return errors.NewInternalServerError("撤销会话失败", err)
+	}
+	if err := s.revocationStore.RevokeSession(ctx, sessionID, time.Now().Add(accessTokenTTL())); err != nil {
+		return errors.NewInternalServerError("撤销会话失败", err)
+	}
+	return nil
+}
+
+// revokeUserSessions 撤销用户的所有会话和访问令牌
+func (s *userService) revokeUserSessions(ctx context.Context, userID int64) error {
+	if err := s.refreshTokenRepo.RevokeByUserID(ctx, userID); err != nil {
+		return errors.NewInternalServerError("撤销用户会话失败", err)
+	}
+	if err := s.revocationStore.RevokeUser(ctx, userID, time.Now().Add(accessTokenTTL())); err != nil {
+		return errors.NewInternalServerError("撤销用户会话失败", err)
+	}
+	return nil
+}
+
+// accessTokenTTL 访问令牌有效期（撤销记录至少需要保留这么久）
+func accessTokenTTL() time.Duration {
+	return time.Duration(config.GetConfig().JWT.ExpiresIn) * time.Hour
+}
+
+// issueTokens 为用户签发访问令牌，并在指定令牌族中保存一个新的刷新令牌
+func (s *userService) issueTokens(ctx context.Context, user *models.User, familyID string) (string, string, *models.RefreshToken, error) {
+	cfg := config.GetConfig()
+	jwtConfig := s.jwtConfig
+	if jwtConfig == nil {
+		jwtConfig = auth.NewJWTConfig(
+			cfg.JWT.SecretKey,
+			accessTokenTTL(),
+		)
+	}
+
+	// 生成访问令牌（Access Token）
+	accessToken, err := jwtConfig.GenerateToken(user.ID, user.Email, user.Name, user.Role, familyID)
+	if err != nil {
+		return "", "", nil, errors.NewInternalServerError("生成访问令牌失败", err)
+	}
+
+	// 生成刷新令牌（Refresh Token），服务端只保存哈希
+	refreshExpiresIn := time.Duration(cfg.JWT.RefreshExpiresIn) * time.Hour
+	if refreshExpiresIn == 0 {
+		refreshExpiresIn = 7 * 24 * time.Hour // 默认7天
+	}
+	refreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", nil, errors.NewInternalServerError("生成刷新令牌失败", err)
+	}
+
+	md := requestctx.FromContext(ctx)
+	record, err := s.refreshTokenRepo.Create(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: auth.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(refreshExpiresIn),
+		UserAgent: md.UserAgent,
+		IP:        md.ClientIP,
+	})
+	if err != nil {
+		return "", "", nil, errors.NewInternalServerError("保存刷新令牌失败", err)
+	}
+
+	return accessToken, refreshToken, record, nil
+}
+
+// revokeReusedFamily 检测到刷新令牌复用时撤销整个令牌族
+func (s *userService) revokeReusedFamily(ctx context.Context, token *models.RefreshToken) {
+	logger.Log.Warn(i18n.LogMessage(i18n.LogRefreshTokenReused),
+		zap.Int64("user_id", token.UserID),
+		zap.String("family_id", token.FamilyID),
+		zap.Int64("token_id", token.ID),
+	)
+	if err := s.revokeSession(ctx, token.FamilyID); err != nil {
+		logger.Log.Error(i18n.LogMessage(i18n.LogInternalError), zap.Error(err))
+	}
+}
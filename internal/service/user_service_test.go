@@ -3,9 +3,18 @@ package service
 import (
 	"context"
 	"errors"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"gin/internal/auth"
+	"gin/internal/config"
+	"gin/internal/database"
+	apperrors "gin/internal/errors"
 	"gin/internal/models"
+	"gin/internal/repository"
+	"gin/internal/storage"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -41,12 +50,20 @@ func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*mo
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindAll(ctx context.Context) ([]*models.User, error) {
-	args := m.Called(ctx)
+func (m *MockUserRepository) FindDeletedByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) List(ctx context.Context, filter models.UserFilter, page models.PageQuery) (*models.Page[*models.User], error) {
+	args := m.Called(ctx, filter, page)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]*models.User), args.Error(1)
+	return args.Get(0).(*models.Page[*models.User]), args.Error(1)
 }
 
 func (m *MockUserRepository) Update(ctx context.Context, id int64, user *models.User) (*models.User, error) {
@@ -57,11 +74,34 @@ func (m *MockUserRepository) Update(ctx context.Context, id int64, user *models.
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) Restore(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Purge(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ListDeletedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	args := m.Called(ctx, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
 // TestUserService_CreateUser 测试创建用户服务
 func TestUserService_CreateUser(t *testing.T) {
 	ctx := context.Background()
@@ -71,13 +111,15 @@ func TestUserService_CreateUser(t *testing.T) {
 		service := NewUserService(mockRepo)
 
 		req := &models.CreateUserRequest{
-			Name:  "张三",
-			Email: "zhangsan@example.com",
-			Age:   25,
+			Name:     "张三",
+			Email:    "zhangsan@example.com",
+			Password: "zhangsan2024",
+			Age:      25,
 		}
 
 		// Mock: 邮箱不存在
 		mockRepo.On("FindByEmail", ctx, req.Email).Return(nil, errors.New("用户不存在"))
+		mockRepo.On("FindDeletedByEmail", ctx, req.Email).Return(nil, errors.New("用户不存在"))
 
 		// Mock: 创建用户成功
 		expectedUser := &models.User{
@@ -97,6 +139,26 @@ func TestUserService_CreateUser(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("密码不符合密码策略应该失败", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		req := &models.CreateUserRequest{
+			Name:     "张三",
+			Email:    "zhangsan@example.com",
+			Password: "123456",
+		}
+		mockRepo.On("FindByEmail", ctx, req.Email).Return(nil, errors.New("用户不存在"))
+		mockRepo.On("FindDeletedByEmail", ctx, req.Email).Return(nil, errors.New("用户不存在"))
+
+		user, err := service.CreateUser(ctx, req)
+		assert.Nil(t, user)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		assert.Contains(t, err.Error(), "密码不符合安全策略")
+
+		mockRepo.AssertNotCalled(t, "Create")
+	})
+
 	t.Run("邮箱已存在应该失败", func(t *testing.T) {
 		mockRepo := new(MockUserRepository)
 		service := NewUserService(mockRepo)
@@ -119,11 +181,51 @@ func TestUserService_CreateUser(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 		mockRepo.AssertNotCalled(t, "Create")
 	})
+
+	t.Run("并发注册同一邮箱时唯一约束冲突返回400", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		req := &models.CreateUserRequest{
+			Name:     "张三",
+			Email:    "zhangsan@example.com",
+			Password: "zhangsan2024",
+		}
+		mockRepo.On("FindByEmail", ctx, req.Email).Return(nil, errors.New("用户不存在"))
+		mockRepo.On("FindDeletedByEmail", ctx, req.Email).Return(nil, errors.New("用户不存在"))
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil, repository.ErrEmailExists)
+
+		user, err := service.CreateUser(ctx, req)
+		assert.Nil(t, user)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		assert.Contains(t, err.Error(), "邮箱已被使用")
+	})
+
+	t.Run("邮箱属于已删除的用户应该失败", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		req := &models.CreateUserRequest{
+			Name:     "张三",
+			Email:    "deleted@example.com",
+			Password: "zhangsan2024",
+		}
+		deletedAt := time.Now()
+		mockRepo.On("FindByEmail", ctx, req.Email).Return(nil, errors.New("用户不存在"))
+		mockRepo.On("FindDeletedByEmail", ctx, req.Email).Return(&models.User{ID: 1, Email: req.Email, DeletedAt: &deletedAt}, nil)
+
+		user, err := service.CreateUser(ctx, req)
+		assert.Nil(t, user)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		assert.Contains(t, err.Error(), "请联系管理员恢复")
+
+		mockRepo.AssertNotCalled(t, "Create")
+	})
 }
 
 // TestUserService_GetUserByID 测试根据ID获取用户
 func TestUserService_GetUserByID(t *testing.T) {
-	ctx := context.Background()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 100, Role: auth.RoleAdmin})
 
 	t.Run("成功获取用户", func(t *testing.T) {
 		mockRepo := new(MockUserRepository)
@@ -222,20 +324,59 @@ func TestUserService_GetAllUsers(t *testing.T) {
 			{ID: 1, Name: "用户1", Email: "user1@example.com", Age: 20},
 			{ID: 2, Name: "用户2", Email: "user2@example.com", Age: 25},
 		}
+		req := &models.ListUsersRequest{
+			UserFilter: models.UserFilter{Role: "user"},
+			PageQuery:  models.PageQuery{Limit: 2, Sort: "name"},
+		}
 
-		mockRepo.On("FindAll", ctx).Return(expectedUsers, nil)
+		mockRepo.On("List", ctx, req.UserFilter, req.PageQuery).Return(&models.Page[*models.User]{Items: expectedUsers, Total: 5, Limit: 2, HasMore: true}, nil)
 
-		users, err := service.GetAllUsers(ctx)
+		page, err := service.GetAllUsers(ctx, req)
 		require.NoError(t, err)
-		assert.Len(t, users, 2)
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, int64(5), page.Total)
 
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("无效的排序字段返回400", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		req := &models.ListUsersRequest{PageQuery: models.PageQuery{Sort: "password"}}
+		mockRepo.On("List", ctx, req.UserFilter, req.PageQuery).Return(nil, repository.ErrInvalidSort)
+
+		_, err := service.GetAllUsers(ctx, req)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("查询已删除的用户需要恢复权限", func(t *testing.T) {
+		auth.Permissions.Load(map[auth.Role][]string{
+			auth.RoleAdmin: {auth.PermissionUsersRead, auth.PermissionUsersRestore},
+			auth.RoleUser:  {auth.PermissionUsersRead},
+		})
+		defer auth.Permissions.Load(nil)
+
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		req := &models.ListUsersRequest{UserFilter: models.UserFilter{Deleted: true}}
+		userCtx := auth.WithPrincipal(ctx, auth.Principal{UserID: 1, Role: auth.RoleUser})
+		_, err := service.GetAllUsers(userCtx, req)
+		assertAppErrorCode(t, err, http.StatusForbidden)
+		mockRepo.AssertNotCalled(t, "List")
+
+		adminCtx := auth.WithPrincipal(ctx, auth.Principal{UserID: 100, Role: auth.RoleAdmin})
+		mockRepo.On("List", adminCtx, req.UserFilter, req.PageQuery).Return(&models.Page[*models.User]{}, nil)
+		_, err = service.GetAllUsers(adminCtx, req)
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
 }
 
 // TestUserService_UpdateUser 测试更新用户
 func TestUserService_UpdateUser(t *testing.T) {
-	ctx := context.Background()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1, Role: auth.RoleUser})
 
 	t.Run("成功更新用户", func(t *testing.T) {
 		mockRepo := new(MockUserRepository)
@@ -260,7 +401,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 			Age:  25,
 		}
 
-		mockRepo.On("FindByID", ctx, int64(1)).Return(existingUser, nil)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser, nil)
 		mockRepo.On("Update", ctx, int64(1), mock.AnythingOfType("*models.User")).Return(updatedUser, nil)
 
 		user, err := service.UpdateUser(ctx, 1, req)
@@ -274,6 +415,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 	t.Run("更新邮箱时检查重复", func(t *testing.T) {
 		mockRepo := new(MockUserRepository)
 		service := NewUserService(mockRepo)
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 100, Role: auth.RoleAdmin})
 
 		existingUser := &models.User{
 			ID:    1,
@@ -287,7 +429,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 		}
 
 		// 用户存在
-		mockRepo.On("FindByID", ctx, int64(1)).Return(existingUser, nil)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existingUser, nil)
 		// 新邮箱已存在
 		existingUser2 := &models.User{ID: 2, Email: "existing@example.com"}
 		mockRepo.On("FindByEmail", ctx, "existing@example.com").Return(existingUser2, nil)
@@ -300,6 +442,160 @@ func TestUserService_UpdateUser(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 		mockRepo.AssertNotCalled(t, "Update")
 	})
+
+	t.Run("If-Match版本与当前版本不一致返回412", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Name: "用户1", Version: 3}, nil)
+
+		user, err := service.UpdateUser(ctx, 1, &models.UpdateUserRequest{Name: "新名称", ExpectedVersion: 2})
+		assert.Nil(t, user)
+		assertAppErrorCode(t, err, http.StatusPreconditionFailed)
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("更新时版本冲突返回409", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Name: "用户1", Version: 3}, nil)
+		mockRepo.On("Update", ctx, int64(1), mock.MatchedBy(func(u *models.User) bool {
+			return u.Version == 3
+		})).Return(nil, repository.ErrVersionConflict)
+
+		user, err := service.UpdateUser(ctx, 1, &models.UpdateUserRequest{Name: "新名称", ExpectedVersion: 3})
+		assert.Nil(t, user)
+		assertAppErrorCode(t, err, http.StatusConflict)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+// TestUserService_Ownership 测试查看、更新用户时的资源归属检查
+func TestUserService_Ownership(t *testing.T) {
+	userCtx := func(id int64, role auth.Role) context.Context {
+		return auth.WithPrincipal(context.Background(), auth.Principal{UserID: id, Role: role})
+	}
+
+	t.Run("普通用户可以查看自己", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		ctx := userCtx(1, auth.RoleUser)
+
+		mockRepo.On("FindByID", ctx, int64(1)).Return(&models.User{ID: 1, Name: "张三"}, nil)
+
+		user, err := service.GetUserByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), user.ID)
+	})
+
+	t.Run("普通用户查看他人返回403", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		user, err := service.GetUserByID(userCtx(1, auth.RoleUser), 2)
+		assert.Nil(t, user)
+		assertAppErrorCode(t, err, http.StatusForbidden)
+
+		// 鉴权失败时不查询仓库，避免泄露用户是否存在
+		mockRepo.AssertNotCalled(t, "FindByID")
+	})
+
+	t.Run("普通用户更新他人返回403", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		user, err := service.UpdateUser(userCtx(1, auth.RoleUser), 2, &models.UpdateUserRequest{Name: "篡改"})
+		assert.Nil(t, user)
+		assertAppErrorCode(t, err, http.StatusForbidden)
+
+		mockRepo.AssertNotCalled(t, "FindByID")
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("拥有users:update权限的自定义角色也不能更新他人", func(t *testing.T) {
+		auth.Permissions.Load(map[auth.Role][]string{"editor": {auth.PermissionUsersUpdate}})
+		defer auth.Permissions.Load(nil)
+
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		_, err := service.UpdateUser(userCtx(1, "editor"), 2, &models.UpdateUserRequest{Name: "篡改"})
+		assertAppErrorCode(t, err, http.StatusForbidden)
+	})
+
+	t.Run("管理员可以更新他人且不改变其角色", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		ctx := userCtx(100, auth.RoleAdmin)
+
+		existing := &models.User{ID: 2, Name: "李四", Email: "lisi@example.com", Age: 30, Role: auth.RoleAdmin}
+		mockRepo.On("FindByID", mock.Anything, int64(2)).Return(existing, nil)
+		mockRepo.On("Update", ctx, int64(2), mock.MatchedBy(func(u *models.User) bool {
+			return u.Name == "李四2" && u.Role == auth.RoleAdmin
+		})).Return(&models.User{ID: 2, Name: "李四2", Role: auth.RoleAdmin}, nil)
+
+		user, err := service.UpdateUser(ctx, 2, &models.UpdateUserRequest{Name: "李四2"})
+		require.NoError(t, err)
+		assert.Equal(t, "李四2", user.Name)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("通过/users/:id修改自己的邮箱返回400", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		ctx := userCtx(1, auth.RoleUser)
+
+		existing := &models.User{ID: 1, Name: "张三", Email: "zhangsan@example.com", Age: 20, Role: auth.RoleUser}
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existing, nil)
+
+		_, err := service.UpdateUser(ctx, 1, &models.UpdateUserRequest{Name: "张三", Email: "attacker@example.com"})
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+
+		// 修改自己的邮箱必须通过 /users/me 验证当前密码，这里不应查重或写库
+		mockRepo.AssertNotCalled(t, "FindByEmail")
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("通过/users/:id修改自己的名称不受邮箱限制", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+		ctx := userCtx(1, auth.RoleUser)
+
+		existing := &models.User{ID: 1, Name: "张三", Email: "zhangsan@example.com", Age: 20, Role: auth.RoleUser}
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(existing, nil)
+		mockRepo.On("Update", ctx, int64(1), mock.MatchedBy(func(u *models.User) bool {
+			return u.Name == "张三2" && u.Email == "zhangsan@example.com"
+		})).Return(&models.User{ID: 1, Name: "张三2", Email: "zhangsan@example.com"}, nil)
+
+		user, err := service.UpdateUser(ctx, 1, &models.UpdateUserRequest{Name: "张三2", Email: "zhangsan@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "张三2", user.Name)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("缺少调用者身份返回401", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		_, err := service.GetUserByID(context.Background(), 1)
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+
+		_, err = service.UpdateUser(context.Background(), 1, &models.UpdateUserRequest{Name: "张三"})
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+
+		mockRepo.AssertNotCalled(t, "FindByID")
+	})
+}
+
+// assertAppErrorCode 断言错误是指定状态码的 AppError
+func assertAppErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	var appErr *apperrors.AppError
+	require.True(t, errors.As(err, &appErr), "期望 AppError，实际为 %v", err)
+	assert.Equal(t, code, appErr.Code)
 }
 
 // TestUserService_DeleteUser 测试删除用户
@@ -340,3 +636,294 @@ func TestUserService_DeleteUser(t *testing.T) {
 		mockRepo.AssertNotCalled(t, "Delete")
 	})
 }
+
+// TestUserService_RestoreAndPurgeUser 测试恢复和彻底删除用户
+func TestUserService_RestoreAndPurgeUser(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 100, Role: auth.RoleAdmin})
+
+	t.Run("成功恢复用户", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		restored := &models.User{ID: 1, Name: "张三", Email: "zhangsan@example.com"}
+		mockRepo.On("Restore", ctx, int64(1)).Return(nil)
+		mockRepo.On("FindByID", mock.Anything, int64(1)).Return(restored, nil)
+
+		user, err := service.RestoreUser(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, restored, user)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("恢复未删除的用户返回404", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("Restore", ctx, int64(1)).Return(repository.ErrUserNotDeleted)
+
+		user, err := service.RestoreUser(ctx, 1)
+		assert.Nil(t, user)
+		assertAppErrorCode(t, err, http.StatusNotFound)
+	})
+
+	t.Run("成功彻底删除用户", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("Purge", ctx, int64(1)).Return(nil)
+
+		require.NoError(t, service.PurgeUser(ctx, 1))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("彻底删除用户时删除其上传的文件", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		fileRepo := new(MockFileRepository)
+		store := storage.NewLocalStorage(t.TempDir())
+		service := NewUserService(mockRepo, WithFileRepository(fileRepo), WithFileStorage(store))
+
+		require.NoError(t, store.Put(ctx, "2024/01/02/abc.png", strings.NewReader(pngContent), int64(len(pngContent)), "image/png"))
+		mockRepo.On("Purge", ctx, int64(1)).Return(nil)
+		fileRepo.On("ListByOwner", ctx, int64(1)).Return([]*models.File{{ID: 5, StorageKey: "2024/01/02/abc.png", OwnerID: 1}}, nil)
+		fileRepo.On("DeleteByOwner", ctx, int64(1)).Return(int64(1), nil)
+
+		require.NoError(t, service.PurgeUser(ctx, 1))
+		mockRepo.AssertExpectations(t)
+		fileRepo.AssertExpectations(t)
+		_, err := store.Get(ctx, "2024/01/02/abc.png")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("清理超过保留期的已删除用户", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		fileRepo := new(MockFileRepository)
+		service := NewUserService(mockRepo, WithFileRepository(fileRepo))
+
+		before := time.Now()
+		mockRepo.On("ListDeletedBefore", ctx, before).Return([]int64{1, 2, 3}, nil)
+		mockRepo.On("Purge", ctx, int64(1)).Return(nil)
+		mockRepo.On("Purge", ctx, int64(2)).Return(repository.ErrUserNotDeleted) // 查询之后被恢复
+		mockRepo.On("Purge", ctx, int64(3)).Return(nil)
+		fileRepo.On("ListByOwner", ctx, mock.Anything).Return([]*models.File{}, nil)
+		fileRepo.On("DeleteByOwner", ctx, mock.Anything).Return(int64(0), nil)
+
+		purged, err := service.PurgeDeletedUsers(ctx, before)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), purged)
+		fileRepo.AssertNotCalled(t, "DeleteByOwner", ctx, int64(2))
+	})
+
+	t.Run("彻底删除未删除的用户返回404", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		mockRepo.On("Purge", ctx, int64(1)).Return(repository.ErrUserNotDeleted)
+
+		assertAppErrorCode(t, service.PurgeUser(ctx, 1), http.StatusNotFound)
+	})
+
+	t.Run("无效的用户ID", func(t *testing.T) {
+		service := NewUserService(new(MockUserRepository))
+
+		_, err := service.RestoreUser(ctx, 0)
+		assertAppErrorCode(t, err, http.StatusBadRequest)
+		assertAppErrorCode(t, service.PurgeUser(ctx, -1), http.StatusBadRequest)
+	})
+}
+
+// newLoginTestUser 创建可用于登录的测试用户
+func newLoginTestUser(t *testing.T) *models.User {
+	hashedPassword, err := auth.HashPassword("123456")
+	require.NoError(t, err)
+	return &models.User{
+		ID:       1,
+		Name:     "张三",
+		Email:    "zhangsan@example.com",
+		Password: hashedPassword,
+		Role:     auth.RoleUser,
+	}
+}
+
+// TestUserService_RefreshToken 测试刷新令牌轮换与复用检测
+func TestUserService_RefreshToken(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (UserService, *models.LoginResponse) {
+		mockRepo := new(MockUserRepository)
+		user := newLoginTestUser(t)
+		mockRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+		// 刷新时必须从主库读取用户
+		mockRepo.On("FindByID", database.WithPrimary(ctx), user.ID).Return(user, nil)
+
+		service := NewUserService(mockRepo, WithRefreshTokenRepository(repository.NewMemoryRefreshTokenRepository()))
+		loginResp, _, err := service.Login(ctx, &models.LoginRequest{Email: user.Email, Password: "123456"})
+		require.NoError(t, err)
+		require.NotEmpty(t, loginResp.RefreshToken)
+		return service, loginResp
+	}
+
+	t.Run("刷新时轮换刷新令牌", func(t *testing.T) {
+		service, loginResp := setup(t)
+
+		resp, err := service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: loginResp.RefreshToken})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEqual(t, loginResp.RefreshToken, resp.RefreshToken)
+
+		// 新令牌可以继续使用
+		_, err = service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: resp.RefreshToken})
+		require.NoError(t, err)
+	})
+
+	t.Run("访问令牌不能作为刷新令牌使用", func(t *testing.T) {
+		service, loginResp := setup(t)
+
+		_, err := service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: loginResp.AccessToken})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "无效的刷新令牌")
+	})
+
+	t.Run("旧令牌复用时撤销整个令牌族", func(t *testing.T) {
+		service, loginResp := setup(t)
+
+		resp, err := service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: loginResp.RefreshToken})
+		require.NoError(t, err)
+
+		// 再次使用已轮换的旧令牌
+		_, err = service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: loginResp.RefreshToken})
+		assert.Error(t, err)
+
+		// 同一令牌族中最新的令牌也被撤销
+		_, err = service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: resp.RefreshToken})
+		assert.Error(t, err)
+	})
+
+	t.Run("退出登录后刷新令牌失效", func(t *testing.T) {
+		service, loginResp := setup(t)
+
+		err := service.Logout(ctx, &models.LogoutRequest{RefreshToken: loginResp.RefreshToken})
+		require.NoError(t, err)
+
+		_, err = service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: loginResp.RefreshToken})
+		assert.Error(t, err)
+	})
+}
+
+// txMarkerKey 标记 ctx 处于 recordingTxManager 开启的事务中
+type txMarkerKey struct{}
+
+// recordingTxManager 记录事务调用并在 ctx 中打上事务标记
+type recordingTxManager struct{}
+
+func (recordingTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(context.WithValue(ctx, txMarkerKey{}, true))
+}
+
+// txCheckingRefreshTokenRepository 记录 Create 与 MarkReplaced 是否在事务中调用
+type txCheckingRefreshTokenRepository struct {
+	repository.RefreshTokenRepository
+	createInTx       []bool
+	markReplacedInTx []bool
+}
+
+func (r *txCheckingRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) (*models.RefreshToken, error) {
+	r.createInTx = append(r.createInTx, ctx.Value(txMarkerKey{}) != nil)
+	return r.RefreshTokenRepository.Create(ctx, token)
+}
+
+func (r *txCheckingRefreshTokenRepository) MarkReplaced(ctx context.Context, id, replacedBy int64) error {
+	r.markReplacedInTx = append(r.markReplacedInTx, ctx.Value(txMarkerKey{}) != nil)
+	return r.RefreshTokenRepository.MarkReplaced(ctx, id, replacedBy)
+}
+
+// TestUserService_RefreshToken_WithinTx 测试刷新时签发新令牌与轮换旧令牌在同一事务中执行
+func TestUserService_RefreshToken_WithinTx(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := new(MockUserRepository)
+	user := newLoginTestUser(t)
+	mockRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+	mockRepo.On("FindByID", mock.Anything, user.ID).Return(user, nil)
+
+	tokenRepo := &txCheckingRefreshTokenRepository{RefreshTokenRepository: repository.NewMemoryRefreshTokenRepository()}
+	service := NewUserService(mockRepo, WithRefreshTokenRepository(tokenRepo), WithTxManager(recordingTxManager{}))
+
+	loginResp, _, err := service.Login(ctx, &models.LoginRequest{Email: user.Email, Password: "123456"})
+	require.NoError(t, err)
+	tokenRepo.createInTx = nil
+
+	_, err = service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: loginResp.RefreshToken})
+	require.NoError(t, err)
+
+	assert.Equal(t, []bool{true}, tokenRepo.createInTx)
+	assert.Equal(t, []bool{true}, tokenRepo.markReplacedInTx)
+}
+
+// TestUserService_Sessions 测试会话列表、撤销会话与强制下线
+func TestUserService_Sessions(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := new(MockUserRepository)
+	user := newLoginTestUser(t)
+	// Login 会清空返回用户的密码，因此每次登录都返回新的副本
+	for i := 0; i < 2; i++ {
+		u := *user
+		mockRepo.On("FindByEmail", ctx, user.Email).Return(&u, nil).Once()
+	}
+	mockRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+
+	store := repository.NewMemoryTokenRevocationStore()
+	service := NewUserService(mockRepo, WithTokenRevocationStore(store))
+
+	login := func() (*models.LoginResponse, *auth.UserClaims) {
+		resp, _, err := service.Login(ctx, &models.LoginRequest{Email: user.Email, Password: "123456"})
+		require.NoError(t, err)
+		claims, err := auth.NewJWTConfig(config.GetConfig().JWT.SecretKey, time.Hour).ParseToken(resp.AccessToken)
+		require.NoError(t, err)
+		require.NotEmpty(t, claims.ID, "访问令牌应包含 jti")
+		return resp, claims
+	}
+
+	first, firstClaims := login()
+	_, secondClaims := login()
+
+	sessions, err := service.ListSessions(ctx, user.ID, firstClaims.SessionID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	for _, session := range sessions {
+		assert.Equal(t, session.ID == firstClaims.SessionID, session.Current)
+	}
+
+	t.Run("撤销会话后该会话的令牌失效", func(t *testing.T) {
+		require.NoError(t, service.RevokeSession(ctx, user.ID, firstClaims.SessionID))
+
+		revoked, err := store.IsRevoked(ctx, firstClaims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		revoked, err = store.IsRevoked(ctx, secondClaims)
+		require.NoError(t, err)
+		assert.False(t, revoked, "其他会话不受影响")
+
+		_, err = service.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+		assert.Error(t, err)
+	})
+
+	t.Run("不能撤销不属于自己的会话", func(t *testing.T) {
+		err := service.RevokeSession(ctx, 999, secondClaims.SessionID)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "会话不存在")
+	})
+
+	t.Run("强制下线撤销所有会话", func(t *testing.T) {
+		require.NoError(t, service.ForceLogout(ctx, user.ID))
+
+		revoked, err := store.IsRevoked(ctx, secondClaims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		sessions, err := service.ListSessions(ctx, user.ID, "")
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+}
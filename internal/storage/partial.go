@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrOffsetMismatch 写入位置与已接收的长度不一致
+var ErrOffsetMismatch = errors.New("上传位置与已接收的长度不一致")
+
+// partialIDPattern 分片上传ID格式：32位十六进制
+var partialIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// PartialUpload 未完成的分片上传（tus 协议）
+type PartialUpload struct {
+	ID        string            `json:"id"`
+	OwnerID   int64             `json:"owner_id"`
+	Length    int64             `json:"length"`             // 文件总长度
+	Offset    int64             `json:"-"`                  // 已接收的字节数，按数据文件的长度计算
+	Metadata  map[string]string `json:"metadata,omitempty"` // 客户端提供的元数据（Upload-Metadata）
+	FileID    int64             `json:"file_id,omitempty"`  // 完成后保存到文件存储的文件ID
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Completed 是否已完成并保存到文件存储
+func (u *PartialUpload) Completed() bool {
+	return u.FileID > 0
+}
+
+// PartialStore 分片上传存储，保存上传状态和已接收的内容
+type PartialStore interface {
+	// Create 创建上传，ID 由调用方生成
+	Create(ctx context.Context, upload *PartialUpload) error
+	// Get 获取上传状态，不存在时返回 ErrNotFound
+	Get(ctx context.Context, id string) (*PartialUpload, error)
+	// Append 从 offset 处追加内容，返回追加后的长度；offset 与已接收的长度不一致时返回 ErrOffsetMismatch
+	// 读取中断时已写入的内容会保留，返回值为实际写入后的长度
+	Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+	// Open 读取已接收的内容，调用方负责关闭
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+	// Complete 记录保存后的文件ID并删除已接收的内容
+	Complete(ctx context.Context, id string, fileID int64) error
+	// Delete 删除上传，不存在时不返回错误
+	Delete(ctx context.Context, id string) error
+	// PurgeExpired 删除 now 之前过期的上传，返回删除的数量
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// diskPartialStore 本地磁盘分片上传存储：每个上传保存为 <id>.info（状态）和 <id>.bin（内容）两个文件
+type diskPartialStore struct {
+	dir   string
+	locks sync.Map // 上传ID -> *sync.Mutex，同一上传的写入串行执行
+}
+
+// NewPartialStore 创建保存在 dir 下的分片上传存储，服务重启后可以继续上传
+func NewPartialStore(dir string) PartialStore {
+	return &diskPartialStore{dir: dir}
+}
+
+// lock 锁定上传，返回解锁函数
+func (s *diskPartialStore) lock(id string) func() {
+	mu, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// paths 返回上传的状态文件和内容文件路径
+func (s *diskPartialStore) paths(id string) (info, data string, err error) {
+	if !partialIDPattern.MatchString(id) {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidKey, id)
+	}
+	return filepath.Join(s.dir, id+".info"), filepath.Join(s.dir, id+".bin"), nil
+}
+
+// Create 创建上传
+func (s *diskPartialStore) Create(ctx context.Context, upload *PartialUpload) error {
+	infoPath, dataPath, err := s.paths(upload.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("创建上传目录失败: %w", err)
+	}
+
+	f, err := os.OpenFile(dataPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("创建上传文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("创建上传文件失败: %w", err)
+	}
+	if err := s.writeInfo(infoPath, upload); err != nil {
+		os.Remove(dataPath)
+		return err
+	}
+	return nil
+}
+
+// Get 获取上传状态
+func (s *diskPartialStore) Get(ctx context.Context, id string) (*PartialUpload, error) {
+	infoPath, dataPath, err := s.paths(id)
+	if err != nil {
+		return nil, err
+	}
+	upload, err := s.readInfo(infoPath)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Completed() {
+		upload.Offset = upload.Length
+		return upload, nil
+	}
+
+	stat, err := os.Stat(dataPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("读取上传文件失败: %w", err)
+	}
+	upload.Offset = stat.Size()
+	return upload, nil
+}
+
+// Append 从 offset 处追加内容
+func (s *diskPartialStore) Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	_, dataPath, err := s.paths(id)
+	if err != nil {
+		return 0, err
+	}
+	defer s.lock(id)()
+
+	f, err := os.OpenFile(dataPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("打开上传文件失败: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("读取上传文件失败: %w", err)
+	}
+	if stat.Size() != offset {
+		return stat.Size(), fmt.Errorf("%w: 已接收 %d，请求位置 %d", ErrOffsetMismatch, stat.Size(), offset)
+	}
+
+	written, err := io.Copy(f, r)
+	if syncErr := f.Sync(); err == nil && syncErr != nil {
+		err = fmt.Errorf("写入上传文件失败: %w", syncErr)
+	}
+	return offset + written, err
+}
+
+// Open 读取已接收的内容
+func (s *diskPartialStore) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	_, dataPath, err := s.paths(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(dataPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("读取上传文件失败: %w", err)
+	}
+	return f, nil
+}
+
+// Complete 记录保存后的文件ID，状态保留到过期，客户端可以查询文件ID
+func (s *diskPartialStore) Complete(ctx context.Context, id string, fileID int64) error {
+	infoPath, dataPath, err := s.paths(id)
+	if err != nil {
+		return err
+	}
+	defer s.lock(id)()
+
+	upload, err := s.readInfo(infoPath)
+	if err != nil {
+		return err
+	}
+	upload.FileID = fileID
+	if err := s.writeInfo(infoPath, upload); err != nil {
+		return err
+	}
+	if err := os.Remove(dataPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("删除上传文件失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除上传
+func (s *diskPartialStore) Delete(ctx context.Context, id string) error {
+	infoPath, dataPath, err := s.paths(id)
+	if err != nil {
+		return err
+	}
+	unlock := s.lock(id)
+	defer func() {
+		unlock()
+		s.locks.Delete(id)
+	}()
+
+	// 先删除状态文件，删除中断时不会留下可以继续写入的上传
+	for _, path := range []string{infoPath, dataPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("删除上传文件失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// PurgeExpired 删除过期的上传
+func (s *diskPartialStore) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("读取上传目录失败: %w", err)
+	}
+
+	var purged int64
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".info")
+		if !ok || !partialIDPattern.MatchString(id) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		upload, err := s.readInfo(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return purged, err
+		}
+		if upload.ExpiresAt.After(now) {
+			continue
+		}
+		if err := s.Delete(ctx, id); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// readInfo 读取状态文件
+func (s *diskPartialStore) readInfo(path string) (*PartialUpload, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("读取上传状态失败: %w", err)
+	}
+	var upload PartialUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, fmt.Errorf("解析上传状态失败: %w", err)
+	}
+	return &upload, nil
+}
+
+// writeInfo 写入状态文件：先写临时文件再重命名，不会留下写了一半的状态
+func (s *diskPartialStore) writeInfo(path string, upload *PartialUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return fmt.Errorf("序列化上传状态失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".info-*")
+	if err != nil {
+		return fmt.Errorf("写入上传状态失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("写入上传状态失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("写入上传状态失败: %w", err)
+	}
+	return nil
+}
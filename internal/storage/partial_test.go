@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPartialStore 测试本地磁盘分片上传存储
+func TestPartialStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	newUpload := func(id string, expiresAt time.Time) *PartialUpload {
+		return &PartialUpload{ID: id, OwnerID: 7, Length: 10, Metadata: map[string]string{"filename": "a.txt"}, CreatedAt: now, ExpiresAt: expiresAt}
+	}
+
+	t.Run("分片追加与重启后继续上传", func(t *testing.T) {
+		dir := t.TempDir()
+		store := NewPartialStore(dir)
+		id := strings.Repeat("a", 32)
+		require.NoError(t, store.Create(ctx, newUpload(id, now.Add(time.Hour))))
+
+		offset, err := store.Append(ctx, id, 0, strings.NewReader("hello"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), offset)
+
+		_, err = store.Append(ctx, id, 0, strings.NewReader("again"))
+		assert.ErrorIs(t, err, ErrOffsetMismatch)
+
+		// 新的存储实例读取同一目录，相当于服务重启
+		store = NewPartialStore(dir)
+		upload, err := store.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), upload.Offset)
+		assert.Equal(t, int64(7), upload.OwnerID)
+		assert.Equal(t, "a.txt", upload.Metadata["filename"])
+
+		offset, err = store.Append(ctx, id, 5, strings.NewReader("world"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), offset)
+
+		r, err := store.Open(ctx, id)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(r)
+		r.Close()
+		assert.Equal(t, "helloworld", string(data))
+
+		require.NoError(t, store.Complete(ctx, id, 42))
+		upload, err = store.Get(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, upload.Completed())
+		assert.Equal(t, int64(42), upload.FileID)
+		assert.Equal(t, int64(10), upload.Offset)
+		_, err = store.Open(ctx, id)
+		assert.ErrorIs(t, err, ErrNotFound, "完成后删除已接收的内容")
+
+		require.NoError(t, store.Delete(ctx, id))
+		_, err = store.Get(ctx, id)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("无效ID", func(t *testing.T) {
+		store := NewPartialStore(t.TempDir())
+		_, err := store.Get(ctx, "../../etc/passwd")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		_, err = store.Get(ctx, strings.Repeat("b", 32))
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("清理过期的上传", func(t *testing.T) {
+		dir := t.TempDir()
+		store := NewPartialStore(dir)
+		expired, active := strings.Repeat("c", 32), strings.Repeat("d", 32)
+		require.NoError(t, store.Create(ctx, newUpload(expired, now.Add(-time.Minute))))
+		require.NoError(t, store.Create(ctx, newUpload(active, now.Add(time.Hour))))
+
+		purged, err := store.PurgeExpired(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		_, err = store.Get(ctx, expired)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.Get(ctx, active)
+		assert.NoError(t, err)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2, "只剩未过期上传的状态和内容文件")
+
+		purged, err = NewPartialStore(t.TempDir()+"/missing").PurgeExpired(ctx, now)
+		require.NoError(t, err)
+		assert.Zero(t, purged)
+	})
+}
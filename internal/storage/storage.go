@@ -0,0 +1,77 @@
+// Package storage 保存上传的文件
+//
+// 提供本地磁盘和 S3 兼容对象存储（AWS S3、MinIO 等）两种实现，由配置 storage.type 选择；
+// 对象键由服务端生成，不使用客户端提供的文件名。
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"gin/internal/config"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("文件不存在")
+
+// ErrInvalidKey 对象键格式无效
+var ErrInvalidKey = errors.New("文件键无效")
+
+// Storage 文件存储接口
+type Storage interface {
+	// Put 写入对象，size 为内容长度；同名对象会被覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，调用方负责关闭；对象不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Open 打开对象用于随机读取（如 HTTP Range 请求），size 为对象长度；对象不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string, size int64) (io.ReadSeekCloser, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// New 根据配置创建文件存储
+func New(cfg *config.StorageConfig) (Storage, error) {
+	switch cfg.Type {
+	case "", "local":
+		if cfg.Local.Dir == "" {
+			return nil, fmt.Errorf("storage.local.dir 不能为空")
+		}
+		return NewLocalStorage(cfg.Local.Dir), nil
+	case "s3":
+		return NewS3Storage(cfg.S3)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", cfg.Type)
+	}
+}
+
+// keyPattern 对象键格式：以 / 分隔的段，每段只包含字母、数字、点、下划线和连字符
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*(/[A-Za-z0-9_-][A-Za-z0-9._-]*)*$`)
+
+// ValidKey 检查对象键是否安全（不能是绝对路径，不能包含 .. 等特殊段）
+func ValidKey(key string) bool {
+	return len(key) <= 512 && keyPattern.MatchString(key) && !strings.Contains(key, "..")
+}
+
+// NewKey 生成新的对象键：日期/随机ID.扩展名，如 2024/01/02/3f2a….png
+// ext 为不带点的扩展名，可为空
+func NewKey(now time.Time, ext string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成文件键失败: %w", err)
+	}
+	key := now.UTC().Format("2006/01/02") + "/" + hex.EncodeToString(buf)
+	if ext != "" {
+		key += "." + ext
+	}
+	if !ValidKey(key) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	return key, nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gin/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidKey 测试对象键校验
+func TestValidKey(t *testing.T) {
+	valid := []string{"2024/01/02/abc.png", "avatar_1", "a-b/c.d.txt"}
+	for _, key := range valid {
+		assert.True(t, ValidKey(key), key)
+	}
+
+	invalid := []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b", "a/", "C:/tmp/x", `a\b`, ".hidden", "a/.git/config", "名字.png"}
+	for _, key := range invalid {
+		assert.False(t, ValidKey(key), key)
+	}
+}
+
+// TestNewKey 测试生成对象键
+func TestNewKey(t *testing.T) {
+	now := time.Date(2024, 1, 2, 23, 0, 0, 0, time.UTC)
+	key, err := NewKey(now, "png")
+	require.NoError(t, err)
+	assert.Regexp(t, `^2024/01/02/[0-9a-f]{32}\.png$`, key)
+
+	other, err := NewKey(now, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotContains(t, other, ".")
+}
+
+// TestLocalStorage 测试本地磁盘存储
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStorage(dir)
+	ctx := context.Background()
+
+	t.Run("写入读取和删除", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "2024/01/02/a.txt", strings.NewReader("hello"), 5, "text/plain"))
+
+		_, err := os.Stat(filepath.Join(dir, "2024", "01", "02", "a.txt"))
+		require.NoError(t, err)
+
+		r, err := store.Get(ctx, "2024/01/02/a.txt")
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		r.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+
+		rs, err := store.Open(ctx, "2024/01/02/a.txt", 5)
+		require.NoError(t, err)
+		_, err = rs.Seek(2, io.SeekStart)
+		require.NoError(t, err)
+		data, _ = io.ReadAll(rs)
+		rs.Close()
+		assert.Equal(t, "llo", string(data))
+
+		require.NoError(t, store.Delete(ctx, "2024/01/02/a.txt"))
+		_, err = store.Open(ctx, "2024/01/02/a.txt", 5)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.Get(ctx, "2024/01/02/a.txt")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, store.Delete(ctx, "2024/01/02/a.txt"), "删除不存在的文件不报错")
+	})
+
+	t.Run("内容长度不一致时不保存", func(t *testing.T) {
+		err := store.Put(ctx, "short.txt", strings.NewReader("abc"), 5, "text/plain")
+		assert.Error(t, err)
+		_, err = store.Get(ctx, "short.txt")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			assert.False(t, strings.HasPrefix(entry.Name(), ".upload-"), "临时文件应该被删除")
+		}
+	})
+
+	t.Run("拒绝路径穿越", func(t *testing.T) {
+		err := store.Put(ctx, "../escape.txt", strings.NewReader("x"), 1, "text/plain")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		_, err = os.Stat(filepath.Join(filepath.Dir(dir), "escape.txt"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+// TestNew 测试根据配置创建文件存储
+func TestNew(t *testing.T) {
+	t.Run("默认使用本地存储", func(t *testing.T) {
+		store, err := New(&config.StorageConfig{Local: config.LocalStorageConfig{Dir: t.TempDir()}})
+		require.NoError(t, err)
+		assert.IsType(t, &localStorage{}, store)
+	})
+
+	t.Run("S3必须配置存储桶和密钥", func(t *testing.T) {
+		_, err := New(&config.StorageConfig{Type: "s3", S3: config.S3StorageConfig{Endpoint: "http://localhost:9000"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("不支持的类型", func(t *testing.T) {
+		_, err := New(&config.StorageConfig{Type: "ftp"})
+		assert.Error(t, err)
+	})
+}